@@ -0,0 +1,333 @@
+package tengo
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/d5/tengo/v2/token"
+)
+
+var (
+	// DecimalDivisionScale is the number of the fractional digits of the
+	// quotient of a decimal division that is not a finite decimal fraction,
+	// e.g. 1/3. Note this setting applies to all compiler/VM instances in the
+	// process.
+	DecimalDivisionScale = 16
+)
+
+// maxBigIntShift is the maximum shift count of a bigint shift, which limits
+// the size of the result.
+const maxBigIntShift = 1 << 26
+
+// BigInt represents an arbitrary-precision integer value. The value must not
+// be modified after the object is created.
+type BigInt struct {
+	ObjectImpl
+	Value *big.Int
+}
+
+func (o *BigInt) String() string {
+	return o.Value.String()
+}
+
+// TypeName returns the name of the type.
+func (o *BigInt) TypeName() string {
+	return "bigint"
+}
+
+// BinaryOp returns another object that is the result of a given binary
+// operator and a right-hand side object. An int operand is promoted to a
+// bigint. The result is a float if the other operand is a float, and a
+// decimal if it's a decimal.
+func (o *BigInt) BinaryOp(op token.Token, rhs Object) (Object, error) {
+	switch rhs := rhs.(type) {
+	case *BigInt:
+		return bigIntOp(op, o.Value, rhs.Value)
+	case *Int:
+		return bigIntOp(op, o.Value, big.NewInt(rhs.Value))
+	case *Float:
+		return (&Float{Value: bigIntToFloat(o.Value)}).BinaryOp(op, rhs)
+	case *Decimal:
+		return decimalOp(op, new(big.Rat).SetInt(o.Value), rhs.Value)
+	}
+	return nil, ErrInvalidOperator
+}
+
+// Copy returns a copy of the type.
+func (o *BigInt) Copy() Object {
+	return &BigInt{Value: new(big.Int).Set(o.Value)}
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (o *BigInt) IsFalsy() bool {
+	return o.Value.Sign() == 0
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object. A bigint is equal to an int or a decimal of the same value.
+func (o *BigInt) Equals(x Object) bool {
+	switch x := x.(type) {
+	case *BigInt:
+		return o.Value.Cmp(x.Value) == 0
+	case *Int:
+		return o.Value.IsInt64() && o.Value.Int64() == x.Value
+	case *Decimal:
+		return x.Value.IsInt() && o.Value.Cmp(x.Value.Num()) == 0
+	}
+	return false
+}
+
+// Hash returns the hash of the value, which is the hash of the int of the
+// same value if it fits in an int.
+func (o *BigInt) Hash() uint64 {
+	return hashBigInt(o.Value)
+}
+
+// Decimal represents an arbitrary-precision decimal value. The value is a
+// finite decimal fraction: the quotient of a division that doesn't terminate
+// is rounded half away from zero to DecimalDivisionScale fractional digits.
+// The value must not be modified after the object is created.
+type Decimal struct {
+	ObjectImpl
+	Value *big.Rat
+}
+
+func (o *Decimal) String() string {
+	return o.Value.FloatString(decimalScale(o.Value))
+}
+
+// TypeName returns the name of the type.
+func (o *Decimal) TypeName() string {
+	return "decimal"
+}
+
+// BinaryOp returns another object that is the result of a given binary
+// operator and a right-hand side object. Int, bigint and float operands are
+// promoted to decimals, and the result is a decimal. A float is converted
+// to the shortest decimal that represents it, e.g. 0.1 is exactly 0.1.
+func (o *Decimal) BinaryOp(op token.Token, rhs Object) (Object, error) {
+	var y *big.Rat
+	switch rhs := rhs.(type) {
+	case *Decimal:
+		y = rhs.Value
+	case *Int:
+		y = new(big.Rat).SetInt64(rhs.Value)
+	case *BigInt:
+		y = new(big.Rat).SetInt(rhs.Value)
+	case *Float:
+		var ok bool
+		if y, ok = floatToDecimal(rhs.Value); !ok {
+			return nil, ErrInvalidOperator
+		}
+	default:
+		return nil, ErrInvalidOperator
+	}
+	return decimalOp(op, o.Value, y)
+}
+
+// Copy returns a copy of the type.
+func (o *Decimal) Copy() Object {
+	return &Decimal{Value: new(big.Rat).Set(o.Value)}
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (o *Decimal) IsFalsy() bool {
+	return o.Value.Sign() == 0
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object. A decimal is equal to an int or a bigint of the same
+// value.
+func (o *Decimal) Equals(x Object) bool {
+	switch x := x.(type) {
+	case *Decimal:
+		return o.Value.Cmp(x.Value) == 0
+	case *Int:
+		return o.Value.IsInt() && o.Value.Num().IsInt64() &&
+			o.Value.Num().Int64() == x.Value
+	case *BigInt:
+		return o.Value.IsInt() && o.Value.Num().Cmp(x.Value) == 0
+	}
+	return false
+}
+
+// Hash returns the hash of the value, which is the hash of the bigint of the
+// same value if it's an integer.
+func (o *Decimal) Hash() uint64 {
+	if o.Value.IsInt() {
+		return hashBigInt(o.Value.Num())
+	}
+	h := hashUint64(hashSeedDecimal, hashBigInt(o.Value.Num()))
+	return hashUint64(h, hashBigInt(o.Value.Denom()))
+}
+
+func bigIntOp(op token.Token, x, y *big.Int) (Object, error) {
+	r := new(big.Int)
+	switch op {
+	case token.Add:
+		r.Add(x, y)
+	case token.Sub:
+		r.Sub(x, y)
+	case token.Mul:
+		r.Mul(x, y)
+	case token.Quo:
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		r.Quo(x, y)
+	case token.Rem:
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		r.Rem(x, y)
+	case token.And:
+		r.And(x, y)
+	case token.Or:
+		r.Or(x, y)
+	case token.Xor:
+		r.Xor(x, y)
+	case token.AndNot:
+		r.AndNot(x, y)
+	case token.Shl, token.Shr:
+		if y.Sign() < 0 || y.Cmp(big.NewInt(maxBigIntShift)) > 0 {
+			return nil, ErrInvalidOperator
+		}
+		if op == token.Shl {
+			r.Lsh(x, uint(y.Uint64()))
+		} else {
+			r.Rsh(x, uint(y.Uint64()))
+		}
+	default:
+		return compareOp(op, x.Cmp(y))
+	}
+	return &BigInt{Value: r}, nil
+}
+
+func decimalOp(op token.Token, x, y *big.Rat) (Object, error) {
+	r := new(big.Rat)
+	switch op {
+	case token.Add:
+		r.Add(x, y)
+	case token.Sub:
+		r.Sub(x, y)
+	case token.Mul:
+		r.Mul(x, y)
+	case token.Quo:
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		r = decimalValue(r.Quo(x, y))
+	case token.Rem:
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		// x - y*q where q is the quotient truncated toward zero
+		q := new(big.Int).Mul(x.Num(), y.Denom())
+		q.Quo(q, new(big.Int).Mul(x.Denom(), y.Num()))
+		r.Sub(x, r.Mul(y, r.SetInt(q)))
+	default:
+		return compareOp(op, x.Cmp(y))
+	}
+	return &Decimal{Value: r}, nil
+}
+
+// compareOp returns the result of the comparison operator op for the result
+// c of the comparison of the operands.
+func compareOp(op token.Token, c int) (Object, error) {
+	var res bool
+	switch op {
+	case token.Less:
+		res = c < 0
+	case token.Greater:
+		res = c > 0
+	case token.LessEq:
+		res = c <= 0
+	case token.GreaterEq:
+		res = c >= 0
+	default:
+		return nil, ErrInvalidOperator
+	}
+	if res {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
+func bigIntToFloat(x *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(x).Float64()
+	return f
+}
+
+// floatToDecimal returns the shortest decimal that represents f. It returns
+// false if f is an infinity or NaN.
+func floatToDecimal(f float64) (*big.Rat, bool) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// decimalValue returns r, or r rounded to DecimalDivisionScale fractional
+// digits if it's not a finite decimal fraction.
+func decimalValue(r *big.Rat) *big.Rat {
+	if _, ok := decimalDigits(r); ok {
+		return r
+	}
+	r, _ = new(big.Rat).SetString(r.FloatString(DecimalDivisionScale))
+	return r
+}
+
+// decimalScale returns the number of the fractional digits to print r
+// exactly, or DecimalDivisionScale if it's not a finite decimal fraction.
+func decimalScale(r *big.Rat) int {
+	if n, ok := decimalDigits(r); ok {
+		return n
+	}
+	return DecimalDivisionScale
+}
+
+// decimalDigits returns the number of the fractional digits of r. It returns
+// false if r is not a finite decimal fraction, i.e. the denominator has a
+// prime factor other than 2 and 5.
+func decimalDigits(r *big.Rat) (int, bool) {
+	if r.IsInt() {
+		return 0, true
+	}
+	d := new(big.Int).Set(r.Denom())
+	twos := int(d.TrailingZeroBits())
+	d.Rsh(d, uint(twos))
+	fives := 0
+	five, m := big.NewInt(5), new(big.Int)
+	for d.BitLen() > 1 {
+		if d.QuoRem(d, five, m); m.Sign() != 0 {
+			return 0, false
+		}
+		fives++
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+// parseDecimal parses the decimal number s, which may have an exponent.
+func parseDecimal(s string) (*big.Rat, bool) {
+	for _, c := range s {
+		if c == '/' {
+			return nil, false // fractions are not decimals
+		}
+	}
+	return new(big.Rat).SetString(s)
+}
+
+func hashBigInt(x *big.Int) uint64 {
+	if x.IsInt64() {
+		return hashUint64(hashSeedInt, uint64(x.Int64())) // same as the int
+	}
+	h := hashUint64(hashSeedBigInt, uint64(x.Sign()+1))
+	for _, w := range x.Bits() {
+		h = hashUint64(h, uint64(w))
+	}
+	return h
+}
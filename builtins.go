@@ -9,22 +9,13 @@ var builtinFuncs = []*BuiltinFunction{
 		Name:  "copy",
 		Value: builtinCopy,
 	},
-	{
-		Name:  "append",
-		Value: builtinAppend,
-	},
+	vmBuiltin("append", builtinAppend),
 	{
 		Name:  "delete",
 		Value: builtinDelete,
 	},
-	{
-		Name:  "splice",
-		Value: builtinSplice,
-	},
-	{
-		Name:  "string",
-		Value: builtinString,
-	},
+	vmBuiltin("splice", builtinSplice),
+	vmBuiltin("string", builtinString),
 	{
 		Name:  "int",
 		Value: builtinInt,
@@ -41,10 +32,7 @@ var builtinFuncs = []*BuiltinFunction{
 		Name:  "char",
 		Value: builtinChar,
 	},
-	{
-		Name:  "bytes",
-		Value: builtinBytes,
-	},
+	vmBuiltin("bytes", builtinBytes),
 	{
 		Name:  "time",
 		Value: builtinTime,
@@ -121,6 +109,38 @@ var builtinFuncs = []*BuiltinFunction{
 		Name:  "format",
 		Value: builtinFormat,
 	},
+	{
+		Name:  "is_record",
+		Value: builtinIsRecord,
+	},
+	{
+		Name:  "bigint",
+		Value: builtinBigInt,
+	},
+	{
+		Name:  "decimal",
+		Value: builtinDecimal,
+	},
+	{
+		Name:  "is_bigint",
+		Value: builtinIsBigInt,
+	},
+	{
+		Name:  "is_decimal",
+		Value: builtinIsDecimal,
+	},
+}
+
+// vmBuiltin returns a builtin function that receives the calling VM, e.g. to
+// count the allocated memory. The VM is nil if it's called outside of a VM.
+func vmBuiltin(name string, fn VMCallableFunc) *BuiltinFunction {
+	return &BuiltinFunction{
+		Name: name,
+		Value: func(args ...Object) (Object, error) {
+			return fn(nil, args...)
+		},
+		vmValue: fn,
+	}
 }
 
 // GetAllBuiltinFunctions returns all builtin function objects.
@@ -155,6 +175,26 @@ func builtinIsInt(args ...Object) (Object, error) {
 	return FalseValue, nil
 }
 
+func builtinIsBigInt(args ...Object) (Object, error) {
+	if len(args) != 1 {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*BigInt); ok {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
+func builtinIsDecimal(args ...Object) (Object, error) {
+	if len(args) != 1 {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*Decimal); ok {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
 func builtinIsFloat(args ...Object) (Object, error) {
 	if len(args) != 1 {
 		return nil, ErrWrongNumArguments
@@ -286,6 +326,27 @@ func builtinIsCallable(args ...Object) (Object, error) {
 	return FalseValue, nil
 }
 
+func builtinIsRecord(args ...Object) (Object, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, ErrWrongNumArguments
+	}
+	var typ *RecordType
+	if len(args) == 2 {
+		var ok bool
+		if typ, ok = args[1].(*RecordType); !ok {
+			return nil, ErrInvalidArgumentType{
+				Name:     "second",
+				Expected: "record-type",
+				Found:    args[1].TypeName(),
+			}
+		}
+	}
+	if r, ok := args[0].(*Record); ok && (typ == nil || r.Type == typ) {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
 func builtinIsIterable(args ...Object) (Object, error) {
 	if len(args) != 1 {
 		return nil, ErrWrongNumArguments
@@ -311,9 +372,9 @@ func builtinLen(args ...Object) (Object, error) {
 	case *Bytes:
 		return &Int{Value: int64(len(arg.Value))}, nil
 	case *Map:
-		return &Int{Value: int64(len(arg.Value))}, nil
+		return &Int{Value: int64(arg.Len())}, nil
 	case *ImmutableMap:
-		return &Int{Value: int64(len(arg.Value))}, nil
+		return &Int{Value: int64(len(arg.Value) + arg.Hashed.Len())}, nil
 	default:
 		return nil, ErrInvalidArgumentType{
 			Name:     "first",
@@ -354,7 +415,7 @@ func builtinCopy(args ...Object) (Object, error) {
 	return args[0].Copy(), nil
 }
 
-func builtinString(args ...Object) (Object, error) {
+func builtinString(vm *VM, args ...Object) (Object, error) {
 	argsLen := len(args)
 	if !(argsLen == 1 || argsLen == 2) {
 		return nil, ErrWrongNumArguments
@@ -367,6 +428,9 @@ func builtinString(args ...Object) (Object, error) {
 		if len(v) > MaxStringLen {
 			return nil, ErrStringLimit
 		}
+		if err := vm.Allocate(int64(len(v))); err != nil {
+			return nil, err
+		}
 		return &String{Value: v}, nil
 	}
 	if argsLen == 2 {
@@ -393,6 +457,42 @@ func builtinInt(args ...Object) (Object, error) {
 	return UndefinedValue, nil
 }
 
+func builtinBigInt(args ...Object) (Object, error) {
+	argsLen := len(args)
+	if !(argsLen == 1 || argsLen == 2) {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*BigInt); ok {
+		return args[0], nil
+	}
+	v, ok := ToBigInt(args[0])
+	if ok {
+		return &BigInt{Value: v}, nil
+	}
+	if argsLen == 2 {
+		return args[1], nil
+	}
+	return UndefinedValue, nil
+}
+
+func builtinDecimal(args ...Object) (Object, error) {
+	argsLen := len(args)
+	if !(argsLen == 1 || argsLen == 2) {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*Decimal); ok {
+		return args[0], nil
+	}
+	v, ok := ToDecimal(args[0])
+	if ok {
+		return &Decimal{Value: v}, nil
+	}
+	if argsLen == 2 {
+		return args[1], nil
+	}
+	return UndefinedValue, nil
+}
+
 func builtinFloat(args ...Object) (Object, error) {
 	argsLen := len(args)
 	if !(argsLen == 1 || argsLen == 2) {
@@ -446,7 +546,7 @@ func builtinChar(args ...Object) (Object, error) {
 	return UndefinedValue, nil
 }
 
-func builtinBytes(args ...Object) (Object, error) {
+func builtinBytes(vm *VM, args ...Object) (Object, error) {
 	argsLen := len(args)
 	if !(argsLen == 1 || argsLen == 2) {
 		return nil, ErrWrongNumArguments
@@ -457,6 +557,9 @@ func builtinBytes(args ...Object) (Object, error) {
 		if n.Value > int64(MaxBytesLen) {
 			return nil, ErrBytesLimit
 		}
+		if err := vm.Allocate(n.Value); err != nil {
+			return nil, err
+		}
 		return &Bytes{Value: make([]byte, int(n.Value))}, nil
 	}
 	v, ok := ToByteSlice(args[0])
@@ -464,6 +567,9 @@ func builtinBytes(args ...Object) (Object, error) {
 		if len(v) > MaxBytesLen {
 			return nil, ErrBytesLimit
 		}
+		if err := vm.Allocate(int64(len(v))); err != nil {
+			return nil, err
+		}
 		return &Bytes{Value: v}, nil
 	}
 	if argsLen == 2 {
@@ -491,14 +597,22 @@ func builtinTime(args ...Object) (Object, error) {
 }
 
 // append(arr, items...)
-func builtinAppend(args ...Object) (Object, error) {
+func builtinAppend(vm *VM, args ...Object) (Object, error) {
 	if len(args) < 2 {
 		return nil, ErrWrongNumArguments
 	}
 	switch arg := args[0].(type) {
 	case *Array:
+		if err := vm.Allocate(objectRefSize *
+			int64(len(arg.Value)+len(args)-1)); err != nil {
+			return nil, err
+		}
 		return &Array{Value: append(arg.Value, args[1:]...)}, nil
 	case *ImmutableArray:
+		if err := vm.Allocate(objectRefSize *
+			int64(len(arg.Value)+len(args)-1)); err != nil {
+			return nil, err
+		}
 		return &Array{Value: append(arg.Value, args[1:]...)}, nil
 	default:
 		return nil, ErrInvalidArgumentType{
@@ -511,7 +625,7 @@ func builtinAppend(args ...Object) (Object, error) {
 
 // builtinDelete deletes Map keys
 // usage: delete(map, "key")
-// key must be a string
+// key must be hashable
 func builtinDelete(args ...Object) (Object, error) {
 	argsLen := len(args)
 	if argsLen != 2 {
@@ -519,15 +633,23 @@ func builtinDelete(args ...Object) (Object, error) {
 	}
 	switch arg := args[0].(type) {
 	case *Map:
-		if key, ok := args[1].(*String); ok {
-			delete(arg.Value, key.Value)
-			return UndefinedValue, nil
+		switch args[1].(type) {
+		case *Int, *BigInt, *Decimal, *Char:
+			// unlike the indexes, they're not converted to strings
+			return nil, ErrInvalidArgumentType{
+				Name:     "second",
+				Expected: "string",
+				Found:    args[1].TypeName(),
+			}
 		}
-		return nil, ErrInvalidArgumentType{
-			Name:     "second",
-			Expected: "string",
-			Found:    args[1].TypeName(),
+		if err := arg.Delete(args[1]); err != nil {
+			return nil, ErrInvalidArgumentType{
+				Name:     "second",
+				Expected: "hashable",
+				Found:    args[1].TypeName(),
+			}
 		}
+		return UndefinedValue, nil
 	default:
 		return nil, ErrInvalidArgumentType{
 			Name:     "first",
@@ -540,7 +662,7 @@ func builtinDelete(args ...Object) (Object, error) {
 // builtinSplice deletes and changes given Array, returns deleted items.
 // usage:
 // deleted_items := splice(array[,start[,delete_count[,item1[,item2[,...]]]])
-func builtinSplice(args ...Object) (Object, error) {
+func builtinSplice(vm *VM, args ...Object) (Object, error) {
 	argsLen := len(args)
 	if argsLen == 0 {
 		return nil, ErrWrongNumArguments
@@ -604,6 +726,10 @@ func builtinSplice(args ...Object) (Object, error) {
 		}
 	}
 	items = append(items, array.Value[endIdx:]...)
+	if err := vm.Allocate(objectRefSize *
+		int64(len(deleted)+len(items))); err != nil {
+		return nil, err
+	}
 	array.Value = append(head, items...)
 
 	// return deleted items
@@ -56,6 +56,12 @@ func Test_builtinDelete(t *testing.T) {
 			wantedErr: tengo.ErrInvalidArgumentType{
 				Name: "second", Expected: "string", Found: "int"},
 		},
+		{name: "nil-map-unhashable-key",
+			args: args{[]tengo.Object{
+				&tengo.Map{}, &tengo.Array{}}}, wantErr: true,
+			wantedErr: tengo.ErrInvalidArgumentType{
+				Name: "second", Expected: "hashable", Found: "array"},
+		},
 		{name: "nil-map-no-key",
 			args: args{[]tengo.Object{&tengo.Map{}}}, wantErr: true,
 			wantedErr: tengo.ErrWrongNumArguments,
@@ -115,6 +115,9 @@ func (b *Bytecode) RemoveDuplicates() {
 				indexMap[curIdx] = newIdx
 				deduped = append(deduped, c)
 			}
+		case *RecordType, *ImmutableArray:
+			indexMap[curIdx] = len(deduped)
+			deduped = append(deduped, c)
 		case *ImmutableMap:
 			modName := inferModuleName(c)
 			newIdx, ok := immutableMaps[modName]
@@ -219,6 +222,13 @@ func fixDecodedObject(
 			}
 			o.Value[k] = fv
 		}
+		for _, e := range o.Hashed.Entries() {
+			fv, err := fixDecodedObject(e.Value, modules)
+			if err != nil {
+				return nil, err
+			}
+			e.Value = fv
+		}
 	case *ImmutableMap:
 		modName := inferModuleName(o)
 		if mod := modules.GetBuiltinModule(modName); mod != nil {
@@ -227,7 +237,8 @@ func fixDecodedObject(
 
 		for k, v := range o.Value {
 			// encoding of user function not supported
-			if _, isUserFunction := v.(*UserFunction); isUserFunction {
+			switch v.(type) {
+			case *UserFunction, *VMFunction:
 				return nil, fmt.Errorf("user function not decodable")
 			}
 
@@ -249,21 +260,21 @@ func updateConstIndexes(insts []byte, indexMap map[int]int) {
 		_, read := parser.ReadOperands(numOperands, insts[i+1:])
 
 		switch op {
-		case parser.OpConstant:
+		case parser.OpConstant, parser.OpFormat:
 			curIdx := int(insts[i+2]) | int(insts[i+1])<<8
 			newIdx, ok := indexMap[curIdx]
 			if !ok {
 				panic(fmt.Errorf("constant index not found: %d", curIdx))
 			}
 			copy(insts[i:], MakeInstruction(op, newIdx))
-		case parser.OpClosure:
+		case parser.OpClosure, parser.OpRecord, parser.OpCallKw:
 			curIdx := int(insts[i+2]) | int(insts[i+1])<<8
-			numFree := int(insts[i+3])
+			operand := int(insts[i+3])
 			newIdx, ok := indexMap[curIdx]
 			if !ok {
 				panic(fmt.Errorf("constant index not found: %d", curIdx))
 			}
-			copy(insts[i:], MakeInstruction(op, newIdx, numFree))
+			copy(insts[i:], MakeInstruction(op, newIdx, operand))
 		}
 
 		i += 1 + read
@@ -291,8 +302,10 @@ func init() {
 	gob.Register(&ImmutableMap{})
 	gob.Register(&Int{})
 	gob.Register(&Map{})
+	gob.Register(&RecordType{})
 	gob.Register(&String{})
 	gob.Register(&Time{})
 	gob.Register(&Undefined{})
 	gob.Register(&UserFunction{})
+	gob.Register(&VMFunction{})
 }
@@ -230,6 +230,29 @@ func TestBytecode_RemoveDuplicates(t *testing.T) {
 				&tengo.Int{Value: 1},
 				&tengo.Int{Value: 2},
 				&tengo.Int{Value: 3})))
+
+	// keyword argument names
+	names := &tengo.ImmutableArray{Value: []tengo.Object{
+		&tengo.String{Value: "a"},
+	}}
+	testBytecodeRemoveDuplicates(t,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpCallKw, 2, 1)),
+			objectsArray(
+				&tengo.Int{Value: 1},
+				&tengo.Int{Value: 1},
+				names)),
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpCallKw, 1, 1)),
+			objectsArray(
+				&tengo.Int{Value: 1},
+				names)))
 }
 
 func TestBytecode_CountObjects(t *testing.T) {
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/stdlib"
+)
+
+// memberPrefixRE matches the text before the cursor when the user types a
+// member of a module, e.g. 'text.con' or 'import("text").con'.
+var memberPrefixRE = regexp.MustCompile(
+	`(?:import\(\s*"(\w+)"\s*\)|([A-Za-z_]\w*))\s*\.\s*\w*$`)
+
+// completion returns the members of the module before the dot at the
+// position.
+func (d *document) completion(pos Position) []CompletionItem {
+	offset := d.offset(pos)
+	lineStart := d.lines[d.position(offset).Line]
+	m := memberPrefixRE.FindStringSubmatch(d.text[lineStart:offset])
+	if m == nil {
+		return nil
+	}
+	module := m[1]
+	if module == "" {
+		module = d.moduleVars[m[2]]
+	}
+
+	var items []CompletionItem
+	for _, member := range moduleMembers(module) {
+		item := CompletionItem{Label: member.name, Kind: CompletionConstant}
+		if member.callable {
+			item.Kind = CompletionFunction
+		}
+		if doc, ok := moduleDocs[module][member.name]; ok {
+			item.Detail = doc.Signature
+			item.Documentation = doc.Doc
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+type moduleMember struct {
+	name     string
+	callable bool
+}
+
+// moduleMembers returns the members of the standard library module sorted by
+// the name.
+func moduleMembers(module string) []moduleMember {
+	var members []moduleMember
+	if mod, ok := stdlib.BuiltinModules[module]; ok {
+		for name, obj := range mod {
+			members = append(members, moduleMember{
+				name:     name,
+				callable: obj.CanCall(),
+			})
+		}
+	} else if src, ok := stdlib.SourceModules[module]; ok {
+		for _, elem := range exportedElements(parseModule(module, src)) {
+			_, callable := elem.Value.(*parser.FuncLit)
+			members = append(members, moduleMember{
+				name:     elem.Key,
+				callable: callable,
+			})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].name < members[j].name
+	})
+	return members
+}
+
+func parseModule(name, src string) *parser.File {
+	fileSet := parser.NewFileSet()
+	file := fileSet.AddFile(name, -1, len(src))
+	p := parser.NewParser(file, []byte(src), nil)
+	parsed, err := p.ParseFile()
+	if err != nil {
+		return nil
+	}
+	return parsed
+}
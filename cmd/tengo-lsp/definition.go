@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/d5/tengo/v2"
+)
+
+// definition returns the location where the identifier at the position is
+// defined; or nil.
+func (d *document) definition(pos Position) *Location {
+	ref := d.refAt(d.offset(pos))
+	if ref == nil || ref.def == nil {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.nodeRange(ref.def)}
+}
+
+// references returns the locations of the identifiers that refer to the
+// same symbol as the identifier at the position.
+func (d *document) references(
+	pos Position,
+	includeDeclaration bool,
+) []Location {
+	target := d.refAt(d.offset(pos))
+	if target == nil {
+		return nil
+	}
+
+	locations := []Location{}
+	for _, ref := range d.refs {
+		if !includeDeclaration && ref.ident == ref.def {
+			continue
+		}
+		if ref.def != target.def {
+			continue
+		}
+		if ref.def == nil && (ref.symbol.Scope != tengo.ScopeBuiltin ||
+			ref.ident.Name != target.ident.Name) {
+			continue
+		}
+		locations = append(locations, Location{
+			URI:   d.uri,
+			Range: d.nodeRange(ref.ident),
+		})
+	}
+	return locations
+}
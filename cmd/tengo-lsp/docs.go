@@ -0,0 +1,367 @@
+// Code generated using gendocs.go; DO NOT EDIT.
+
+package main
+
+// builtinDocs are the descriptions of the builtin functions.
+var builtinDocs = map[string]string{
+	"append":             "Appends object(s) to an array (first argument) and returns a new array object. (Like Go's `append` builtin.) Currently, this function takes array type only.",
+	"bigint":             "Tries to convert an object to bigint object. Floats and decimals are truncated toward zero, and strings are parsed as decimal integers.",
+	"bool":               "Tries to convert an object to bool object. See [this](https://github.com/d5/tengo/blob/master/docs/runtime-types.md) for more details on type conversion.",
+	"bytes":              "Tries to convert an object to bytes object. See [this](https://github.com/d5/tengo/blob/master/docs/runtime-types.md) for more details on type conversion.",
+	"char":               "Tries to convert an object to char object. See [this](https://github.com/d5/tengo/blob/master/docs/runtime-types.md) for more details on type conversion.",
+	"copy":               "Creates a copy of the given variable. `copy` function calls `Object.Copy` interface method, which is expected to return a deep-copy of the value it holds.",
+	"decimal":            "Tries to convert an object to decimal object. Floats are converted to their shortest decimal representations, and strings are parsed as decimal numbers with optional exponents.",
+	"delete":             "Deletes the element with the specified key from the map type. First argument must be a map type and second argument must be a [hashable](https://github.com/d5/tengo/blob/master/docs/runtime-types.md#hashable-map-keys) type other than an int, a bigint, a decimal or a char, which are converted to strings in the map. `delete` returns `undefined` value if successful and it mutates given map.",
+	"float":              "Tries to convert an object to float object. See [this](https://github.com/d5/tengo/blob/master/docs/runtime-types.md) for more details on type conversion.",
+	"format":             "Returns a formatted string. The first argument must be a String object. See [this](https://github.com/d5/tengo/blob/master/docs/formatting.md) for more details on formatting.",
+	"int":                "Tries to convert an object to int object. See [this](https://github.com/d5/tengo/blob/master/docs/runtime-types.md) for more details on type conversion.",
+	"is_array":           "Returns `true` if the object's type is array. Or it returns `false`.",
+	"is_bigint":          "Returns `true` if the object's type is bigint. Or it returns `false`.",
+	"is_bool":            "Returns `true` if the object's type is bool. Or it returns `false`.",
+	"is_bytes":           "Returns `true` if the object's type is bytes. Or it returns `false`.",
+	"is_callable":        "Returns `true` if the object is callable (e.g. function, closure, builtin function, or user-provided callable objects). Or it returns `false`.",
+	"is_char":            "Returns `true` if the object's type is char. Or it returns `false`.",
+	"is_decimal":         "Returns `true` if the object's type is decimal. Or it returns `false`.",
+	"is_error":           "Returns `true` if the object's type is error. Or it returns `false`.",
+	"is_float":           "Returns `true` if the object's type is float. Or it returns `false`.",
+	"is_function":        "Returns `true` if the object's type is function or closure. Or it returns `false`. Note that `is_function` returns `false` for builtin functions and user-provided callable objects.",
+	"is_immutable_array": "Returns `true` if the object's type is immutable array. Or it returns `false`.",
+	"is_immutable_map":   "Returns `true` if the object's type is immutable map. Or it returns `false`.",
+	"is_int":             "Returns `true` if the object's type is int. Or it returns `false`.",
+	"is_iterable":        "Returns `true` if the object's type is iterable: array, immutable array, map, immutable map, string, and bytes are iterable types in Tengo.",
+	"is_map":             "Returns `true` if the object's type is map. Or it returns `false`.",
+	"is_record":          "Returns `true` if the object is a [record](https://github.com/d5/tengo/blob/master/docs/tutorial.md#record-types). Or it returns `false`. If the second argument is a record type, it returns `true` only if the object is a record of the type.",
+	"is_string":          "Returns `true` if the object's type is string. Or it returns `false`.",
+	"is_undefined":       "Returns `true` if the object's type is undefined. Or it returns `false`.",
+	"len":                "Returns the number of elements if the given variable is array, string, map, or module map.",
+	"splice":             "Deletes and/or changes the contents of a given array and returns deleted items as a new array. `splice` is similar to JS `Array.prototype.splice()` except splice is a builtin function and first argument must an array. First argument must be an array, and if second and third arguments are provided those must be integers otherwise runtime error is returned.",
+	"string":             "Tries to convert an object to string object. See [Runtime Types](https://github.com/d5/tengo/blob/master/docs/runtime-types.md) for more details on type conversion.",
+	"time":               "Tries to convert an object to time value.",
+	"type_name":          "Returns the type_name of an object.",
+}
+
+// moduleDocs are the signatures and descriptions of the standard library
+// module members.
+var moduleDocs = map[string]map[string]memberDoc{
+	"base64": {
+		"decode":         {"decode(s)", "returns the bytes represented by the base64 string s."},
+		"encode":         {"encode(src)", "returns the base64 encoding of src."},
+		"raw_decode":     {"raw_decode(s)", "returns the bytes represented by the base64 string s which omits the padding."},
+		"raw_encode":     {"raw_encode(src)", "returns the base64 encoding of src but omits the padding."},
+		"raw_url_decode": {"raw_url_decode(s)", "returns the bytes represented by the url-base64 string s which omits the padding."},
+		"raw_url_encode": {"raw_url_encode(src)", "returns the url-base64 encoding of src but omits the padding."},
+		"url_decode":     {"url_decode(s)", "returns the bytes represented by the url-base64 string s."},
+		"url_encode":     {"url_encode(src)", "returns the url-base64 encoding of src."},
+	},
+	"concurrency": {
+		"chan":       {"chan(size int) => chan", "returns a channel of objects with the buffer size. The size is 0 (unbuffered) if omitted."},
+		"go":         {"go(fn function, args...) => task", "calls the function with the arguments in a new goroutine on a child VM, and returns a task. The child VM runs on copies of the global variables, the function, its captured variables and the arguments, so the changes to them are not seen by the caller. Use channels to share the values between the VMs. Generators can't be copied, and it returns a runtime error if they are passed to the child VM. The allocation, instruction and memory limits of the script apply to the total of the script and all its child VMs. The child VMs are aborted when the script is aborted or finishes."},
+		"mutex":      {"mutex() => mutex", "returns a mutual exclusion lock."},
+		"select":     {"select(cases...) => [int, object]", "blocks until one of the cases can proceed. A case is a channel to receive a value from, or an array of a channel and a value to send (a copy of the value is sent). It returns the index of the case and the received value. It returns an error if the chosen case sends to a closed channel."},
+		"wait":       {"wait(tasks...) => [object]", "waits for the tasks to finish, and returns their return values."},
+		"wait_group": {"wait_group() => wait_group", "returns a counter of the tasks to wait for."},
+	},
+	"enum": {
+		"all":      {"all(x, fn) => bool", "returns true if the given function `fn` evaluates to a truthy value on all of the items in `x`. It returns undefined if `x` is not enumerable."},
+		"any":      {"any(x, fn) => bool", "returns true if the given function `fn` evaluates to a truthy value on any of the items in `x`. It returns undefined if `x` is not enumerable."},
+		"at":       {"at(x, key) => object", "returns an element at the given index (if `x` is array) or key (if `x` is map). It returns undefined if `x` is not enumerable."},
+		"chunk":    {"chunk(x, size) => [object]", "returns an array of elements split into groups the length of size. If `x` can't be split evenly, the final chunk will be the remaining elements. It returns undefined if `x` is not array."},
+		"each":     {"each(x, fn)", "iterates over elements of `x` and invokes `fn` for each element. `fn` is invoked with two arguments: `key` and `value`. `key` is an int index if `x` is array. `key` is a map key if `x` is map. It does not iterate and returns undefined if `x` is not enumerable.`"},
+		"filter":   {"filter(x, fn) => [object]", "iterates over elements of `x`, returning an array of all elements `fn` returns truthy for. `fn` is invoked with two arguments: `key` and `value`. `key` is an int index if `x` is array. `key` is a map key if `x` is map. It returns undefined if `x` is not enumerable."},
+		"find":     {"find(x, fn) => object", "iterates over elements of `x`, returning value of the first element `fn` returns truthy for. `fn` is invoked with two arguments: `key` and `value`. `key` is an int index if `x` is array. `key` is a map key if `x` is map. It returns undefined if `x` is not enumerable."},
+		"find_key": {"find_key(x, fn) => int/string", "iterates over elements of `x`, returning key or index of the first element `fn` returns truthy for. `fn` is invoked with two arguments: `key` and `value`. `key` is an int index if `x` is array. `key` is a map key if `x` is map. It returns undefined if `x` is not enumerable."},
+		"key":      {"key(k, _) => object", "returns the first argument."},
+		"map":      {"map(x, fn) => [object]", "creates an array of values by running each element in `x` through `fn`. `fn` is invoked with two arguments: `key` and `value`. `key` is an int index if `x` is array. `key` is a map key if `x` is map. It returns undefined if `x` is not enumerable."},
+		"value":    {"value(_, v) => object", "returns the second argument."},
+	},
+	"fmt": {
+		"print":   {"print(args...)", "Prints a string representation of the given variable to the standard output. Unlike Go's `fmt.Print` function, no spaces are added between the operands."},
+		"printf":  {"printf(format, args...)", "Prints a formatted string to the standard output. It does not append the newline character at the end. The first argument must a String object. See [this](https://github.com/d5/tengo/blob/master/docs/formatting.md) for more details on formatting."},
+		"println": {"println(args...)", "Prints a string representation of the given variable to the standard output with a newline appended. Unlike Go's `fmt.Println` function, no spaces are added between the operands."},
+		"sprintf": {"sprintf(format, args...)", "Returns a formatted string. Alias of the builtin function `format`. The first argument must be a String object. See [this](https://github.com/d5/tengo/blob/master/docs/formatting.md) for more details on formatting."},
+	},
+	"hex": {
+		"decode": {"decode(s)", "returns the bytes represented by the hexadecimal string s."},
+		"encode": {"encode(src)", "returns the hexadecimal encoding of src."},
+	},
+	"http": {
+		"get":     {"get(url string, options map) => response/error", "sends a GET request to the URL, and returns the response."},
+		"post":    {"post(url string, body string/bytes, options map) => response/error", "sends a POST request of the body to the URL, and returns the response."},
+		"request": {"request(method string, url string, options map) => response/error", "sends a request of the method to the URL, and returns the response."},
+	},
+	"json": {
+		"decode":      {"decode(b string/bytes, exact bool) => object", "Parses the JSON string and returns an object. The numbers are decoded as floats, unless `exact` is `true`: then the integers are decoded as ints, or bigints if they overflow an int, and the other numbers as decimals, without losing precision."},
+		"encode":      {"encode(o object) => bytes", "Returns the JSON string (bytes) of the object. Bigints and decimals are encoded as numbers without losing precision. Map keys other than strings are encoded as the strings of their JSON values, and it's an error if two keys of a map are encoded as the same string, e.g. `1.5` and `\"1.5\"`. Unlike Go's JSON package, this function does not HTML-escape texts, but, one can use `html_escape` function if needed."},
+		"html_escape": {"html_escape(b string/bytes) => bytes", "Return an HTML-safe form of input JSON bytes string."},
+		"indent":      {"indent(b string/bytes) => bytes", "Returns an indented form of input JSON bytes string."},
+	},
+	"math": {
+		"abs":       {"abs(x float) => float", "returns the absolute value of x."},
+		"acos":      {"acos(x float) => float", "returns the arccosine, in radians, of x."},
+		"acosh":     {"acosh(x float) => float", "returns the inverse hyperbolic cosine of x."},
+		"asin":      {"asin(x float) => float", "returns the arcsine, in radians, of x."},
+		"asinh":     {"asinh(x float) => float", "returns the inverse hyperbolic sine of x."},
+		"atan":      {"atan(x float) => float", "returns the arctangent, in radians, of x."},
+		"atan2":     {"atan2(y float, xfloat) => float", "returns the arc tangent of y/x, using the signs of the two to determine the quadrant of the return value."},
+		"atanh":     {"atanh(x float) => float", "returns the inverse hyperbolic tangent of x."},
+		"cbrt":      {"cbrt(x float) => float", "returns the cube root of x."},
+		"ceil":      {"ceil(x float) => float", "returns the least integer value greater than or equal to x."},
+		"copysign":  {"copysign(x float, y float) => float", "returns a value with the magnitude of x and the sign of y."},
+		"cos":       {"cos(x float) => float", "returns the cosine of the radian argument x."},
+		"cosh":      {"cosh(x float) => float", "returns the hyperbolic cosine of x."},
+		"dim":       {"dim(x float, y float) => float", "returns the maximum of x-y or 0."},
+		"e":         {"e", ""},
+		"erf":       {"erf(x float) => float", "returns the error function of x."},
+		"erfc":      {"erfc(x float) => float", "returns the complementary error function of x."},
+		"exp":       {"exp(x float) => float", "returns e**x, the base-e exponential of x."},
+		"exp2":      {"exp2(x float) => float", "returns 2**x, the base-2 exponential of x."},
+		"expm1":     {"expm1(x float) => float", "returns e**x - 1, the base-e exponential of x minus 1. It is more accurate than Exp(x) - 1 when x is near zero."},
+		"floor":     {"floor(x float) => float", "returns the greatest integer value less than or equal to x."},
+		"gamma":     {"gamma(x float) => float", "returns the Gamma function of x."},
+		"hypot":     {"hypot(p float, q float) => float", "returns `Sqrt(p * p + q * q)`, taking care to avoid unnecessary overflow and underflow."},
+		"ilogb":     {"ilogb(x float) => float", "returns the binary exponent of x as an integer."},
+		"inf":       {"inf(sign int) => float", "returns positive infinity if sign >= 0, negative infinity if sign < 0."},
+		"is_inf":    {"is_inf(f float, sign int) => float", "reports whether f is an infinity, according to sign. If sign > 0, IsInf reports whether f is positive infinity. If sign < 0, IsInf reports whether f is negative infinity. If sign == 0, IsInf reports whether f is either infinity."},
+		"is_nan":    {"is_nan(f float) => float", "reports whether f is an IEEE 754 ``not-a-number'' value."},
+		"j0":        {"j0(x float) => float", "returns the order-zero Bessel function of the first kind."},
+		"j1":        {"j1(x float) => float", "returns the order-one Bessel function of the first kind."},
+		"jn":        {"jn(n int, x float) => float", "returns the order-n Bessel function of the first kind."},
+		"ldexp":     {"ldexp(frac float, exp int) => float", "is the inverse of frexp. It returns frac × 2**exp."},
+		"ln10":      {"ln10", ""},
+		"ln10E":     {"ln10E", ""},
+		"ln2":       {"ln2", ""},
+		"log":       {"log(x float) => float", "returns the natural logarithm of x."},
+		"log10":     {"log10(x float) => float", "returns the decimal logarithm of x."},
+		"log1p":     {"log1p(x float) => float", "returns the natural logarithm of 1 plus its argument x. It is more accurate than Log(1 + x) when x is near zero."},
+		"log2":      {"log2(x float) => float", "returns the binary logarithm of x."},
+		"log2E":     {"log2E", ""},
+		"logb":      {"logb(x float) => float", "returns the binary exponent of x."},
+		"max":       {"max(x float, y float) => float", "returns the larger of x or y."},
+		"min":       {"min(x float, y float) => float", "returns the smaller of x or y."},
+		"mod":       {"mod(x float, y float) => float", "returns the floating-point remainder of x/y."},
+		"nan":       {"nan() => float", "returns an IEEE 754 ``not-a-number'' value."},
+		"nextafter": {"nextafter(x float, y float) => float", "returns the next representable float64 value after x towards y."},
+		"phi":       {"phi", ""},
+		"pi":        {"pi", ""},
+		"pow":       {"pow(x float, y float) => float", "returns x**y, the base-x exponential of y."},
+		"pow10":     {"pow10(n int) => float", "returns 10**n, the base-10 exponential of n."},
+		"remainder": {"remainder(x float, y float) => float", "returns the IEEE 754 floating-point remainder of x/y."},
+		"signbit":   {"signbit(x float) => float", "returns true if x is negative or negative zero."},
+		"sin":       {"sin(x float) => float", "returns the sine of the radian argument x."},
+		"sinh":      {"sinh(x float) => float", "returns the hyperbolic sine of x."},
+		"sprtPi":    {"sprtPi", ""},
+		"sqrt":      {"sqrt(x float) => float", "returns the square root of x."},
+		"sqrt2":     {"sqrt2", ""},
+		"sqrtE":     {"sqrtE", ""},
+		"sqrtPhi":   {"sqrtPhi", ""},
+		"tan":       {"tan(x float) => float", "returns the tangent of the radian argument x."},
+		"tanh":      {"tanh(x float) => float", "returns the hyperbolic tangent of x."},
+		"trunc":     {"trunc(x float) => float", "returns the integer value of x."},
+		"y0":        {"y0(x float) => float", "returns the order-zero Bessel function of the second kind."},
+		"y1":        {"y1(x float) => float", "returns the order-one Bessel function of the second kind."},
+		"yn":        {"yn(n int, x float) => float", "returns the order-n Bessel function of the second kind."},
+	},
+	"os": {
+		"args":                {"args() => [string]", "returns command-line arguments, starting with the program name."},
+		"chdir":               {"chdir(dir string) => error", "changes the current working directory to the named directory."},
+		"chmod":               {"chmod(name string, mode int) => error", "changes the mode of the named file to mode."},
+		"chown":               {"chown(name string, uid int, gid int) => error", "changes the numeric uid and gid of the named file."},
+		"clearenv":            {"clearenv()", "deletes all environment variables."},
+		"create":              {"create(name string) => File/error", "creates the named file with mode 0666 (before umask), truncating it if it already exists."},
+		"dev_null":            {"dev_null", ""},
+		"environ":             {"environ() => [string]", "returns a copy of strings representing the environment."},
+		"exec":                {"exec(name string, args...) => Command/error", "returns the Command to execute the named program with the given arguments."},
+		"exec_look_path":      {"exec_look_path(file string) => string/error", "searches for an executable named file in the directories named by the PATH environment variable."},
+		"exit":                {"exit(code int)", "causes the current program to exit with the given status code."},
+		"expand_env":          {"expand_env(s string) => string", "replaces ${var} or $var in the string according to the values of the current environment variables."},
+		"find_process":        {"find_process(pid int) => Process/error", "looks for a running process by its pid."},
+		"getegid":             {"getegid() => int", "returns the numeric effective group id of the caller."},
+		"getenv":              {"getenv(key string) => string", "retrieves the value of the environment variable named by the key."},
+		"geteuid":             {"geteuid() => int", "returns the numeric effective user id of the caller."},
+		"getgid":              {"getgid() => int", "returns the numeric group id of the caller."},
+		"getgroups":           {"getgroups() => [int]/error", "returns a list of the numeric ids of groups that the caller belongs to."},
+		"getpagesize":         {"getpagesize() => int", "returns the underlying system's memory page size."},
+		"getpid":              {"getpid() => int", "returns the process id of the caller."},
+		"getppid":             {"getppid() => int", "returns the process id of the caller's parent."},
+		"getuid":              {"getuid() => int", "returns the numeric user id of the caller."},
+		"getwd":               {"getwd() => string/error", "returns a rooted path name corresponding to the current directory."},
+		"hostname":            {"hostname() => string/error", "returns the host name reported by the kernel."},
+		"lchown":              {"lchown(name string, uid int, gid int) => error", "changes the numeric uid and gid of the named file."},
+		"link":                {"link(oldname string, newname string) => error", "creates newname as a hard link to the oldname file."},
+		"lookup_env":          {"lookup_env(key string) => string/false", "retrieves the value of the environment variable named by the key."},
+		"mkdir":               {"mkdir(name string, perm int) => error", "creates a new directory with the specified name and permission bits (before umask)."},
+		"mkdir_all":           {"mkdir_all(name string, perm int) => error", "creates a directory named path, along with any necessary parents, and returns nil, or else returns an error."},
+		"mode_append":         {"mode_append", ""},
+		"mode_char_device":    {"mode_char_device", ""},
+		"mode_device":         {"mode_device", ""},
+		"mode_dir":            {"mode_dir", ""},
+		"mode_exclusive":      {"mode_exclusive", ""},
+		"mode_irregular":      {"mode_irregular", ""},
+		"mode_named_pipe":     {"mode_named_pipe", ""},
+		"mode_perm":           {"mode_perm", ""},
+		"mode_setgui":         {"mode_setgui", ""},
+		"mode_setuid":         {"mode_setuid", ""},
+		"mode_socket":         {"mode_socket", ""},
+		"mode_sticky":         {"mode_sticky", ""},
+		"mode_symlink":        {"mode_symlink", ""},
+		"mode_temporary":      {"mode_temporary", ""},
+		"mode_type":           {"mode_type", ""},
+		"o_append":            {"o_append", ""},
+		"o_create":            {"o_create", ""},
+		"o_excl":              {"o_excl", ""},
+		"o_rdonly":            {"o_rdonly", ""},
+		"o_rdwr":              {"o_rdwr", ""},
+		"o_sync":              {"o_sync", ""},
+		"o_trunc":             {"o_trunc", ""},
+		"o_wronly":            {"o_wronly", ""},
+		"open":                {"open(name string) => File/error", "opens the named file for reading. If successful, methods on the returned file can be used for reading; the associated file descriptor has mode O_RDONLY."},
+		"open_file":           {"open_file(name string, flag int, perm int) => File/error", "is the generalized open call; most users will use Open or Create instead. It opens the named file with specified flag (O_RDONLY etc.) and perm (before umask), if applicable."},
+		"path_list_separator": {"path_list_separator", ""},
+		"path_separator":      {"path_separator", ""},
+		"read_file":           {"read_file(name string) => bytes/error", "reads the contents of a file into a byte array"},
+		"readlink":            {"readlink(name string) => string/error", "returns the destination of the named symbolic link."},
+		"remove":              {"remove(name string) => error", "removes the named file or (empty) directory."},
+		"remove_all":          {"remove_all(name string) => error", "removes path and any children it contains."},
+		"rename":              {"rename(oldpath string, newpath string) => error", "renames (moves) oldpath to newpath."},
+		"seek_cur":            {"seek_cur", ""},
+		"seek_end":            {"seek_end", ""},
+		"seek_set":            {"seek_set", ""},
+		"setenv":              {"setenv(key string, value string) => error", "sets the value of the environment variable named by the key."},
+		"start_process":       {"start_process(name string, argv [string], dir string, env [string]) => Process/error", "starts a new process with the program, arguments and attributes specified by name, argv and attr. The argv slice will become os.Args in the new process, so it normally starts with the program name."},
+		"stat":                {"stat(filename string) => FileInfo/error", "returns a file info structure describing the file"},
+		"symlink":             {"symlink(oldname string newname string) => error", "creates newname as a symbolic link to oldname."},
+		"temp_dir":            {"temp_dir() => string", "returns the default directory to use for temporary files."},
+		"truncate":            {"truncate(name string, size int) => error", "changes the size of the named file."},
+		"unsetenv":            {"unsetenv(key string) => error", "unsets a single environment variable."},
+	},
+	"rand": {
+		"exp_float":  {"exp_float() => float", "returns an exponentially distributed float64 in the range (0, +math.MaxFloat64] with an exponential distribution whose rate parameter (lambda) is 1 and whose mean is 1/lambda (1) from the default Source."},
+		"float":      {"float() => float", "returns, as a float64, a pseudo-random number in [0.0,1.0) from the default Source."},
+		"int":        {"int() => int", "returns a non-negative pseudo-random 63-bit integer as an int64 from the default Source."},
+		"intn":       {"intn(n int) => int", "returns, as an int64, a non-negative pseudo-random number in [0,n) from the default Source. It panics if n <= 0."},
+		"norm_float": {"norm_float) => float", "returns a normally distributed float64 in the range [-math.MaxFloat64, +math.MaxFloat64] with standard normal distribution (mean = 0, stddev = 1) from the default Source."},
+		"perm":       {"perm(n int) => [int]", "returns, as a slice of n ints, a pseudo-random permutation of the integers [0,n) from the default Source."},
+		"rand":       {"rand(src_seed int) => Rand", "returns a new Rand that uses random values from src to generate other random values."},
+		"read":       {"read(p bytes) => int/error", "generates len(p) random bytes from the default Source and writes them into p. It always returns len(p) and a nil error."},
+		"seed":       {"seed(seed int)", "uses the provided seed value to initialize the default Source to a deterministic state."},
+	},
+	"text": {
+		"atoi":           {"atoi(str string) => int/error", "returns the result of ParseInt(s, 10, 0) converted to type int."},
+		"compare":        {"compare(a string, b string) => int", "returns an integer comparing two strings lexicographically. The result will be 0 if a==b, -1 if a < b, and +1 if a > b."},
+		"contains":       {"contains(s string, substr string) => bool", "reports whether substr is within s."},
+		"contains_any":   {"contains_any(s string, chars string) => bool", "reports whether any Unicode code points in chars are within s."},
+		"count":          {"count(s string, substr string) => int", "counts the number of non-overlapping instances of substr in s."},
+		"equal_fold":     {"equal_fold(s string, t string) => bool", "reports whether s and t, interpreted as UTF-8 strings,"},
+		"fields":         {"fields(s string) => [string]", "splits the string s around each instance of one or more consecutive white space characters, as defined by unicode.IsSpace, returning a slice of substrings of s or an empty slice if s contains only white space."},
+		"format_bool":    {"format_bool(b bool) => string", "returns \"true\" or \"false\" according to the value of b."},
+		"format_float":   {"format_float(f float, fmt string, prec int, bits int) => string", "converts the floating-point number f to a string, according to the format fmt and precision prec."},
+		"format_int":     {"format_int(i int, base int) => string", "returns the string representation of i in the given base, for 2 <= base <= 36. The result uses the lower-case letters 'a' to 'z' for digit values >= 10."},
+		"has_prefix":     {"has_prefix(s string, prefix string) => bool", "tests whether the string s begins with prefix."},
+		"has_suffix":     {"has_suffix(s string, suffix string) => bool", "tests whether the string s ends with suffix."},
+		"index":          {"index(s string, substr string) => int", "returns the index of the first instance of substr in s, or -1 if substr is not present in s."},
+		"index_any":      {"index_any(s string, chars string) => int", "returns the index of the first instance of any Unicode code point from chars in s, or -1 if no Unicode code point from chars is present in s."},
+		"itoa":           {"itoa(i int) => string", "is shorthand for format_int(i, 10)."},
+		"join":           {"join(arr string, sep string) => string", "concatenates the elements of a to create a single string. The separator string sep is placed between elements in the resulting string."},
+		"last_index":     {"last_index(s string, substr string) => int", "returns the index of the last instance of substr in s, or -1 if substr is not present in s."},
+		"last_index_any": {"last_index_any(s string, chars string) => int", "returns the index of the last instance of any Unicode code point from chars in s, or -1 if no Unicode code point from chars is present in s."},
+		"pad_left":       {"pad_left(s string, pad_len int, pad_with string) => string", "returns a copy of the string s padded on the left with the contents of the string pad_with to length pad_len. If pad_with is not specified, white space is used as the default padding."},
+		"pad_right":      {"pad_right(s string, pad_len int, pad_with string) => string", "returns a copy of the string s padded on the right with the contents of the string pad_with to length pad_len. If pad_with is not specified, white space is used as the default padding."},
+		"parse_bool":     {"parse_bool(s string) => bool/error", "returns the boolean value represented by the string. It accepts 1, t, T, TRUE, true, True, 0, f, F, FALSE, false, False. Any other value returns an error."},
+		"parse_float":    {"parse_float(s string, bits int) => float/error", "converts the string s to a floating-point number with the precision specified by bitSize: 32 for float32, or 64 for float64. When bitSize=32, the result still has type float64, but it will be convertible to float32 without changing its value."},
+		"parse_int":      {"parse_int(s string, base int, bits int) => int/error", "interprets a string s in the given base (0, 2 to 36) and bit size (0 to 64) and returns the corresponding value i."},
+		"quote":          {"quote(s string) => string", "returns a double-quoted Go string literal representing s. The returned string uses Go escape sequences (\\t, \\n, \\xFF, \\u0100) for control characters and non-printable characters as defined by IsPrint."},
+		"re_compile":     {"re_compile(pattern string) => Regexp/error", "parses a regular expression and returns, if successful, a Regexp object that can be used to match against text."},
+		"re_find":        {"re_find(pattern string, text string, count int) => [[{text: string, begin: int, end: int}]]/undefined", "returns an array holding all matches, each of which is an array of map object that contains matching text, begin and end (exclusive) index."},
+		"re_match":       {"re_match(pattern string, text string) => bool/error", "reports whether the string s contains any match of the regular expression pattern."},
+		"re_replace":     {"re_replace(pattern string, text string, repl string) => string/error", "returns a copy of src, replacing matches of the pattern with the replacement string repl."},
+		"re_split":       {"re_split(pattern string, text string, count int) => [string]/error", "slices s into substrings separated by the expression and returns a slice of the substrings between those expression matches."},
+		"repeat":         {"repeat(s string, count int) => string", "returns a new string consisting of count copies of the string s."},
+		"replace":        {"replace(s string, old string, new string, n int) => string", "returns a copy of the string s with the first n non-overlapping instances of old replaced by new."},
+		"split":          {"split(s string, sep string) => [string]", "slices s into all substrings separated by sep and returns a slice of the substrings between those separators."},
+		"split_after":    {"split_after(s string, sep string) => [string]", "slices s into all substrings after each instance of sep and returns a slice of those substrings."},
+		"split_after_n":  {"split_after_n(s string, sep string, n int) => [string]", "slices s into substrings after each instance of sep and returns a slice of those substrings."},
+		"split_n":        {"split_n(s string, sep string, n int) => [string]", "slices s into substrings separated by sep and returns a slice of the substrings between those separators."},
+		"substr":         {"substr(s string, lower int, upper int) => string => string", "returns a substring of the string s specified by the lower and upper parameters."},
+		"title":          {"title(s string) => string", "returns a copy of the string s with all Unicode letters that begin words mapped to their title case."},
+		"to_lower":       {"to_lower(s string) => string", "returns a copy of the string s with all Unicode letters mapped to their lower case."},
+		"to_title":       {"to_title(s string) => string", "returns a copy of the string s with all Unicode letters mapped to their title case."},
+		"to_upper":       {"to_upper(s string) => string", "returns a copy of the string s with all Unicode letters mapped to their upper case."},
+		"trim":           {"trim(s string, cutset string) => string", "returns a slice of the string s with all leading and trailing Unicode code points contained in cutset removed."},
+		"trim_left":      {"trim_left(s string, cutset string) => string", "returns a slice of the string s with all leading Unicode code points contained in cutset removed."},
+		"trim_prefix":    {"trim_prefix(s string, prefix string) => string", "returns s without the provided leading prefix string."},
+		"trim_right":     {"trim_right(s string, cutset string) => string", "returns a slice of the string s, with all trailing Unicode code points contained in cutset removed."},
+		"trim_space":     {"trim_space(s string) => string", "returns a slice of the string s, with all leading and trailing white space removed, as defined by Unicode."},
+		"trim_suffix":    {"trim_suffix(s string, suffix string) => string", "returns s without the provided trailing suffix string."},
+		"unquote":        {"unquote(s string) => string/error", "interprets s as a single-quoted, double-quoted, or backquoted Go string literal, returning the string value that s quotes.  (If s is single-quoted, it would be a Go character literal; Unquote returns the corresponding one-character string.)"},
+	},
+	"times": {
+		"add":                  {"add(t time, duration int) => time", "returns the time t+d."},
+		"add_date":             {"add_date(t time, years int, months int, days int) => time", "returns the time corresponding to adding the given number of years, months, and days to t. For example, AddDate(-1, 2, 3) applied to January 1, 2011 returns March 4, 2010."},
+		"after":                {"after(t time, u time) => bool", "reports whether the time instant t is after u."},
+		"april":                {"april", ""},
+		"august":               {"august", ""},
+		"before":               {"before(t time, u time) => bool", "reports whether the time instant t is before u."},
+		"date":                 {"date(year int, month int, day int, hour int, min int, sec int, nsec int) => time", "returns the Time corresponding to \"yyyy-mm-dd hh:mm:ss + nsec nanoseconds\". Current location is used."},
+		"december":             {"december", ""},
+		"duration_hours":       {"duration_hours(duration int) => float", "returns the duration as a floating point number of hours."},
+		"duration_minutes":     {"duration_minutes(duration int) => float", "returns the duration as a floating point number of minutes."},
+		"duration_nanoseconds": {"duration_nanoseconds(duration int) => int", "returns the duration as an integer of nanoseconds."},
+		"duration_seconds":     {"duration_seconds(duration int) => float", "returns the duration as a floating point number of seconds."},
+		"duration_string":      {"duration_string(duration int) => string", "returns a string representation of duration."},
+		"february":             {"february", ""},
+		"format_ansic":         {"format_ansic", "time format \"Mon Jan _2 15:04:05 2006\""},
+		"format_kitchen":       {"format_kitchen", "time format \"3:04PM\""},
+		"format_rfc1123":       {"format_rfc1123", "time format \"Mon, 02 Jan 2006 15:04:05 MST\""},
+		"format_rfc1123z":      {"format_rfc1123z", "time format \"Mon, 02 Jan 2006 15:04:05 -0700\""},
+		"format_rfc3339":       {"format_rfc3339", "time format \"2006-01-02T15:04:05Z07:00\""},
+		"format_rfc3339_nano":  {"format_rfc3339_nano", "time format \"2006-01-02T15:04:05.999999999Z07:00\""},
+		"format_rfc822":        {"format_rfc822", "time format \"02 Jan 06 15:04 MST\""},
+		"format_rfc822z":       {"format_rfc822z", "time format \"02 Jan 06 15:04 -0700\""},
+		"format_rfc850":        {"format_rfc850", "time format \"Monday, 02-Jan-06 15:04:05 MST\""},
+		"format_ruby_date":     {"format_ruby_date", "time format \"Mon Jan 02 15:04:05 -0700 2006\""},
+		"format_stamp":         {"format_stamp", "time format \"Jan _2 15:04:05\""},
+		"format_stamp_micro":   {"format_stamp_micro", "time format \"Jan _2 15:04:05.000000\""},
+		"format_stamp_milli":   {"format_stamp_milli", "time format \"Jan _2 15:04:05.000\""},
+		"format_stamp_nano":    {"format_stamp_nano", "time format \"Jan _2 15:04:05.000000000\""},
+		"format_unix_date":     {"format_unix_date", "time format \"Mon Jan _2 15:04:05 MST 2006\""},
+		"hour":                 {"hour", ""},
+		"is_zero":              {"is_zero(t time) => bool", "reports whether t represents the zero time instant, January 1, year 1, 00:00:00 UTC."},
+		"january":              {"january", ""},
+		"july":                 {"july", ""},
+		"june":                 {"june", ""},
+		"march":                {"march", ""},
+		"may":                  {"may", ""},
+		"microsecond":          {"microsecond", ""},
+		"millisecond":          {"millisecond", ""},
+		"minute":               {"minute", ""},
+		"month_string":         {"month_string(month int) => string", "returns the English name of the month (\"January\", \"February\", ...)."},
+		"nanosecond":           {"nanosecond", ""},
+		"november":             {"november", ""},
+		"now":                  {"now() => time", "returns the current local time."},
+		"october":              {"october", ""},
+		"parse":                {"parse(format string, s string) => time", "parses a formatted string and returns the time value it represents. The layout defines the format by showing how the reference time, defined to be \"Mon Jan 2 15:04:05 -0700 MST 2006\" would be interpreted if it were the value; it serves as an example of the input format. The same interpretation will then be made to the input string."},
+		"parse_duration":       {"parse_duration(s string) => int", "parses a duration string. A duration string is a possibly signed sequence of decimal numbers, each with optional fraction and a unit suffix, such as \"300ms\", \"-1.5h\" or \"2h45m\". Valid time units are \"ns\", \"us\" (or \"µs\"), \"ms\", \"s\", \"m\", \"h\"."},
+		"second":               {"second", ""},
+		"september":            {"september", ""},
+		"since":                {"since(t time) => int", "returns the time elapsed since t."},
+		"sleep":                {"sleep(duration int)", "pauses the current goroutine for at least the duration d. A negative or zero duration causes Sleep to return immediately."},
+		"sub":                  {"sub(t time, u time) => int", "returns the duration t-u."},
+		"time_day":             {"time_day(t time) => int", "returns the day of the month specified by t."},
+		"time_format":          {"time_format(t time, format) => string", "returns a textual representation of he time value formatted according to layout, which defines the format by showing how the reference time, defined to be \"Mon Jan 2 15:04:05 -0700 MST 2006\" would be displayed if it were the value; it serves as an example of the desired output. The same display rules will then be applied to the time value."},
+		"time_hour":            {"time_hour(t time) => int", "returns the hour within the day specified by t, in the range [0, 23]."},
+		"time_location":        {"time_location(t time) => string", "returns the time zone name associated with t."},
+		"time_minute":          {"time_minute(t time) => int", "returns the minute offset within the hour specified by t, in the range [0, 59]."},
+		"time_month":           {"time_month(t time) => int", "returns the month of the year specified by t."},
+		"time_nanosecond":      {"time_nanosecond(t time) => int", "returns the nanosecond offset within the second specified by t, in the range [0, 999999999]."},
+		"time_second":          {"time_second(t time) => int", "returns the second offset within the minute specified by t, in the range [0, 59]."},
+		"time_string":          {"time_string(t time) => string", "returns the time formatted using the format string \"2006-01-02 15:04:05.999999999 -0700 MST\"."},
+		"time_unix":            {"time_unix(t time) => int", "returns t as a Unix time, the number of seconds elapsed since January 1, 1970 UTC. The result does not depend on the location associated with t."},
+		"time_unix_nano":       {"time_unix_nano(t time) => int", "returns t as a Unix time, the number of nanoseconds elapsed since January 1, 1970 UTC. The result is undefined if the Unix time in nanoseconds cannot be represented by an int64 (a date before the year 1678 or after 2262). Note that this means the result of calling UnixNano on the zero Time is undefined. The result does not depend on the location associated with t."},
+		"time_weekday":         {"time_weekday(t time) => int", "returns the day of the week specified by t."},
+		"time_year":            {"time_year(t time) => int", "returns the year in which t occurs."},
+		"to_local":             {"to_local(t time) => time", "returns t with the location set to local time."},
+		"to_utc":               {"to_utc(t time) => time", "returns t with the location set to UTC."},
+		"unix":                 {"unix(sec int, nsec int) => time", "returns the local Time corresponding to the given Unix time, sec seconds and nsec nanoseconds since January 1, 1970 UTC."},
+		"until":                {"until(t time) => int", "returns the duration until t."},
+	},
+}
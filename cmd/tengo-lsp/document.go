@@ -0,0 +1,292 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+)
+
+// document is an opened source file and the result of its analysis.
+type document struct {
+	uri         string
+	text        string
+	lines       []int // offsets of the line starts
+	file        *parser.SourceFile
+	parsed      *parser.File // or nil if the source has syntax errors
+	diagnostics []Diagnostic
+	refs        []*reference
+	members     []*memberRef
+	moduleDefs  map[*parser.Ident]string // variables holding the modules
+	moduleVars  map[string]string        // module names by variable names
+	funcDefs    map[*parser.Ident]bool   // variables defined as functions
+}
+
+var moduleVarRE = regexp.MustCompile(
+	`([A-Za-z_]\w*)\s*:?=\s*import\(\s*"(\w+)"\s*\)`)
+
+// reference is an identifier resolved by the compiler.
+type reference struct {
+	ident  *parser.Ident
+	def    *parser.Ident // or nil for builtins
+	symbol *tengo.Symbol
+}
+
+// memberRef is a selector that may refer to a module member, e.g.
+// 'text.contains' or 'import("text").contains'.
+type memberRef struct {
+	sel    *parser.StringLit
+	module string        // module name of the import expression; or empty
+	base   *parser.Ident // variable that may hold the module; or nil
+}
+
+func newDocument(uri, text string, modules *tengo.ModuleMap) *document {
+	d := &document{
+		uri:        uri,
+		text:       text,
+		lines:      []int{0},
+		moduleDefs: make(map[*parser.Ident]string),
+		moduleVars: make(map[string]string),
+		funcDefs:   make(map[*parser.Ident]bool),
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	// module variables are found from the text for the completion while
+	// the source is being edited and has syntax errors
+	for _, m := range moduleVarRE.FindAllStringSubmatch(text, -1) {
+		d.moduleVars[m[1]] = m[2]
+	}
+	d.analyze(modules)
+	return d
+}
+
+// analyze parses and compiles the source, and collects the diagnostics and
+// the resolved identifiers.
+func (d *document) analyze(modules *tengo.ModuleMap) {
+	path, name := uriPath(d.uri), d.uri
+	if path != "" {
+		name = filepath.Base(path)
+	}
+	fileSet := parser.NewFileSet()
+	d.file = fileSet.AddFile(name, -1, len(d.text))
+	p := parser.NewParser(d.file, []byte(d.text), nil)
+	parsed, err := p.ParseFile()
+	if err != nil {
+		if list, ok := err.(parser.ErrorList); ok {
+			for _, e := range list {
+				d.addDiagnostic(e.Pos.Offset, e.Pos.Offset, e.Msg)
+			}
+		} else {
+			d.addDiagnostic(0, 0, err.Error())
+		}
+		return
+	}
+	d.parsed = parsed
+	d.inspect()
+
+	c := tengo.NewCompiler(d.file, nil, nil, modules, nil)
+	if path != "" {
+		c.EnableFileImport(true)
+		c.SetImportDir(filepath.Dir(path))
+	}
+	c.SetSymbolHook(func(ident, def *parser.Ident, symbol *tengo.Symbol) {
+		d.refs = append(d.refs, &reference{
+			ident:  ident,
+			def:    def,
+			symbol: symbol,
+		})
+	})
+	if err := c.Compile(parsed); err != nil {
+		if e, ok := err.(*tengo.CompilerError); ok && d.contains(e.Node) {
+			d.addDiagnostic(d.file.Offset(e.Node.Pos()),
+				d.file.Offset(e.Node.End()), e.Err.Error())
+		} else {
+			d.addDiagnostic(0, 0, err.Error())
+		}
+	}
+}
+
+// inspect finds the variables holding the imported modules or the functions,
+// and the selectors of the module members.
+func (d *document) inspect() {
+	parser.Inspect(d.parsed, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.AssignStmt:
+			if len(n.LHS) != 1 || len(n.RHS) != 1 {
+				break
+			}
+			ident, ok := n.LHS[0].(*parser.Ident)
+			if !ok {
+				break
+			}
+			switch rhs := n.RHS[0].(type) {
+			case *parser.ImportExpr:
+				d.moduleDefs[ident] = rhs.ModuleName
+			case *parser.FuncLit:
+				d.funcDefs[ident] = true
+			}
+		case *parser.RecordStmt:
+			d.funcDefs[n.Name] = true
+		case *parser.SelectorExpr:
+			sel, ok := n.Sel.(*parser.StringLit)
+			if !ok {
+				break
+			}
+			switch x := n.Expr.(type) {
+			case *parser.ImportExpr:
+				d.members = append(d.members, &memberRef{
+					sel:    sel,
+					module: x.ModuleName,
+				})
+			case *parser.Ident:
+				d.members = append(d.members, &memberRef{
+					sel:  sel,
+					base: x,
+				})
+			}
+		}
+		return true
+	})
+}
+
+// memberModule returns the module name of the member selector; or empty if
+// the selector does not refer to a module.
+func (d *document) memberModule(m *memberRef) string {
+	if m.base == nil {
+		return m.module
+	}
+	for _, ref := range d.refs {
+		if ref.ident == m.base && ref.def != nil {
+			return d.moduleDefs[ref.def]
+		}
+	}
+	return ""
+}
+
+// isFunc returns true if the expression is a function literal or a variable
+// defined as a function.
+func (d *document) isFunc(expr parser.Expr) bool {
+	switch expr := expr.(type) {
+	case *parser.FuncLit:
+		return true
+	case *parser.Ident:
+		for _, ref := range d.refs {
+			if ref.ident == expr && ref.def != nil {
+				return d.funcDefs[ref.def]
+			}
+		}
+	}
+	return false
+}
+
+// refAt returns the resolved identifier at the offset; or nil.
+func (d *document) refAt(offset int) *reference {
+	for _, ref := range d.refs {
+		if d.nodeAt(ref.ident, offset) {
+			return ref
+		}
+	}
+	return nil
+}
+
+// memberAt returns the module member selector at the offset; or nil.
+func (d *document) memberAt(offset int) *memberRef {
+	for _, m := range d.members {
+		if d.nodeAt(m.sel, offset) {
+			return m
+		}
+	}
+	return nil
+}
+
+// nodeAt returns true if the offset is within the node or immediately after
+// the node.
+func (d *document) nodeAt(node parser.Node, offset int) bool {
+	return d.contains(node) &&
+		d.file.Offset(node.Pos()) <= offset &&
+		offset <= d.file.Offset(node.End())
+}
+
+// contains returns true if the node belongs to the source file.
+func (d *document) contains(node parser.Node) bool {
+	if node == nil || !node.Pos().IsValid() {
+		return false
+	}
+	pos, end := int(node.Pos()), int(node.End())
+	return d.file.Base <= pos && end <= d.file.Base+d.file.Size
+}
+
+func (d *document) addDiagnostic(start, end int, msg string) {
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Range:    Range{Start: d.position(start), End: d.position(end)},
+		Severity: SeverityError,
+		Source:   "tengo",
+		Message:  msg,
+	})
+}
+
+// nodeRange returns the range of the node in the document.
+func (d *document) nodeRange(node parser.Node) Range {
+	return Range{
+		Start: d.position(d.file.Offset(node.Pos())),
+		End:   d.position(d.file.Offset(node.End())),
+	}
+}
+
+// position converts the byte offset into the position in the document.
+func (d *document) position(offset int) Position {
+	if offset > len(d.text) {
+		offset = len(d.text)
+	}
+	line := sort.Search(len(d.lines), func(i int) bool {
+		return d.lines[i] > offset
+	}) - 1
+	var character int
+	for _, r := range d.text[d.lines[line]:offset] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// offset converts the position in the document into the byte offset.
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lines) {
+		return len(d.text)
+	}
+	offset := d.lines[pos.Line]
+	for character := 0; character < pos.Character &&
+		offset < len(d.text) && d.text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		character += utf16Len(r)
+		offset += size
+	}
+	return offset
+}
+
+// utf16Len returns the number of UTF-16 code units of the rune.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2 // surrogate pair
+	}
+	return 1
+}
+
+// uriPath returns the file path of the "file" URI; or empty if the URI is
+// not a file.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
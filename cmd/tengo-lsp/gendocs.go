@@ -0,0 +1,165 @@
+// +build ignore
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const docsDir = "../../docs"
+
+var (
+	stdlibDocFileRE = regexp.MustCompile(`^stdlib-(\w+)\.md$`)
+	memberRE        = regexp.MustCompile("^- `(\\w+)([^`]*)`:?\\s*(.*)$")
+)
+
+func main() {
+	builtins := readBuiltinDocs(filepath.Join(docsDir, "builtins.md"))
+
+	modules := make(map[string]map[string][2]string)
+	files, err := filepath.Glob(filepath.Join(docsDir, "stdlib-*.md"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, file := range files {
+		m := stdlibDocFileRE.FindStringSubmatch(filepath.Base(file))
+		if m != nil {
+			modules[m[1]] = readModuleDocs(file)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString(`// Code generated using gendocs.go; DO NOT EDIT.
+
+package main
+
+// builtinDocs are the descriptions of the builtin functions.
+var builtinDocs = map[string]string{` + "\n")
+	for _, name := range sortedKeys(builtins) {
+		out.WriteString(strconv.Quote(name) + ": " +
+			strconv.Quote(builtins[name]) + ",\n")
+	}
+	out.WriteString(`}
+
+// moduleDocs are the signatures and descriptions of the standard library
+// module members.
+var moduleDocs = map[string]map[string]memberDoc{` + "\n")
+	var moduleNames []string
+	for name := range modules {
+		moduleNames = append(moduleNames, name)
+	}
+	sort.Strings(moduleNames)
+	for _, modName := range moduleNames {
+		out.WriteString(strconv.Quote(modName) + ": {\n")
+		members := modules[modName]
+		var names []string
+		for name := range members {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			out.WriteString(strconv.Quote(name) + ": {" +
+				strconv.Quote(members[name][0]) + ", " +
+				strconv.Quote(members[name][1]) + "},\n")
+		}
+		out.WriteString("},\n")
+	}
+	out.WriteString("}\n")
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	const target = "docs.go"
+	if err := ioutil.WriteFile(target, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readBuiltinDocs reads the first paragraph of each builtin function section.
+func readBuiltinDocs(file string) map[string]string {
+	docs := make(map[string]string)
+	var name string
+	var paragraph []string
+	forEachLine(file, func(line string) {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			name = strings.TrimSpace(line[3:])
+			paragraph = nil
+		case name == "":
+		case line == "" || strings.HasPrefix(line, "```"):
+			if len(paragraph) > 0 {
+				docs[name] = strings.Join(paragraph, " ")
+				name = ""
+			}
+		default:
+			paragraph = append(paragraph, strings.TrimSpace(line))
+		}
+	})
+	return docs
+}
+
+// readModuleDocs reads the list items of "Constants" and "Functions"
+// sections. It returns the signatures and the descriptions of the members.
+func readModuleDocs(file string) map[string][2]string {
+	docs := make(map[string][2]string)
+	var section, name string
+	forEachLine(file, func(line string) {
+		if strings.HasPrefix(line, "## ") {
+			section = strings.TrimSpace(line[3:])
+			name = ""
+			return
+		}
+		if section != "Constants" && section != "Functions" {
+			return
+		}
+		if m := memberRE.FindStringSubmatch(line); m != nil {
+			name = m[1]
+			docs[name] = [2]string{m[1] + m[2], m[3]}
+			return
+		}
+		if name != "" && strings.HasPrefix(line, "  ") {
+			doc := docs[name]
+			doc[1] = strings.TrimSpace(doc[1] + " " + strings.TrimSpace(line))
+			docs[name] = doc
+			return
+		}
+		name = ""
+	})
+	return docs
+}
+
+func forEachLine(file string, fn func(line string)) {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fn(strings.TrimRight(scanner.Text(), " "))
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/stdlib"
+)
+
+// memberDoc is the documentation of a standard library module member.
+type memberDoc struct {
+	Signature string
+	Doc       string
+}
+
+// hover returns the information of the identifier or the module member at
+// the position; or nil.
+func (d *document) hover(pos Position) *Hover {
+	offset := d.offset(pos)
+	if m := d.memberAt(offset); m != nil {
+		module := d.memberModule(m)
+		if module == "" {
+			return nil
+		}
+		doc, ok := moduleDocs[module][m.sel.Value]
+		if !ok {
+			obj, ok := stdlib.BuiltinModules[module][m.sel.Value]
+			if !ok {
+				return nil
+			}
+			doc = memberDoc{Signature: m.sel.Value, Doc: obj.TypeName()}
+		}
+		return &Hover{
+			Contents: markdown(fmt.Sprintf("```tengo\n%s.%s\n```\n\n%s",
+				module, doc.Signature, doc.Doc)),
+			Range: d.nodeRange(m.sel),
+		}
+	}
+
+	ref := d.refAt(offset)
+	if ref == nil {
+		return nil
+	}
+	var value string
+	switch {
+	case ref.symbol.Scope == tengo.ScopeBuiltin:
+		value = fmt.Sprintf("```tengo\nbuiltin %s\n```\n\n%s",
+			ref.ident.Name, builtinDocs[ref.ident.Name])
+	case ref.def == nil:
+		return nil
+	default:
+		value = fmt.Sprintf("```tengo\n%s\n```\n\n%s variable",
+			d.lineText(ref.def), strings.ToLower(string(ref.symbol.Scope)))
+		if module, ok := d.moduleDefs[ref.def]; ok {
+			value += fmt.Sprintf(" holding module `%s`", module)
+		}
+	}
+	return &Hover{Contents: markdown(value), Range: d.nodeRange(ref.ident)}
+}
+
+// lineText returns the source line of the definition.
+func (d *document) lineText(def *parser.Ident) string {
+	line := d.position(d.file.Offset(def.Pos())).Line
+	end := len(d.text)
+	if line+1 < len(d.lines) {
+		end = d.lines[line+1]
+	}
+	return strings.TrimSpace(d.text[d.lines[line]:end])
+}
+
+func markdown(value string) MarkupContent {
+	return MarkupContent{Kind: "markdown", Value: value}
+}
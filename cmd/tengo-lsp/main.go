@@ -0,0 +1,33 @@
+// tengo-lsp is a language server for Tengo source files. It communicates
+// with the editors using the Language Server Protocol over stdin and stdout.
+package main
+
+//go:generate go run gendocs.go
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+var (
+	showVersion bool
+	version     = "dev"
+)
+
+func init() {
+	flag.BoolVar(&showVersion, "version", false, "Show version")
+}
+
+func main() {
+	flag.Parse()
+	if showVersion {
+		fmt.Println(version)
+		return
+	}
+
+	if err := NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
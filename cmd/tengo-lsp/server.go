@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/stdlib"
+)
+
+// JSON-RPC error codes
+const (
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+// Server is a language server that communicates with the client using
+// JSON-RPC messages over the reader and the writer.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	modules  *tengo.ModuleMap
+	docs     map[string]*document
+	shutdown bool
+}
+
+// NewServer creates a Server.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:      bufio.NewReader(in),
+		out:     out,
+		modules: stdlib.GetModuleMap(stdlib.AllModuleNames()...),
+		docs:    make(map[string]*document),
+	}
+}
+
+// Run reads and handles the messages until the client sends "exit"
+// notification or closes the connection.
+func (s *Server) Run() error {
+	for {
+		data, err := s.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			return err
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(&req)
+		if req.ID == nil {
+			continue // notification
+		}
+		res := &response{JSONRPC: "2.0", ID: req.ID}
+		if err != nil {
+			var resErr *responseError
+			if !errors.As(err, &resErr) {
+				resErr = &responseError{Message: err.Error()}
+			}
+			res.Error = resErr
+		} else if res.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+		if err := s.write(res); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req *request) (interface{}, error) {
+	if s.shutdown {
+		return nil, &responseError{
+			Code:    codeInvalidRequest,
+			Message: "server is shut down",
+		}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return &InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:   textDocumentSyncFull,
+				DefinitionProvider: true,
+				ReferencesProvider: true,
+				HoverProvider:      true,
+				CompletionProvider: CompletionOptions{
+					TriggerCharacters: []string{"."},
+				},
+				DocumentSymbolProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "tengo-lsp", Version: version},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI,
+			params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		n := len(params.ContentChanges)
+		if n == 0 {
+			return nil, nil
+		}
+		return nil, s.update(params.TextDocument.URI,
+			params.ContentChanges[n-1].Text)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics",
+			&PublishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []Diagnostic{},
+			})
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.definition(params.Position), nil
+		}
+		return nil, nil
+	case "textDocument/references":
+		var params ReferenceParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.references(params.Position,
+				params.Context.IncludeDeclaration), nil
+		}
+		return nil, nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.hover(params.Position), nil
+		}
+		return nil, nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.completion(params.Position), nil
+		}
+		return nil, nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.symbols(), nil
+		}
+		return nil, nil
+	}
+
+	if req.ID == nil {
+		return nil, nil // ignore unknown notifications
+	}
+	return nil, &responseError{
+		Code:    codeMethodNotFound,
+		Message: fmt.Sprintf("method not found: %s", req.Method),
+	}
+}
+
+// update analyzes the new content of the document and publishes the
+// diagnostics.
+func (s *Server) update(uri, text string) error {
+	d := newDocument(uri, text, s.modules)
+	s.docs[uri] = d
+	diagnostics := d.diagnostics
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	return s.notify("textDocument/publishDiagnostics",
+		&PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return s.write(&notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// read reads the content of the next message.
+func (s *Server) read() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid message header: %w", err)
+	}
+	length, err := strconv.Atoi(
+		strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(s.in, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *Server) write(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data),
+		data)
+	return err
+}
+
+func unmarshalParams(req *request, params interface{}) error {
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/v2/require"
+)
+
+const testSrc = `text := import("text")
+a := 1
+f := func(x) {
+	b := len(x)
+	return func() { return a + b + text.count("ab", "b") }
+}
+export {
+	f: f,
+	value: a
+}`
+
+func TestServer_Diagnostics(t *testing.T) {
+	expectDiagnostics(t, "a := 1\nb := c + a\n", `[{"range":{`+
+		`"start":{"line":1,"character":5},"end":{"line":1,"character":6}},`+
+		`"severity":1,"source":"tengo",`+
+		`"message":"unresolved reference 'c'"}]`)
+	expectDiagnostics(t, "a := \n", `[{"range":{`+
+		`"start":{"line":1,"character":0},"end":{"line":1,"character":0}},`+
+		`"severity":1,"source":"tengo",`+
+		`"message":"expected operand, found 'EOF'"}]`)
+	expectDiagnostics(t, testSrc, `[]`)
+}
+
+func TestServer_Definition(t *testing.T) {
+	// free variable
+	expectResult(t, testSrc, "textDocument/definition", pos(4, 29),
+		`{"uri":"file:///test/main.tengo","range":{`+
+			`"start":{"line":3,"character":1},"end":{"line":3,"character":2}}}`)
+	// builtin function
+	expectResult(t, testSrc, "textDocument/definition", pos(3, 7), `null`)
+	// not an identifier
+	expectResult(t, testSrc, "textDocument/definition", pos(6, 0), `null`)
+}
+
+func TestServer_References(t *testing.T) {
+	params := `{"position":{"line":1,"character":0},` +
+		`"context":{"includeDeclaration":%t}}`
+	expectResult(t, testSrc, "textDocument/references",
+		fmt.Sprintf(params, true), `[`+
+			`{"uri":"file:///test/main.tengo","range":{"start":{"line":1,`+
+			`"character":0},"end":{"line":1,"character":1}}},`+
+			`{"uri":"file:///test/main.tengo","range":{"start":{"line":4,`+
+			`"character":24},"end":{"line":4,"character":25}}},`+
+			`{"uri":"file:///test/main.tengo","range":{"start":{"line":8,`+
+			`"character":8},"end":{"line":8,"character":9}}}]`)
+	expectResult(t, testSrc, "textDocument/references",
+		fmt.Sprintf(params, false), `[`+
+			`{"uri":"file:///test/main.tengo","range":{"start":{"line":4,`+
+			`"character":24},"end":{"line":4,"character":25}}},`+
+			`{"uri":"file:///test/main.tengo","range":{"start":{"line":8,`+
+			`"character":8},"end":{"line":8,"character":9}}}]`)
+}
+
+func TestServer_Hover(t *testing.T) {
+	hover := func(line, character int) string {
+		var h *Hover
+		res := sendRequest(t, testSrc, "textDocument/hover", pos(line, character))
+		require.NoError(t, json.Unmarshal(res, &h))
+		if h == nil {
+			return ""
+		}
+		return h.Contents.Value
+	}
+	require.Equal(t, "```tengo\ntext.count(s string, substr string) => int"+
+		"\n```\n\ncounts the number of non-overlapping instances of "+
+		"substr in s.", hover(4, 39))
+	require.True(t, strings.HasPrefix(hover(3, 7),
+		"```tengo\nbuiltin len\n```\n\nReturns the number of elements"))
+	require.Equal(t, "```tengo\nb := len(x)\n```\n\nfree variable",
+		hover(4, 29))
+	require.Equal(t, "```tengo\ntext := import(\"text\")\n```\n\n"+
+		"global variable holding module `text`", hover(4, 34))
+	require.Equal(t, "", hover(6, 0))
+}
+
+func TestServer_Completion(t *testing.T) {
+	labels := func(src string, line, character int) []string {
+		var items []CompletionItem
+		res := sendRequest(t, src, "textDocument/completion",
+			pos(line, character))
+		require.NoError(t, json.Unmarshal(res, &items))
+		var labels []string
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+	require.Equal(t, []string{"decode", "encode", "html_escape", "indent"},
+		labels(`j := import("json"); j.`, 0, 23))
+	require.Equal(t, []string{"decode", "encode", "html_escape", "indent"},
+		labels(`import("json").enc`, 0, 18))
+	require.Equal(t, 11, len(labels("enum := import(\"enum\")\nenum.", 1, 5)))
+	require.Equal(t, 0, len(labels("a := {}\na.", 1, 2)))
+}
+
+func TestServer_DocumentSymbol(t *testing.T) {
+	expectResult(t, testSrc, "textDocument/documentSymbol", `{}`, `[`+
+		`{"name":"f","kind":12,"range":{"start":{"line":7,"character":1},`+
+		`"end":{"line":7,"character":5}},"selectionRange":{"start":{`+
+		`"line":7,"character":1},"end":{"line":7,"character":2}}},`+
+		`{"name":"value","kind":13,"range":{"start":{"line":8,`+
+		`"character":1},"end":{"line":8,"character":9}},"selectionRange":{`+
+		`"start":{"line":8,"character":1},"end":{"line":8,"character":6}}}]`)
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	messages := runServer(t,
+		`{"jsonrpc":"2.0","id":1,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{}}`,
+		`{"jsonrpc":"2.0","method":"exit"}`)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":null}`, messages[0])
+	require.Equal(t, `{"jsonrpc":"2.0","id":2,"error":{"code":-32600,`+
+		`"message":"server is shut down"}}`, messages[1])
+}
+
+func expectDiagnostics(t *testing.T, src, expected string) {
+	var msg struct {
+		Params struct {
+			Diagnostics json.RawMessage
+		}
+	}
+	messages := runServer(t, didOpen(src))
+	require.NoError(t, json.Unmarshal([]byte(messages[0]), &msg))
+	require.Equal(t, expected, string(msg.Params.Diagnostics))
+}
+
+func expectResult(t *testing.T, src, method, params, expected string) {
+	require.Equal(t, expected, string(sendRequest(t, src, method, params)))
+}
+
+// sendRequest opens the source and sends the request. It returns the result of
+// the response.
+func sendRequest(t *testing.T, src, method, params string) json.RawMessage {
+	var res response
+	if params = strings.TrimPrefix(params, "{"); params != "}" {
+		params = "," + params
+	}
+	params = `{"textDocument":{"uri":"file:///test/main.tengo"}` + params
+	messages := runServer(t, didOpen(src), fmt.Sprintf(`{"jsonrpc":"2.0",`+
+		`"id":1,"method":%q,"params":%s}`, method, params))
+	require.NoError(t, json.Unmarshal([]byte(messages[1]), &res))
+	require.Nil(t, res.Error)
+	return res.Result
+}
+
+func didOpen(src string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen",`+
+		`"params":{"textDocument":{"uri":"file:///test/main.tengo",`+
+		`"languageId":"tengo","version":1,"text":%q}}}`, src)
+}
+
+func pos(line, character int) string {
+	return fmt.Sprintf(`{"position":{"line":%d,"character":%d}}`, line,
+		character)
+}
+
+// runServer sends the messages to the server, and returns the messages sent
+// by the server.
+func runServer(t *testing.T, messages ...string) []string {
+	var in, out bytes.Buffer
+	for _, msg := range messages {
+		_, _ = fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	require.NoError(t, NewServer(&in, &out).Run())
+
+	var res []string
+	s := &Server{in: bufio.NewReader(&out)}
+	for {
+		data, err := s.read()
+		if err != nil {
+			break
+		}
+		res = append(res, string(data))
+	}
+	return res
+}
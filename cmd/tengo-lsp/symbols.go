@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/d5/tengo/v2/parser"
+)
+
+// symbols returns the names exported by the document.
+func (d *document) symbols() []DocumentSymbol {
+	var symbols []DocumentSymbol
+	for _, elem := range exportedElements(d.parsed) {
+		kind := SymbolVariable
+		if d.isFunc(elem.Value) {
+			kind = SymbolFunction
+		}
+		keyStart := d.file.Offset(elem.KeyPos)
+		keyEnd := d.file.Offset(elem.ColonPos)
+		for keyEnd > keyStart && isSpace(d.text[keyEnd-1]) {
+			keyEnd--
+		}
+		symbols = append(symbols, DocumentSymbol{
+			Name:  elem.Key,
+			Kind:  kind,
+			Range: d.nodeRange(elem),
+			SelectionRange: Range{
+				Start: d.position(keyStart),
+				End:   d.position(keyEnd),
+			},
+		})
+	}
+	return symbols
+}
+
+// exportedElements returns the elements of the map exported by the file; or
+// nil if the file does not export a map.
+func exportedElements(file *parser.File) []*parser.MapElementLit {
+	if file == nil {
+		return nil
+	}
+	maps := make(map[string]*parser.MapLit) // global variables of maps
+	for _, stmt := range file.Stmts {
+		switch stmt := stmt.(type) {
+		case *parser.AssignStmt:
+			if len(stmt.LHS) != 1 || len(stmt.RHS) != 1 {
+				continue
+			}
+			if ident, ok := stmt.LHS[0].(*parser.Ident); ok {
+				m, _ := stmt.RHS[0].(*parser.MapLit)
+				maps[ident.Name] = m
+			}
+		case *parser.ExportStmt:
+			switch result := stmt.Result.(type) {
+			case *parser.MapLit:
+				return namedElements(result.Elements)
+			case *parser.Ident:
+				if m := maps[result.Name]; m != nil {
+					return namedElements(m.Elements)
+				}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// namedElements returns the map elements with the string keys, excluding
+// the spread maps.
+func namedElements(elems []*parser.MapElementLit) []*parser.MapElementLit {
+	var named []*parser.MapElementLit
+	for _, elem := range elems {
+		if elem.KeyExpr == nil && !elem.IsSpread() {
+			named = append(named, elem)
+		}
+	}
+	return named
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// scalar describes the conversions of a Go type that is converted to and from
+// an object with a single function call.
+type scalar struct {
+	to       string // function that converts an object to the value
+	expected string // object type name in ErrInvalidArgumentType errors
+	from     string // format of the object of the value; FromInterface if ""
+}
+
+var scalars = map[string]scalar{
+	"int":           {"tengo.ToStrictInt", "int", "&tengo.Int{Value: int64(%s)}"},
+	"int64":         {"tengo.ToStrictInt64", "int", "&tengo.Int{Value: %s}"},
+	"float64":       {"tengo.ToFloat64", "float", "&tengo.Float{Value: %s}"},
+	"string":        {"tengo.ToStrictString", "string", ""},
+	"bool":          {"tengo.ToBool", "bool", ""},
+	"[]byte":        {"tengo.ToByteSlice", "bytes", ""},
+	"time.Time":     {"tengo.ToTime", "time", ""},
+	"time.Duration": {"tengo.ToDuration", "int/string", "&tengo.Int{Value: int64(%s)}"},
+}
+
+// generic types are converted with ToInterface and FromInterface.
+var generic = map[string]string{
+	"interface{}":            "",
+	"[]interface{}":          "array",
+	"map[string]interface{}": "map",
+	"tengo.Object":           "",
+}
+
+var argNames = []string{"first", "second", "third", "fourth", "fifth",
+	"sixth", "seventh", "eighth", "ninth", "tenth"}
+
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+	argName bool // _tengoArgName is used
+	err     error
+}
+
+// generate returns the source of the adapters of the functions in the
+// package pkgName.
+func generate(pkgName string, funcs []*ast.FuncDecl) ([]byte, error) {
+	g := &generator{imports: map[string]bool{}}
+	for _, fn := range funcs {
+		g.function(fn)
+		if g.err != nil {
+			return nil, fmt.Errorf("%s: %w", fn.Name.Name, g.err)
+		}
+	}
+	if g.argName {
+		g.imports["fmt"] = true
+		g.printf(`
+func _tengoArgName(i int) string {
+	names := [...]string{%s}
+	if i < len(names) {
+		return names[i]
+	}
+	return fmt.Sprintf("#%%d", i+1)
+}
+`, quoteAll(argNames))
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by tengo-wrap; DO NOT EDIT.\n\n")
+	src.WriteString("package " + pkgName + "\n\nimport (\n")
+	for _, path := range []string{"fmt", "time"} {
+		if g.imports[path] {
+			src.WriteString(strconv.Quote(path) + "\n")
+		}
+	}
+	src.WriteString("\n\"github.com/d5/tengo/v2\"\n)\n")
+	src.Write(g.buf.Bytes())
+	return format.Source(src.Bytes())
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) function(fn *ast.FuncDecl) {
+	name := fn.Name.Name
+	params := fieldTypes(fn.Type.Params)
+	results := fieldTypes(fn.Type.Results)
+	variadic := len(params) > 0 &&
+		strings.HasPrefix(params[len(params)-1], "...")
+
+	if len(params) > len(argNames) {
+		g.err = fmt.Errorf("too many parameters")
+		return
+	}
+
+	g.printf("\n// %sFunc is a tengo.CallableFunc that calls %s.\n", name, name)
+	g.printf("func %sFunc(args ...tengo.Object) (tengo.Object, error) {\n",
+		name)
+	if variadic {
+		g.printf("if len(args) < %d {\n", len(params)-1)
+	} else {
+		g.printf("if len(args) != %d {\n", len(params))
+	}
+	g.printf("return nil, tengo.ErrWrongNumArguments\n}\n")
+
+	var callArgs []string
+	for i, typ := range params {
+		arg := fmt.Sprintf("a%d", i+1)
+		if strings.HasPrefix(typ, "...") {
+			g.variadicArg(arg, i, typ[3:])
+			arg += "..."
+		} else {
+			g.convertArg(arg, fmt.Sprintf("args[%d]", i),
+				strconv.Quote(argNames[i]), typ)
+		}
+		callArgs = append(callArgs, arg)
+	}
+
+	errOut := len(results) > 0 && results[len(results)-1] == "error"
+	var rets []string
+	for i := range results {
+		rets = append(rets, fmt.Sprintf("r%d", i+1))
+	}
+	if errOut {
+		rets[len(rets)-1] = "err"
+	}
+	call := fmt.Sprintf("%s(%s)", name, strings.Join(callArgs, ", "))
+	if len(rets) == 0 {
+		g.printf("%s\n", call)
+	} else {
+		g.printf("%s := %s\n", strings.Join(rets, ", "), call)
+	}
+	if errOut {
+		g.printf(`if err != nil {
+	return &tengo.Error{Value: &tengo.String{Value: err.Error()}}, nil
+}
+`)
+		results = results[:len(results)-1]
+	}
+
+	var objs []string
+	for i, typ := range results {
+		obj := fmt.Sprintf("o%d", i+1)
+		g.convertResult(obj, fmt.Sprintf("r%d", i+1), typ)
+		objs = append(objs, obj)
+	}
+	switch len(objs) {
+	case 0:
+		g.printf("return tengo.UndefinedValue, nil\n")
+	case 1:
+		g.printf("return o1, nil\n")
+	default:
+		g.printf("return &tengo.Array{Value: []tengo.Object{%s}}, nil\n",
+			strings.Join(objs, ", "))
+	}
+	g.printf("}\n")
+}
+
+// variadicArg writes the code that converts the arguments from the index i
+// to the slice dst of the element type typ.
+func (g *generator) variadicArg(dst string, i int, typ string) {
+	if strings.HasPrefix(typ, "[]") && typ != "[]byte" &&
+		typ != "[]interface{}" {
+		g.err = fmt.Errorf("unsupported variadic parameter type ...%s", typ)
+		return
+	}
+	g.useType(typ)
+	g.argName = true
+	g.printf("%s := make([]%s, 0, len(args)-%d)\n", dst, typ, i)
+	g.printf("for i, arg := range args[%d:] {\n", i)
+	g.convertArg("v", "arg", fmt.Sprintf("_tengoArgName(%d+i)", i), typ)
+	g.printf("%s = append(%s, v)\n}\n", dst, dst)
+}
+
+// convertArg writes the code that converts the object src to the value dst
+// of the type typ. name is the expression of the argument name.
+func (g *generator) convertArg(dst, src, name, typ string) {
+	typeError := func(name, expected, found string) string {
+		return fmt.Sprintf(`return nil, tengo.ErrInvalidArgumentType{
+	Name: %s,
+	Expected: %q,
+	Found: %s.TypeName(),
+}`, name, expected, found)
+	}
+
+	if s, ok := scalars[typ]; ok {
+		g.printf("%s, ok := %s(%s)\nif !ok {\n%s\n}\n",
+			dst, s.to, src, typeError(name, s.expected, src))
+		return
+	}
+	if expected, ok := generic[typ]; ok {
+		switch typ {
+		case "tengo.Object":
+			g.printf("%s := %s\n", dst, src)
+		case "interface{}":
+			g.printf("%s := tengo.ToInterface(%s)\n", dst, src)
+		default:
+			g.printf("%s, ok := tengo.ToInterface(%s).(%s)\nif !ok {\n%s\n}\n",
+				dst, src, typ, typeError(name, expected, src))
+		}
+		return
+	}
+
+	elem := strings.TrimPrefix(typ, "[]")
+	s, ok := scalars[elem]
+	if !ok || elem == typ {
+		g.err = fmt.Errorf("unsupported parameter type %s", typ)
+		return
+	}
+	g.useType(elem)
+	g.imports["fmt"] = true
+	elems := dst + "Elems"
+	g.printf(`var %s []tengo.Object
+switch arg := %s.(type) {
+case *tengo.Array:
+	%s = arg.Value
+case *tengo.ImmutableArray:
+	%s = arg.Value
+default:
+	%s
+}
+%s := make([]%s, len(%s))
+for i, elem := range %s {
+	v, ok := %s(elem)
+	if !ok {
+		%s
+	}
+	%s[i] = v
+}
+`, elems, src, elems, elems, typeError(name, "array", src),
+		dst, elem, elems, elems, s.to,
+		typeError(fmt.Sprintf(`fmt.Sprintf("%%s[%%d]", %s, i)`, name),
+			s.expected, "elem"),
+		dst)
+}
+
+// convertResult writes the code that converts the value src of the type typ
+// to the object dst.
+func (g *generator) convertResult(dst, src, typ string) {
+	if s, ok := scalars[typ]; ok {
+		if s.from != "" {
+			g.printf("%s := "+s.from+"\n", dst, src)
+		} else {
+			g.fromInterface(dst+", err :=", src)
+		}
+		return
+	}
+	if _, ok := generic[typ]; ok {
+		g.fromInterface(dst+", err :=", src)
+		return
+	}
+
+	elem := strings.TrimPrefix(typ, "[]")
+	s, ok := scalars[elem]
+	if !ok || elem == typ {
+		g.err = fmt.Errorf("unsupported result type %s", typ)
+		return
+	}
+	g.printf("%s := &tengo.Array{Value: make([]tengo.Object, len(%s))}\n",
+		dst, src)
+	g.printf("for i, v := range %s {\n", src)
+	if s.from != "" {
+		g.printf("%s.Value[i] = "+s.from+"\n", dst, "v")
+	} else {
+		g.fromInterface("e, err :=", "v")
+		g.printf("%s.Value[i] = e\n", dst)
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) fromInterface(assign, src string) {
+	g.printf(`%s tengo.FromInterface(%s)
+if err != nil {
+	return nil, err
+}
+`, assign, src)
+}
+
+func (g *generator) useType(typ string) {
+	if strings.Contains(typ, "time.") {
+		g.imports["time"] = true
+	}
+}
+
+// fieldTypes returns the types of the fields, one for each name.
+func fieldTypes(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var res []string
+	for _, f := range fields.List {
+		typ := types.ExprString(f.Type)
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			res = append(res, typ)
+		}
+	}
+	return res
+}
+
+func quoteAll(ss []string) string {
+	var res []string
+	for _, s := range ss {
+		res = append(res, strconv.Quote(s))
+	}
+	return strings.Join(res, ", ")
+}
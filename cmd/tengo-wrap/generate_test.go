@@ -0,0 +1,98 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"testing"
+
+	"github.com/d5/tengo/v2"
+	funcs "github.com/d5/tengo/v2/cmd/tengo-wrap/testdata"
+	"github.com/d5/tengo/v2/require"
+)
+
+func TestGenerate(t *testing.T) {
+	funcs := parseFuncs(t, "testdata/funcs.go")
+	var decls []*ast.FuncDecl
+	for _, name := range []string{
+		"Repeat", "Join", "Split", "Sum", "After", "Keys", "Noop",
+	} {
+		decls = append(decls, funcs[name])
+	}
+	src, err := generate("funcs", decls)
+	require.NoError(t, err)
+	expected, err := ioutil.ReadFile("testdata/funcs_tengo.go")
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(src))
+}
+
+func TestGenerate_Unsupported(t *testing.T) {
+	for src, expected := range map[string]string{
+		`func F(c chan int) {}`:                          "F: unsupported parameter type chan int",
+		`func F(m map[int]string) {}`:                    "F: unsupported parameter type map[int]string",
+		`func F(v ...[]string) {}`:                       "F: unsupported variadic parameter type ...[]string",
+		`func F() (f func()) { return }`:                 "F: unsupported result type func()",
+		`func F(a, b, c, d, e, f, g, h, i, j, k int) {}`: "F: too many parameters",
+	} {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "", "package p\n"+src, 0)
+		require.NoError(t, err)
+		_, err = generate("p", []*ast.FuncDecl{file.Decls[0].(*ast.FuncDecl)})
+		require.Error(t, err, src)
+		require.Equal(t, expected, err.Error())
+	}
+}
+
+func parseFuncs(t *testing.T, filename string) map[string]*ast.FuncDecl {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, nil, 0)
+	require.NoError(t, err)
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			funcs[fn.Name.Name] = fn
+		}
+	}
+	return funcs
+}
+
+func TestGenerate_Conversions(t *testing.T) {
+	// the generated adapters convert the arguments like WrapFunc
+	str := func(s string) tengo.Object { return &tengo.String{Value: s} }
+	ints := func(vs ...tengo.Object) tengo.Object {
+		return &tengo.Array{Value: vs}
+	}
+	for _, c := range []struct {
+		adapter tengo.CallableFunc
+		fn      interface{}
+		args    []tengo.Object
+		ok      bool
+	}{
+		{funcs.RepeatFunc, funcs.Repeat,
+			[]tengo.Object{str("ab"), &tengo.Int{Value: 2}}, true},
+		{funcs.RepeatFunc, funcs.Repeat,
+			[]tengo.Object{str("ab"), &tengo.Float{Value: 2}}, true},
+		{funcs.RepeatFunc, funcs.Repeat,
+			[]tengo.Object{str("ab"), &tengo.Float{Value: 1.5}}, false},
+		{funcs.RepeatFunc, funcs.Repeat,
+			[]tengo.Object{&tengo.Int{Value: 1}, &tengo.Int{Value: 2}}, false},
+		{funcs.JoinFunc, funcs.Join,
+			[]tengo.Object{str(","), str("a"), str("b")}, true},
+		{funcs.JoinFunc, funcs.Join,
+			[]tengo.Object{str(","), str("a"), &tengo.Int{Value: 1}}, false},
+		{funcs.SumFunc, funcs.Sum,
+			[]tengo.Object{ints(&tengo.Int{Value: 1}), &tengo.Int{Value: 2}},
+			true},
+		{funcs.SumFunc, funcs.Sum,
+			[]tengo.Object{ints(&tengo.Float{Value: 1e19}), &tengo.Int{Value: 2}},
+			false},
+	} {
+		expected, err := tengo.WrapFunc(c.fn)(c.args...)
+		require.Equal(t, c.ok, err == nil, "%v: %v", c.args, err)
+		res, err := c.adapter(c.args...)
+		require.Equal(t, c.ok, err == nil, "%v: %v", c.args, err)
+		if c.ok {
+			require.Equal(t, expected, res, "%v", c.args)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// tengo-wrap generates the adapters of Go functions to tengo.CallableFunc
+// that convert the arguments and the results without reflection. It's meant
+// to be run by go generate in the package of the functions:
+//
+//	//go:generate go run github.com/d5/tengo/v2/cmd/tengo-wrap -o funcs_tengo.go Repeat Split
+//
+// For each function Name, it generates NameFunc that converts the arguments
+// and the results in the same way as tengo.WrapFunc does. A function that has
+// a parameter or a result of an unsupported type is an error: use
+// tengo.WrapFunc for it.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	output string
+	dir    string
+)
+
+func init() {
+	flag.StringVar(&output, "o", "tengo_funcs.go", "Output file name")
+	flag.StringVar(&dir, "dir", ".", "Package directory")
+}
+
+func main() {
+	flag.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr,
+			"Usage: tengo-wrap [-o file] [-dir dir] functions...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Args()); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "tengo-wrap: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(names []string) error {
+	outPath := filepath.Join(dir, output)
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") &&
+			fi.Name() != filepath.Base(outPath)
+	}, 0)
+	if err != nil {
+		return err
+	}
+	pkg, err := selectPackage(pkgs)
+	if err != nil {
+		return err
+	}
+
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				funcs[fn.Name.Name] = fn
+			}
+		}
+	}
+	var decls []*ast.FuncDecl
+	for _, name := range names {
+		fn, ok := funcs[name]
+		if !ok {
+			return fmt.Errorf("function %s not found in package %s",
+				name, pkg.Name)
+		}
+		decls = append(decls, fn)
+	}
+
+	src, err := generate(pkg.Name, decls)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outPath, src, 0644)
+}
+
+// selectPackage returns the package named by GOPACKAGE environment variable
+// that go generate sets, or the only package in the directory.
+func selectPackage(pkgs map[string]*ast.Package) (*ast.Package, error) {
+	if name := os.Getenv("GOPACKAGE"); name != "" {
+		if pkg, ok := pkgs[name]; ok {
+			return pkg, nil
+		}
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("found %d packages in %s", len(pkgs), dir)
+	}
+	for _, pkg := range pkgs {
+		return pkg, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+//go:generate go run .. -o funcs_tengo.go Repeat Join Split Sum After Keys Noop
+
+package funcs
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+func Repeat(s string, count int) string {
+	return strings.Repeat(s, count)
+}
+
+func Join(sep string, elems ...string) string {
+	return strings.Join(elems, sep)
+}
+
+func Split(s string, sep string) []string {
+	return strings.Split(s, sep)
+}
+
+func Sum(values []int64, scale float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, errors.New("no values")
+	}
+	var sum float64
+	for _, v := range values {
+		sum += float64(v) * scale
+	}
+	return sum, nil
+}
+
+func After(t time.Time, d time.Duration) (time.Time, bool) {
+	return t.Add(d), d > 0
+}
+
+func Keys(m map[string]interface{}) (keys []interface{}) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return
+}
+
+func Noop() {}
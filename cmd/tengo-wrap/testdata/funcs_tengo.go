@@ -0,0 +1,221 @@
+// Code generated by tengo-wrap; DO NOT EDIT.
+
+package funcs
+
+import (
+	"fmt"
+
+	"github.com/d5/tengo/v2"
+)
+
+// RepeatFunc is a tengo.CallableFunc that calls Repeat.
+func RepeatFunc(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 2 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	a1, ok := tengo.ToStrictString(args[0])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "string",
+			Found:    args[0].TypeName(),
+		}
+	}
+	a2, ok := tengo.ToStrictInt(args[1])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "second",
+			Expected: "int",
+			Found:    args[1].TypeName(),
+		}
+	}
+	r1 := Repeat(a1, a2)
+	o1, err := tengo.FromInterface(r1)
+	if err != nil {
+		return nil, err
+	}
+	return o1, nil
+}
+
+// JoinFunc is a tengo.CallableFunc that calls Join.
+func JoinFunc(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) < 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	a1, ok := tengo.ToStrictString(args[0])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "string",
+			Found:    args[0].TypeName(),
+		}
+	}
+	a2 := make([]string, 0, len(args)-1)
+	for i, arg := range args[1:] {
+		v, ok := tengo.ToStrictString(arg)
+		if !ok {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     _tengoArgName(1 + i),
+				Expected: "string",
+				Found:    arg.TypeName(),
+			}
+		}
+		a2 = append(a2, v)
+	}
+	r1 := Join(a1, a2...)
+	o1, err := tengo.FromInterface(r1)
+	if err != nil {
+		return nil, err
+	}
+	return o1, nil
+}
+
+// SplitFunc is a tengo.CallableFunc that calls Split.
+func SplitFunc(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 2 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	a1, ok := tengo.ToStrictString(args[0])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "string",
+			Found:    args[0].TypeName(),
+		}
+	}
+	a2, ok := tengo.ToStrictString(args[1])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "second",
+			Expected: "string",
+			Found:    args[1].TypeName(),
+		}
+	}
+	r1 := Split(a1, a2)
+	o1 := &tengo.Array{Value: make([]tengo.Object, len(r1))}
+	for i, v := range r1 {
+		e, err := tengo.FromInterface(v)
+		if err != nil {
+			return nil, err
+		}
+		o1.Value[i] = e
+	}
+	return o1, nil
+}
+
+// SumFunc is a tengo.CallableFunc that calls Sum.
+func SumFunc(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 2 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	var a1Elems []tengo.Object
+	switch arg := args[0].(type) {
+	case *tengo.Array:
+		a1Elems = arg.Value
+	case *tengo.ImmutableArray:
+		a1Elems = arg.Value
+	default:
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "array",
+			Found:    args[0].TypeName(),
+		}
+	}
+	a1 := make([]int64, len(a1Elems))
+	for i, elem := range a1Elems {
+		v, ok := tengo.ToStrictInt64(elem)
+		if !ok {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     fmt.Sprintf("%s[%d]", "first", i),
+				Expected: "int",
+				Found:    elem.TypeName(),
+			}
+		}
+		a1[i] = v
+	}
+	a2, ok := tengo.ToFloat64(args[1])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "second",
+			Expected: "float",
+			Found:    args[1].TypeName(),
+		}
+	}
+	r1, err := Sum(a1, a2)
+	if err != nil {
+		return &tengo.Error{Value: &tengo.String{Value: err.Error()}}, nil
+	}
+	o1 := &tengo.Float{Value: r1}
+	return o1, nil
+}
+
+// AfterFunc is a tengo.CallableFunc that calls After.
+func AfterFunc(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 2 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	a1, ok := tengo.ToTime(args[0])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "time",
+			Found:    args[0].TypeName(),
+		}
+	}
+	a2, ok := tengo.ToDuration(args[1])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "second",
+			Expected: "int/string",
+			Found:    args[1].TypeName(),
+		}
+	}
+	r1, r2 := After(a1, a2)
+	o1, err := tengo.FromInterface(r1)
+	if err != nil {
+		return nil, err
+	}
+	o2, err := tengo.FromInterface(r2)
+	if err != nil {
+		return nil, err
+	}
+	return &tengo.Array{Value: []tengo.Object{o1, o2}}, nil
+}
+
+// KeysFunc is a tengo.CallableFunc that calls Keys.
+func KeysFunc(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	a1, ok := tengo.ToInterface(args[0]).(map[string]interface{})
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "map",
+			Found:    args[0].TypeName(),
+		}
+	}
+	r1 := Keys(a1)
+	o1, err := tengo.FromInterface(r1)
+	if err != nil {
+		return nil, err
+	}
+	return o1, nil
+}
+
+// NoopFunc is a tengo.CallableFunc that calls Noop.
+func NoopFunc(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	Noop()
+	return tengo.UndefinedValue, nil
+}
+
+func _tengoArgName(i int) string {
+	names := [...]string{"first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth", "tenth"}
+	if i < len(names) {
+		return names[i]
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+)
+
+const debugPrompt = "(tengo) "
+
+// CompileAndDebug compiles the source code and executes it with the debugger
+// prompt. The execution is paused at the first line of the source code.
+func CompileAndDebug(
+	modules *tengo.ModuleMap,
+	data []byte,
+	inputFile string,
+	in io.Reader,
+	out io.Writer,
+) (err error) {
+	bytecode, err := compileSrc(modules, data, inputFile)
+	if err != nil {
+		return
+	}
+
+	stdin := bufio.NewScanner(in)
+	mainFile := filepath.Base(inputFile)
+	lines := strings.Split(string(data), "\n")
+	machine := tengo.NewVM(bytecode, nil, -1)
+
+	var last []string
+	d := tengo.NewDebugger(machine, func(d *tengo.Debugger) {
+		pos := d.Pos()
+		_, _ = fmt.Fprintf(out, "%s\n", pos)
+		if pos.Filename == mainFile {
+			listLines(out, lines, pos.Line, pos.Line)
+		}
+
+		for {
+			_, _ = fmt.Fprint(out, debugPrompt)
+			if !stdin.Scan() {
+				machine.Abort()
+				return
+			}
+			args := strings.Fields(stdin.Text())
+			if len(args) == 0 {
+				args = last // repeat the last command
+			}
+			if len(args) == 0 {
+				continue
+			}
+			last = args
+
+			switch args[0] {
+			case "c", "continue":
+				d.Continue()
+				return
+			case "s", "step":
+				d.StepIn()
+				return
+			case "n", "next":
+				d.StepOver()
+				return
+			case "finish":
+				d.StepOut()
+				return
+			case "q", "quit":
+				machine.Abort()
+				return
+			case "b", "break", "d", "delete":
+				if len(args) < 2 {
+					_, _ = fmt.Fprintln(out, "missing [file:]line")
+					continue
+				}
+				bp, err := parseBreakpoint(args[1], mainFile)
+				if err != nil {
+					_, _ = fmt.Fprintln(out, err.Error())
+					continue
+				}
+				if args[0] == "b" || args[0] == "break" {
+					d.SetBreakpoint(bp)
+				} else {
+					d.ClearBreakpoint(bp)
+				}
+			case "breakpoints":
+				for _, bp := range d.Breakpoints() {
+					_, _ = fmt.Fprintf(out, "%s:%d\n", bp.Filename, bp.Line)
+				}
+			case "bt", "backtrace":
+				for i, f := range d.Frames() {
+					_, _ = fmt.Fprintf(out, "#%d %s\n", i, f.Pos)
+				}
+			case "locals":
+				frame := d.Frames()[0]
+				printVariables(out, frame.Locals())
+				printVariables(out, frame.Free())
+			case "globals":
+				printVariables(out, d.Globals())
+			case "p", "print":
+				if len(args) < 2 {
+					_, _ = fmt.Fprintln(out, "missing variable name")
+					continue
+				}
+				if v := findVariable(d, args[1]); v != nil {
+					_, _ = fmt.Fprintln(out, v.Value.String())
+				} else {
+					_, _ = fmt.Fprintf(out, "unknown variable '%s'\n",
+						args[1])
+				}
+			case "l", "list":
+				if pos.Filename != mainFile {
+					_, _ = fmt.Fprintln(out, "source not available")
+					continue
+				}
+				listLines(out, lines, pos.Line-5, pos.Line+5)
+			case "h", "help":
+				debugHelp(out)
+			default:
+				_, _ = fmt.Fprintf(out, "unknown command '%s'\n", args[0])
+			}
+		}
+	})
+	d.Pause()
+	err = machine.Run()
+	return
+}
+
+func parseBreakpoint(
+	s, mainFile string,
+) (pos parser.SourceFilePos, err error) {
+	pos.Filename = mainFile
+	if idx := strings.LastIndexByte(s, ':'); idx >= 0 {
+		pos.Filename = s[:idx]
+		s = s[idx+1:]
+	}
+	pos.Line, err = strconv.Atoi(s)
+	if err != nil || pos.Line <= 0 {
+		err = fmt.Errorf("invalid line number: %s", s)
+	}
+	return
+}
+
+func findVariable(d *tengo.Debugger, name string) *tengo.DebugVariable {
+	frame := d.Frames()[0]
+	for _, vars := range [][]*tengo.DebugVariable{
+		frame.Locals(), frame.Free(), d.Globals(),
+	} {
+		for _, v := range vars {
+			if v.Name == name {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func printVariables(out io.Writer, vars []*tengo.DebugVariable) {
+	for _, v := range vars {
+		_, _ = fmt.Fprintf(out, "%s = %s\n", v.Name, v.Value.String())
+	}
+}
+
+func listLines(out io.Writer, lines []string, from, to int) {
+	if from < 1 {
+		from = 1
+	}
+	if to > len(lines) {
+		to = len(lines)
+	}
+	for i := from; i <= to; i++ {
+		_, _ = fmt.Fprintf(out, "%d\t%s\n", i, lines[i-1])
+	}
+}
+
+func debugHelp(out io.Writer) {
+	_, _ = fmt.Fprintln(out, "Commands:")
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, "	b, break [file:]line     set breakpoint")
+	_, _ = fmt.Fprintln(out, "	d, delete [file:]line    delete breakpoint")
+	_, _ = fmt.Fprintln(out, "	breakpoints              list breakpoints")
+	_, _ = fmt.Fprintln(out, "	c, continue              continue to next breakpoint")
+	_, _ = fmt.Fprintln(out, "	s, step                  step into next line")
+	_, _ = fmt.Fprintln(out, "	n, next                  step over to next line")
+	_, _ = fmt.Fprintln(out, "	finish                   step out of current function")
+	_, _ = fmt.Fprintln(out, "	bt, backtrace            print function frames")
+	_, _ = fmt.Fprintln(out, "	locals                   print local and free variables")
+	_, _ = fmt.Fprintln(out, "	globals                  print global variables")
+	_, _ = fmt.Fprintln(out, "	p, print name            print variable")
+	_, _ = fmt.Fprintln(out, "	l, list                  list source lines")
+	_, _ = fmt.Fprintln(out, "	q, quit                  stop execution")
+	_, _ = fmt.Fprintln(out, "	h, help                  show this help")
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/d5/tengo/v2/format"
+)
+
+// RunFormat formats the source files and returns the exit code. It writes the
+// formatted source to out unless -w or -d flag is given. With -d flag, it
+// writes the differences and returns 1 if any file is not formatted.
+func RunFormat(args []string, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	write := flags.Bool("w", false, "Write result to source file")
+	diff := flags.Bool("d", false, "Display diffs instead of formatted source")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		_, _ = fmt.Fprintln(errOut, "Usage: tengo fmt [-w] [-d] files...")
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		err := walkSourceFiles(path, func(filename string) error {
+			changed, err := formatFile(filename, *write, *diff, out)
+			if err != nil {
+				return err
+			}
+			if changed && *diff {
+				code = 1
+			}
+			return nil
+		})
+		if err != nil {
+			_, _ = fmt.Fprintln(errOut, err.Error())
+			code = 2
+		}
+	}
+	return code
+}
+
+// walkSourceFiles calls fn for the file at path, or for each source file in
+// the directory tree rooted at path.
+func walkSourceFiles(path string, fn func(filename string) error) error {
+	return filepath.Walk(path,
+		func(filename string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() ||
+				filename != path && filepath.Ext(filename) != sourceFileExt {
+				return nil
+			}
+			return fn(filename)
+		})
+}
+
+// formatFile formats the source file and returns true if the formatted source
+// is different from the original.
+func formatFile(
+	filename string,
+	write, diff bool,
+	out io.Writer,
+) (bool, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	res, err := format.Source(src)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	changed := !bytes.Equal(src, res)
+
+	if diff {
+		if changed {
+			_, err = io.WriteString(out,
+				unifiedDiff(filename, string(src), string(res)))
+		}
+	}
+	if write {
+		if changed {
+			err = ioutil.WriteFile(filename, res, 0644)
+		}
+	} else if !diff {
+		_, err = out.Write(res)
+	}
+	return changed, err
+}
+
+// unifiedDiff returns the line differences between a and b in unified
+// format.
+func unifiedDiff(filename, a, b string) string {
+	const context = 3
+	x, y := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and
+	// y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// edit script: ' ' for common lines, '-' for x only and '+' for y only
+	type edit struct {
+		op   byte
+		line string
+		i, j int // line indexes in x and y before the edit
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			edits = append(edits, edit{' ', x[i], i, j})
+			i++
+			j++
+		case j == len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', x[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', y[j], i, j})
+			j++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("--- " + filename + ".orig\n")
+	sb.WriteString("+++ " + filename + "\n")
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		// hunk from start to end including the context lines
+		start := k - context
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			n := end
+			for n < len(edits) && edits[n].op == ' ' {
+				n++
+			}
+			if n == len(edits) || n-end > 2*context {
+				break
+			}
+			end = n
+		}
+		if end += context; end > len(edits) {
+			end = len(edits)
+		}
+
+		var na, nb int
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				na++
+			}
+			if e.op != '-' {
+				nb++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n",
+			hunkRange(edits[start].i, na), hunkRange(edits[start].j, nb)))
+		for _, e := range edits[start:end] {
+			sb.WriteByte(e.op)
+			sb.WriteString(e.line)
+			sb.WriteByte('\n')
+		}
+		k = end
+	}
+	return sb.String()
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+func splitLines(s string) []string {
+	lines := strings.Split(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
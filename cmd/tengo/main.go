@@ -26,6 +26,8 @@ var (
 	showHelp      bool
 	showVersion   bool
 	resolvePath   bool // TODO Remove this flag at version 3
+	debug         bool
+	cpuProfile    string
 	version       = "dev"
 )
 
@@ -35,6 +37,9 @@ func init() {
 	flag.BoolVar(&showVersion, "version", false, "Show version")
 	flag.BoolVar(&resolvePath, "resolve", false,
 		"Resolve relative import paths")
+	flag.BoolVar(&debug, "debug", false, "Run source file with debugger")
+	flag.StringVar(&cpuProfile, "cpuprofile", "",
+		"Write execution profile to file")
 	flag.Parse()
 }
 
@@ -47,7 +52,15 @@ func main() {
 		return
 	}
 
+	if flag.Arg(0) == "fmt" {
+		os.Exit(RunFormat(flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
 	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+	if flag.Arg(0) == "vet" {
+		os.Exit(RunVet(modules, flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+
 	inputFile := flag.Arg(0)
 	if inputFile == "" {
 		// REPL
@@ -79,6 +92,13 @@ func main() {
 			_, _ = fmt.Fprintln(os.Stderr, err.Error())
 			os.Exit(1)
 		}
+	} else if debug {
+		err := CompileAndDebug(modules, inputData, inputFile,
+			os.Stdin, os.Stdout)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
 	} else if filepath.Ext(inputFile) == sourceFileExt {
 		err := CompileAndRun(modules, inputData, inputFile)
 		if err != nil {
@@ -141,7 +161,7 @@ func CompileAndRun(
 	}
 
 	machine := tengo.NewVM(bytecode, nil, -1)
-	err = machine.Run()
+	err = runVM(machine)
 	return
 }
 
@@ -154,7 +174,34 @@ func RunCompiled(modules *tengo.ModuleMap, data []byte) (err error) {
 	}
 
 	machine := tengo.NewVM(bytecode, nil, -1)
+	err = runVM(machine)
+	return
+}
+
+// runVM runs the VM and writes the execution profile if -cpuprofile flag is
+// given.
+func runVM(machine *tengo.VM) (err error) {
+	if cpuProfile == "" {
+		return machine.Run()
+	}
+
+	profiler := tengo.NewProfiler(machine)
 	err = machine.Run()
+
+	out, ferr := os.Create(cpuProfile)
+	if ferr != nil {
+		if err == nil {
+			err = ferr
+		}
+		return
+	}
+	ferr = profiler.WriteProfile(out)
+	if cerr := out.Close(); ferr == nil {
+		ferr = cerr
+	}
+	if err == nil {
+		err = ferr
+	}
 	return
 }
 
@@ -255,10 +302,14 @@ func doHelp() {
 	fmt.Println("Usage:")
 	fmt.Println()
 	fmt.Println("	tengo [flags] {input-file}")
+	fmt.Println("	tengo fmt [-w] [-d] {input-file ...}")
+	fmt.Println("	tengo vet [-disable rules] {input-file ...}")
 	fmt.Println()
 	fmt.Println("Flags:")
 	fmt.Println()
 	fmt.Println("	-o        compile output file")
+	fmt.Println("	-debug    run source file with debugger")
+	fmt.Println("	-cpuprofile file  write execution profile to file")
 	fmt.Println("	-version  show version")
 	fmt.Println()
 	fmt.Println("Examples:")
@@ -280,6 +331,19 @@ func doHelp() {
 	fmt.Println()
 	fmt.Println("	          Run bytecode file (myapp)")
 	fmt.Println()
+	fmt.Println("	tengo -debug myapp.tengo")
+	fmt.Println()
+	fmt.Println("	          Run source file (myapp.tengo) with debugger")
+	fmt.Println()
+	fmt.Println("	tengo fmt -w myapp.tengo")
+	fmt.Println()
+	fmt.Println("	          Format source file (myapp.tengo) in place")
+	fmt.Println("	          Use -d to display diffs and exit with 1 if not formatted")
+	fmt.Println()
+	fmt.Println("	tengo vet myapp.tengo")
+	fmt.Println()
+	fmt.Println("	          Report suspicious code in source file (myapp.tengo)")
+	fmt.Println()
 	fmt.Println()
 }
 
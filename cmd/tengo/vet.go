@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/lint"
+)
+
+// RunVet checks the source files using the linter and returns the exit code.
+// It returns 1 if any problem is found.
+func RunVet(
+	modules *tengo.ModuleMap,
+	args []string,
+	out, errOut io.Writer,
+) int {
+	flags := flag.NewFlagSet("vet", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	disable := flags.String("disable", "",
+		"Comma-separated list of rules to disable")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		_, _ = fmt.Fprintln(errOut,
+			"Usage: tengo vet [-disable rules] files...")
+		return 2
+	}
+
+	linter := lint.NewLinter(modules)
+	if *disable != "" {
+		linter.Disable(strings.Split(*disable, ",")...)
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		err := walkSourceFiles(path, func(filename string) error {
+			src, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			if len(src) > 1 && string(src[:2]) == "#!" {
+				copy(src, "//")
+			}
+			diags, err := linter.LintSource(filename, src)
+			if err != nil {
+				return err
+			}
+			for _, d := range diags {
+				_, _ = fmt.Fprintln(out, d.String())
+				code = 1
+			}
+			return nil
+		})
+		if err != nil {
+			_, _ = fmt.Fprintln(errOut, err.Error())
+			code = 1
+		}
+	}
+	return code
+}
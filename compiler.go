@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"path"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/d5/tengo/v2/parser"
@@ -18,13 +20,18 @@ type compilationScope struct {
 	Instructions []byte
 	SymbolInit   map[string]bool
 	SourceMap    map[int]parser.Pos
+	Handlers     []*parser.BlockStmt // finally blocks of active error handlers
+	Locals       []*LocalSymbol
+	Generator    bool // function contains yield statements
 }
 
 // loop represents a loop construct that the compiler uses to track the current
 // loop.
 type loop struct {
-	Continues []int
-	Breaks    []int
+	Continues   []int
+	Breaks      []int
+	NumHandlers int
+	Switch      bool // switch statement accepts break only
 }
 
 // CompilerError represents a compiler error.
@@ -52,8 +59,14 @@ type Compiler struct {
 	modules         *ModuleMap
 	compiledModules map[string]*CompiledFunction
 	allowFileImport bool
+	fs              FS
 	loops           []*loop
 	loopIndex       int
+	blockEnds       []parser.Pos // end positions of the enclosing blocks
+	symbolHook      func(ident, def *parser.Ident, symbol *Symbol)
+	symbolDefs      map[*Symbol]*parser.Ident
+	chainJumps      []int // jumps of the optional links of the current chain
+	chainLink       bool  // the next expression continues the chain
 	trace           io.Writer
 	indent          int
 }
@@ -134,7 +147,8 @@ func (c *Compiler) Compile(node parser.Node) error {
 			return err
 		}
 	case *parser.BinaryExpr:
-		if node.Token == token.LAnd || node.Token == token.LOr {
+		switch node.Token {
+		case token.LAnd, token.LOr, token.Coalesce:
 			return c.compileLogical(node)
 		}
 		if node.Token == token.Less {
@@ -216,6 +230,8 @@ func (c *Compiler) Compile(node parser.Node) error {
 		}
 		c.emit(node, parser.OpConstant,
 			c.addConstant(&String{Value: node.Value}))
+	case *parser.InterpStringLit:
+		return c.compileInterpStringLit(node)
 	case *parser.CharLit:
 		c.emit(node, parser.OpConstant,
 			c.addConstant(&Char{Value: node.Value}))
@@ -241,10 +257,8 @@ func (c *Compiler) Compile(node parser.Node) error {
 		}
 	case *parser.IfStmt:
 		// open new symbol table for the statement
-		c.symbolTable = c.symbolTable.Fork(true)
-		defer func() {
-			c.symbolTable = c.symbolTable.Parent(false)
-		}()
+		c.enterBlock(node)
+		defer c.leaveBlock()
 
 		if node.Init != nil {
 			if err := c.Compile(node.Init); err != nil {
@@ -283,19 +297,31 @@ func (c *Compiler) Compile(node parser.Node) error {
 		return c.compileForStmt(node)
 	case *parser.ForInStmt:
 		return c.compileForInStmt(node)
+	case *parser.TryStmt:
+		return c.compileTryStmt(node)
+	case *parser.SwitchStmt:
+		return c.compileSwitchStmt(node)
 	case *parser.BranchStmt:
 		if node.Token == token.Break {
 			curLoop := c.currentLoop()
 			if curLoop == nil {
 				return c.errorf(node, "break not allowed outside loop")
 			}
+			err := c.compileHandlerExits(node, curLoop.NumHandlers)
+			if err != nil {
+				return err
+			}
 			pos := c.emit(node, parser.OpJump, 0)
 			curLoop.Breaks = append(curLoop.Breaks, pos)
 		} else if node.Token == token.Continue {
-			curLoop := c.currentLoop()
+			curLoop := c.continuableLoop()
 			if curLoop == nil {
 				return c.errorf(node, "continue not allowed outside loop")
 			}
+			err := c.compileHandlerExits(node, curLoop.NumHandlers)
+			if err != nil {
+				return err
+			}
 			pos := c.emit(node, parser.OpJump, 0)
 			curLoop.Continues = append(curLoop.Continues, pos)
 		} else {
@@ -307,10 +333,8 @@ func (c *Compiler) Compile(node parser.Node) error {
 			return nil
 		}
 
-		c.symbolTable = c.symbolTable.Fork(true)
-		defer func() {
-			c.symbolTable = c.symbolTable.Parent(false)
-		}()
+		c.enterBlock(node)
+		defer c.leaveBlock()
 
 		for _, stmt := range node.Stmts {
 			if err := c.Compile(stmt); err != nil {
@@ -327,6 +351,7 @@ func (c *Compiler) Compile(node parser.Node) error {
 		if !ok {
 			return c.errorf(node, "unresolved reference '%s'", node.Name)
 		}
+		c.resolved(node, symbol)
 
 		switch symbol.Scope {
 		case ScopeGlobal:
@@ -339,46 +364,19 @@ func (c *Compiler) Compile(node parser.Node) error {
 			c.emit(node, parser.OpGetFree, symbol.Index)
 		}
 	case *parser.ArrayLit:
-		for _, elem := range node.Elements {
-			if err := c.Compile(elem); err != nil {
-				return err
-			}
-		}
-		c.emit(node, parser.OpArray, len(node.Elements))
+		return c.compileArrayLit(node)
 	case *parser.MapLit:
-		for _, elt := range node.Elements {
-			// key
-			if len(elt.Key) > MaxStringLen {
-				return c.error(node, ErrStringLimit)
-			}
-			c.emit(node, parser.OpConstant,
-				c.addConstant(&String{Value: elt.Key}))
-
-			// value
-			if err := c.Compile(elt.Value); err != nil {
-				return err
-			}
-		}
-		c.emit(node, parser.OpMap, len(node.Elements)*2)
+		return c.compileMapLit(node)
+	case *parser.SpreadExpr:
+		return c.errorf(node, "spread not allowed here")
 
 	case *parser.SelectorExpr: // selector on RHS side
-		if err := c.Compile(node.Expr); err != nil {
-			return err
-		}
-		if err := c.Compile(node.Sel); err != nil {
-			return err
-		}
-		c.emit(node, parser.OpIndex)
+		return c.compileIndex(node, node.Expr, node.Sel, node.Optional)
 	case *parser.IndexExpr:
-		if err := c.Compile(node.Expr); err != nil {
-			return err
-		}
-		if err := c.Compile(node.Index); err != nil {
-			return err
-		}
-		c.emit(node, parser.OpIndex)
+		return c.compileIndex(node, node.Expr, node.Index, node.Optional)
 	case *parser.SliceExpr:
-		if err := c.Compile(node.Expr); err != nil {
+		defer c.beginChain()()
+		if err := c.compileChainOperand(node.Expr); err != nil {
 			return err
 		}
 		if node.Low != nil {
@@ -397,92 +395,9 @@ func (c *Compiler) Compile(node parser.Node) error {
 		}
 		c.emit(node, parser.OpSliceIndex)
 	case *parser.FuncLit:
-		c.enterScope()
-
-		for _, p := range node.Type.Params.List {
-			s := c.symbolTable.Define(p.Name)
-
-			// function arguments is not assigned directly.
-			s.LocalAssigned = true
-		}
-
-		if err := c.Compile(node.Body); err != nil {
-			return err
-		}
-
-		// code optimization
-		c.optimizeFunc(node)
-
-		freeSymbols := c.symbolTable.FreeSymbols()
-		numLocals := c.symbolTable.MaxSymbols()
-		instructions, sourceMap := c.leaveScope()
-
-		for _, s := range freeSymbols {
-			switch s.Scope {
-			case ScopeLocal:
-				if !s.LocalAssigned {
-					// Here, the closure is capturing a local variable that's
-					// not yet assigned its value. One example is a local
-					// recursive function:
-					//
-					//   func() {
-					//     foo := func(x) {
-					//       // ..
-					//       return foo(x-1)
-					//     }
-					//   }
-					//
-					// which translate into
-					//
-					//   0000 GETL    0
-					//   0002 CLOSURE ?     1
-					//   0006 DEFL    0
-					//
-					// . So the local variable (0) is being captured before
-					// it's assigned the value.
-					//
-					// Solution is to transform the code into something like
-					// this:
-					//
-					//   func() {
-					//     foo := undefined
-					//     foo = func(x) {
-					//       // ..
-					//       return foo(x-1)
-					//     }
-					//   }
-					//
-					// that is equivalent to
-					//
-					//   0000 NULL
-					//   0001 DEFL    0
-					//   0003 GETL    0
-					//   0005 CLOSURE ?     1
-					//   0009 SETL    0
-					//
-					c.emit(node, parser.OpNull)
-					c.emit(node, parser.OpDefineLocal, s.Index)
-					s.LocalAssigned = true
-				}
-				c.emit(node, parser.OpGetLocalPtr, s.Index)
-			case ScopeFree:
-				c.emit(node, parser.OpGetFreePtr, s.Index)
-			}
-		}
-
-		compiledFunction := &CompiledFunction{
-			Instructions:  instructions,
-			NumLocals:     numLocals,
-			NumParameters: len(node.Type.Params.List),
-			VarArgs:       node.Type.Params.VarArgs,
-			SourceMap:     sourceMap,
-		}
-		if len(freeSymbols) > 0 {
-			c.emit(node, parser.OpClosure,
-				c.addConstant(compiledFunction), len(freeSymbols))
-		} else {
-			c.emit(node, parser.OpConstant, c.addConstant(compiledFunction))
-		}
+		return c.compileFuncLit(node, false)
+	case *parser.RecordStmt:
+		return c.compileRecord(node)
 	case *parser.ReturnStmt:
 		if c.symbolTable.Parent(true) == nil {
 			// outside the function
@@ -490,15 +405,35 @@ func (c *Compiler) Compile(node parser.Node) error {
 		}
 
 		if node.Result == nil {
+			if err := c.compileHandlerExits(node, 0); err != nil {
+				return err
+			}
 			c.emit(node, parser.OpReturn, 0)
 		} else {
 			if err := c.Compile(node.Result); err != nil {
 				return err
 			}
+			if err := c.compileHandlerExits(node, 0); err != nil {
+				return err
+			}
 			c.emit(node, parser.OpReturn, 1)
 		}
+	case *parser.YieldStmt:
+		if c.scopeIndex == 0 {
+			// outside the function, including the top level of a module
+			return c.errorf(node, "yield not allowed outside function")
+		}
+
+		if node.Result == nil {
+			c.emit(node, parser.OpNull)
+		} else if err := c.Compile(node.Result); err != nil {
+			return err
+		}
+		c.emit(node, parser.OpYield)
+		c.scopes[c.scopeIndex].Generator = true
 	case *parser.CallExpr:
-		if err := c.Compile(node.Func); err != nil {
+		defer c.beginChain()()
+		if err := c.compileChainOperand(node.Func); err != nil {
 			return err
 		}
 		for _, arg := range node.Args {
@@ -506,6 +441,9 @@ func (c *Compiler) Compile(node parser.Node) error {
 				return err
 			}
 		}
+		if len(node.Keywords) > 0 {
+			return c.compileCallKw(node)
+		}
 		ellipsis := 0
 		if node.Ellipsis.IsValid() {
 			ellipsis = 1
@@ -542,14 +480,21 @@ func (c *Compiler) Compile(node parser.Node) error {
 				moduleName += ".tengo"
 			}
 
-			modulePath, err := filepath.Abs(
-				filepath.Join(c.importDir, moduleName))
-			if err != nil {
-				return c.errorf(node, "module file path error: %s",
-					err.Error())
+			var modulePath string
+			var moduleSrc []byte
+			var err error
+			if c.fs != nil {
+				modulePath = path.Join("/", c.importDir, moduleName)
+				moduleSrc, err = readFile(c.fs, modulePath)
+			} else {
+				modulePath, err = filepath.Abs(
+					filepath.Join(c.importDir, moduleName))
+				if err != nil {
+					return c.errorf(node, "module file path error: %s",
+						err.Error())
+				}
+				moduleSrc, err = ioutil.ReadFile(modulePath)
 			}
-
-			moduleSrc, err := ioutil.ReadFile(modulePath)
 			if err != nil {
 				return c.errorf(node, "module file read error: %s",
 					err.Error())
@@ -572,12 +517,22 @@ func (c *Compiler) Compile(node parser.Node) error {
 
 		// export statement is simply ignore when compiling non-module code
 		if c.parent == nil {
+			if c.symbolHook != nil {
+				// still resolve the identifiers of the exported value
+				if err := c.Compile(node.Result); err != nil {
+					return err
+				}
+				c.emit(node, parser.OpPop)
+			}
 			break
 		}
 		if err := c.Compile(node.Result); err != nil {
 			return err
 		}
 		c.emit(node, parser.OpImmutable)
+		if err := c.compileHandlerExits(node, 0); err != nil {
+			return err
+		}
 		c.emit(node, parser.OpReturn, 1)
 	case *parser.ErrorExpr:
 		if err := c.Compile(node.Expr); err != nil {
@@ -624,51 +579,432 @@ func (c *Compiler) Bytecode() *Bytecode {
 		MainFunction: &CompiledFunction{
 			Instructions: append(c.currentInstructions(), parser.OpSuspend),
 			SourceMap:    c.currentSourceMap(),
+			Locals:       c.globalSymbols(),
 		},
 		Constants: c.constants,
 	}
 }
 
+// globalSymbols returns the variables defined in the main scope including the
+// global variables that were defined in the symbol table before compilation.
+func (c *Compiler) globalSymbols() []*LocalSymbol {
+	locals := c.scopes[0].Locals
+	root := c.symbolTable
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	defined := make(map[*Symbol]bool)
+	for _, local := range locals {
+		if symbol, ok := root.store[local.Name]; ok &&
+			symbol.Index == local.Index {
+			defined[symbol] = true
+		}
+	}
+	var globals []*LocalSymbol
+	for name, symbol := range root.store {
+		if symbol.Scope == ScopeGlobal && !defined[symbol] {
+			globals = append(globals, &LocalSymbol{
+				Name:  name,
+				Index: symbol.Index,
+			})
+		}
+	}
+	sort.Slice(globals, func(i, j int) bool {
+		return globals[i].Index < globals[j].Index
+	})
+	return append(globals, locals...)
+}
+
 // EnableFileImport enables or disables module loading from local files.
 // Local file modules are disabled by default.
 func (c *Compiler) EnableFileImport(enable bool) {
 	c.allowFileImport = enable
 }
 
+// SetSymbolHook sets a function that is called whenever the compiler defines
+// or resolves a symbol for an identifier of the source file. def is the
+// identifier where the symbol is defined, or nil for the builtin functions and
+// the symbols defined before the compilation. Identifiers of the imported
+// modules are not reported, but the exported value of the source file is
+// compiled and reported even if it's not a module.
+func (c *Compiler) SetSymbolHook(
+	hook func(ident, def *parser.Ident, symbol *Symbol),
+) {
+	c.symbolHook = hook
+	c.symbolDefs = make(map[*Symbol]*parser.Ident)
+}
+
 // SetImportDir sets the initial import directory path for file imports.
 func (c *Compiler) SetImportDir(dir string) {
 	c.importDir = dir
 }
 
+// SetFS sets the file system that the file imports read instead of the
+// host's file system. The import directory is then a path in fsys.
+func (c *Compiler) SetFS(fsys FS) {
+	c.fs = fsys
+}
+
+// compileCallKw compiles the keyword arguments of the call, whose function
+// and positional arguments are compiled, and the call.
+func (c *Compiler) compileCallKw(node *parser.CallExpr) error {
+	names := make([]Object, 0, len(node.Keywords))
+	seen := make(map[string]bool, len(node.Keywords))
+	for _, kw := range node.Keywords {
+		if seen[kw.Name] {
+			return c.errorf(kw, "keyword argument '%s' repeated", kw.Name)
+		}
+		seen[kw.Name] = true
+		if err := c.Compile(kw.Value); err != nil {
+			return err
+		}
+		names = append(names, &String{Value: kw.Name})
+	}
+	c.emit(node, parser.OpCallKw,
+		c.addConstant(&ImmutableArray{Value: names}),
+		len(node.Args)+len(node.Keywords))
+	return nil
+}
+
+// compileFuncLit compiles the function literal. If receiver is true, the
+// function is a method of a record type that takes the receiver record as
+// "self".
+func (c *Compiler) compileFuncLit(node *parser.FuncLit, receiver bool) error {
+	c.enterScope()
+
+	numParams := len(node.Type.Params.List)
+	if receiver {
+		// methods take the receiver as the hidden first parameter
+		c.defineSymbol(node, "self").LocalAssigned = true
+		numParams++
+	}
+	paramNames := make([]string, 0, numParams)
+	if receiver {
+		paramNames = append(paramNames, "self")
+	}
+	params := make([]*Symbol, len(node.Type.Params.List))
+	for i, p := range node.Type.Params.List {
+		s := c.defineSymbol(p, p.Name)
+		params[i] = s
+		paramNames = append(paramNames, p.Name)
+
+		// function arguments is not assigned directly.
+		s.LocalAssigned = true
+	}
+
+	// the parameter with a default value takes the value when the argument
+	// is omitted or undefined
+	for i, def := range node.Type.Params.Defaults {
+		if def == nil {
+			continue
+		}
+		c.emit(def, parser.OpGetLocal, params[i].Index)
+		c.emit(def, parser.OpNull)
+		c.emit(def, parser.OpEqual)
+		jumpPos := c.emit(def, parser.OpJumpFalsy, 0)
+		if err := c.Compile(def); err != nil {
+			return err
+		}
+		c.emit(def, parser.OpSetLocal, params[i].Index)
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+	}
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	// code optimization
+	c.optimizeFunc(node)
+
+	freeSymbols := c.symbolTable.FreeSymbols()
+	numLocals := c.symbolTable.MaxSymbols()
+	locals := c.scopes[c.scopeIndex].Locals
+	generator := c.scopes[c.scopeIndex].Generator
+	instructions, sourceMap := c.leaveScope()
+
+	for _, s := range freeSymbols {
+		switch s.Scope {
+		case ScopeLocal:
+			if !s.LocalAssigned {
+				// Here, the closure is capturing a local variable that's
+				// not yet assigned its value. One example is a local
+				// recursive function:
+				//
+				//   func() {
+				//     foo := func(x) {
+				//       // ..
+				//       return foo(x-1)
+				//     }
+				//   }
+				//
+				// which translate into
+				//
+				//   0000 GETL    0
+				//   0002 CLOSURE ?     1
+				//   0006 DEFL    0
+				//
+				// . So the local variable (0) is being captured before
+				// it's assigned the value.
+				//
+				// Solution is to transform the code into something like
+				// this:
+				//
+				//   func() {
+				//     foo := undefined
+				//     foo = func(x) {
+				//       // ..
+				//       return foo(x-1)
+				//     }
+				//   }
+				//
+				// that is equivalent to
+				//
+				//   0000 NULL
+				//   0001 DEFL    0
+				//   0003 GETL    0
+				//   0005 CLOSURE ?     1
+				//   0009 SETL    0
+				//
+				c.emit(node, parser.OpNull)
+				c.emit(node, parser.OpDefineLocal, s.Index)
+				s.LocalAssigned = true
+			}
+			c.emit(node, parser.OpGetLocalPtr, s.Index)
+		case ScopeFree:
+			c.emit(node, parser.OpGetFreePtr, s.Index)
+		}
+	}
+
+	var freeNames []string
+	for _, s := range freeSymbols {
+		freeNames = append(freeNames, s.Name)
+	}
+
+	compiledFunction := &CompiledFunction{
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: numParams,
+		VarArgs:       node.Type.Params.VarArgs,
+		SourceMap:     sourceMap,
+		Locals:        locals,
+		FreeNames:     freeNames,
+		Generator:     generator,
+		NumDefaults:   node.Type.Params.NumDefaults(),
+		ParamNames:    paramNames,
+	}
+	if len(freeSymbols) > 0 {
+		c.emit(node, parser.OpClosure,
+			c.addConstant(compiledFunction), len(freeSymbols))
+	} else {
+		c.emit(node, parser.OpConstant, c.addConstant(compiledFunction))
+	}
+	return nil
+}
+
+// compileRecord compiles the record statement into a record type that is
+// assigned to the variable of the type name.
+func (c *Compiler) compileRecord(node *parser.RecordStmt) error {
+	names := make(map[string]bool)
+	var fields []string
+	for _, f := range node.Fields.List {
+		if names[f.Name] {
+			return c.errorf(f, "duplicate field '%s'", f.Name)
+		}
+		names[f.Name] = true
+		fields = append(fields, f.Name)
+	}
+	for _, m := range node.Methods {
+		if names[m.Key] {
+			return c.errorf(m, "duplicate field or method '%s'", m.Key)
+		}
+		names[m.Key] = true
+	}
+
+	name := node.Name.Name
+	if _, depth, exists := c.symbolTable.Resolve(name, false); depth == 0 &&
+		exists {
+		return c.errorf(node, "'%s' redeclared in this block", name)
+	}
+	symbol := c.defineSymbol(node.Name, name)
+
+	// methods are defined after the type name so that they can refer to it
+	for _, m := range node.Methods {
+		fn, ok := m.Value.(*parser.FuncLit)
+		if !ok {
+			return c.errorf(m.Value, "method must be a function literal")
+		}
+		c.emit(m, parser.OpConstant,
+			c.addConstant(&String{Value: m.Key}))
+		if err := c.compileFuncLit(fn, true); err != nil {
+			return err
+		}
+	}
+	c.emit(node, parser.OpRecord, c.addConstant(&RecordType{
+		Name:   name,
+		Fields: fields,
+	}), len(node.Methods))
+
+	switch symbol.Scope {
+	case ScopeGlobal:
+		c.emit(node, parser.OpSetGlobal, symbol.Index)
+	case ScopeLocal:
+		if symbol.LocalAssigned {
+			// already defined by a method capturing the type
+			c.emit(node, parser.OpSetLocal, symbol.Index)
+		} else {
+			c.emit(node, parser.OpDefineLocal, symbol.Index)
+		}
+		symbol.LocalAssigned = true
+	}
+	return nil
+}
+
+// compileArrayLit compiles an array literal. The elements between the spread
+// elements are collected into arrays, and the spread elements and the
+// collected arrays extend the first array in order: "[a, b..., c]" is
+// compiled to "ARR [a]; b; SPREAD; ARR [c]; SPREAD".
+func (c *Compiler) compileArrayLit(node *parser.ArrayLit) error {
+	var n int // number of the elements not collected yet
+	var started bool
+	for _, elem := range node.Elements {
+		spread, ok := elem.(*parser.SpreadExpr)
+		if !ok {
+			if err := c.Compile(elem); err != nil {
+				return err
+			}
+			n++
+			continue
+		}
+		if !started || n > 0 {
+			c.emit(node, parser.OpArray, n)
+			if started {
+				c.emit(node, parser.OpSpread)
+			}
+			started, n = true, 0
+		}
+		if err := c.Compile(spread.Expr); err != nil {
+			return err
+		}
+		c.emit(spread, parser.OpSpread)
+	}
+	if !started || n > 0 {
+		c.emit(node, parser.OpArray, n)
+		if started {
+			c.emit(node, parser.OpSpread)
+		}
+	}
+	return nil
+}
+
+// compileMapLit compiles a map literal. The spread elements are compiled in
+// the same way as compileArrayLit, so the later entries override the earlier
+// ones: "{a: 1, m..., b: 2}" is compiled to
+// "MAP {a: 1}; m; SPREAD; MAP {b: 2}; SPREAD".
+func (c *Compiler) compileMapLit(node *parser.MapLit) error {
+	var n int // number of the entries not collected yet
+	var started bool
+	for _, elt := range node.Elements {
+		if elt.IsSpread() {
+			if !started || n > 0 {
+				c.emit(node, parser.OpMap, n*2)
+				if started {
+					c.emit(node, parser.OpSpread)
+				}
+				started, n = true, 0
+			}
+			spread := elt.Value.(*parser.SpreadExpr)
+			if err := c.Compile(spread.Expr); err != nil {
+				return err
+			}
+			c.emit(spread, parser.OpSpread)
+			continue
+		}
+
+		// key
+		if elt.KeyExpr != nil {
+			if err := c.Compile(elt.KeyExpr); err != nil {
+				return err
+			}
+		} else {
+			if len(elt.Key) > MaxStringLen {
+				return c.error(node, ErrStringLimit)
+			}
+			c.emit(node, parser.OpConstant,
+				c.addConstant(&String{Value: elt.Key}))
+		}
+
+		// value
+		if err := c.Compile(elt.Value); err != nil {
+			return err
+		}
+		n++
+	}
+	if !started || n > 0 {
+		c.emit(node, parser.OpMap, n*2)
+		if started {
+			c.emit(node, parser.OpSpread)
+		}
+	}
+	return nil
+}
+
 func (c *Compiler) compileAssign(
 	node parser.Node,
 	lhs, rhs []parser.Expr,
 	op token.Token,
 ) error {
 	numLHS, numRHS := len(lhs), len(rhs)
-	if numLHS > 1 || numRHS > 1 {
+	if numRHS > 1 {
 		return c.errorf(node, "tuple assignment not allowed")
 	}
+	if numLHS > 1 {
+		return c.compileDestructuring(node, lhs, nil, rhs[0], op)
+	}
+	switch pattern := lhs[0].(type) {
+	case *parser.ArrayLit:
+		return c.compileDestructuring(node, pattern.Elements, nil, rhs[0], op)
+	case *parser.MapLit:
+		var keys []string
+		var targets []parser.Expr
+		for _, elt := range pattern.Elements {
+			if elt.KeyExpr != nil || elt.IsSpread() {
+				return c.errorf(elt, "invalid destructuring target")
+			}
+			keys = append(keys, elt.Key)
+			targets = append(targets, elt.Value)
+		}
+		return c.compileDestructuring(node, targets, keys, rhs[0], op)
+	}
+
+	if link := optionalLink(lhs[0]); link != nil {
+		return c.errorf(link, "optional chaining not allowed in assignment")
+	}
 
 	// resolve and compile left-hand side
 	ident, selectors := resolveAssignLHS(lhs[0])
 	numSel := len(selectors)
+	var name string
+	if ident != nil {
+		name = ident.Name
+	}
 
 	if op == token.Define && numSel > 0 {
 		// using selector on new variable does not make sense
 		return c.errorf(node, "operator ':=' not allowed with selector")
 	}
 
-	symbol, depth, exists := c.symbolTable.Resolve(ident, false)
+	symbol, depth, exists := c.symbolTable.Resolve(name, false)
 	if op == token.Define {
 		if depth == 0 && exists {
-			return c.errorf(node, "'%s' redeclared in this block", ident)
+			return c.errorf(node, "'%s' redeclared in this block", name)
 		}
-		symbol = c.symbolTable.Define(ident)
+		symbol = c.defineSymbol(lhs[0], name)
 	} else {
 		if !exists {
-			return c.errorf(node, "unresolved reference '%s'", ident)
+			return c.errorf(node, "unresolved reference '%s'", name)
 		}
+		c.resolved(ident, symbol)
 	}
 
 	// +=, -=, *=, /=
@@ -716,7 +1052,111 @@ func (c *Compiler) compileAssign(
 			return err
 		}
 	}
+	c.emitStore(node, symbol, numSel, op)
+	return nil
+}
+
+// compileDestructuring compiles an assignment of the elements of an array to
+// the targets, e.g. "a, b := f()" or "[a, _, c] = arr"; or an assignment of
+// the values of the keys to the targets if keys is not nil, e.g.
+// "{name, age: a} := m". The blank identifier "_" discards the value.
+func (c *Compiler) compileDestructuring(
+	node parser.Node,
+	targets []parser.Expr,
+	keys []string,
+	rhs parser.Expr,
+	op token.Token,
+) error {
+	if op != token.Assign && op != token.Define {
+		return c.errorf(node, "operator '%s' not allowed with destructuring",
+			op.String())
+	}
+	if len(targets) == 0 {
+		return c.errorf(node, "nothing to destructure")
+	}
+
+	// resolve or define the targets before compiling the right-hand side
+	symbols := make([]*Symbol, len(targets))
+	selectors := make([][]parser.Expr, len(targets))
+	names := make(map[string]bool)
+	for i, target := range targets {
+		if link := optionalLink(target); link != nil {
+			return c.errorf(link,
+				"optional chaining not allowed in assignment")
+		}
+		ident, sel := resolveAssignLHS(target)
+		if ident == nil {
+			return c.errorf(target, "invalid destructuring target")
+		}
+		if ident.Name == "_" && len(sel) == 0 {
+			continue // blank identifier
+		}
+		if op == token.Define {
+			if len(sel) > 0 {
+				return c.errorf(target,
+					"operator ':=' not allowed with selector")
+			}
+			if names[ident.Name] {
+				return c.errorf(target, "'%s' repeated in destructuring",
+					ident.Name)
+			}
+			names[ident.Name] = true
+			if _, depth, exists := c.symbolTable.Resolve(ident.Name,
+				false); depth == 0 && exists {
+				return c.errorf(target, "'%s' redeclared in this block",
+					ident.Name)
+			}
+			symbols[i] = c.defineSymbol(ident, ident.Name)
+		} else {
+			symbol, _, exists := c.symbolTable.Resolve(ident.Name, false)
+			if !exists {
+				return c.errorf(target, "unresolved reference '%s'",
+					ident.Name)
+			}
+			c.resolved(ident, symbol)
+			symbols[i] = symbol
+		}
+		selectors[i] = sel
+	}
 
+	if err := c.Compile(rhs); err != nil {
+		return err
+	}
+	if keys != nil {
+		for _, key := range keys {
+			c.emit(node, parser.OpConstant,
+				c.addConstant(&String{Value: key}))
+		}
+		c.emit(node, parser.OpUnpackMap, len(keys))
+	} else {
+		c.emit(node, parser.OpUnpack, len(targets))
+	}
+
+	// the values are on the stack with the first one on top
+	for i, target := range targets {
+		if symbols[i] == nil {
+			c.emit(target, parser.OpPop)
+			continue
+		}
+		sel := selectors[i]
+		for j := len(sel) - 1; j >= 0; j-- {
+			if err := c.Compile(sel[j]); err != nil {
+				return err
+			}
+		}
+		c.emitStore(target, symbols[i], len(sel), op)
+	}
+	return nil
+}
+
+// emitStore emits the instruction to store the value on the stack in the
+// variable, or in the element of the variable if numSel > 0.
+func (c *Compiler) emitStore(
+	node parser.Node,
+	symbol *Symbol,
+	numSel int,
+	op token.Token,
+) {
 	switch symbol.Scope {
 	case ScopeGlobal:
 		if numSel > 0 {
@@ -747,7 +1187,6 @@ func (c *Compiler) compileAssign(
 		panic(fmt.Errorf("invalid assignment variable scope: %s",
 			symbol.Scope))
 	}
-	return nil
 }
 
 func (c *Compiler) compileLogical(node *parser.BinaryExpr) error {
@@ -758,9 +1197,12 @@ func (c *Compiler) compileLogical(node *parser.BinaryExpr) error {
 
 	// jump position
 	var jumpPos int
-	if node.Token == token.LAnd {
+	switch node.Token {
+	case token.LAnd:
 		jumpPos = c.emit(node, parser.OpAndJump, 0)
-	} else {
+	case token.Coalesce:
+		jumpPos = c.emit(node, parser.OpCoalesceJump, 0)
+	default:
 		jumpPos = c.emit(node, parser.OpOrJump, 0)
 	}
 
@@ -773,11 +1215,66 @@ func (c *Compiler) compileLogical(node *parser.BinaryExpr) error {
 	return nil
 }
 
+// compileIndex compiles the selector or index expression. If it's optional,
+// it jumps to the end of the chain with undefined when the operand is
+// undefined or not indexable.
+func (c *Compiler) compileIndex(
+	node, expr, index parser.Expr,
+	optional bool,
+) error {
+	defer c.beginChain()()
+	if err := c.compileChainOperand(expr); err != nil {
+		return err
+	}
+	if optional {
+		c.chainJumps = append(c.chainJumps,
+			c.emit(node, parser.OpOptionalJump, 0))
+	}
+	if err := c.Compile(index); err != nil {
+		return err
+	}
+	if optional {
+		c.chainJumps = append(c.chainJumps,
+			c.emit(node, parser.OpOptionalIndex, 0))
+	} else {
+		c.emit(node, parser.OpIndex)
+	}
+	return nil
+}
+
+// beginChain begins a chain of selector, index, slice and call expressions,
+// unless the expression being compiled is the operand of another one in the
+// chain. It returns the function to end the chain, which sets the jumps of
+// the optional links to the end of the chain.
+func (c *Compiler) beginChain() func() {
+	if c.chainLink {
+		c.chainLink = false
+		return func() {}
+	}
+	outer := c.chainJumps
+	c.chainJumps = nil
+	return func() {
+		for _, pos := range c.chainJumps {
+			c.changeOperand(pos, len(c.currentInstructions()))
+		}
+		c.chainJumps = outer
+	}
+}
+
+// compileChainOperand compiles the operand of a selector, index, slice or
+// call expression. The operand continues the chain if it's also one of them.
+func (c *Compiler) compileChainOperand(expr parser.Expr) error {
+	switch expr.(type) {
+	case *parser.SelectorExpr, *parser.IndexExpr, *parser.SliceExpr,
+		*parser.CallExpr:
+		c.chainLink = true
+	}
+	return c.Compile(expr)
+}
+
 func (c *Compiler) compileForStmt(stmt *parser.ForStmt) error {
-	c.symbolTable = c.symbolTable.Fork(true)
-	defer func() {
-		c.symbolTable = c.symbolTable.Parent(false)
-	}()
+	c.enterBlock(stmt)
+	defer c.leaveBlock()
 
 	// init statement
 	if stmt.Init != nil {
@@ -840,10 +1337,8 @@ func (c *Compiler) compileForStmt(stmt *parser.ForStmt) error {
 }
 
 func (c *Compiler) compileForInStmt(stmt *parser.ForInStmt) error {
-	c.symbolTable = c.symbolTable.Fork(true)
-	defer func() {
-		c.symbolTable = c.symbolTable.Parent(false)
-	}()
+	c.enterBlock(stmt)
+	defer c.leaveBlock()
 
 	// for-in statement is compiled like following:
 	//
@@ -889,7 +1384,7 @@ func (c *Compiler) compileForInStmt(stmt *parser.ForInStmt) error {
 
 	// assign key variable
 	if stmt.Key.Name != "_" {
-		keySymbol := c.symbolTable.Define(stmt.Key.Name)
+		keySymbol := c.defineSymbol(stmt.Key, stmt.Key.Name)
 		if itSymbol.Scope == ScopeGlobal {
 			c.emit(stmt, parser.OpGetGlobal, itSymbol.Index)
 		} else {
@@ -906,7 +1401,7 @@ func (c *Compiler) compileForInStmt(stmt *parser.ForInStmt) error {
 
 	// assign value variable
 	if stmt.Value.Name != "_" {
-		valueSymbol := c.symbolTable.Define(stmt.Value.Name)
+		valueSymbol := c.defineSymbol(stmt.Value, stmt.Value.Name)
 		if itSymbol.Scope == ScopeGlobal {
 			c.emit(stmt, parser.OpGetGlobal, itSymbol.Index)
 		} else {
@@ -949,6 +1444,319 @@ func (c *Compiler) compileForInStmt(stmt *parser.ForInStmt) error {
 	return nil
 }
 
+func (c *Compiler) compileInterpStringLit(
+	node *parser.InterpStringLit,
+) error {
+	// interpolated string literal is compiled to the concatenation of its
+	// parts. it always starts with a string value so that the values of the
+	// placeholders are converted to strings by the string addition.
+	first := 0
+	if len(node.Parts) > 0 {
+		if _, ok := node.Parts[0].(*parser.StringLit); ok {
+			first = 1
+		}
+	}
+	if first == 0 {
+		c.emit(node, parser.OpConstant, c.addConstant(&String{Value: ""}))
+	}
+	for i, part := range node.Parts {
+		switch part := part.(type) {
+		case *parser.StringLit:
+			if err := c.Compile(part); err != nil {
+				return err
+			}
+		case *parser.InterpExpr:
+			if err := c.Compile(part.Expr); err != nil {
+				return err
+			}
+			if part.Verb != "" {
+				c.emit(part, parser.OpFormat,
+					c.addConstant(&String{Value: part.Verb}))
+			}
+		}
+		if i >= first {
+			c.emit(part, parser.OpBinaryOp, int(token.Add))
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileSwitchStmt(stmt *parser.SwitchStmt) error {
+	c.enterBlock(stmt)
+	defer c.leaveBlock()
+
+	// switch statement is compiled like following:
+	//
+	//   :sw := tag
+	//   if !(:sw == case0_value0) { goto case0 }
+	//   if !(:sw == case0_value1) { goto case0 }
+	//   if !(:sw == case1_value0) { goto case1 }
+	//   goto default     (or end if no default clause)
+	//   case0:
+	//     ... body ...
+	//     goto end
+	//   case1:
+	//     ... body ...
+	//     goto end
+	//   default:
+	//     ... body ...
+	//   end:
+	//
+	// without tag, case values are used as the conditions.
+	var swSymbol *Symbol
+	if stmt.Tag != nil {
+		swSymbol = c.symbolTable.Define(":sw")
+		if err := c.Compile(stmt.Tag); err != nil {
+			return err
+		}
+		if swSymbol.Scope == ScopeGlobal {
+			c.emit(stmt, parser.OpSetGlobal, swSymbol.Index)
+		} else {
+			c.emit(stmt, parser.OpDefineLocal, swSymbol.Index)
+		}
+	}
+
+	// case conditions
+	caseJumps := make([][]int, len(stmt.Cases))
+	for i, clause := range stmt.Cases {
+		for _, expr := range clause.List {
+			if swSymbol != nil {
+				if swSymbol.Scope == ScopeGlobal {
+					c.emit(expr, parser.OpGetGlobal, swSymbol.Index)
+				} else {
+					c.emit(expr, parser.OpGetLocal, swSymbol.Index)
+				}
+			}
+			if err := c.Compile(expr); err != nil {
+				return err
+			}
+			if swSymbol != nil {
+				c.emit(expr, parser.OpEqual)
+			}
+			c.emit(expr, parser.OpLNot)
+			caseJumps[i] = append(caseJumps[i],
+				c.emit(expr, parser.OpJumpFalsy, 0))
+		}
+	}
+	defaultJump := c.emit(stmt, parser.OpJump, 0)
+
+	// enter switch
+	loop := c.enterLoop()
+	loop.Switch = true
+
+	// case bodies
+	var endJumps []int
+	hasDefault := false
+	for i, clause := range stmt.Cases {
+		curPos := len(c.currentInstructions())
+		for _, pos := range caseJumps[i] {
+			c.changeOperand(pos, curPos)
+		}
+		if clause.List == nil {
+			c.changeOperand(defaultJump, curPos)
+			hasDefault = true
+		}
+		if err := c.compileCaseBody(clause); err != nil {
+			c.leaveLoop()
+			return err
+		}
+		if i < len(stmt.Cases)-1 {
+			endJumps = append(endJumps, c.emit(clause, parser.OpJump, 0))
+		}
+	}
+
+	c.leaveLoop()
+
+	// update all end jump positions
+	endPos := len(c.currentInstructions())
+	if !hasDefault {
+		c.changeOperand(defaultJump, endPos)
+	}
+	for _, pos := range endJumps {
+		c.changeOperand(pos, endPos)
+	}
+	for _, pos := range loop.Breaks {
+		c.changeOperand(pos, endPos)
+	}
+	return nil
+}
+
+func (c *Compiler) compileCaseBody(clause *parser.CaseClause) error {
+	c.enterBlock(clause)
+	defer c.leaveBlock()
+
+	for _, stmt := range clause.Body {
+		if err := c.Compile(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileTryStmt(stmt *parser.TryStmt) error {
+	// try statement is compiled like following:
+	//
+	//     TRY     catch        // set up error handler
+	//     ... body ...
+	//     TRYEND               // remove error handler
+	//     ... finally ...
+	//     JMP     end
+	//   catch:                 // error object is on top of the stack
+	//     DEFL    err
+	//     TRY     finally      // if finally block exists
+	//     ... catch ...
+	//     TRYEND               // if finally block exists
+	//     ... finally ...
+	//     JMP     end          // if finally block exists
+	//   finally:               // error object is on top of the stack
+	//     DEFL    :err
+	//     ... finally ...
+	//     GETL    :err
+	//     THROW                // re-raise the error object
+	//   end:
+	//
+	// If there's no catch block, the error handler of the body jumps to the
+	// "finally" label. Finally blocks are also compiled before "return",
+	// "break" and "continue" statements leaving the try statement.
+	var endJumps []int
+
+	// try body
+	tryPos := c.emit(stmt, parser.OpTry, 0)
+	if err := c.compileHandlerBody(stmt.Body, stmt.Finally); err != nil {
+		return err
+	}
+	endJumps = append(endJumps, c.emit(stmt, parser.OpJump, 0))
+
+	// catch block
+	if stmt.Catch != nil {
+		c.changeOperand(tryPos, len(c.currentInstructions()))
+		pos, err := c.compileCatch(stmt)
+		if err != nil {
+			return err
+		}
+		if stmt.Finally != nil {
+			tryPos = pos
+			endJumps = append(endJumps, c.emit(stmt, parser.OpJump, 0))
+		}
+	}
+
+	// finally block for the errors not handled by catch block
+	if stmt.Finally != nil {
+		c.changeOperand(tryPos, len(c.currentInstructions()))
+		if err := c.compileFinallyThrow(stmt); err != nil {
+			return err
+		}
+	}
+
+	curPos := len(c.currentInstructions())
+	for _, pos := range endJumps {
+		c.changeOperand(pos, curPos)
+	}
+	return nil
+}
+
+// compileFinallyThrow compiles the finally block that runs before re-raising
+// the error not handled by the catch block. The error object is kept in the
+// local variable ":err" so that the finally block can jump out of the
+// statement.
+func (c *Compiler) compileFinallyThrow(stmt *parser.TryStmt) error {
+	c.enterBlock(stmt.Finally)
+	defer c.leaveBlock()
+
+	errSymbol := c.symbolTable.Define(":err")
+	if errSymbol.Scope == ScopeGlobal {
+		c.emit(stmt, parser.OpSetGlobal, errSymbol.Index)
+	} else {
+		errSymbol.LocalAssigned = true
+		c.emit(stmt, parser.OpDefineLocal, errSymbol.Index)
+	}
+	if err := c.Compile(stmt.Finally); err != nil {
+		return err
+	}
+	if errSymbol.Scope == ScopeGlobal {
+		c.emit(stmt, parser.OpGetGlobal, errSymbol.Index)
+	} else {
+		c.emit(stmt, parser.OpGetLocal, errSymbol.Index)
+	}
+	c.emit(stmt, parser.OpThrow)
+	return nil
+}
+
+// compileCatch compiles the catch block of the try statement. It returns the
+// position of the error handler instruction of the catch block if the
+// statement has a finally block.
+func (c *Compiler) compileCatch(stmt *parser.TryStmt) (int, error) {
+	c.enterBlock(stmt.Catch)
+	defer c.leaveBlock()
+
+	// assign error variable
+	if stmt.CatchIdent != nil && stmt.CatchIdent.Name != "_" {
+		symbol := c.defineSymbol(stmt.CatchIdent, stmt.CatchIdent.Name)
+		if symbol.Scope == ScopeGlobal {
+			c.emit(stmt, parser.OpSetGlobal, symbol.Index)
+		} else {
+			symbol.LocalAssigned = true
+			c.emit(stmt, parser.OpDefineLocal, symbol.Index)
+		}
+	} else {
+		c.emit(stmt, parser.OpPop)
+	}
+
+	if stmt.Finally == nil {
+		return -1, c.Compile(stmt.Catch)
+	}
+	tryPos := c.emit(stmt, parser.OpTry, 0)
+	return tryPos, c.compileHandlerBody(stmt.Catch, stmt.Finally)
+}
+
+// compileHandlerBody compiles the body of an error handler followed by the
+// finally block.
+func (c *Compiler) compileHandlerBody(
+	body, finally *parser.BlockStmt,
+) error {
+	scope := &c.scopes[c.scopeIndex]
+	scope.Handlers = append(scope.Handlers, finally)
+	if c.trace != nil {
+		c.printTrace("HNDLE", len(scope.Handlers))
+	}
+	err := c.Compile(body)
+	scope = &c.scopes[c.scopeIndex]
+	scope.Handlers = scope.Handlers[:len(scope.Handlers)-1]
+	if c.trace != nil {
+		c.printTrace("HNDLL", len(scope.Handlers))
+	}
+	if err != nil {
+		return err
+	}
+
+	c.emit(body, parser.OpTryEnd)
+	if finally != nil {
+		return c.Compile(finally)
+	}
+	return nil
+}
+
+// compileHandlerExits removes the error handlers of the current function above
+// the given depth and compiles their finally blocks. It's used before the
+// statements jumping out of try statements.
+func (c *Compiler) compileHandlerExits(node parser.Node, depth int) error {
+	handlers := c.scopes[c.scopeIndex].Handlers
+	defer func() {
+		c.scopes[c.scopeIndex].Handlers = handlers
+	}()
+
+	for i := len(handlers) - 1; i >= depth; i-- {
+		c.scopes[c.scopeIndex].Handlers = handlers[:i]
+		c.emit(node, parser.OpTryEnd)
+		if handlers[i] != nil {
+			if err := c.Compile(handlers[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (c *Compiler) checkCyclicImports(
 	node parser.Node,
 	modulePath string,
@@ -1026,8 +1834,48 @@ func (c *Compiler) storeCompiledModule(
 	c.compiledModules[modulePath] = module
 }
 
+func (c *Compiler) enterBlock(node parser.Node) {
+	c.blockEnds = append(c.blockEnds, node.End())
+	c.symbolTable = c.symbolTable.Fork(true)
+}
+
+func (c *Compiler) leaveBlock() {
+	c.blockEnds = c.blockEnds[:len(c.blockEnds)-1]
+	c.symbolTable = c.symbolTable.Parent(false)
+}
+
+// defineSymbol defines a new symbol in the current symbol table, and keeps
+// its name in the compiled function for debuggers.
+func (c *Compiler) defineSymbol(node parser.Node, name string) *Symbol {
+	symbol := c.symbolTable.Define(name)
+	local := &LocalSymbol{
+		Name:  name,
+		Index: symbol.Index,
+		Pos:   node.Pos(),
+	}
+	if n := len(c.blockEnds); n > 0 {
+		local.End = c.blockEnds[n-1]
+	}
+	scope := &c.scopes[c.scopeIndex]
+	scope.Locals = append(scope.Locals, local)
+	if ident, ok := node.(*parser.Ident); ok && c.symbolHook != nil {
+		c.symbolDefs[symbol] = ident
+		c.symbolHook(ident, ident, symbol)
+	}
+	return symbol
+}
+
+// resolved reports the symbol resolved for the identifier to the symbol hook.
+func (c *Compiler) resolved(ident *parser.Ident, symbol *Symbol) {
+	if c.symbolHook == nil {
+		return
+	}
+	def := c.symbolDefs[c.symbolTable.original(symbol)]
+	c.symbolHook(ident, def, symbol)
+}
+
 func (c *Compiler) enterLoop() *loop {
-	loop := &loop{}
+	loop := &loop{NumHandlers: len(c.scopes[c.scopeIndex].Handlers)}
 	c.loops = append(c.loops, loop)
 	c.loopIndex++
 	if c.trace != nil {
@@ -1051,6 +1899,16 @@ func (c *Compiler) currentLoop() *loop {
 	return nil
 }
 
+// continuableLoop returns the innermost loop skipping switch statements.
+func (c *Compiler) continuableLoop() *loop {
+	for i := c.loopIndex; i >= 0; i-- {
+		if !c.loops[i].Switch {
+			return c.loops[i]
+		}
+	}
+	return nil
+}
+
 func (c *Compiler) currentInstructions() []byte {
 	return c.scopes[c.scopeIndex].Instructions
 }
@@ -1066,6 +1924,7 @@ func (c *Compiler) enterScope() {
 	}
 	c.scopes = append(c.scopes, scope)
 	c.scopeIndex++
+	c.blockEnds = append(c.blockEnds, parser.NoPos)
 	c.symbolTable = c.symbolTable.Fork(false)
 	if c.trace != nil {
 		c.printTrace("SCOPE", c.scopeIndex)
@@ -1080,6 +1939,7 @@ func (c *Compiler) leaveScope() (
 	sourceMap = c.currentSourceMap()
 	c.scopes = c.scopes[:len(c.scopes)-1]
 	c.scopeIndex--
+	c.blockEnds = c.blockEnds[:len(c.blockEnds)-1]
 	c.symbolTable = c.symbolTable.Parent(true)
 	if c.trace != nil {
 		c.printTrace("SCOPL", c.scopeIndex)
@@ -1098,7 +1958,10 @@ func (c *Compiler) fork(
 	child.parent = c              // parent to set to current compiler
 	child.allowFileImport = c.allowFileImport
 	child.importDir = c.importDir
-	if isFile && c.importDir != "" {
+	child.fs = c.fs
+	if isFile && c.fs != nil {
+		child.importDir = path.Dir(modulePath)
+	} else if isFile && c.importDir != "" {
 		child.importDir = filepath.Dir(modulePath)
 	}
 	return child
@@ -1172,7 +2035,9 @@ func (c *Compiler) optimizeFunc(node parser.Node) {
 		func(pos int, opcode parser.Opcode, operands []int) bool {
 			switch opcode {
 			case parser.OpJump, parser.OpJumpFalsy,
-				parser.OpAndJump, parser.OpOrJump:
+				parser.OpAndJump, parser.OpOrJump, parser.OpTry,
+				parser.OpOptionalJump, parser.OpOptionalIndex,
+				parser.OpCoalesceJump:
 				dsts[operands[0]] = true
 			}
 			return true
@@ -1212,7 +2077,8 @@ func (c *Compiler) optimizeFunc(node parser.Node) {
 		func(pos int, opcode parser.Opcode, operands []int) bool {
 			switch opcode {
 			case parser.OpJump, parser.OpJumpFalsy, parser.OpAndJump,
-				parser.OpOrJump:
+				parser.OpOrJump, parser.OpTry, parser.OpOptionalJump,
+				parser.OpOptionalIndex, parser.OpCoalesceJump:
 				newDst, ok := posMap[operands[0]]
 				if ok {
 					copy(newInsts[pos:],
@@ -1289,21 +2155,43 @@ func (c *Compiler) printTrace(a ...interface{}) {
 
 func resolveAssignLHS(
 	expr parser.Expr,
-) (name string, selectors []parser.Expr) {
+) (ident *parser.Ident, selectors []parser.Expr) {
 	switch term := expr.(type) {
 	case *parser.SelectorExpr:
-		name, selectors = resolveAssignLHS(term.Expr)
+		ident, selectors = resolveAssignLHS(term.Expr)
 		selectors = append(selectors, term.Sel)
 		return
 	case *parser.IndexExpr:
-		name, selectors = resolveAssignLHS(term.Expr)
+		ident, selectors = resolveAssignLHS(term.Expr)
 		selectors = append(selectors, term.Index)
 	case *parser.Ident:
-		name = term.Name
+		ident = term
 	}
 	return
 }
 
+// optionalLink returns the first optional link of the selector or index
+// chain expr, or nil if the chain has none.
+func optionalLink(expr parser.Expr) parser.Expr {
+	var link parser.Expr
+	for {
+		switch term := expr.(type) {
+		case *parser.SelectorExpr:
+			if term.Optional {
+				link = term
+			}
+			expr = term.Expr
+		case *parser.IndexExpr:
+			if term.Optional {
+				link = term
+			}
+			expr = term.Expr
+		default:
+			return link
+		}
+	}
+}
+
 func iterateInstructions(
 	b []byte,
 	fn func(pos int, opcode parser.Opcode, operands []int) bool,
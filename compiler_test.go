@@ -502,6 +502,34 @@ func TestCompiler_Compile(t *testing.T) {
 				intObject(1),
 				intObject(2))))
 
+	expectCompile(t, `f := func(a, b=2) { return b }; f(1, b: 3)`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpSetGlobal, 0),
+				tengo.MakeInstruction(parser.OpGetGlobal, 0),
+				tengo.MakeInstruction(parser.OpConstant, 2),
+				tengo.MakeInstruction(parser.OpConstant, 3),
+				tengo.MakeInstruction(parser.OpCallKw, 4, 2),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				intObject(2),
+				compiledFunction(2, 2,
+					tengo.MakeInstruction(parser.OpGetLocal, 1),
+					tengo.MakeInstruction(parser.OpNull),
+					tengo.MakeInstruction(parser.OpEqual),
+					tengo.MakeInstruction(parser.OpJumpFalsy, 12),
+					tengo.MakeInstruction(parser.OpConstant, 0),
+					tengo.MakeInstruction(parser.OpSetLocal, 1),
+					tengo.MakeInstruction(parser.OpGetLocal, 1),
+					tengo.MakeInstruction(parser.OpReturn, 1)),
+				intObject(1),
+				intObject(3),
+				&tengo.ImmutableArray{Value: []tengo.Object{
+					&tengo.String{Value: "b"},
+				}})))
+
 	expectCompile(t, `func() { return 5 + 10 }`,
 		bytecode(
 			concatInsts(
@@ -1024,6 +1052,18 @@ func TestCompilerErrorReport(t *testing.T) {
 		"Compile Error: tuple assignment not allowed\n\tat test:1:1")
 	expectCompileError(t, `a.b := 1`,
 		"not allowed with selector")
+	expectCompileError(t, `[a, b] += [1, 2]`,
+		"Compile Error: operator '+=' not allowed with destructuring\n\tat test:1:1")
+	expectCompileError(t, `a, a := [1, 2]`,
+		"Compile Error: 'a' repeated in destructuring\n\tat test:1:4")
+	expectCompileError(t, `a, b = [1, 2]`,
+		"Compile Error: unresolved reference 'a'\n\tat test:1:1")
+	expectCompileError(t, `{a: 1} := m`,
+		"invalid destructuring target")
+	expectCompileError(t, `[] := a`, "nothing to destructure")
+	expectCompileError(t, `f := func(a) {}; f(a: 1, a: 2)`,
+		"Compile Error: keyword argument 'a' repeated\n\tat test:1:26")
+	expectCompileError(t, `a := [b...]`, "unresolved reference 'b'")
 	expectCompileError(t, `a:=1; a:=3`,
 		"Compile Error: 'a' redeclared in this block\n\tat test:1:7")
 
@@ -1222,6 +1262,45 @@ func() {
 				tengo.MakeInstruction(parser.OpReturn, 0)))))
 }
 
+func TestCompilerSymbolHook(t *testing.T) {
+	src := `
+a := 1
+f := func(x) {
+	b := len(x)
+	return func() { return a + b }
+}
+a = f([])()`
+	fileSet := parser.NewFileSet()
+	file := fileSet.AddFile("test", -1, len(src))
+	p := parser.NewParser(file, []byte(src), nil)
+	parsed, err := p.ParseFile()
+	require.NoError(t, err)
+
+	var refs []string
+	c := tengo.NewCompiler(file, nil, nil, nil, nil)
+	c.SetSymbolHook(func(ident, def *parser.Ident, symbol *tengo.Symbol) {
+		ref := fmt.Sprintf("%s %s %s", ident.Name,
+			file.Position(ident.Pos()), symbol.Scope)
+		if def != nil {
+			ref += " " + file.Position(def.Pos()).String()
+		}
+		refs = append(refs, ref)
+	})
+	require.NoError(t, c.Compile(parsed))
+	require.Equal(t, []string{
+		"a test:2:1 GLOBAL test:2:1",
+		"f test:3:1 GLOBAL test:3:1",
+		"x test:3:11 LOCAL test:3:11",
+		"b test:4:2 LOCAL test:4:2",
+		"len test:4:7 BUILTIN",
+		"x test:4:11 LOCAL test:3:11",
+		"a test:5:25 GLOBAL test:2:1",
+		"b test:5:29 FREE test:4:2",
+		"a test:7:1 GLOBAL test:2:1",
+		"f test:7:5 GLOBAL test:3:1",
+	}, refs)
+}
+
 func concatInsts(instructions ...[]byte) []byte {
 	var concat []byte
 	for _, i := range instructions {
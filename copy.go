@@ -0,0 +1,147 @@
+package tengo
+
+// DeepCopy returns a copy of the object that shares no mutable values with
+// it, so that the copy can be used by a VM running concurrently, e.g. a child
+// VM spawned by VM.Spawn. Unlike Copy, it also copies the variables captured
+// by the functions, the immutable arrays and maps stay immutable, and the
+// references between the copied values are kept. The other objects are copied
+// by their Copy methods, so the objects whose Copy returns the object itself,
+// e.g. the channels of the "concurrency" module, are shared, as are the objects
+// without a Copy method. It returns ErrNotCopyable error if the object holds a
+// generator.
+func DeepCopy(o Object) (Object, error) {
+	return newCopier().copy(o)
+}
+
+// copier deep-copies objects. The copies of the containers and the variables
+// are reused, so that the values referenced more than once, or by themselves,
+// are copied once.
+type copier struct {
+	copies map[Object]Object
+}
+
+func newCopier() *copier {
+	return &copier{copies: make(map[Object]Object)}
+}
+
+func (c *copier) copy(o Object) (Object, error) {
+	if o == nil {
+		return nil, nil
+	}
+	if cp, ok := c.copies[o]; ok {
+		return cp, nil
+	}
+	switch o := o.(type) {
+	case *Array:
+		cp := &Array{Value: make([]Object, len(o.Value))}
+		c.copies[o] = cp
+		return cp, c.copyAll(cp.Value, o.Value)
+	case *ImmutableArray:
+		cp := &ImmutableArray{Value: make([]Object, len(o.Value))}
+		c.copies[o] = cp
+		return cp, c.copyAll(cp.Value, o.Value)
+	case *Map:
+		cp := &Map{}
+		c.copies[o] = cp
+		var err error
+		cp.Value, cp.Hashed, err = c.copyMap(o.Value, o.Hashed)
+		return cp, err
+	case *ImmutableMap:
+		cp := &ImmutableMap{}
+		c.copies[o] = cp
+		var err error
+		cp.Value, cp.Hashed, err = c.copyMap(o.Value, o.Hashed)
+		return cp, err
+	case *Record:
+		cp := &Record{Type: o.Type, Values: make([]Object, len(o.Values))}
+		c.copies[o] = cp
+		return cp, c.copyAll(cp.Values, o.Values)
+	case *BoundMethod:
+		cp := &BoundMethod{}
+		c.copies[o] = cp
+		fn, err := c.copy(o.Fn)
+		if err != nil {
+			return nil, err
+		}
+		cp.Fn = fn.(*CompiledFunction)
+		cp.Recv, err = c.copy(o.Recv)
+		return cp, err
+	case *CompiledFunction:
+		if len(o.Free) == 0 {
+			return o, nil // compiled functions are immutable
+		}
+		cp := *o
+		cp.Free = make([]*ObjectPtr, len(o.Free))
+		c.copies[o] = &cp
+		for i, p := range o.Free {
+			v, err := c.copy(p)
+			if err != nil {
+				return nil, err
+			}
+			cp.Free[i] = v.(*ObjectPtr)
+		}
+		return &cp, nil
+	case *ObjectPtr:
+		cp := &ObjectPtr{}
+		c.copies[o] = cp
+		if o.Value != nil {
+			v, err := c.copy(*o.Value)
+			if err != nil {
+				return nil, err
+			}
+			cp.Value = &v
+		}
+		return cp, nil
+	case *Error:
+		cp := &Error{Pos: o.Pos}
+		c.copies[o] = cp
+		var err error
+		cp.Value, err = c.copy(o.Value)
+		return cp, err
+	case *Generator:
+		return nil, ErrNotCopyable
+	}
+	if cp := o.Copy(); cp != nil {
+		return cp, nil
+	}
+	return o, nil // e.g. ObjectImpl
+}
+
+// copyAll copies the objects of src to dst.
+func (c *copier) copyAll(dst, src []Object) error {
+	for i, v := range src {
+		cp, err := c.copy(v)
+		if err != nil {
+			return err
+		}
+		dst[i] = cp
+	}
+	return nil
+}
+
+// copyMap copies the entries of a map.
+func (c *copier) copyMap(
+	kv map[string]Object,
+	hashed *HashedMap,
+) (map[string]Object, *HashedMap, error) {
+	kvCopy := make(map[string]Object, len(kv))
+	for k, v := range kv {
+		cp, err := c.copy(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		kvCopy[k] = cp
+	}
+	var hashedCopy *HashedMap
+	if hashed.Len() > 0 {
+		hashedCopy = NewHashedMap()
+		for _, e := range hashed.Entries() {
+			cp, err := c.copy(e.Value)
+			if err != nil {
+				return nil, nil, err
+			}
+			hashedCopy.Set(e.Key, cp)
+		}
+	}
+	return kvCopy, hashedCopy, nil
+}
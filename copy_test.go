@@ -0,0 +1,42 @@
+package tengo_test
+
+import (
+	"testing"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/require"
+)
+
+func TestDeepCopy(t *testing.T) {
+	inner := &tengo.Array{Value: []tengo.Object{&tengo.Int{Value: 1}}}
+	m := &tengo.Map{Value: map[string]tengo.Object{
+		"a": inner,
+		"b": inner,
+		"c": &tengo.ImmutableArray{Value: []tengo.Object{inner}},
+	}}
+	inner.Value = append(inner.Value, m) // cycle
+
+	o, err := tengo.DeepCopy(m)
+	require.NoError(t, err)
+	cp := o.(*tengo.Map)
+	a := cp.Value["a"].(*tengo.Array)
+	require.True(t, a != inner)
+	require.True(t, cp.Value["b"] == a)
+	require.True(t, cp.Value["c"].(*tengo.ImmutableArray).Value[0] == a)
+	require.True(t, a.Value[1] == cp)
+	require.Equal(t, inner.Value[0], a.Value[0])
+
+	// captured variables are copied
+	v := tengo.Object(inner)
+	fn := &tengo.CompiledFunction{Free: []*tengo.ObjectPtr{{Value: &v}}}
+	o, err = tengo.DeepCopy(fn)
+	require.NoError(t, err)
+	p := o.(*tengo.CompiledFunction).Free[0]
+	require.True(t, p != fn.Free[0])
+	require.True(t, *p.Value != inner)
+
+	_, err = tengo.DeepCopy(&tengo.Array{
+		Value: []tengo.Object{&tengo.Generator{}},
+	})
+	require.Equal(t, tengo.ErrNotCopyable, err)
+}
@@ -0,0 +1,293 @@
+package tengo
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/d5/tengo/v2/parser"
+)
+
+type debugMode int
+
+const (
+	debugContinue debugMode = iota
+	debugStepIn
+	debugStepOver
+	debugStepOut
+)
+
+// Debugger pauses the execution of a VM at the breakpoints or after stepping
+// through the source lines, and calls the pause handler that can inspect the
+// function frames and the variables of the paused VM.
+//
+// Debugger methods must be called before running the VM or from the pause
+// handler, except Pause that can be called from any goroutine.
+type Debugger struct {
+	vm          *VM
+	onPause     func(d *Debugger)
+	breakpoints map[parser.SourceFilePos]bool
+	pausing     int64
+	paused      bool
+	pos         parser.SourceFilePos // current position while paused
+	mode        debugMode
+	stepFrame   int                  // frame index where stepping began
+	stepLine    parser.SourceFilePos // source line where stepping began
+	lastFrame   int
+	lastLine    parser.SourceFilePos
+}
+
+// NewDebugger creates a Debugger attached to the VM. onPause is called on the
+// goroutine running the VM whenever the VM is paused, and the VM resumes when
+// it returns. By default, the execution continues to the next breakpoint
+// unless onPause calls StepIn, StepOver or StepOut.
+func NewDebugger(vm *VM, onPause func(d *Debugger)) *Debugger {
+	d := &Debugger{
+		vm:          vm,
+		onPause:     onPause,
+		breakpoints: make(map[parser.SourceFilePos]bool),
+	}
+	vm.debugger = d
+	return d
+}
+
+// SetBreakpoint sets a breakpoint at the source line of pos. Column and Offset
+// of pos are ignored.
+func (d *Debugger) SetBreakpoint(pos parser.SourceFilePos) {
+	d.breakpoints[sourceLine(pos)] = true
+}
+
+// ClearBreakpoint removes the breakpoint at the source line of pos.
+func (d *Debugger) ClearBreakpoint(pos parser.SourceFilePos) {
+	delete(d.breakpoints, sourceLine(pos))
+}
+
+// Breakpoints returns the source lines of all breakpoints.
+func (d *Debugger) Breakpoints() []parser.SourceFilePos {
+	var breakpoints []parser.SourceFilePos
+	for pos := range d.breakpoints {
+		breakpoints = append(breakpoints, pos)
+	}
+	sort.Slice(breakpoints, func(i, j int) bool {
+		if breakpoints[i].Filename != breakpoints[j].Filename {
+			return breakpoints[i].Filename < breakpoints[j].Filename
+		}
+		return breakpoints[i].Line < breakpoints[j].Line
+	})
+	return breakpoints
+}
+
+// Pause pauses the VM before the next instruction is executed. It's safe to
+// call Pause from other goroutines.
+func (d *Debugger) Pause() {
+	atomic.StoreInt64(&d.pausing, 1)
+}
+
+// Continue resumes the execution until the next breakpoint.
+func (d *Debugger) Continue() {
+	d.mode = debugContinue
+}
+
+// StepIn resumes the execution until it reaches another source line,
+// including the lines of the called functions.
+func (d *Debugger) StepIn() {
+	d.mode = debugStepIn
+}
+
+// StepOver resumes the execution until it reaches another source line of the
+// current function or the function returns.
+func (d *Debugger) StepOver() {
+	d.mode = debugStepOver
+}
+
+// StepOut resumes the execution until the current function returns.
+func (d *Debugger) StepOut() {
+	d.mode = debugStepOut
+}
+
+// IsPaused returns true if the VM is paused.
+func (d *Debugger) IsPaused() bool {
+	return d.paused
+}
+
+// Pos returns the source position where the VM is paused.
+func (d *Debugger) Pos() parser.SourceFilePos {
+	return d.pos
+}
+
+// Frames returns the function frames of the paused VM starting from the
+// innermost one.
+func (d *Debugger) Frames() []*DebugFrame {
+	v := d.vm
+	var frames []*DebugFrame
+	for i := v.framesIndex - 1; i >= 0; i-- {
+		f := &v.frames[i]
+		pos := d.framePos(i)
+		if pos == parser.NoPos {
+			continue // entry frame of VM.RunCompiled
+		}
+		frames = append(frames, &DebugFrame{
+			Fn:    f.fn,
+			Pos:   v.fileSet.Position(pos),
+			vm:    v,
+			frame: f,
+			pos:   pos,
+		})
+	}
+	return frames
+}
+
+// Globals returns the global variables accessible from the main function.
+func (d *Debugger) Globals() []*DebugVariable {
+	var vars []*DebugVariable
+	main := d.vm.frames[0].fn
+	for _, s := range visibleSymbols(main, d.framePos(0)) {
+		if s.Index < len(d.vm.globals) {
+			vars = append(vars, &DebugVariable{
+				Name:  s.Name,
+				Value: debugValue(d.vm.globals[s.Index]),
+			})
+		}
+	}
+	return vars
+}
+
+// framePos returns the source position of the instruction being executed in
+// the i-th frame.
+func (d *Debugger) framePos(i int) parser.Pos {
+	v := d.vm
+	f := &v.frames[i]
+	if i == v.framesIndex-1 {
+		return f.fn.SourcePos(v.ip)
+	}
+	return f.fn.SourcePos(f.ip - 1)
+}
+
+// trace is called by the VM before executing each instruction.
+func (d *Debugger) trace() {
+	v := d.vm
+	pausing := atomic.CompareAndSwapInt64(&d.pausing, 1, 0)
+	if !pausing && d.mode == debugContinue && len(d.breakpoints) == 0 {
+		return
+	}
+
+	pos := v.curFrame.fn.SourcePos(v.ip)
+	if pos == parser.NoPos {
+		if pausing {
+			d.Pause() // pause at the next instruction with source position
+		}
+		return
+	}
+	filePos := v.fileSet.Position(pos)
+	line := sourceLine(filePos)
+	newLine := line != d.lastLine || v.framesIndex != d.lastFrame
+	d.lastLine, d.lastFrame = line, v.framesIndex
+	if !pausing && !newLine {
+		return
+	}
+
+	switch d.mode {
+	case debugStepIn:
+		pausing = pausing || v.framesIndex != d.stepFrame ||
+			line != d.stepLine
+	case debugStepOver:
+		pausing = pausing || v.framesIndex < d.stepFrame ||
+			v.framesIndex == d.stepFrame && line != d.stepLine
+	case debugStepOut:
+		pausing = pausing || v.framesIndex < d.stepFrame
+	}
+	if pausing || d.breakpoints[line] {
+		d.pause(filePos)
+	}
+}
+
+func (d *Debugger) pause(pos parser.SourceFilePos) {
+	d.mode = debugContinue
+	d.pos = pos
+	d.paused = true
+	d.onPause(d)
+	d.paused = false
+	d.stepFrame, d.stepLine = d.vm.framesIndex, sourceLine(pos)
+}
+
+// DebugFrame represents a function frame of the paused VM.
+type DebugFrame struct {
+	Fn    *CompiledFunction
+	Pos   parser.SourceFilePos
+	vm    *VM
+	frame *frame
+	pos   parser.Pos
+}
+
+// Locals returns the local variables accessible at the current position of
+// the frame.
+func (f *DebugFrame) Locals() []*DebugVariable {
+	if f.frame == &f.vm.frames[0] {
+		return nil // variables of the main function are globals
+	}
+	var vars []*DebugVariable
+	for _, s := range visibleSymbols(f.Fn, f.pos) {
+		if s.Index < f.Fn.NumLocals {
+			vars = append(vars, &DebugVariable{
+				Name:  s.Name,
+				Value: debugValue(f.vm.stack[f.frame.basePointer+s.Index]),
+			})
+		}
+	}
+	return vars
+}
+
+// Free returns the free variables of the frame.
+func (f *DebugFrame) Free() []*DebugVariable {
+	var vars []*DebugVariable
+	for i, name := range f.Fn.FreeNames {
+		if i < len(f.frame.freeVars) {
+			vars = append(vars, &DebugVariable{
+				Name:  name,
+				Value: debugValue(*f.frame.freeVars[i].Value),
+			})
+		}
+	}
+	return vars
+}
+
+// DebugVariable represents a variable of the paused VM.
+type DebugVariable struct {
+	Name  string
+	Value Object
+}
+
+// visibleSymbols returns the variables of the function that are accessible at
+// the position. A variable shadows the ones of the same name defined earlier.
+func visibleSymbols(fn *CompiledFunction, pos parser.Pos) []*LocalSymbol {
+	var symbols []*LocalSymbol
+	indexes := make(map[string]int)
+	for _, s := range fn.Locals {
+		if s.Pos > pos || s.End != parser.NoPos && pos >= s.End {
+			continue
+		}
+		if i, ok := indexes[s.Name]; ok {
+			symbols[i] = s
+			continue
+		}
+		indexes[s.Name] = len(symbols)
+		symbols = append(symbols, s)
+	}
+	return symbols
+}
+
+func debugValue(o Object) Object {
+	switch o := o.(type) {
+	case nil:
+		return UndefinedValue
+	case *ObjectPtr:
+		if o.Value == nil || *o.Value == nil {
+			return UndefinedValue
+		}
+		return *o.Value
+	}
+	return o
+}
+
+func sourceLine(pos parser.SourceFilePos) parser.SourceFilePos {
+	return parser.SourceFilePos{Filename: pos.Filename, Line: pos.Line}
+}
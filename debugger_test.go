@@ -0,0 +1,119 @@
+package tengo_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/require"
+)
+
+const debugSrc = `
+a := 1
+f := func(x) {
+	y := x * 2
+	g := func() {
+		return y + a
+	}
+	return g()
+}
+b := f(3)
+c := b + 1
+`
+
+func TestDebugger_Breakpoint(t *testing.T) {
+	var lines []int
+	var locals, free []string
+	runDebug(t, debugSrc, func(d *tengo.Debugger) {
+		d.SetBreakpoint(parser.SourceFilePos{Filename: "test", Line: 6})
+		d.SetBreakpoint(parser.SourceFilePos{Filename: "test", Line: 11})
+		d.ClearBreakpoint(parser.SourceFilePos{Filename: "test", Line: 11})
+		require.Equal(t, 1, len(d.Breakpoints()))
+	}, func(d *tengo.Debugger) {
+		require.True(t, d.IsPaused())
+		lines = append(lines, d.Pos().Line)
+		frames := d.Frames()
+		require.Equal(t, 3, len(frames))
+		require.Equal(t, 6, frames[0].Pos.Line)
+		require.Equal(t, 8, frames[1].Pos.Line)
+		require.Equal(t, 10, frames[2].Pos.Line)
+		locals = debugVars(frames[1].Locals())
+		free = debugVars(frames[0].Free())
+		require.Equal(t, 0, len(frames[2].Locals()))
+	})
+	require.Equal(t, []int{6}, lines)
+	require.Equal(t, []string{"x=3", "y=6", "g=<compiled-function>"}, locals)
+	require.Equal(t, []string{"y=6"}, free)
+}
+
+func TestDebugger_Step(t *testing.T) {
+	var lines []int
+	step := func(step func(d *tengo.Debugger)) {
+		lines = nil
+		runDebug(t, debugSrc, func(d *tengo.Debugger) {
+			d.SetBreakpoint(parser.SourceFilePos{Filename: "test", Line: 4})
+		}, func(d *tengo.Debugger) {
+			lines = append(lines, d.Pos().Line)
+			step(d)
+		})
+	}
+
+	step((*tengo.Debugger).StepIn)
+	require.Equal(t, []int{4, 5, 8, 6, 8, 10, 11}, lines)
+	step((*tengo.Debugger).StepOver)
+	require.Equal(t, []int{4, 5, 8, 10, 11}, lines)
+	step((*tengo.Debugger).StepOut)
+	require.Equal(t, []int{4, 10}, lines)
+	step((*tengo.Debugger).Continue)
+	require.Equal(t, []int{4}, lines)
+}
+
+func TestDebugger_Globals(t *testing.T) {
+	var globals []string
+	runDebug(t, debugSrc, func(d *tengo.Debugger) {
+		d.SetBreakpoint(parser.SourceFilePos{Filename: "test", Line: 11})
+	}, func(d *tengo.Debugger) {
+		globals = debugVars(d.Globals())
+	})
+	require.Equal(t, []string{"a=1", "f=<compiled-function>", "b=7",
+		"c=<undefined>"}, globals)
+}
+
+func TestDebugger_Pause(t *testing.T) {
+	var pos []string
+	runDebug(t, debugSrc, func(d *tengo.Debugger) {
+		d.Pause()
+	}, func(d *tengo.Debugger) {
+		pos = append(pos, d.Pos().String())
+	})
+	require.Equal(t, []string{"test:2:6"}, pos)
+}
+
+func runDebug(
+	t *testing.T,
+	src string,
+	init func(d *tengo.Debugger),
+	onPause func(d *tengo.Debugger),
+) {
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("test", -1, len(src))
+	p := parser.NewParser(srcFile, []byte(src), nil)
+	file, err := p.ParseFile()
+	require.NoError(t, err)
+
+	c := tengo.NewCompiler(srcFile, nil, nil, nil, nil)
+	require.NoError(t, c.Compile(file))
+
+	v := tengo.NewVM(c.Bytecode(), nil, -1)
+	init(tengo.NewDebugger(v, onPause))
+	require.NoError(t, v.Run())
+}
+
+func debugVars(vars []*tengo.DebugVariable) []string {
+	var res []string
+	for _, v := range vars {
+		res = append(res, fmt.Sprintf("%s=%s", v.Name, v.Value))
+	}
+	return res
+}
@@ -12,6 +12,20 @@ var (
 	// ErrObjectAllocLimit is an objects allocation limit error.
 	ErrObjectAllocLimit = errors.New("object allocation limit exceeded")
 
+	// ErrInstructionLimit is an instruction limit error.
+	ErrInstructionLimit = errors.New("instruction limit exceeded")
+
+	// ErrMemoryLimit is a memory limit error.
+	ErrMemoryLimit = errors.New("memory limit exceeded")
+
+	// ErrGeneratorRunning is an error returned when a generator is resumed
+	// while it's running, e.g. when it iterates itself.
+	ErrGeneratorRunning = errors.New("generator already running")
+
+	// ErrNotCopyable is an error where an object can't be copied to another
+	// VM, e.g. a generator. See DeepCopy.
+	ErrNotCopyable = errors.New("not copyable")
+
 	// ErrIndexOutOfBounds is an error where a given index is out of the
 	// bounds.
 	ErrIndexOutOfBounds = errors.New("index out of bounds")
@@ -25,6 +39,10 @@ var (
 	// ErrInvalidIndexOnError represents an invalid index on error.
 	ErrInvalidIndexOnError = errors.New("invalid index on error")
 
+	// ErrUnknownField is an error where a record doesn't have the field or
+	// the method.
+	ErrUnknownField = errors.New("unknown field")
+
 	// ErrInvalidOperator represents an error for invalid operator usage.
 	ErrInvalidOperator = errors.New("invalid operator")
 
@@ -49,6 +67,21 @@ var (
 	// ErrNotImplemented is an error where an Object has not implemented a
 	// required method.
 	ErrNotImplemented = errors.New("not implemented")
+
+	// ErrIntegerOverflow is an error where an int operation overflows in the
+	// checked arithmetic mode.
+	ErrIntegerOverflow = errors.New("integer overflow")
+
+	// ErrDivisionByZero is an error where a number is divided by zero.
+	ErrDivisionByZero = errors.New("division by zero")
+
+	// ErrVMAborted is an error to denote the VM was forcibly terminated
+	// while running a re-entrant call.
+	ErrVMAborted = errors.New("virtual machine aborted")
+
+	// ErrPermissionDenied is an error where a Go function requires a
+	// capability that's not granted to the VM. See VM.CheckCapability.
+	ErrPermissionDenied = errors.New("permission denied")
 )
 
 // ErrInvalidArgumentType represents an invalid argument value type error.
@@ -0,0 +1,54 @@
+// Package format implements the canonical formatting of Tengo source code.
+//
+// The source code is indented with tabs, and each statement is placed on its
+// own line. Up to one empty line between statements is preserved. Array, map
+// and call argument lists are printed on a single line unless the closing
+// bracket is on a different line than the last element in the source, in
+// which case each element is placed on its own line. Function literals with a
+// single simple statement may stay on one line. Comments are preserved.
+package format
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/d5/tengo/v2/parser"
+)
+
+// Source formats the Tengo source code. It returns an error if the source has
+// syntax errors. A shebang line at the beginning of the source is preserved.
+func Source(src []byte) ([]byte, error) {
+	// keep the shebang line as a comment
+	shebang := len(src) > 1 && string(src[:2]) == "#!"
+	if shebang {
+		src = append([]byte("//"), src[2:]...)
+	}
+
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("", -1, len(src))
+	p := parser.NewParserWithMode(srcFile, src, nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := File(&buf, file); err != nil {
+		return nil, err
+	}
+	out := buf.Bytes()
+	if shebang {
+		copy(out, "#!")
+	}
+	return out, nil
+}
+
+// File writes the formatted source code of the parsed file to w. The file
+// should be parsed with parser.ParseComments mode to keep the comments.
+func File(w io.Writer, file *parser.File) error {
+	p := newPrinter(file)
+	p.stmtList(file.Stmts, file.End())
+	p.leading(file.End())
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
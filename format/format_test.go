@@ -0,0 +1,183 @@
+package format_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/d5/tengo/v2/format"
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/require"
+)
+
+func TestSource(t *testing.T) {
+	expectFormat(t, `a:=1;b:=2`, "a := 1\nb := 2\n")
+	expectFormat(t, "\n\n\na := 1\n\n\n\nb := 2\n\n", "a := 1\n\nb := 2\n")
+	expectFormat(t, `a := [1,2,  3]`, "a := [1, 2, 3]\n")
+	expectFormat(t, `a := {b:1, "c":2, "d-e":3, "if": 4}`,
+		"a := {b: 1, c: 2, \"d-e\": 3, \"if\": 4}\n")
+	expectFormat(t, `a := f(1,
+2)`, "a := f(1, 2)\n")
+	expectFormat(t, `a := f(1,
+  [2]...
+)`, "a := f(\n\t1,\n\t[2]...\n)\n")
+	expectFormat(t, `a := {
+  b: 1,
+
+  c: [
+  ]
+}`, "a := {\n\tb: 1,\n\n\tc: []\n}\n")
+	expectFormat(t, `a := x &&
+y || z`, "a := x &&\n\ty || z\n")
+	expectFormat(t, `a := -b+c*(d-e)%f[1:]`,
+		"a := -b + c * (d - e) % f[1:]\n")
+	expectFormat(t, `a := b ? c : d[e].f`, "a := b ? c : d[e].f\n")
+	expectFormat(t, `a := import( "fmt" )`, "a := import(\"fmt\")\n")
+	expectFormat(t, `a := immutable({ b : error( "c" ) })`,
+		"a := immutable({b: error(\"c\")})\n")
+	expectFormat(t, `a := f"${b} ${c:%d}"`, "a := f\"${b} ${c:%d}\"\n")
+	expectFormat(t, `a := {1:b, -2: c, ( d+1 ):e, 'f': g}`,
+		"a := {1: b, -2: c, (d + 1): e, 'f': g}\n")
+
+	// functions
+	expectFormat(t, `f := func(a,b,...c){return a}`,
+		"f := func(a, b, ...c) { return a }\n")
+	expectFormat(t, `f := func() {
+return 1 }`, "f := func() {\n\treturn 1\n}\n")
+	expectFormat(t, `f := func() {}`, "f := func() {}\n")
+	expectFormat(t, `f := func() { a := 1; return a }`,
+		"f := func() {\n\ta := 1\n\treturn a\n}\n")
+	expectFormat(t, `f := func() { if a { return } }`,
+		"f := func() {\n\tif a {\n\t\treturn\n\t}\n}\n")
+	expectFormat(t, `f := func(){yield  a+1}`, "f := func() { yield a + 1 }\n")
+	expectFormat(t, `f := func() { yield; yield 1 }`,
+		"f := func() {\n\tyield\n\tyield 1\n}\n")
+
+	// statements
+	expectFormat(t, `if a:=1;a>0 { b++ } else if c { d-- } else { }`,
+		"if a := 1; a > 0 {\n\tb++\n} else if c {\n\td--\n} else {}\n")
+	expectFormat(t, `for { break }`, "for {\n\tbreak\n}\n")
+	expectFormat(t, `for a < 1 { continue }`, "for a < 1 {\n\tcontinue\n}\n")
+	expectFormat(t, `for i:=0;i<1;i++ {}`, "for i := 0; i < 1; i++ {}\n")
+	expectFormat(t, `for ;; {}`, "for {}\n")
+	expectFormat(t, `for i:=0;;{}`, "for i := 0; ; {}\n")
+	expectFormat(t, `for v in a {}`, "for v in a {}\n")
+	expectFormat(t, `for k,v in a {}`, "for k, v in a {}\n")
+	expectFormat(t, `for _,v in a {}`, "for _, v in a {}\n")
+	expectFormat(t, `try { a() } catch e { b(e) } finally { c() }`,
+		"try {\n\ta()\n} catch e {\n\tb(e)\n} finally {\n\tc()\n}\n")
+	expectFormat(t, `try { a() } catch { }`, "try {\n\ta()\n} catch {}\n")
+	expectFormat(t, `switch a { case 1,2: b(); c()
+default: d() }`,
+		"switch a {\ncase 1, 2:\n\tb()\n\tc()\ndefault:\n\td()\n}\n")
+	expectFormat(t, `switch { case a: }`, "switch {\ncase a:\n}\n")
+	expectFormat(t, `export {a: 1}`, "export {a: 1}\n")
+	expectFormat(t, `f := func(a,b = 1, ...c) {}`, "f := func(a, b=1, ...c) {}\n")
+	expectFormat(t, `f(1,b:2 , c :3)`, "f(1, b: 2, c: 3)\n")
+	expectFormat(t, `f(1,
+b: 2
+)`, "f(\n\t1,\n\tb: 2\n)\n")
+	expectFormat(t, `a,b := f()`, "a, b := f()\n")
+	expectFormat(t, `[x,_] = arr`, "[x, _] = arr\n")
+	expectFormat(t, `{name,age:years} := m`, "{name, age: years} := m\n")
+	expectFormat(t, `a := [b ..., 1]`, "a := [b..., 1]\n")
+	expectFormat(t, `a := {m...,b:1}`, "a := {m..., b: 1}\n")
+	expectFormat(t, `a := b?.c ?.d?[ e ]??f`, "a := b?.c?.d?[e] ?? f\n")
+	expectFormat(t, `record  Point( x,y )`, "record Point(x, y)\n")
+	expectFormat(t, `record Point(x) { len: func() { return self.x } }`,
+		"record Point(x) {len: func() { return self.x }}\n")
+	expectFormat(t, `record Point(x) {
+len: func() { return self.x },
+init: func(x) { self.x = x }
+}`, "record Point(x) {\n\tlen: func() { return self.x },\n\tinit: func(x) { self.x = x }\n}\n")
+
+	// comments
+	expectFormat(t, `// a
+a := 1 // b
+/* c */ b := 2   // d`, "// a\na := 1 // b\n/* c */\nb := 2 // d\n")
+	expectFormat(t, `f := func() { // a
+  // b
+
+  return 1 // c
+  // d
+}
+// e`, "f := func() { // a\n\t// b\n\n\treturn 1 // c\n\t// d\n}\n// e\n")
+	expectFormat(t, `a := [
+  1, // b
+  // c
+  2
+  // d
+]`, "a := [\n\t1, // b\n\t// c\n\t2\n\t// d\n]\n")
+	expectFormat(t, `a := [ // b
+]`, "a := [\n\t// b\n]\n")
+	expectFormat(t, `f := func() { return 1 /* a */ }`,
+		"f := func() {\n\treturn 1 /* a */\n}\n")
+	expectFormat(t, `switch a { // a
+case 1: // b
+  b()
+  // c
+
+// d
+default:
+}`, "switch a { // a\ncase 1: // b\n\tb()\n\t// c\n\n// d\ndefault:\n}\n")
+
+	_, err := format.Source([]byte(`a := `))
+	require.Error(t, err)
+}
+
+func TestSourceFiles(t *testing.T) {
+	for _, filename := range []string{
+		"../stdlib/srcmod_enum.tengo",
+		"../testdata/cli/one.tengo",
+		"../testdata/cli/test.tengo",
+		"../testdata/cli/three.tengo",
+	} {
+		src, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		out, err := format.Source(src)
+		require.NoError(t, err, filename)
+		require.Equal(t, parse(t, src), parse(t, out), filename)
+		require.Equal(t, countComments(t, src), countComments(t, out),
+			filename)
+
+		out2, err := format.Source(out)
+		require.NoError(t, err, filename)
+		require.Equal(t, string(out), string(out2), filename)
+	}
+}
+
+func expectFormat(t *testing.T, input, expected string) {
+	out, err := format.Source([]byte(input))
+	require.NoError(t, err, input)
+	require.Equal(t, expected, string(out), input)
+
+	// formatting is idempotent and does not change the meaning
+	out2, err := format.Source(out)
+	require.NoError(t, err, expected)
+	require.Equal(t, expected, string(out2), expected)
+	require.Equal(t, parse(t, []byte(input)), parse(t, out), input)
+}
+
+func parse(t *testing.T, src []byte) string {
+	file := parseFile(t, src)
+	return file.String()
+}
+
+func countComments(t *testing.T, src []byte) int {
+	var n int
+	for _, g := range parseFile(t, src).Comments {
+		n += len(g.List)
+	}
+	return n
+}
+
+func parseFile(t *testing.T, src []byte) *parser.File {
+	if len(src) > 1 && string(src[:2]) == "#!" {
+		src = append([]byte("//"), src[2:]...)
+	}
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("test", -1, len(src))
+	p := parser.NewParserWithMode(srcFile, src, nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	require.NoError(t, err)
+	return file
+}
@@ -0,0 +1,546 @@
+package format
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/token"
+)
+
+type printer struct {
+	file      *parser.SourceFile
+	comments  []*parser.Comment // comments not printed yet
+	buf       bytes.Buffer
+	indent    int
+	lineStart bool // true if nothing is written on the current line yet
+	lastLine  int  // source line of the last printed item; or 0
+}
+
+func newPrinter(file *parser.File) *printer {
+	p := &printer{file: file.InputFile, lineStart: true}
+	for _, g := range file.Comments {
+		p.comments = append(p.comments, g.List...)
+	}
+	return p
+}
+
+func (p *printer) write(s string) {
+	if p.lineStart && s != "" {
+		p.buf.WriteString(strings.Repeat("\t", p.indent))
+		p.lineStart = false
+	}
+	p.buf.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+	p.lineStart = true
+}
+
+func (p *printer) line(pos parser.Pos) int {
+	return p.file.Position(pos).Line
+}
+
+// blankLine writes an empty line if there's one or more empty lines between
+// the last printed item and pos in the source.
+func (p *printer) blankLine(pos parser.Pos) {
+	if p.lastLine > 0 && p.line(pos) > p.lastLine+1 {
+		p.newline()
+	}
+}
+
+// leading writes the comments before pos on their own lines.
+func (p *printer) leading(pos parser.Pos) {
+	for len(p.comments) > 0 && p.comments[0].Pos() < pos {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.blankLine(c.Pos())
+		p.write(c.Text)
+		p.newline()
+		p.lastLine = p.line(c.End())
+	}
+}
+
+// trailing writes the comments before limit that are on the source line at
+// the end of the current output line.
+func (p *printer) trailing(line int, limit parser.Pos) {
+	for len(p.comments) > 0 && p.comments[0].Pos() < limit &&
+		p.line(p.comments[0].Pos()) == line {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.write(" " + c.Text)
+		p.lastLine = p.line(c.End())
+	}
+}
+
+// hasComments returns true if there are comments between pos and end.
+func (p *printer) hasComments(pos, end parser.Pos) bool {
+	for _, c := range p.comments {
+		if c.Pos() >= end {
+			break
+		}
+		if c.Pos() > pos {
+			return true
+		}
+	}
+	return false
+}
+
+// stmtList writes the statements on their own lines. end is the position of
+// the next item after the statements.
+func (p *printer) stmtList(list []parser.Stmt, end parser.Pos) {
+	list = nonEmptyStmts(list)
+	for i, s := range list {
+		next := end
+		if i+1 < len(list) {
+			next = list[i+1].Pos()
+		}
+		p.leading(s.Pos())
+		p.blankLine(s.Pos())
+		p.stmt(s)
+		p.lastLine = p.line(s.End())
+		p.trailing(p.lastLine, next)
+		p.newline()
+	}
+}
+
+// block writes the block statement. If oneLine is true and the block has a
+// single simple statement on one line in the source, the block is written on
+// one line.
+func (p *printer) block(b *parser.BlockStmt, oneLine bool) {
+	stmts := nonEmptyStmts(b.Stmts)
+	if p.hasComments(b.LBrace, b.RBrace) {
+		oneLine = false
+	} else if len(stmts) == 0 {
+		p.write("{}")
+		return
+	}
+	if oneLine && len(stmts) == 1 && isSimpleStmt(stmts[0]) &&
+		p.line(b.LBrace) == p.line(b.RBrace) {
+		p.write("{ ")
+		p.stmt(stmts[0])
+		p.write(" }")
+		return
+	}
+
+	p.write("{")
+	next := b.RBrace
+	if len(stmts) > 0 {
+		next = stmts[0].Pos()
+	}
+	p.trailing(p.line(b.LBrace), next)
+	p.newline()
+	p.indent++
+	p.lastLine = 0
+	p.stmtList(stmts, b.RBrace)
+	p.leading(b.RBrace)
+	p.indent--
+	p.write("}")
+}
+
+func (p *printer) stmt(s parser.Stmt) {
+	switch s := s.(type) {
+	case *parser.AssignStmt:
+		p.exprList(s.LHS)
+		p.write(" " + s.Token.String() + " ")
+		p.exprList(s.RHS)
+	case *parser.BlockStmt:
+		p.block(s, false)
+	case *parser.BranchStmt:
+		p.write(s.Token.String())
+		if s.Label != nil {
+			p.write(" " + s.Label.Name)
+		}
+	case *parser.ExportStmt:
+		p.write("export ")
+		p.expr(s.Result)
+	case *parser.ExprStmt:
+		p.expr(s.Expr)
+	case *parser.ForInStmt:
+		p.write("for ")
+		if s.Key.Name != "_" || s.Key.Pos() != s.Value.Pos() {
+			p.write(s.Key.Name + ", ")
+		}
+		p.write(s.Value.Name + " in ")
+		p.expr(s.Iterable)
+		p.write(" ")
+		p.block(s.Body, false)
+	case *parser.ForStmt:
+		p.write("for ")
+		if s.Init != nil || s.Post != nil {
+			if s.Init != nil {
+				p.stmt(s.Init)
+			}
+			p.write("; ")
+			if s.Cond != nil {
+				p.expr(s.Cond)
+			}
+			p.write(";")
+			if s.Post != nil {
+				p.write(" ")
+				p.stmt(s.Post)
+			}
+			p.write(" ")
+		} else if s.Cond != nil {
+			p.expr(s.Cond)
+			p.write(" ")
+		}
+		p.block(s.Body, false)
+	case *parser.IfStmt:
+		p.write("if ")
+		if s.Init != nil {
+			p.stmt(s.Init)
+			p.write("; ")
+		}
+		p.expr(s.Cond)
+		p.write(" ")
+		p.block(s.Body, false)
+		if s.Else != nil {
+			p.write(" else ")
+			p.stmt(s.Else)
+		}
+	case *parser.IncDecStmt:
+		p.expr(s.Expr)
+		p.write(s.Token.String())
+	case *parser.ReturnStmt:
+		p.write("return")
+		if s.Result != nil {
+			p.write(" ")
+			p.expr(s.Result)
+		}
+	case *parser.YieldStmt:
+		p.write("yield")
+		if s.Result != nil {
+			p.write(" ")
+			p.expr(s.Result)
+		}
+	case *parser.RecordStmt:
+		p.write("record " + s.Name.Name)
+		p.params(s.Fields)
+		if s.LBrace.IsValid() {
+			methods := make([]parser.Expr, len(s.Methods))
+			for i, m := range s.Methods {
+				methods[i] = m
+			}
+			p.write(" {")
+			p.list(s.LBrace, methods, s.RBrace, false)
+			p.write("}")
+		}
+	case *parser.TryStmt:
+		p.write("try ")
+		p.block(s.Body, false)
+		if s.Catch != nil {
+			p.write(" catch ")
+			if s.CatchIdent != nil {
+				p.write(s.CatchIdent.Name + " ")
+			}
+			p.block(s.Catch, false)
+		}
+		if s.Finally != nil {
+			p.write(" finally ")
+			p.block(s.Finally, false)
+		}
+	case *parser.SwitchStmt:
+		p.write("switch ")
+		if s.Tag != nil {
+			p.expr(s.Tag)
+			p.write(" ")
+		}
+		p.write("{")
+		p.trailing(p.line(s.LBrace), s.RBrace)
+		p.newline()
+		p.lastLine = 0
+		for i, c := range s.Cases {
+			next := s.RBrace
+			if i+1 < len(s.Cases) {
+				next = s.Cases[i+1].Pos()
+			}
+			p.caseClause(c, next)
+		}
+		p.leading(s.RBrace)
+		p.write("}")
+	default:
+		p.write(s.String())
+	}
+}
+
+func (p *printer) caseClause(c *parser.CaseClause, next parser.Pos) {
+	p.leading(c.Case)
+	p.blankLine(c.Case)
+	if c.List == nil {
+		p.write("default:")
+	} else {
+		p.write("case ")
+		p.exprList(c.List)
+		p.write(":")
+	}
+	body := nonEmptyStmts(c.Body)
+	limit := next
+	if len(body) > 0 {
+		limit = body[0].Pos()
+	}
+	p.lastLine = p.line(c.Colon)
+	p.trailing(p.lastLine, limit)
+	p.newline()
+	p.indent++
+	p.stmtList(body, next)
+	// the comments indented deeper than the case keyword belong to the body
+	column := p.file.Position(c.Case).Column
+	for len(p.comments) > 0 && p.comments[0].Pos() < next &&
+		p.file.Position(p.comments[0].Pos()).Column > column {
+		p.leading(p.comments[0].Pos() + 1)
+	}
+	p.indent--
+}
+
+func (p *printer) exprList(list []parser.Expr) {
+	for i, e := range list {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expr(e)
+	}
+}
+
+func (p *printer) expr(e parser.Expr) {
+	switch e := e.(type) {
+	case *parser.ArrayLit:
+		p.write("[")
+		p.list(e.LBrack, e.Elements, e.RBrack, false)
+		p.write("]")
+	case *parser.BinaryExpr:
+		p.expr(e.LHS)
+		p.write(" " + e.Token.String())
+		if p.line(e.RHS.Pos()) > p.line(e.TokenPos) {
+			// keep the line break after the operator
+			p.indent++
+			p.newline()
+			p.expr(e.RHS)
+			p.indent--
+		} else {
+			p.write(" ")
+			p.expr(e.RHS)
+		}
+	case *parser.CallExpr:
+		args := e.Args
+		for _, kw := range e.Keywords {
+			args = append(args[:len(args):len(args)], kw)
+		}
+		p.expr(e.Func)
+		p.write("(")
+		p.list(e.LParen, args, e.RParen, e.Ellipsis.IsValid())
+		p.write(")")
+	case *parser.CondExpr:
+		p.expr(e.Cond)
+		p.write(" ? ")
+		p.expr(e.True)
+		p.write(" : ")
+		p.expr(e.False)
+	case *parser.ErrorExpr:
+		p.write("error(")
+		p.expr(e.Expr)
+		p.write(")")
+	case *parser.FuncLit:
+		p.write("func")
+		p.params(e.Type.Params)
+		p.write(" ")
+		p.block(e.Body, true)
+	case *parser.Ident:
+		p.write(e.Name)
+	case *parser.ImmutableExpr:
+		p.write("immutable(")
+		p.expr(e.Expr)
+		p.write(")")
+	case *parser.ImportExpr:
+		p.write("import(" + strconv.Quote(e.ModuleName) + ")")
+	case *parser.IndexExpr:
+		p.expr(e.Expr)
+		if e.Optional {
+			p.write("?")
+		}
+		p.write("[")
+		p.expr(e.Index)
+		p.write("]")
+	case *parser.KeywordArg:
+		p.write(e.Name + ": ")
+		p.expr(e.Value)
+	case *parser.MapLit:
+		elements := make([]parser.Expr, len(e.Elements))
+		for i, elem := range e.Elements {
+			elements[i] = elem
+		}
+		p.write("{")
+		p.list(e.LBrace, elements, e.RBrace, false)
+		p.write("}")
+	case *parser.MapElementLit:
+		if e.IsSpread() || e.IsShorthand() {
+			p.expr(e.Value)
+			return
+		}
+		if e.KeyExpr != nil {
+			p.expr(e.KeyExpr)
+			p.write(": ")
+		} else {
+			p.write(mapKey(e.Key) + ": ")
+		}
+		p.expr(e.Value)
+	case *parser.ParenExpr:
+		p.write("(")
+		p.expr(e.Expr)
+		p.write(")")
+	case *parser.SelectorExpr:
+		p.expr(e.Expr)
+		if e.Optional {
+			p.write("?")
+		}
+		p.write(".")
+		if sel, ok := e.Sel.(*parser.StringLit); ok {
+			p.write(sel.Value)
+		} else {
+			p.expr(e.Sel)
+		}
+	case *parser.SliceExpr:
+		p.expr(e.Expr)
+		p.write("[")
+		if e.Low != nil {
+			p.expr(e.Low)
+		}
+		p.write(":")
+		if e.High != nil {
+			p.expr(e.High)
+		}
+		p.write("]")
+	case *parser.SpreadExpr:
+		p.expr(e.Expr)
+		p.write("...")
+	case *parser.UnaryExpr:
+		p.write(e.Token.String())
+		p.expr(e.Expr)
+	case *parser.BoolLit:
+		p.write(e.Literal)
+	case *parser.CharLit:
+		p.write(e.Literal)
+	case *parser.FloatLit:
+		p.write(e.Literal)
+	case *parser.IntLit:
+		p.write(e.Literal)
+	case *parser.InterpStringLit:
+		p.write(e.Literal)
+	case *parser.StringLit:
+		p.write(e.Literal)
+	case *parser.UndefinedLit:
+		p.write("undefined")
+	default:
+		p.write(e.String())
+	}
+}
+
+// list writes the elements separated by commas. Each element is written on
+// its own line if the closing bracket is on a different line than the last
+// element in the source.
+func (p *printer) list(
+	opening parser.Pos,
+	list []parser.Expr,
+	closing parser.Pos,
+	ellipsis bool,
+) {
+	n := len(list)
+	if n == 0 {
+		if p.hasComments(opening, closing) {
+			p.indent++
+			p.newline()
+			p.lastLine = 0
+			p.leading(closing)
+			p.indent--
+		}
+		return
+	}
+	if p.line(closing) == p.line(list[n-1].End()) {
+		p.exprList(list)
+		if ellipsis {
+			p.write("...")
+		}
+		return
+	}
+
+	p.indent++
+	p.newline()
+	p.lastLine = 0
+	for i, e := range list {
+		next := closing
+		if i+1 < n {
+			next = list[i+1].Pos()
+		}
+		p.leading(e.Pos())
+		p.blankLine(e.Pos())
+		p.expr(e)
+		if i+1 < n {
+			p.write(",")
+		} else if ellipsis {
+			p.write("...")
+		}
+		p.lastLine = p.line(e.End())
+		p.trailing(p.lastLine, next)
+		p.newline()
+	}
+	p.leading(closing)
+	p.indent--
+}
+
+func (p *printer) params(params *parser.IdentList) {
+	p.write("(")
+	for i, ident := range params.List {
+		if i > 0 {
+			p.write(", ")
+		}
+		if params.VarArgs && i == len(params.List)-1 {
+			p.write("...")
+		}
+		p.write(ident.Name)
+		if i < len(params.Defaults) && params.Defaults[i] != nil {
+			p.write("=")
+			p.expr(params.Defaults[i])
+		}
+	}
+	p.write(")")
+}
+
+// mapKey returns the map key as an identifier if possible, or as a quoted
+// string.
+func mapKey(key string) string {
+	if token.Lookup(key) != token.Ident {
+		return strconv.Quote(key) // keyword
+	}
+	for i, ch := range key {
+		if ch != '_' && !unicode.IsLetter(ch) &&
+			(i == 0 || !unicode.IsDigit(ch)) {
+			return strconv.Quote(key)
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
+
+func isSimpleStmt(s parser.Stmt) bool {
+	switch s.(type) {
+	case *parser.AssignStmt, *parser.BranchStmt, *parser.ExprStmt,
+		*parser.IncDecStmt, *parser.ReturnStmt, *parser.YieldStmt:
+		return true
+	}
+	return false
+}
+
+func nonEmptyStmts(list []parser.Stmt) []parser.Stmt {
+	var stmts []parser.Stmt
+	for _, s := range list {
+		if _, ok := s.(*parser.EmptyStmt); !ok {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
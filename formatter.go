@@ -1,7 +1,9 @@
 package tengo
 
 import (
+	"math/big"
 	"strconv"
+	"strings"
 	"sync"
 	"unicode/utf8"
 )
@@ -930,6 +932,44 @@ func (p *pp) fmtBytes(v []byte, verb rune, typeString string) {
 	}
 }
 
+// fmtBigInt formats a big integer. The sign and the base prefix flags are
+// not supported.
+func (p *pp) fmtBigInt(v *big.Int, verb rune) {
+	switch verb {
+	case 'v', 'd':
+		p.fmt.padString(v.Text(10))
+	case 'b':
+		p.fmt.padString(v.Text(2))
+	case 'o', 'O':
+		p.fmt.padString(v.Text(8))
+	case 'x':
+		p.fmt.padString(v.Text(16))
+	case 'X':
+		p.fmt.padString(strings.ToUpper(v.Text(16)))
+	default:
+		p.badVerb(verb)
+	}
+}
+
+// fmtDecimal formats a decimal. The precision of 'f' verb rounds the value
+// half away from zero, and the other float verbs format the value as a
+// float.
+func (p *pp) fmtDecimal(v *Decimal, verb rune) {
+	switch verb {
+	case 'v', 's':
+		p.fmt.padString(v.String())
+	case 'f', 'F':
+		if p.fmt.precPresent {
+			p.fmt.padString(v.Value.FloatString(p.fmt.prec))
+		} else {
+			p.fmt.padString(v.String())
+		}
+	default:
+		f, _ := v.Value.Float64()
+		p.fmtFloat(f, 64, verb)
+	}
+}
+
 func (p *pp) printArg(arg Object, verb rune) {
 	p.arg = arg
 
@@ -957,6 +997,10 @@ func (p *pp) printArg(arg Object, verb rune) {
 		p.fmtFloat(f.Value, 64, verb)
 	case *Int:
 		p.fmtInteger(uint64(f.Value), signed, verb)
+	case *BigInt:
+		p.fmtBigInt(f.Value, verb)
+	case *Decimal:
+		p.fmtDecimal(f, verb)
 	case *String:
 		p.fmtString(f.Value, verb)
 	case *Bytes:
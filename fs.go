@@ -0,0 +1,588 @@
+package tengo
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is a file system that the scripts access instead of the host's file
+// system. See Script.SetFS. The names are slash-separated paths from the root
+// of the file system, and ".." elements can't go above the root.
+type FS interface {
+	// Open opens the named file for reading.
+	Open(name string) (File, error)
+
+	// Stat returns the FileInfo of the named file.
+	Stat(name string) (os.FileInfo, error)
+}
+
+// WritableFS is a file system that the scripts can also modify.
+type WritableFS interface {
+	FS
+
+	// OpenFile opens the named file with the flag (os.O_RDONLY etc.) and
+	// the permission bits used if the file is created.
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+
+	// Mkdir creates the named directory with the permission bits.
+	Mkdir(name string, perm os.FileMode) error
+
+	// Remove removes the named file or empty directory.
+	Remove(name string) error
+}
+
+// File is a file opened from an FS. A file opened for writing also
+// implements io.Writer, and a seekable file io.Seeker.
+type File interface {
+	io.Reader
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+// errDirNotEmpty is the error of removing a directory that's not empty.
+var errDirNotEmpty = errors.New("directory not empty")
+
+// errIsDir is the error of reading or writing a directory as a file.
+var errIsDir = errors.New("is a directory")
+
+// fsPath returns the cleaned absolute path of the name in a file system.
+func fsPath(name string) string {
+	return path.Clean("/" + name)
+}
+
+// readFile reads the named file of the file system.
+func readFile(fsys FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return ioutil.ReadAll(f)
+}
+
+// DirFS returns a file system rooted at the directory dir of the host. The
+// scripts can't access the files outside of the directory: the symbolic links
+// in the directory are resolved, and the names that lead outside of it fail
+// with a permission error. The links are checked before each access, so a link
+// replaced by another process in the meantime may still be followed.
+func DirFS(dir string) WritableFS {
+	return dirFS(dir)
+}
+
+type dirFS string
+
+// Open opens the named file for reading.
+func (dir dirFS) Open(name string) (File, error) {
+	path, err := dir.join("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, pathError(err, name)
+	}
+	return &dirFile{f: f, name: name}, nil
+}
+
+// Stat returns the FileInfo of the named file.
+func (dir dirFS) Stat(name string) (os.FileInfo, error) {
+	path, err := dir.join("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, pathError(err, name)
+	}
+	return fi, nil
+}
+
+// OpenFile opens the named file with the flag and the permission bits.
+func (dir dirFS) OpenFile(
+	name string,
+	flag int,
+	perm os.FileMode,
+) (File, error) {
+	path, err := dir.join("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, pathError(err, name)
+	}
+	return &dirFile{f: f, name: name}, nil
+}
+
+// Mkdir creates the named directory with the permission bits.
+func (dir dirFS) Mkdir(name string, perm os.FileMode) error {
+	path, err := dir.join("mkdir", name, false)
+	if err != nil {
+		return err
+	}
+	return pathError(os.Mkdir(path, perm), name)
+}
+
+// Remove removes the named file or empty directory. A symbolic link is
+// removed itself, not the file that it links to.
+func (dir dirFS) Remove(name string) error {
+	path, err := dir.join("remove", name, false)
+	if err != nil {
+		return err
+	}
+	return pathError(os.Remove(path), name)
+}
+
+// join returns the host path of the name with the symbolic links resolved,
+// except the last element of the name if follow is false. It returns a
+// permission error if the path leads outside of the directory.
+func (dir dirFS) join(op, name string, follow bool) (string, error) {
+	root, err := filepath.EvalSymlinks(string(dir))
+	if err != nil {
+		return "", pathError(err, name)
+	}
+	path := filepath.Join(root, filepath.FromSlash(fsPath(name)))
+	if path == root {
+		return root, nil
+	}
+	if follow {
+		path, err = evalSymlinks(path)
+	} else {
+		var parent string
+		parent, err = evalSymlinks(filepath.Dir(path))
+		path = filepath.Join(parent, filepath.Base(path))
+	}
+	if err != nil {
+		return "", pathError(err, name)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+	}
+	return path, nil
+}
+
+// evalSymlinks is like filepath.EvalSymlinks, but the elements at the end of
+// the path may not exist, e.g. the file to create. It returns a permission
+// error if such an element is a symbolic link to a file that doesn't exist,
+// because the link could be followed outside of the directory when the file
+// is created.
+func evalSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil || !os.IsNotExist(err) {
+		return resolved, err
+	}
+	if _, err := os.Lstat(path); err == nil {
+		return "", &os.PathError{Op: "lstat", Path: path, Err: os.ErrPermission}
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return "", err
+	}
+	resolved, err = evalSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolved, filepath.Base(path)), nil
+}
+
+// pathError replaces the host path in the error with the name so that the
+// errors don't reveal the directory to the scripts.
+func pathError(err error, name string) error {
+	if e, ok := err.(*os.PathError); ok {
+		e.Path = name
+	}
+	return err
+}
+
+// dirFile is a file of dirFS. It hides the host path and the methods of
+// os.File that could access the files outside of the directory.
+type dirFile struct {
+	f    *os.File
+	name string
+}
+
+// Name returns the name of the file in the file system.
+func (f *dirFile) Name() string {
+	return f.name
+}
+
+// Read reads the file.
+func (f *dirFile) Read(b []byte) (int, error) {
+	n, err := f.f.Read(b)
+	return n, pathError(err, f.name)
+}
+
+// Write writes the file.
+func (f *dirFile) Write(b []byte) (int, error) {
+	n, err := f.f.Write(b)
+	return n, pathError(err, f.name)
+}
+
+// Seek sets the offset of the next Read or Write.
+func (f *dirFile) Seek(offset int64, whence int) (int64, error) {
+	n, err := f.f.Seek(offset, whence)
+	return n, pathError(err, f.name)
+}
+
+// Stat returns the FileInfo of the file.
+func (f *dirFile) Stat() (os.FileInfo, error) {
+	fi, err := f.f.Stat()
+	return fi, pathError(err, f.name)
+}
+
+// Sync commits the contents of the file to the storage.
+func (f *dirFile) Sync() error {
+	return pathError(f.f.Sync(), f.name)
+}
+
+// Close closes the file.
+func (f *dirFile) Close() error {
+	return pathError(f.f.Close(), f.name)
+}
+
+// ReadOnlyFS returns a file system that reads the files of fsys, but can't
+// modify them.
+func ReadOnlyFS(fsys FS) FS {
+	return readOnlyFS{fs: fsys}
+}
+
+type readOnlyFS struct {
+	fs FS
+}
+
+// Open opens the named file for reading.
+func (r readOnlyFS) Open(name string) (File, error) {
+	return r.fs.Open(name)
+}
+
+// Stat returns the FileInfo of the named file.
+func (r readOnlyFS) Stat(name string) (os.FileInfo, error) {
+	return r.fs.Stat(name)
+}
+
+// MemFS is an in-memory file system. It's safe for concurrent use.
+type MemFS struct {
+	mu    sync.RWMutex
+	nodes map[string]*memNode // by the cleaned absolute paths
+}
+
+type memNode struct {
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// NewMemFS creates an empty in-memory file system.
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: map[string]*memNode{
+		"/": {mode: os.ModeDir | 0777, modTime: time.Now()},
+	}}
+}
+
+// WriteFile writes the data to the named file with the permission bits,
+// creating the parent directories if they don't exist.
+func (m *MemFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := fsPath(name)
+	var dirs []string
+	dir := path.Dir(p)
+	for m.nodes[dir] == nil {
+		dirs = append(dirs, dir)
+		dir = path.Dir(dir)
+	}
+	if !m.nodes[dir].mode.IsDir() {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	if n := m.nodes[p]; n != nil && n.mode.IsDir() {
+		return &os.PathError{Op: "open", Path: name, Err: errIsDir}
+	}
+	now := time.Now()
+	for _, dir := range dirs {
+		m.nodes[dir] = &memNode{mode: os.ModeDir | 0777, modTime: now}
+	}
+	m.nodes[p] = &memNode{
+		data:    append([]byte(nil), data...),
+		mode:    perm & os.ModePerm,
+		modTime: now,
+	}
+	return nil
+}
+
+// Open opens the named file for reading.
+func (m *MemFS) Open(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// Stat returns the FileInfo of the named file.
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p := fsPath(name)
+	n := m.nodes[p]
+	if n == nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return n.info(p), nil
+}
+
+// OpenFile opens the named file with the flag and the permission bits.
+func (m *MemFS) OpenFile(
+	name string,
+	flag int,
+	perm os.FileMode,
+) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := fsPath(name)
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	n := m.nodes[p]
+	switch {
+	case n == nil:
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: "open", Path: name,
+				Err: os.ErrNotExist}
+		}
+		parent := m.nodes[path.Dir(p)]
+		if parent == nil || !parent.mode.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: name,
+				Err: os.ErrNotExist}
+		}
+		n = &memNode{mode: perm & os.ModePerm, modTime: time.Now()}
+		m.nodes[p] = n
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case n.mode.IsDir() && writable:
+		return nil, &os.PathError{Op: "open", Path: name, Err: errIsDir}
+	case writable && flag&os.O_TRUNC != 0:
+		n.data = nil
+		n.modTime = time.Now()
+	}
+	return &memFile{fs: m, node: n, path: p, name: name, flag: flag}, nil
+}
+
+// Mkdir creates the named directory with the permission bits.
+func (m *MemFS) Mkdir(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := fsPath(name)
+	if m.nodes[p] != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	parent := m.nodes[path.Dir(p)]
+	if parent == nil || !parent.mode.IsDir() {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrNotExist}
+	}
+	m.nodes[p] = &memNode{
+		mode:    os.ModeDir | perm&os.ModePerm,
+		modTime: time.Now(),
+	}
+	return nil
+}
+
+// Remove removes the named file or empty directory.
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := fsPath(name)
+	n := m.nodes[p]
+	if n == nil {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	if p == "/" {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+	}
+	if n.mode.IsDir() {
+		for other := range m.nodes {
+			if strings.HasPrefix(other, p+"/") {
+				return &os.PathError{Op: "remove", Path: name,
+					Err: errDirNotEmpty}
+			}
+		}
+	}
+	delete(m.nodes, p)
+	return nil
+}
+
+// Names returns the sorted paths of the files and the directories, except
+// the root directory.
+func (m *MemFS) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.nodes)-1)
+	for p := range m.nodes {
+		if p != "/" {
+			names = append(names, p)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (n *memNode) info(p string) os.FileInfo {
+	return &memFileInfo{
+		name:    path.Base(p),
+		size:    int64(len(n.data)),
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
+
+// memFile is a file of MemFS.
+type memFile struct {
+	fs     *MemFS
+	node   *memNode
+	path   string
+	name   string
+	flag   int
+	offset int64
+	closed bool
+}
+
+// Name returns the name of the file.
+func (f *memFile) Name() string {
+	return f.name
+}
+
+// Read reads the file at the current offset.
+func (f *memFile) Read(b []byte) (int, error) {
+	if err := f.check("read", f.flag&os.O_WRONLY == 0); err != nil {
+		return 0, err
+	}
+	f.fs.mu.RLock()
+	defer f.fs.mu.RUnlock()
+
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.node.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+// Write writes the file at the current offset, or at the end of the file if
+// it's opened with os.O_APPEND.
+func (f *memFile) Write(b []byte) (int, error) {
+	writable := f.flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if err := f.check("write", writable); err != nil {
+		return 0, err
+	}
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.data))
+	}
+	end := f.offset + int64(len(b))
+	if end > int64(len(f.node.data)) {
+		data := make([]byte, end)
+		copy(data, f.node.data)
+		f.node.data = data
+	}
+	copy(f.node.data[f.offset:], b)
+	f.offset = end
+	f.node.modTime = time.Now()
+	return len(b), nil
+}
+
+// Seek sets the offset of the next Read or Write.
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.check("seek", true); err != nil {
+		return 0, err
+	}
+	f.fs.mu.RLock()
+	defer f.fs.mu.RUnlock()
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Stat returns the FileInfo of the file.
+func (f *memFile) Stat() (os.FileInfo, error) {
+	if f.closed {
+		return nil, &os.PathError{Op: "stat", Path: f.name, Err: os.ErrClosed}
+	}
+	f.fs.mu.RLock()
+	defer f.fs.mu.RUnlock()
+	return f.node.info(f.path), nil
+}
+
+// Close closes the file.
+func (f *memFile) Close() error {
+	if f.closed {
+		return &os.PathError{Op: "close", Path: f.name, Err: os.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+// check returns the error of the operation op on the file, or nil if the
+// file is open, not a directory, and allowed is true.
+func (f *memFile) check(op string, allowed bool) error {
+	switch {
+	case f.closed:
+		return &os.PathError{Op: op, Path: f.name, Err: os.ErrClosed}
+	case f.node.mode.IsDir():
+		return &os.PathError{Op: op, Path: f.name, Err: errIsDir}
+	case !allowed:
+		return &os.PathError{Op: op, Path: f.name, Err: os.ErrPermission}
+	}
+	return nil
+}
+
+// memFileInfo is the os.FileInfo of a file of MemFS.
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *memFileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *memFileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *memFileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+func (fi *memFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *memFileInfo) IsDir() bool {
+	return fi.mode.IsDir()
+}
+
+func (fi *memFileInfo) Sys() interface{} {
+	return nil
+}
@@ -0,0 +1,174 @@
+package tengo_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/require"
+)
+
+func TestMemFS(t *testing.T) {
+	fsys := tengo.NewMemFS()
+	testWritableFS(t, fsys)
+	require.Equal(t, "/dir", strings.Join(fsys.Names(), ","))
+
+	require.NoError(t, fsys.WriteFile("a/b/c", []byte("foo"), 0600))
+	require.Equal(t, "/a,/a/b,/a/b/c,/dir", strings.Join(fsys.Names(), ","))
+	fi, err := fsys.Stat("a/b/c")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), fi.Size())
+	require.Equal(t, "c", fi.Name())
+	require.True(t, fi.Mode() == 0600)
+	require.True(t, fsys.WriteFile("a/b/c/d", nil, 0600) != nil)
+	require.True(t, fsys.WriteFile("a/b", nil, 0600) != nil)
+
+	// a directory is not empty
+	err = fsys.Remove("a")
+	require.True(t, err != nil && strings.Contains(err.Error(),
+		"directory not empty"), err)
+	err = fsys.Remove("/")
+	require.True(t, errors.Is(err, os.ErrPermission))
+}
+
+func TestDirFS(t *testing.T) {
+	root, err := ioutil.TempDir("", "tengo")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(root) }()
+	dir := filepath.Join(root, "dir")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "secret"),
+		[]byte("foo"), 0644))
+
+	fsys := tengo.DirFS(dir)
+	testWritableFS(t, fsys)
+
+	// the names can't go above the root, and the errors don't reveal the
+	// host path
+	_, err = fsys.Open("../secret")
+	require.True(t, errors.Is(err, os.ErrNotExist))
+	require.Equal(t, "open ../secret: no such file or directory",
+		err.Error())
+	_, err = fsys.Stat("/../../secret")
+	require.True(t, errors.Is(err, os.ErrNotExist))
+
+	// the symbolic links can't lead outside of the root
+	if err := os.Symlink(root, filepath.Join(dir, "up")); err != nil {
+		t.Skip("symbolic links are not supported:", err)
+	}
+	require.NoError(t, os.Symlink(filepath.Join(root, "secret"),
+		filepath.Join(dir, "link")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "new"),
+		filepath.Join(dir, "dangling")))
+	require.NoError(t, os.Symlink("sub", filepath.Join(dir, "inner")))
+	for _, name := range []string{"link", "up/secret", "up/dir/link"} {
+		_, err = fsys.Open(name)
+		require.True(t, errors.Is(err, os.ErrPermission), "%s: %v", name, err)
+		require.Equal(t, "open "+name+": permission denied", err.Error())
+		_, err = fsys.Stat(name)
+		require.True(t, errors.Is(err, os.ErrPermission), "%s: %v", name, err)
+	}
+	for _, name := range []string{"dangling", "up/new", "up/new/x"} {
+		_, err = fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE, 0644)
+		require.True(t, errors.Is(err, os.ErrPermission), "%s: %v", name, err)
+		require.Error(t, fsys.Mkdir(name, 0755), name)
+	}
+	_, err = os.Stat(filepath.Join(root, "new"))
+	require.True(t, errors.Is(err, os.ErrNotExist))
+
+	// the links inside of the root are followed, and removing a link
+	// removes the link itself
+	require.NoError(t, fsys.Mkdir("sub", 0755))
+	f, err := fsys.OpenFile("inner/a", os.O_WRONLY|os.O_CREATE, 0644)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	_, err = fsys.Stat("sub/a")
+	require.NoError(t, err)
+	require.NoError(t, fsys.Remove("link"))
+	_, err = os.Stat(filepath.Join(root, "secret"))
+	require.NoError(t, err)
+}
+
+func TestReadOnlyFS(t *testing.T) {
+	fsys := tengo.NewMemFS()
+	require.NoError(t, fsys.WriteFile("a", []byte("foo"), 0644))
+
+	ro := tengo.ReadOnlyFS(fsys)
+	_, ok := ro.(tengo.WritableFS)
+	require.False(t, ok)
+	f, err := ro.Open("a")
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "foo", string(b))
+	_, err = f.(io.Writer).Write([]byte("bar"))
+	require.True(t, errors.Is(err, os.ErrPermission))
+	require.NoError(t, f.Close())
+}
+
+// testWritableFS tests the common behaviors of an empty writable file system.
+func testWritableFS(t *testing.T, fsys tengo.WritableFS) {
+	_, err := fsys.Open("a")
+	require.True(t, errors.Is(err, os.ErrNotExist))
+
+	// create, write and read a file
+	f, err := fsys.OpenFile("a", os.O_RDWR|os.O_CREATE, 0644)
+	require.NoError(t, err)
+	_, err = f.(io.Writer).Write([]byte("hello world"))
+	require.NoError(t, err)
+	pos, err := f.(io.Seeker).Seek(6, io.SeekStart)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), pos)
+	b, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(b))
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	require.Equal(t, "a", fi.Name())
+	require.Equal(t, int64(11), fi.Size())
+	require.NoError(t, f.Close())
+
+	// open flags
+	_, err = fsys.OpenFile("a", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	require.True(t, errors.Is(err, os.ErrExist))
+	f, err = fsys.OpenFile("/a", os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.(io.Writer).Write([]byte("!"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Equal(t, "hello world!", readAll(t, fsys, "a"))
+	f, err = fsys.OpenFile("./a", os.O_WRONLY|os.O_TRUNC, 0)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Equal(t, "", readAll(t, fsys, "a"))
+
+	// directories
+	require.NoError(t, fsys.Mkdir("dir", 0755))
+	require.True(t, errors.Is(fsys.Mkdir("dir", 0755), os.ErrExist))
+	require.True(t, errors.Is(fsys.Mkdir("x/y", 0755), os.ErrNotExist))
+	fi, err = fsys.Stat("dir")
+	require.NoError(t, err)
+	require.True(t, fi.IsDir())
+	f, err = fsys.OpenFile("dir/b", os.O_WRONLY|os.O_CREATE, 0644)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Error(t, fsys.Remove("dir"))
+	require.NoError(t, fsys.Remove("dir/b"))
+
+	require.NoError(t, fsys.Remove("a"))
+	require.True(t, errors.Is(fsys.Remove("a"), os.ErrNotExist))
+}
+
+func readAll(t *testing.T, fsys tengo.FS, name string) string {
+	f, err := fsys.Open(name)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	b, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	return string(b)
+}
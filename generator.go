@@ -0,0 +1,171 @@
+package tengo
+
+import (
+	"github.com/d5/tengo/v2/parser"
+)
+
+// generatorNext is the instructions of the entry frame that resumes a
+// generator outside of the VM loop.
+var generatorNext = []byte{parser.OpIteratorNext, parser.OpSuspend}
+
+// Generator represents a generator returned by calling a generator function,
+// i.e. a function that contains yield statements. A generator is an Iterator
+// of the yielded values indexed from 0. The function body runs on the VM that
+// called it only when the next value is requested, and it's suspended at each
+// yield statement with its frame and stack saved in the generator. A
+// generator can be iterated only once. The finally blocks of a generator that
+// is abandoned before it's done don't run.
+type Generator struct {
+	ObjectImpl
+	vm       *VM
+	fn       *CompiledFunction
+	stack    []Object       // local variables and operands of the frame
+	handlers []errorHandler // error handlers relative to the frame
+	ip       int
+	key      int64
+	value    Object
+	running  bool
+	done     bool
+	err      error
+}
+
+// TypeName returns the name of the type.
+func (o *Generator) TypeName() string {
+	return "generator"
+}
+
+func (o *Generator) String() string {
+	return "<generator>"
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (o *Generator) IsFalsy() bool {
+	return o.done
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (o *Generator) Equals(x Object) bool {
+	return o == x
+}
+
+// Copy returns the generator itself. The state of a generator cannot be
+// copied.
+func (o *Generator) Copy() Object {
+	return o
+}
+
+// CanIterate returns whether the Object can be Iterated.
+func (o *Generator) CanIterate() bool {
+	return true
+}
+
+// Iterate returns the generator itself.
+func (o *Generator) Iterate() Iterator {
+	return o
+}
+
+// Next resumes the generator and returns true if it yields a value. It's used
+// when the generator is iterated outside of the loop of its VM, e.g. by a Go
+// function or after the VM finishes. It must not be called while the VM is
+// running in another goroutine. If the generator fails, Next returns false
+// and Err returns the error.
+func (o *Generator) Next() bool {
+	res, err := o.vm.runEntry(generatorNext, o)
+	if err != nil {
+		o.err = err
+		return false
+	}
+	return res == TrueValue
+}
+
+// Key returns the index of the current value.
+func (o *Generator) Key() Object {
+	return &Int{Value: o.key}
+}
+
+// Value returns the current value yielded by the generator.
+func (o *Generator) Value() Object {
+	return o.value
+}
+
+// Err returns the runtime error that stopped the generator when it was
+// resumed by Next.
+func (o *Generator) Err() error {
+	return o.err
+}
+
+// finish marks the generator done and releases its frame.
+func (o *Generator) finish() {
+	o.running, o.done = false, true
+	o.stack, o.handlers, o.value = nil, nil, UndefinedValue
+}
+
+// resume pushes the frame of the generator on top of the stack to continue
+// the execution from the last yield. The generator in the stack is replaced
+// by the result of the next iteration when it yields or returns.
+func (v *VM) resume(g *Generator) bool {
+	if g.done {
+		v.stack[v.sp-1] = FalseValue
+		return true
+	}
+	if g.running {
+		v.err = ErrGeneratorRunning
+		return false
+	}
+	if !v.growFrames() || !v.growStack(v.sp+len(g.stack)+stackReserve) {
+		v.err = ErrStackOverflow
+		return false
+	}
+
+	v.curFrame.ip = v.ip
+	v.curFrame = &v.frames[v.framesIndex]
+	v.curFrame.fn = g.fn
+	v.curFrame.freeVars = g.fn.Free
+	v.curFrame.basePointer = v.sp
+	v.curFrame.gen = g
+	v.curInsts = g.fn.Instructions
+	v.ip = g.ip
+	v.framesIndex++
+	for _, h := range g.handlers {
+		v.handlers = append(v.handlers, errorHandler{
+			framesIndex: v.framesIndex,
+			sp:          v.sp + h.sp,
+			ip:          h.ip,
+		})
+	}
+	v.sp += copy(v.stack[v.sp:], g.stack)
+	g.running = true
+	return true
+}
+
+// yield saves the frame of the generator with the value on top of the stack,
+// and returns to the caller frame with the result of the iteration.
+func (v *VM) yield() {
+	g := v.curFrame.gen
+	base := v.curFrame.basePointer
+	g.key++
+	g.value = v.stack[v.sp-1]
+	g.stack = append(g.stack[:0], v.stack[base:v.sp-1]...)
+	n := len(v.handlers)
+	for n > 0 && v.handlers[n-1].framesIndex == v.framesIndex {
+		n--
+	}
+	g.handlers = g.handlers[:0]
+	for _, h := range v.handlers[n:] {
+		g.handlers = append(g.handlers, errorHandler{
+			sp: h.sp - base,
+			ip: h.ip,
+		})
+	}
+	v.handlers = v.handlers[:n]
+	g.ip = v.ip
+	g.running = false
+
+	v.framesIndex--
+	v.curFrame = &v.frames[v.framesIndex-1]
+	v.curInsts = v.curFrame.fn.Instructions
+	v.ip = v.curFrame.ip
+	v.sp = base
+	v.stack[v.sp-1] = TrueValue
+}
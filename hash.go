@@ -0,0 +1,201 @@
+package tengo
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Hashable represents an object that can be used as a map key. Strings,
+// ints, big ints, decimals, floats, chars, bools, times and immutable arrays
+// of hashable values are hashable. A user type can be used as a map key by implementing this
+// interface: objects that are equal (see Object.Equals) must have the same
+// hash, and their value must not change while they're used as keys.
+type Hashable interface {
+	Object
+
+	// Hash returns the hash of the value.
+	Hash() uint64
+}
+
+// ToHashable returns the object as a map key. It returns false if the object
+// is not hashable, or it's an immutable array with an element that is not
+// hashable.
+func ToHashable(o Object) (Hashable, bool) {
+	h, ok := o.(Hashable)
+	if !ok || o == UndefinedValue {
+		return nil, false
+	}
+	if arr, ok := o.(*ImmutableArray); ok {
+		for _, elem := range arr.Value {
+			if _, ok := ToHashable(elem); !ok {
+				return nil, false
+			}
+		}
+	}
+	return h, true
+}
+
+// MapEntry represents a key-value pair of a map.
+type MapEntry struct {
+	Key   Hashable
+	Value Object
+}
+
+// HashedMap holds the entries of a map with the keys that are not converted
+// to strings, e.g. floats and immutable arrays (see Map.IndexSet). The
+// entries are looked up by the hashes of the keys, and the keys of the same
+// hash are compared using Equals. A nil HashedMap is an empty map.
+type HashedMap struct {
+	buckets map[uint64][]*MapEntry
+	size    int
+}
+
+// NewHashedMap creates an empty HashedMap.
+func NewHashedMap() *HashedMap {
+	return &HashedMap{buckets: make(map[uint64][]*MapEntry)}
+}
+
+// Len returns the number of the entries.
+func (m *HashedMap) Len() int {
+	if m == nil {
+		return 0
+	}
+	return m.size
+}
+
+// Get returns the value for the key.
+func (m *HashedMap) Get(key Hashable) (Object, bool) {
+	if m == nil {
+		return nil, false
+	}
+	for _, e := range m.buckets[key.Hash()] {
+		if e.Key.Equals(key) {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the value for the key.
+func (m *HashedMap) Set(key Hashable, value Object) {
+	h := key.Hash()
+	for _, e := range m.buckets[h] {
+		if e.Key.Equals(key) {
+			e.Value = value
+			return
+		}
+	}
+	m.buckets[h] = append(m.buckets[h], &MapEntry{Key: key, Value: value})
+	m.size++
+}
+
+// Delete deletes the entry of the key.
+func (m *HashedMap) Delete(key Hashable) {
+	if m == nil {
+		return
+	}
+	h := key.Hash()
+	bucket := m.buckets[h]
+	for i, e := range bucket {
+		if !e.Key.Equals(key) {
+			continue
+		}
+		if len(bucket) == 1 {
+			delete(m.buckets, h)
+		} else {
+			m.buckets[h] = append(bucket[:i:i], bucket[i+1:]...)
+		}
+		m.size--
+		return
+	}
+}
+
+// Entries returns the entries in no particular order.
+func (m *HashedMap) Entries() []*MapEntry {
+	if m == nil {
+		return nil
+	}
+	entries := make([]*MapEntry, 0, m.size)
+	for _, bucket := range m.buckets {
+		entries = append(entries, bucket...)
+	}
+	return entries
+}
+
+// Copy returns a copy of the map with copies of the values; or nil if the map
+// is empty.
+func (m *HashedMap) Copy() *HashedMap {
+	if m.Len() == 0 {
+		return nil
+	}
+	c := NewHashedMap()
+	for _, e := range m.Entries() {
+		c.Set(e.Key, e.Value.Copy())
+	}
+	return c
+}
+
+// Equals returns true if both maps have the same keys, and the values of the
+// keys are equal.
+func (m *HashedMap) Equals(x *HashedMap) bool {
+	if m.Len() != x.Len() {
+		return false
+	}
+	for _, e := range m.Entries() {
+		v, ok := x.Get(e.Key)
+		if !ok || !e.Value.Equals(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// GobEncode encodes the entries of the map.
+func (m *HashedMap) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(m.Entries())
+	return buf.Bytes(), err
+}
+
+// GobDecode decodes the entries of the map.
+func (m *HashedMap) GobDecode(b []byte) error {
+	var entries []*MapEntry
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&entries); err != nil {
+		return err
+	}
+	*m = *NewHashedMap()
+	for _, e := range entries {
+		m.Set(e.Key, e.Value)
+	}
+	return nil
+}
+
+// hashString returns the FNV-1a hash of the string.
+func hashString(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// hashUint64 returns the hash of the value, which is different for each seed.
+func hashUint64(seed, v uint64) uint64 {
+	v += seed * 0x9e3779b97f4a7c15
+	v = (v ^ (v >> 30)) * 0xbf58476d1ce4e5b9
+	v = (v ^ (v >> 27)) * 0x94d049bb133111eb
+	return v ^ (v >> 31)
+}
+
+// seeds of the hashes of the builtin types
+const (
+	hashSeedInt uint64 = iota + 1
+	hashSeedFloat
+	hashSeedChar
+	hashSeedBool
+	hashSeedTime
+	hashSeedArray
+	hashSeedBigInt
+	hashSeedDecimal
+)
@@ -112,10 +112,25 @@ type MapIterator struct {
 	ObjectImpl
 	v map[string]Object
 	k []string
+	e []*MapEntry // entries of the keys other than strings
 	i int
 	l int
 }
 
+func newMapIterator(v map[string]Object, hashed *HashedMap) *MapIterator {
+	var keys []string
+	for k := range v {
+		keys = append(keys, k)
+	}
+	entries := hashed.Entries()
+	return &MapIterator{
+		v: v,
+		k: keys,
+		e: entries,
+		l: len(keys) + len(entries),
+	}
+}
+
 // TypeName returns the name of the type.
 func (i *MapIterator) TypeName() string {
 	return "map-iterator"
@@ -138,7 +153,7 @@ func (i *MapIterator) Equals(Object) bool {
 
 // Copy returns a copy of the type.
 func (i *MapIterator) Copy() Object {
-	return &MapIterator{v: i.v, k: i.k, i: i.i, l: i.l}
+	return &MapIterator{v: i.v, k: i.k, e: i.e, i: i.i, l: i.l}
 }
 
 // Next returns true if there are more elements to iterate.
@@ -149,12 +164,18 @@ func (i *MapIterator) Next() bool {
 
 // Key returns the key or index value of the current element.
 func (i *MapIterator) Key() Object {
+	if i.i > len(i.k) {
+		return i.e[i.i-len(i.k)-1].Key
+	}
 	k := i.k[i.i-1]
 	return &String{Value: k}
 }
 
 // Value returns the value of the current element.
 func (i *MapIterator) Value() Object {
+	if i.i > len(i.k) {
+		return i.e[i.i-len(i.k)-1].Value
+	}
 	k := i.k[i.i-1]
 	return i.v[k]
 }
@@ -0,0 +1,623 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/token"
+)
+
+// builtinArgs are the minimum and maximum numbers of the arguments of the
+// builtin functions. The maximum is -1 if the function is variadic.
+var builtinArgs = map[string][2]int{
+	"len":                {1, 1},
+	"copy":               {1, 1},
+	"append":             {2, -1},
+	"delete":             {2, 2},
+	"splice":             {1, -1},
+	"string":             {1, 2},
+	"int":                {1, 2},
+	"bigint":             {1, 2},
+	"decimal":            {1, 2},
+	"bool":               {1, 1},
+	"float":              {1, 2},
+	"char":               {1, 2},
+	"bytes":              {1, 2},
+	"time":               {1, 2},
+	"is_int":             {1, 1},
+	"is_bigint":          {1, 1},
+	"is_decimal":         {1, 1},
+	"is_float":           {1, 1},
+	"is_string":          {1, 1},
+	"is_bool":            {1, 1},
+	"is_char":            {1, 1},
+	"is_bytes":           {1, 1},
+	"is_array":           {1, 1},
+	"is_immutable_array": {1, 1},
+	"is_map":             {1, 1},
+	"is_immutable_map":   {1, 1},
+	"is_iterable":        {1, 1},
+	"is_time":            {1, 1},
+	"is_error":           {1, 1},
+	"is_undefined":       {1, 1},
+	"is_function":        {1, 1},
+	"is_callable":        {1, 1},
+	"type_name":          {1, 1},
+	"format":             {1, -1},
+	"is_record":          {1, 2},
+}
+
+// variable is a variable defined in the source code.
+type variable struct {
+	ident    *parser.Ident
+	used     bool
+	param    bool   // function parameter
+	defining bool   // true while checking the value assigned at definition
+	module   string // name of the imported module; or empty
+}
+
+// scope mirrors a symbol table of the compiler and keeps the variables
+// defined in the scope.
+type scope struct {
+	table  *tengo.SymbolTable
+	parent *scope
+	vars   map[string]*variable
+}
+
+type checker struct {
+	linter   *Linter
+	file     *parser.SourceFile
+	scope    *scope
+	loops    int // number of enclosing loops in the current function
+	switches int // number of enclosing switches in the current function
+	diags    []*Diagnostic
+}
+
+func newChecker(linter *Linter, file *parser.SourceFile) *checker {
+	table := tengo.NewSymbolTable()
+	for idx, fn := range tengo.GetAllBuiltinFunctions() {
+		table.DefineBuiltin(idx, fn.Name)
+	}
+	for _, name := range linter.globals {
+		table.Define(name)
+	}
+	return &checker{
+		linter: linter,
+		file:   file,
+		scope:  &scope{table: table, vars: make(map[string]*variable)},
+	}
+}
+
+func (c *checker) report(
+	node parser.Node,
+	rule, format string,
+	args ...interface{},
+) {
+	c.diags = append(c.diags, &Diagnostic{
+		Pos:     c.file.Position(node.Pos()),
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// enter opens a new scope for a block or for a function.
+func (c *checker) enter(block bool) {
+	c.scope = &scope{
+		table:  c.scope.table.Fork(block),
+		parent: c.scope,
+		vars:   make(map[string]*variable),
+	}
+}
+
+// leave closes the current scope and reports its unused variables. Global
+// variables are only reported if they hold the imported modules, because the
+// others can be read by the host application.
+func (c *checker) leave() {
+	s := c.scope
+	global := s.parent == nil
+	for name, v := range s.vars {
+		if v.used || v.param || name == "_" || global && v.module == "" {
+			continue
+		}
+		c.report(v.ident, RuleUnused, "'%s' declared but not used", name)
+	}
+	c.scope = s.parent
+}
+
+// define defines a new variable in the current scope.
+func (c *checker) define(ident *parser.Ident) (*variable, *tengo.Symbol) {
+	if ident.Name != "_" {
+		c.shadow(ident)
+	}
+	v := &variable{ident: ident}
+	c.scope.vars[ident.Name] = v
+	return v, c.scope.table.Define(ident.Name)
+}
+
+// shadow reports if the identifier shadows a variable of the outer scopes.
+func (c *checker) shadow(ident *parser.Ident) {
+	symbol, depth, ok := c.scope.table.Resolve(ident.Name, false)
+	if !ok || depth == 0 && symbol.Scope != tengo.ScopeFree {
+		return
+	}
+	if symbol.Scope == tengo.ScopeBuiltin {
+		c.report(ident, RuleShadow,
+			"'%s' shadows builtin function", ident.Name)
+		return
+	}
+	if v := c.lookup(ident.Name, 0); v != nil {
+		pos := c.file.Position(v.ident.Pos())
+		c.report(ident, RuleShadow, "'%s' shadows declaration at %d:%d",
+			ident.Name, pos.Line, pos.Column)
+	}
+}
+
+// resolve returns the variable that the identifier refers to. It returns nil
+// if the identifier refers to a builtin function or to a global variable
+// defined outside the source code.
+func (c *checker) resolve(ident *parser.Ident) (*variable, bool) {
+	symbol, depth, ok := c.scope.table.Resolve(ident.Name, false)
+	if !ok {
+		c.report(ident, RuleUndefined, "unresolved reference '%s'",
+			ident.Name)
+		return nil, false
+	}
+	if symbol.Scope == tengo.ScopeBuiltin {
+		return nil, true
+	}
+	return c.lookup(ident.Name, depth), true
+}
+
+// lookup finds the variable in the scope that is depth levels up from the
+// current scope or in its outer scopes.
+func (c *checker) lookup(name string, depth int) *variable {
+	s := c.scope
+	for ; depth > 0 && s != nil; depth-- {
+		s = s.parent
+	}
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// stmts checks the statements and reports the first statement that cannot
+// be reached.
+func (c *checker) stmts(list []parser.Stmt) {
+	terminated := false
+	for _, stmt := range list {
+		if _, ok := stmt.(*parser.EmptyStmt); ok {
+			continue
+		}
+		if terminated {
+			c.report(stmt, RuleUnreachable, "unreachable code")
+			terminated = false
+		}
+		c.stmt(stmt)
+		switch stmt.(type) {
+		case *parser.ReturnStmt, *parser.BranchStmt:
+			terminated = true
+		}
+	}
+}
+
+func (c *checker) stmt(stmt parser.Stmt) {
+	switch stmt := stmt.(type) {
+	case *parser.AssignStmt:
+		c.assign(stmt.LHS, stmt.RHS, stmt.Token)
+	case *parser.BlockStmt:
+		c.enter(true)
+		c.stmts(stmt.Stmts)
+		c.leave()
+	case *parser.BranchStmt:
+		if stmt.Token == token.Break && c.loops+c.switches == 0 {
+			c.report(stmt, RuleBranch, "break not allowed outside loop")
+		} else if stmt.Token == token.Continue && c.loops == 0 {
+			c.report(stmt, RuleBranch, "continue not allowed outside loop")
+		}
+	case *parser.ExportStmt:
+		c.expr(stmt.Result)
+	case *parser.ExprStmt:
+		c.expr(stmt.Expr)
+	case *parser.ForInStmt:
+		c.enter(true)
+		c.expr(stmt.Iterable)
+		for _, ident := range []*parser.Ident{stmt.Key, stmt.Value} {
+			if ident.Name != "_" {
+				_, symbol := c.define(ident)
+				symbol.LocalAssigned = true
+			}
+		}
+		c.loops++
+		c.stmt(stmt.Body)
+		c.loops--
+		c.leave()
+	case *parser.ForStmt:
+		c.enter(true)
+		if stmt.Init != nil {
+			c.stmt(stmt.Init)
+		}
+		if stmt.Cond != nil {
+			c.expr(stmt.Cond)
+		}
+		c.loops++
+		c.stmt(stmt.Body)
+		c.loops--
+		if stmt.Post != nil {
+			c.stmt(stmt.Post)
+		}
+		c.leave()
+	case *parser.IfStmt:
+		c.enter(true)
+		if stmt.Init != nil {
+			c.stmt(stmt.Init)
+		}
+		c.expr(stmt.Cond)
+		c.stmt(stmt.Body)
+		if stmt.Else != nil {
+			c.stmt(stmt.Else)
+		}
+		c.leave()
+	case *parser.IncDecStmt:
+		c.assign([]parser.Expr{stmt.Expr}, nil, stmt.Token)
+	case *parser.ReturnStmt:
+		if stmt.Result != nil {
+			c.expr(stmt.Result)
+		}
+	case *parser.YieldStmt:
+		if stmt.Result != nil {
+			c.expr(stmt.Result)
+		}
+	case *parser.RecordStmt:
+		if _, depth, ok := c.scope.table.Resolve(stmt.Name.Name, false); ok &&
+			depth == 0 {
+			return // redeclared in the block: a compile error
+		}
+		v, symbol := c.define(stmt.Name)
+		symbol.LocalAssigned = true
+		v.defining = true // references in the methods are not uses
+		for _, m := range stmt.Methods {
+			if fn, ok := m.Value.(*parser.FuncLit); ok {
+				c.funcLit(fn, true)
+			}
+		}
+		v.defining = false
+	case *parser.TryStmt:
+		c.stmt(stmt.Body)
+		if stmt.Catch != nil {
+			c.enter(true)
+			if stmt.CatchIdent != nil && stmt.CatchIdent.Name != "_" {
+				_, symbol := c.define(stmt.CatchIdent)
+				symbol.LocalAssigned = true
+			}
+			c.stmt(stmt.Catch)
+			c.leave()
+		}
+		if stmt.Finally != nil {
+			c.stmt(stmt.Finally)
+		}
+	case *parser.SwitchStmt:
+		c.enter(true)
+		if stmt.Tag != nil {
+			c.expr(stmt.Tag)
+		}
+		for _, clause := range stmt.Cases {
+			c.exprs(clause.List)
+		}
+		c.switches++
+		for _, clause := range stmt.Cases {
+			c.enter(true)
+			c.stmts(clause.Body)
+			c.leave()
+		}
+		c.switches--
+		c.leave()
+	}
+}
+
+func (c *checker) assign(lhs, rhs []parser.Expr, op token.Token) {
+	if len(rhs) == 1 && (op == token.Define || op == token.Assign) {
+		if targets, ok := destructuringTargets(lhs); ok {
+			c.destructure(targets, rhs[0], op)
+			return
+		}
+	}
+	if len(lhs) != 1 || len(rhs) > 1 {
+		c.exprs(lhs)
+		c.exprs(rhs)
+		return // tuple assignment is a compile error
+	}
+
+	ident, selectors := assignTarget(lhs[0])
+	if ident == nil {
+		c.exprs(lhs)
+		c.exprs(rhs)
+		return
+	}
+	if op == token.Define {
+		if _, depth, ok := c.scope.table.Resolve(ident.Name, false); ok &&
+			depth == 0 {
+			c.exprs(rhs)
+			return // redeclared in the block: a compile error
+		}
+		v, symbol := c.define(ident)
+		v.defining = true // recursive references are not uses
+		c.exprs(rhs)
+		v.defining = false
+		symbol.LocalAssigned = true
+		if imp, ok := rhs[0].(*parser.ImportExpr); ok {
+			v.module = imp.ModuleName
+		}
+		return
+	}
+
+	v, _ := c.resolve(ident)
+	if v != nil && len(selectors) > 0 {
+		v.used = true
+		if v.module != "" {
+			c.report(lhs[0], RuleModule,
+				"cannot assign to member of module '%s'", v.module)
+		}
+	} else if v != nil && op == token.Assign {
+		v.module = ""
+	}
+	c.exprs(selectors)
+	c.exprs(rhs)
+}
+
+func (c *checker) destructure(
+	targets []parser.Expr,
+	rhs parser.Expr,
+	op token.Token,
+) {
+	if op == token.Define {
+		var defined []*variable
+		var symbols []*tengo.Symbol
+		for _, target := range targets {
+			ident, ok := target.(*parser.Ident)
+			if !ok || ident.Name == "_" {
+				continue
+			}
+			if _, depth, ok := c.scope.table.Resolve(ident.Name, false); ok &&
+				depth == 0 {
+				continue // redeclared in the block: a compile error
+			}
+			v, symbol := c.define(ident)
+			v.defining = true // recursive references are not uses
+			defined = append(defined, v)
+			symbols = append(symbols, symbol)
+		}
+		c.expr(rhs)
+		for i, v := range defined {
+			v.defining = false
+			symbols[i].LocalAssigned = true
+		}
+		return
+	}
+
+	for _, target := range targets {
+		ident, selectors := assignTarget(target)
+		if ident == nil {
+			c.expr(target)
+			continue
+		}
+		if ident.Name == "_" && len(selectors) == 0 {
+			continue
+		}
+		v, _ := c.resolve(ident)
+		if v != nil && len(selectors) > 0 {
+			v.used = true
+			if v.module != "" {
+				c.report(target, RuleModule,
+					"cannot assign to member of module '%s'", v.module)
+			}
+		} else if v != nil {
+			v.module = ""
+		}
+		c.exprs(selectors)
+	}
+	c.expr(rhs)
+}
+
+func (c *checker) exprs(list []parser.Expr) {
+	for _, e := range list {
+		c.expr(e)
+	}
+}
+
+func (c *checker) expr(expr parser.Expr) {
+	switch expr := expr.(type) {
+	case *parser.ArrayLit:
+		c.exprs(expr.Elements)
+	case *parser.BinaryExpr:
+		c.expr(expr.LHS)
+		c.expr(expr.RHS)
+	case *parser.CallExpr:
+		c.call(expr)
+	case *parser.CondExpr:
+		c.expr(expr.Cond)
+		c.expr(expr.True)
+		c.expr(expr.False)
+	case *parser.ErrorExpr:
+		c.expr(expr.Expr)
+	case *parser.FuncLit:
+		c.funcLit(expr, false)
+	case *parser.Ident:
+		if v, _ := c.resolve(expr); v != nil && !v.defining {
+			v.used = true
+		}
+	case *parser.ImmutableExpr:
+		c.expr(expr.Expr)
+	case *parser.IndexExpr:
+		c.expr(expr.Expr)
+		if expr.Index != nil {
+			c.expr(expr.Index)
+		}
+	case *parser.InterpExpr:
+		c.expr(expr.Expr)
+	case *parser.InterpStringLit:
+		c.exprs(expr.Parts)
+	case *parser.MapLit:
+		for _, elem := range expr.Elements {
+			if elem.KeyExpr != nil {
+				c.expr(elem.KeyExpr)
+			}
+			c.expr(elem.Value)
+		}
+	case *parser.ParenExpr:
+		c.expr(expr.Expr)
+	case *parser.SelectorExpr:
+		c.selector(expr)
+	case *parser.SpreadExpr:
+		c.expr(expr.Expr)
+	case *parser.SliceExpr:
+		c.expr(expr.Expr)
+		if expr.Low != nil {
+			c.expr(expr.Low)
+		}
+		if expr.High != nil {
+			c.expr(expr.High)
+		}
+	case *parser.UnaryExpr:
+		c.expr(expr.Expr)
+	}
+}
+
+func (c *checker) call(expr *parser.CallExpr) {
+	c.expr(expr.Func)
+	c.exprs(expr.Args)
+	for _, kw := range expr.Keywords {
+		c.expr(kw.Value)
+	}
+
+	ident, ok := expr.Func.(*parser.Ident)
+	if !ok || expr.Ellipsis.IsValid() {
+		return
+	}
+	symbol, _, ok := c.scope.table.Resolve(ident.Name, false)
+	if !ok || symbol.Scope != tengo.ScopeBuiltin {
+		return
+	}
+	if len(expr.Keywords) > 0 {
+		c.report(expr.Keywords[0], RuleArgs,
+			"'%s' does not accept keyword arguments", ident.Name)
+		return
+	}
+	n, ok := builtinArgs[ident.Name]
+	if !ok {
+		return
+	}
+	numArgs := len(expr.Args)
+	if numArgs < n[0] || n[1] >= 0 && numArgs > n[1] {
+		var want string
+		switch {
+		case n[1] < 0:
+			want = fmt.Sprintf("at least %d", n[0])
+		case n[0] == n[1]:
+			want = fmt.Sprintf("%d", n[0])
+		default:
+			want = fmt.Sprintf("%d or %d", n[0], n[1])
+		}
+		c.report(expr, RuleArgs, "'%s' expects %s argument(s), got %d",
+			ident.Name, want, numArgs)
+	}
+}
+
+func (c *checker) selector(expr *parser.SelectorExpr) {
+	c.expr(expr.Expr)
+	c.expr(expr.Sel)
+
+	ident, ok := expr.Expr.(*parser.Ident)
+	if !ok {
+		return
+	}
+	sel, ok := expr.Sel.(*parser.StringLit)
+	if !ok {
+		return
+	}
+	v := c.lookupResolved(ident)
+	if v == nil || v.module == "" {
+		return
+	}
+	members := c.linter.moduleMembers(v.module)
+	if members != nil && !members[sel.Value] {
+		c.report(expr.Sel, RuleModule, "module '%s' has no member '%s'",
+			v.module, sel.Value)
+	}
+}
+
+// lookupResolved returns the variable that the identifier refers to without
+// reporting undefined references.
+func (c *checker) lookupResolved(ident *parser.Ident) *variable {
+	symbol, depth, ok := c.scope.table.Resolve(ident.Name, false)
+	if !ok || symbol.Scope == tengo.ScopeBuiltin {
+		return nil
+	}
+	return c.lookup(ident.Name, depth)
+}
+
+// funcLit checks the function literal. If receiver is true, the function is
+// a method of a record type that takes the receiver as "self".
+func (c *checker) funcLit(expr *parser.FuncLit, receiver bool) {
+	loops, switches := c.loops, c.switches
+	c.loops, c.switches = 0, 0
+	c.enter(false)
+	if receiver {
+		self := &parser.Ident{Name: "self", NamePos: expr.Pos()}
+		c.scope.vars[self.Name] = &variable{ident: self, param: true}
+		c.scope.table.Define(self.Name).LocalAssigned = true
+	}
+	for _, ident := range expr.Type.Params.List {
+		c.scope.vars[ident.Name] = &variable{ident: ident, param: true}
+		c.scope.table.Define(ident.Name).LocalAssigned = true
+	}
+	for _, def := range expr.Type.Params.Defaults {
+		if def != nil {
+			c.expr(def)
+		}
+	}
+	c.stmt(expr.Body)
+	c.leave()
+	c.loops, c.switches = loops, switches
+}
+
+// destructuringTargets returns the targets if the left-hand side of the
+// assignment destructures its value.
+func destructuringTargets(lhs []parser.Expr) ([]parser.Expr, bool) {
+	if len(lhs) > 1 {
+		return lhs, true
+	}
+	switch pattern := lhs[0].(type) {
+	case *parser.ArrayLit:
+		return pattern.Elements, len(pattern.Elements) > 0
+	case *parser.MapLit:
+		var targets []parser.Expr
+		for _, elem := range pattern.Elements {
+			if elem.KeyExpr != nil || elem.IsSpread() {
+				return nil, false
+			}
+			targets = append(targets, elem.Value)
+		}
+		return targets, len(targets) > 0
+	}
+	return nil, false
+}
+
+// assignTarget returns the variable identifier and the selector expressions
+// of the left-hand side of an assignment.
+func assignTarget(expr parser.Expr) (*parser.Ident, []parser.Expr) {
+	switch term := expr.(type) {
+	case *parser.SelectorExpr:
+		ident, selectors := assignTarget(term.Expr)
+		return ident, append(selectors, term.Sel)
+	case *parser.IndexExpr:
+		ident, selectors := assignTarget(term.Expr)
+		return ident, append(selectors, term.Index)
+	case *parser.Ident:
+		return term, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,220 @@
+// Package lint implements the static checks of Tengo source code that find
+// suspicious constructs before running the scripts.
+//
+// Each diagnostic has a rule code. The rules can be disabled for a Linter
+// using Disable, or in the source code using the comments:
+//
+//	// lint:ignore unused,shadow
+//	a := 1 // lint:ignore
+//	// lint:disable unreachable
+//
+// "lint:ignore" suppresses the diagnostics on the same line as the comment
+// and on the next line, and "lint:disable" suppresses them in the whole file.
+// If no rule codes are given, all rules are suppressed.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+)
+
+// List of rule codes.
+const (
+	RuleUnused      = "unused"      // variable is never used
+	RuleShadow      = "shadow"      // variable shadows an outer variable
+	RuleUndefined   = "undefined"   // reference to an undefined variable
+	RuleModule      = "module"      // undefined or assigned module member
+	RuleArgs        = "args"        // wrong number of builtin arguments
+	RuleUnreachable = "unreachable" // statement after return or branch
+	RuleBranch      = "branch"      // break or continue outside loop
+)
+
+// Diagnostic represents a problem found in the source code.
+type Diagnostic struct {
+	Pos     parser.SourceFilePos
+	Rule    string
+	Message string
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Pos, d.Message, d.Rule)
+}
+
+// Linter checks the source files.
+type Linter struct {
+	modules  *tengo.ModuleMap
+	globals  []string
+	disabled map[string]bool
+	members  map[string]map[string]bool // module name to member names
+}
+
+// NewLinter creates a Linter. The members of the modules in the module map
+// are used to check the selectors on the imported modules.
+func NewLinter(modules *tengo.ModuleMap) *Linter {
+	if modules == nil {
+		modules = tengo.NewModuleMap()
+	}
+	return &Linter{
+		modules:  modules,
+		disabled: make(map[string]bool),
+		members:  make(map[string]map[string]bool),
+	}
+}
+
+// AddGlobals adds the names of the global variables that are defined outside
+// the source code, e.g. using Script.Add.
+func (l *Linter) AddGlobals(names ...string) {
+	l.globals = append(l.globals, names...)
+}
+
+// Disable disables the rules.
+func (l *Linter) Disable(rules ...string) {
+	for _, rule := range rules {
+		l.disabled[rule] = true
+	}
+}
+
+// Lint checks the parsed file and returns the diagnostics sorted by their
+// positions. The file should be parsed with parser.ParseComments mode for the
+// comments disabling the rules.
+func (l *Linter) Lint(file *parser.File) []*Diagnostic {
+	c := newChecker(l, file.InputFile)
+	c.stmts(file.Stmts)
+	c.leave()
+
+	d := newDirectives(file)
+	var diags []*Diagnostic
+	for _, diag := range c.diags {
+		if !l.disabled[diag.Rule] && !d.suppressed(diag) {
+			diags = append(diags, diag)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Pos, diags[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diags
+}
+
+// LintSource parses and checks the source code.
+func (l *Linter) LintSource(
+	filename string,
+	src []byte,
+) ([]*Diagnostic, error) {
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile(filename, -1, len(src))
+	p := parser.NewParserWithMode(srcFile, src, nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	return l.Lint(file), nil
+}
+
+// moduleMembers returns the member names of the module, or nil if they are
+// not known.
+func (l *Linter) moduleMembers(name string) map[string]bool {
+	if members, ok := l.members[name]; ok {
+		return members
+	}
+
+	var members map[string]bool
+	switch mod := l.modules.Get(name).(type) {
+	case *tengo.BuiltinModule:
+		members = make(map[string]bool)
+		for k := range mod.Attrs {
+			members[k] = true
+		}
+	case *tengo.SourceModule:
+		members = exportedNames(mod.Src)
+	}
+	l.members[name] = members
+	return members
+}
+
+// exportedNames returns the keys of the map literal exported by the source
+// module, or nil if the module does not export a map literal.
+func exportedNames(src []byte) map[string]bool {
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("", -1, len(src))
+	p := parser.NewParser(srcFile, src, nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil
+	}
+	for _, stmt := range file.Stmts {
+		export, ok := stmt.(*parser.ExportStmt)
+		if !ok {
+			continue
+		}
+		m, ok := export.Result.(*parser.MapLit)
+		if !ok {
+			return nil
+		}
+		names := make(map[string]bool)
+		for _, elem := range m.Elements {
+			if elem.KeyExpr == nil {
+				names[elem.Key] = true
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// directives are the comments that suppress the diagnostics.
+type directives struct {
+	file  map[string]bool         // rules disabled in the file
+	lines map[int]map[string]bool // rules ignored at the lines
+}
+
+func newDirectives(file *parser.File) *directives {
+	d := &directives{
+		file:  make(map[string]bool),
+		lines: make(map[int]map[string]bool),
+	}
+	for _, g := range file.Comments {
+		for _, c := range g.List {
+			text := strings.TrimPrefix(c.Text, "//")
+			text = strings.TrimSpace(strings.TrimPrefix(text, "/*"))
+			text = strings.TrimSpace(strings.TrimSuffix(text, "*/"))
+
+			var rules map[string]bool
+			switch {
+			case strings.HasPrefix(text, "lint:disable"):
+				rules = d.file
+			case strings.HasPrefix(text, "lint:ignore"):
+				line := file.InputFile.Position(c.Pos()).Line
+				rules = make(map[string]bool)
+				d.lines[line] = rules
+				d.lines[line+1] = rules
+			default:
+				continue
+			}
+			fields := strings.Fields(text)
+			if len(fields) < 2 {
+				rules[""] = true // all rules
+				continue
+			}
+			for _, rule := range strings.Split(fields[1], ",") {
+				rules[strings.TrimSpace(rule)] = true
+			}
+		}
+	}
+	return d
+}
+
+func (d *directives) suppressed(diag *Diagnostic) bool {
+	if d.file[""] || d.file[diag.Rule] {
+		return true
+	}
+	rules := d.lines[diag.Pos.Line]
+	return rules[""] || rules[diag.Rule]
+}
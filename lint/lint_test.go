@@ -0,0 +1,200 @@
+package lint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/lint"
+	"github.com/d5/tengo/v2/require"
+)
+
+func TestLint_Unused(t *testing.T) {
+	expectLint(t, `a := 1; b := func() { c := 1 }`,
+		"1:23: 'c' declared but not used (unused)")
+	expectLint(t, `func(a, b) { return a }`)
+	expectLint(t, `func() { a := 1; a = 2 }`,
+		"1:10: 'a' declared but not used (unused)")
+	expectLint(t, `func() { a := 1; a++ }`,
+		"1:10: 'a' declared but not used (unused)")
+	expectLint(t, `func() { a := [1]; a[0] = 2 }`)
+	expectLint(t, `func() { a := 1; return func() { return a } }`)
+	expectLint(t, `func() { f := func() { return f() } }`,
+		"1:10: 'f' declared but not used (unused)")
+	expectLint(t, `for k, v in [1] { export k }`,
+		"1:8: 'v' declared but not used (unused)")
+	expectLint(t, `for _, v in [1] { v++ }`,
+		"1:8: 'v' declared but not used (unused)")
+	expectLint(t, `try { a := 1 } catch e {}`,
+		"1:7: 'a' declared but not used (unused)",
+		"1:22: 'e' declared but not used (unused)")
+	expectLint(t, `mod := import("mod")`,
+		"1:1: 'mod' declared but not used (unused)")
+	expectLint(t, `mod := import("mod"); export mod`)
+	expectLint(t, `func() { a, b := [1, 2]; return a }`,
+		"1:13: 'b' declared but not used (unused)")
+	expectLint(t, `func() { [a, _] := [1]; {b, c: d} := {}; return [a, d] }`,
+		"1:26: 'b' declared but not used (unused)")
+	expectLint(t, `func() { a := 1; b := 2; a, b = [3, 4] }`,
+		"1:10: 'a' declared but not used (unused)",
+		"1:18: 'b' declared but not used (unused)")
+}
+
+func TestLint_Shadow(t *testing.T) {
+	expectLint(t, `a := 1; if a { a := 2; export a }`,
+		"1:16: 'a' shadows declaration at 1:1 (shadow)")
+	expectLint(t, `a := 1; f := func() { a := 2; return a }`,
+		"1:23: 'a' shadows declaration at 1:1 (shadow)")
+	expectLint(t, `f := func(a) { return func() { a; a := 2; return a } }`,
+		"1:35: 'a' shadows declaration at 1:11 (shadow)")
+	expectLint(t, `func() { len := 1; return len }`,
+		"1:10: 'len' shadows builtin function (shadow)")
+	expectLint(t, `a := 1; f := func(a) { return a }`)
+	expectLint(t, `a := 1; for a in [1] { export a }`,
+		"1:13: 'a' shadows declaration at 1:1 (shadow)")
+}
+
+func TestLint_Undefined(t *testing.T) {
+	expectLint(t, `a := b`, "1:6: unresolved reference 'b' (undefined)")
+	expectLint(t, `b = 1`, "1:1: unresolved reference 'b' (undefined)")
+	expectLint(t, `func() { a := a; return a }`,
+		"1:15: unresolved reference 'a' (undefined)")
+	expectLint(t, `if a := 1; a { }; a++`,
+		"1:19: unresolved reference 'a' (undefined)")
+	expectLint(t, `a := func() { return a() }`)
+	expectLint(t, `a := f"${b}"`, "1:10: unresolved reference 'b' (undefined)")
+	expectLint(t, `a := {(b): 1}`, "1:8: unresolved reference 'b' (undefined)")
+	expectLint(t, `a, b = [1, 2]`,
+		"1:1: unresolved reference 'a' (undefined)",
+		"1:4: unresolved reference 'b' (undefined)")
+	expectLint(t, `f := func(a=b) {}; f(a: c)`,
+		"1:13: unresolved reference 'b' (undefined)",
+		"1:25: unresolved reference 'c' (undefined)")
+	expectLint(t, `a := [b..., {c...}]`,
+		"1:7: unresolved reference 'b' (undefined)",
+		"1:14: unresolved reference 'c' (undefined)")
+	expectLint(t, `a := b?.c?[d] ?? e`,
+		"1:6: unresolved reference 'b' (undefined)",
+		"1:12: unresolved reference 'd' (undefined)",
+		"1:18: unresolved reference 'e' (undefined)")
+	expectLint(t, `func() { yield b }`,
+		"1:16: unresolved reference 'b' (undefined)")
+	expectLint(t, `record P(x) { f: func() { return self.x + y } }; export P`,
+		"1:43: unresolved reference 'y' (undefined)")
+
+	l := lint.NewLinter(nil)
+	l.AddGlobals("b")
+	expectLinter(t, l, `a := b`)
+}
+
+func TestLint_Module(t *testing.T) {
+	expectLint(t, `mod := import("mod"); mod.a(); mod.c()`,
+		"1:36: module 'mod' has no member 'c' (module)")
+	expectLint(t, `mod := import("src"); export [mod.a, mod.c]`,
+		"1:42: module 'src' has no member 'c' (module)")
+	expectLint(t, `mod := import("mod"); mod.a = 1; mod.b[0] = 2`,
+		"1:23: cannot assign to member of module 'mod' (module)",
+		"1:34: cannot assign to member of module 'mod' (module)")
+	expectLint(t, `mod := import("mod"); mod = {}; mod.c = 1`)
+	expectLint(t, `mod := import("unknown"); export mod.c`)
+	expectLint(t, `mod := import("mod"); func(mod) { mod.c }`,
+		"1:1: 'mod' declared but not used (unused)")
+}
+
+func TestLint_Args(t *testing.T) {
+	expectLint(t, `len(1); len(); len(1, 2)`,
+		"1:9: 'len' expects 1 argument(s), got 0 (args)",
+		"1:16: 'len' expects 1 argument(s), got 2 (args)")
+	expectLint(t, `append([]); append([], 1, 2)`,
+		"1:1: 'append' expects at least 2 argument(s), got 1 (args)")
+	expectLint(t, `int(); int(1, 2, 3)`,
+		"1:1: 'int' expects 1 or 2 argument(s), got 0 (args)",
+		"1:8: 'int' expects 1 or 2 argument(s), got 3 (args)")
+	expectLint(t, `a := [1]; len(a...)`)
+	expectLint(t, `func(len) { return len() }`)
+	expectLint(t, `len(v: 1)`,
+		"1:5: 'len' does not accept keyword arguments (args)")
+	expectLint(t, `f := func(a, b=1) { return a + b }; f(1, b: 2)`)
+	expectLint(t, `func(a, b=a) { return b }`)
+}
+
+func TestLint_Unreachable(t *testing.T) {
+	expectLint(t, `func() { return; a := 1; return a }`,
+		"1:18: unreachable code (unreachable)")
+	expectLint(t, `for { break; continue }`,
+		"1:14: unreachable code (unreachable)")
+	expectLint(t, `func() { if true { return }; return }`)
+	expectLint(t, `switch { case true: break; len([]) }`,
+		"1:28: unreachable code (unreachable)")
+}
+
+func TestLint_Branch(t *testing.T) {
+	expectLint(t, `break`,
+		"1:1: break not allowed outside loop (branch)")
+	expectLint(t, `continue`,
+		"1:1: continue not allowed outside loop (branch)")
+	expectLint(t, `for { func() { break } }`,
+		"1:16: break not allowed outside loop (branch)")
+	expectLint(t, `switch { default: break }`)
+	expectLint(t, `switch { default: continue }`,
+		"1:19: continue not allowed outside loop (branch)")
+	expectLint(t, `for { switch { default: continue } }`)
+}
+
+func TestLint_Disable(t *testing.T) {
+	src := `
+func() { len := 1; return len } // lint:ignore shadow
+// lint:ignore
+a := b
+// lint:ignore unused
+func() { c := 1 }
+func() { c := 1 } /* lint:ignore shadow */
+d := e`
+	expectLint(t, src,
+		"7:10: 'c' declared but not used (unused)",
+		"8:6: unresolved reference 'e' (undefined)")
+	expectLint(t, src+"\n// lint:disable undefined,unused")
+	expectLint(t, src+"\n// lint:disable")
+
+	l := lint.NewLinter(nil)
+	l.Disable(lint.RuleUnused)
+	expectLinter(t, l, src, "8:6: unresolved reference 'e' (undefined)")
+}
+
+func TestLintSource(t *testing.T) {
+	l := lint.NewLinter(nil)
+	_, err := l.LintSource("test", []byte(`a := `))
+	require.Error(t, err)
+
+	diags, err := l.LintSource("test", []byte(`a := b`))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(diags))
+	require.Equal(t, "test:1:6: unresolved reference 'b' (undefined)",
+		diags[0].String())
+}
+
+func expectLint(t *testing.T, input string, expected ...string) {
+	modules := tengo.NewModuleMap()
+	modules.AddBuiltinModule("mod", map[string]tengo.Object{
+		"a": &tengo.Int{},
+		"b": &tengo.Array{},
+	})
+	modules.AddSourceModule("src", []byte(`b := 1; export {a: 1, b: b}`))
+	expectLinter(t, lint.NewLinter(modules), input, expected...)
+}
+
+func expectLinter(
+	t *testing.T,
+	l *lint.Linter,
+	input string,
+	expected ...string,
+) {
+	diags, err := l.LintSource("", []byte(input))
+	require.NoError(t, err, input)
+	var actual []string
+	for _, d := range diags {
+		actual = append(actual, d.String())
+	}
+	require.Equal(t, strings.Join(expected, "\n"),
+		strings.Join(actual, "\n"), input)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
@@ -79,6 +80,16 @@ type Object interface {
 	CanCall() bool
 }
 
+// VMCallable is an optional interface for callable objects that need access
+// to the VM calling them, e.g. to call back into script functions using
+// VM.RunCompiled. The VM uses CallVM instead of Call for such objects.
+type VMCallable interface {
+	// CallVM should take the calling VM and an arbitrary number of arguments
+	// and returns a return value and/or an error, which the VM will consider
+	// as a run-time error.
+	CallVM(vm *VM, args ...Object) (ret Object, err error)
+}
+
 // ObjectImpl represents a default Object Implementation. To defined a new
 // value type, one can embed ObjectImpl in their type declarations to avoid
 // implementing all non-significant methods. TypeName() and String() methods
@@ -301,6 +312,14 @@ func (o *Bool) Equals(x Object) bool {
 	return o == x
 }
 
+// Hash returns the hash of the value.
+func (o *Bool) Hash() uint64 {
+	if o.value {
+		return hashUint64(hashSeedBool, 1)
+	}
+	return hashUint64(hashSeedBool, 0)
+}
+
 // GobDecode decodes bool value from input bytes.
 func (o *Bool) GobDecode(b []byte) (err error) {
 	o.value = b[0] == 1
@@ -320,8 +339,9 @@ func (o *Bool) GobEncode() (b []byte, err error) {
 // BuiltinFunction represents a builtin function.
 type BuiltinFunction struct {
 	ObjectImpl
-	Name  string
-	Value CallableFunc
+	Name    string
+	Value   CallableFunc
+	vmValue VMCallableFunc // used instead of Value if called by a VM
 }
 
 // TypeName returns the name of the type.
@@ -335,7 +355,7 @@ func (o *BuiltinFunction) String() string {
 
 // Copy returns a copy of the type.
 func (o *BuiltinFunction) Copy() Object {
-	return &BuiltinFunction{Value: o.Value}
+	return &BuiltinFunction{Value: o.Value, vmValue: o.vmValue}
 }
 
 // Equals returns true if the value of the type is equal to the value of
@@ -349,6 +369,14 @@ func (o *BuiltinFunction) Call(args ...Object) (Object, error) {
 	return o.Value(args...)
 }
 
+// CallVM executes a builtin function with the calling VM.
+func (o *BuiltinFunction) CallVM(vm *VM, args ...Object) (Object, error) {
+	if o.vmValue != nil {
+		return o.vmValue(vm, args...)
+	}
+	return o.Value(args...)
+}
+
 // CanCall returns whether the Object can be Called.
 func (o *BuiltinFunction) CanCall() bool {
 	return true
@@ -567,6 +595,11 @@ func (o *Char) Equals(x Object) bool {
 	return o.Value == t.Value
 }
 
+// Hash returns the hash of the value.
+func (o *Char) Hash() uint64 {
+	return hashUint64(hashSeedChar, uint64(o.Value))
+}
+
 // CompiledFunction represents a compiled function.
 type CompiledFunction struct {
 	ObjectImpl
@@ -576,6 +609,11 @@ type CompiledFunction struct {
 	VarArgs       bool
 	SourceMap     map[int]parser.Pos
 	Free          []*ObjectPtr
+	Locals        []*LocalSymbol // variables defined in the function
+	FreeNames     []string       // names of the free variables
+	Generator     bool           // calling the function returns a Generator
+	NumDefaults   int            // number of the parameters with default values
+	ParamNames    []string       // names of the parameters for keyword arguments
 }
 
 // TypeName returns the name of the type.
@@ -594,7 +632,13 @@ func (o *CompiledFunction) Copy() Object {
 		NumLocals:     o.NumLocals,
 		NumParameters: o.NumParameters,
 		VarArgs:       o.VarArgs,
+		SourceMap:     o.SourceMap,
 		Free:          append([]*ObjectPtr{}, o.Free...), // DO NOT Copy() of elements; these are variable pointers
+		Locals:        o.Locals,
+		FreeNames:     o.FreeNames,
+		Generator:     o.Generator,
+		NumDefaults:   o.NumDefaults,
+		ParamNames:    o.ParamNames,
 	}
 }
 
@@ -620,10 +664,24 @@ func (o *CompiledFunction) CanCall() bool {
 	return true
 }
 
+// LocalSymbol represents a variable defined in a compiled function, and the
+// range of the source positions where the variable is accessible: from Pos to
+// End, or to the end of the function if End is NoPos. Variables defined in the
+// main function are global variables.
+type LocalSymbol struct {
+	Name  string
+	Index int
+	Pos   parser.Pos // position of the definition
+	End   parser.Pos // end of the enclosing block
+}
+
 // Error represents an error value.
 type Error struct {
 	ObjectImpl
 	Value Object
+	Pos   parser.SourceFilePos // source position of the caught runtime error
+	cause error                // caught runtime error
+	ip    int                  // instruction pointer of the caught error
 }
 
 // TypeName returns the name of the type.
@@ -645,7 +703,7 @@ func (o *Error) IsFalsy() bool {
 
 // Copy returns a copy of the type.
 func (o *Error) Copy() Object {
-	return &Error{Value: o.Value.Copy()}
+	return &Error{Value: o.Value.Copy(), Pos: o.Pos}
 }
 
 // Equals returns true if the value of the type is equal to the value of
@@ -656,11 +714,17 @@ func (o *Error) Equals(x Object) bool {
 
 // IndexGet returns an element at a given index.
 func (o *Error) IndexGet(index Object) (res Object, err error) {
-	if strIdx, _ := ToString(index); strIdx != "value" {
+	switch strIdx, _ := ToString(index); strIdx {
+	case "value":
+		res = o.Value
+	case "pos":
+		res = UndefinedValue
+		if o.Pos.IsValid() {
+			res = &String{Value: o.Pos.String()}
+		}
+	default:
 		err = ErrInvalidIndexOnError
-		return
 	}
-	res = o.Value
 	return
 }
 
@@ -777,6 +841,14 @@ func (o *Float) BinaryOp(op token.Token, rhs Object) (Object, error) {
 			}
 			return FalseValue, nil
 		}
+	case *BigInt:
+		return o.BinaryOp(op, &Float{Value: bigIntToFloat(rhs.Value)})
+	case *Decimal:
+		x, ok := floatToDecimal(o.Value)
+		if !ok {
+			return nil, ErrInvalidOperator
+		}
+		return decimalOp(op, x, rhs.Value)
 	}
 	return nil, ErrInvalidOperator
 }
@@ -801,6 +873,14 @@ func (o *Float) Equals(x Object) bool {
 	return o.Value == t.Value
 }
 
+// Hash returns the hash of the value.
+func (o *Float) Hash() uint64 {
+	if o.Value == 0 {
+		return hashUint64(hashSeedFloat, 0) // -0 == +0
+	}
+	return hashUint64(hashSeedFloat, math.Float64bits(o.Value))
+}
+
 // ImmutableArray represents an immutable array of objects.
 type ImmutableArray struct {
 	ObjectImpl
@@ -869,6 +949,18 @@ func (o *ImmutableArray) Equals(x Object) bool {
 	return true
 }
 
+// Hash returns the hash of the elements. The elements that are not hashable
+// don't change the hash.
+func (o *ImmutableArray) Hash() uint64 {
+	h := hashUint64(hashSeedArray, uint64(len(o.Value)))
+	for _, e := range o.Value {
+		if e, ok := e.(Hashable); ok {
+			h = hashUint64(h, e.Hash())
+		}
+	}
+	return h
+}
+
 // IndexGet returns an element at a given index.
 func (o *ImmutableArray) IndexGet(index Object) (res Object, err error) {
 	intIdx, ok := index.(*Int)
@@ -901,7 +993,8 @@ func (o *ImmutableArray) CanIterate() bool {
 // ImmutableMap represents an immutable map object.
 type ImmutableMap struct {
 	ObjectImpl
-	Value map[string]Object
+	Value  map[string]Object
+	Hashed *HashedMap // entries of the keys other than strings
 }
 
 // TypeName returns the name of the type.
@@ -910,11 +1003,7 @@ func (o *ImmutableMap) TypeName() string {
 }
 
 func (o *ImmutableMap) String() string {
-	var pairs []string
-	for k, v := range o.Value {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", k, v.String()))
-	}
-	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+	return mapString(o.Value, o.Hashed)
 }
 
 // Copy returns a copy of the type.
@@ -923,63 +1012,28 @@ func (o *ImmutableMap) Copy() Object {
 	for k, v := range o.Value {
 		c[k] = v.Copy()
 	}
-	return &Map{Value: c}
+	return &Map{Value: c, Hashed: o.Hashed.Copy()}
 }
 
 // IsFalsy returns true if the value of the type is falsy.
 func (o *ImmutableMap) IsFalsy() bool {
-	return len(o.Value) == 0
+	return len(o.Value)+o.Hashed.Len() == 0
 }
 
 // IndexGet returns the value for the given key.
 func (o *ImmutableMap) IndexGet(index Object) (res Object, err error) {
-	strIdx, ok := ToString(index)
-	if !ok {
-		err = ErrInvalidIndexType
-		return
-	}
-	res, ok = o.Value[strIdx]
-	if !ok {
-		res = UndefinedValue
-	}
-	return
+	return mapIndexGet(o.Value, o.Hashed, index)
 }
 
 // Equals returns true if the value of the type is equal to the value of
 // another object.
 func (o *ImmutableMap) Equals(x Object) bool {
-	var xVal map[string]Object
-	switch x := x.(type) {
-	case *Map:
-		xVal = x.Value
-	case *ImmutableMap:
-		xVal = x.Value
-	default:
-		return false
-	}
-	if len(o.Value) != len(xVal) {
-		return false
-	}
-	for k, v := range o.Value {
-		tv := xVal[k]
-		if !v.Equals(tv) {
-			return false
-		}
-	}
-	return true
+	return mapEquals(o.Value, o.Hashed, x)
 }
 
 // Iterate creates an immutable map iterator.
 func (o *ImmutableMap) Iterate() Iterator {
-	var keys []string
-	for k := range o.Value {
-		keys = append(keys, k)
-	}
-	return &MapIterator{
-		v: o.Value,
-		k: keys,
-		l: len(keys),
-	}
+	return newMapIterator(o.Value, o.Hashed)
 }
 
 // CanIterate returns whether the Object can be Iterated.
@@ -1126,6 +1180,10 @@ func (o *Int) BinaryOp(op token.Token, rhs Object) (Object, error) {
 			}
 			return FalseValue, nil
 		}
+	case *BigInt:
+		return bigIntOp(op, big.NewInt(o.Value), rhs.Value)
+	case *Decimal:
+		return decimalOp(op, new(big.Rat).SetInt64(o.Value), rhs.Value)
 	case *Char:
 		switch op {
 		case token.Add:
@@ -1168,19 +1226,28 @@ func (o *Int) IsFalsy() bool {
 }
 
 // Equals returns true if the value of the type is equal to the value of
-// another object.
+// another object. An int is equal to a bigint or a decimal of the same value.
 func (o *Int) Equals(x Object) bool {
-	t, ok := x.(*Int)
-	if !ok {
-		return false
+	switch x := x.(type) {
+	case *Int:
+		return o.Value == x.Value
+	case *BigInt, *Decimal:
+		return x.Equals(o)
 	}
-	return o.Value == t.Value
+	return false
+}
+
+// Hash returns the hash of the value.
+func (o *Int) Hash() uint64 {
+	return hashUint64(hashSeedInt, uint64(o.Value))
 }
 
-// Map represents a map of objects.
+// Map represents a map of objects. The entries of the string keys are in
+// Value, and the entries of the other hashable keys are in Hashed.
 type Map struct {
 	ObjectImpl
-	Value map[string]Object
+	Value  map[string]Object
+	Hashed *HashedMap // entries of the keys other than strings
 }
 
 // TypeName returns the name of the type.
@@ -1189,11 +1256,7 @@ func (o *Map) TypeName() string {
 }
 
 func (o *Map) String() string {
-	var pairs []string
-	for k, v := range o.Value {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", k, v.String()))
-	}
-	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+	return mapString(o.Value, o.Hashed)
 }
 
 // Copy returns a copy of the type.
@@ -1202,74 +1265,66 @@ func (o *Map) Copy() Object {
 	for k, v := range o.Value {
 		c[k] = v.Copy()
 	}
-	return &Map{Value: c}
+	return &Map{Value: c, Hashed: o.Hashed.Copy()}
 }
 
 // IsFalsy returns true if the value of the type is falsy.
 func (o *Map) IsFalsy() bool {
-	return len(o.Value) == 0
+	return len(o.Value)+o.Hashed.Len() == 0
 }
 
 // Equals returns true if the value of the type is equal to the value of
 // another object.
 func (o *Map) Equals(x Object) bool {
-	var xVal map[string]Object
-	switch x := x.(type) {
-	case *Map:
-		xVal = x.Value
-	case *ImmutableMap:
-		xVal = x.Value
-	default:
-		return false
-	}
-	if len(o.Value) != len(xVal) {
-		return false
-	}
-	for k, v := range o.Value {
-		tv := xVal[k]
-		if !v.Equals(tv) {
-			return false
-		}
-	}
-	return true
+	return mapEquals(o.Value, o.Hashed, x)
 }
 
 // IndexGet returns the value for the given key.
 func (o *Map) IndexGet(index Object) (res Object, err error) {
-	strIdx, ok := ToString(index)
+	return mapIndexGet(o.Value, o.Hashed, index)
+}
+
+// IndexSet sets the value for the given key. Ints, big ints, decimals and
+// chars are converted to string keys in Value, and the other hashable keys
+// are kept in Hashed.
+func (o *Map) IndexSet(index, value Object) (err error) {
+	str, key, ok := mapKey(index)
 	if !ok {
-		err = ErrInvalidIndexType
-		return
+		return ErrInvalidIndexType
 	}
-	res, ok = o.Value[strIdx]
-	if !ok {
-		res = UndefinedValue
+	if key == nil {
+		o.Value[str] = value
+		return nil
 	}
-	return
+	if o.Hashed == nil {
+		o.Hashed = NewHashedMap()
+	}
+	o.Hashed.Set(key, value)
+	return nil
 }
 
-// IndexSet sets the value for the given key.
-func (o *Map) IndexSet(index, value Object) (err error) {
-	strIdx, ok := ToString(index)
+// Delete deletes the entry of the given key.
+func (o *Map) Delete(index Object) error {
+	str, key, ok := mapKey(index)
 	if !ok {
-		err = ErrInvalidIndexType
-		return
+		return ErrInvalidIndexType
 	}
-	o.Value[strIdx] = value
+	if key == nil {
+		delete(o.Value, str)
+		return nil
+	}
+	o.Hashed.Delete(key)
 	return nil
 }
 
+// Len returns the number of the entries.
+func (o *Map) Len() int {
+	return len(o.Value) + o.Hashed.Len()
+}
+
 // Iterate creates a map iterator.
 func (o *Map) Iterate() Iterator {
-	var keys []string
-	for k := range o.Value {
-		keys = append(keys, k)
-	}
-	return &MapIterator{
-		v: o.Value,
-		k: keys,
-		l: len(keys),
-	}
+	return newMapIterator(o.Value, o.Hashed)
 }
 
 // CanIterate returns whether the Object can be Iterated.
@@ -1277,6 +1332,77 @@ func (o *Map) CanIterate() bool {
 	return true
 }
 
+func mapString(kv map[string]Object, hashed *HashedMap) string {
+	var pairs []string
+	for k, v := range kv {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", k, v.String()))
+	}
+	for _, e := range hashed.Entries() {
+		pairs = append(pairs,
+			fmt.Sprintf("%s: %s", e.Key.String(), e.Value.String()))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+}
+
+func mapEquals(kv map[string]Object, hashed *HashedMap, x Object) bool {
+	var xVal map[string]Object
+	var xHashed *HashedMap
+	switch x := x.(type) {
+	case *Map:
+		xVal, xHashed = x.Value, x.Hashed
+	case *ImmutableMap:
+		xVal, xHashed = x.Value, x.Hashed
+	default:
+		return false
+	}
+	if len(kv) != len(xVal) {
+		return false
+	}
+	for k, v := range kv {
+		tv, ok := xVal[k]
+		if !ok || !v.Equals(tv) {
+			return false
+		}
+	}
+	return hashed.Equals(xHashed)
+}
+
+func mapIndexGet(
+	kv map[string]Object,
+	hashed *HashedMap,
+	index Object,
+) (Object, error) {
+	str, key, ok := mapKey(index)
+	if !ok {
+		return nil, ErrInvalidIndexType
+	}
+	var res Object
+	if key == nil {
+		res = kv[str]
+	} else {
+		res, _ = hashed.Get(key)
+	}
+	if res == nil {
+		return UndefinedValue, nil
+	}
+	return res, nil
+}
+
+// mapKey returns the string key of the index, or the hashed key if the index
+// is not converted to a string. Strings, and ints, big ints, decimals and
+// chars converted to strings, are the string keys, so m[1] and m["1"] are the
+// same entry. It returns false if the index is not hashable.
+func mapKey(index Object) (string, Hashable, bool) {
+	switch index := index.(type) {
+	case *String:
+		return index.Value, nil, true
+	case *Int, *BigInt, *Decimal, *Char:
+		return index.String(), nil, true
+	}
+	key, ok := ToHashable(index)
+	return "", key, ok
+}
+
 // ObjectPtr represents a free variable.
 type ObjectPtr struct {
 	ObjectImpl
@@ -1398,6 +1524,11 @@ func (o *String) Equals(x Object) bool {
 	return o.Value == t.Value
 }
 
+// Hash returns the hash of the value.
+func (o *String) Hash() uint64 {
+	return hashString(o.Value)
+}
+
 // IndexGet returns a character at a given index.
 func (o *String) IndexGet(index Object) (res Object, err error) {
 	intIdx, ok := index.(*Int)
@@ -1514,6 +1645,12 @@ func (o *Time) Equals(x Object) bool {
 	return o.Value.Equal(t.Value)
 }
 
+// Hash returns the hash of the value.
+func (o *Time) Hash() uint64 {
+	return hashUint64(hashSeedTime,
+		uint64(o.Value.Unix())*1e9+uint64(o.Value.Nanosecond()))
+}
+
 // Undefined represents an undefined value.
 type Undefined struct {
 	ObjectImpl
@@ -1574,12 +1711,19 @@ func (o *Undefined) Value() Object {
 	return o
 }
 
-// UserFunction represents a user function.
+// UserFunction represents a user function. If KwArgs is true, the keyword
+// arguments of a call from the script are passed to the function as a
+// trailing *Map argument, which is empty if there's none. Otherwise, calling
+// the function with keyword arguments is a runtime error. If VMValue is set,
+// it's called instead of Value when the function is called by a VM (see
+// VMCallable).
 type UserFunction struct {
 	ObjectImpl
 	Name       string
 	Value      CallableFunc
+	VMValue    VMCallableFunc
 	EncodingID string
+	KwArgs     bool
 }
 
 // TypeName returns the name of the type.
@@ -1593,7 +1737,7 @@ func (o *UserFunction) String() string {
 
 // Copy returns a copy of the type.
 func (o *UserFunction) Copy() Object {
-	return &UserFunction{Value: o.Value}
+	return &UserFunction{Value: o.Value, VMValue: o.VMValue, KwArgs: o.KwArgs}
 }
 
 // Equals returns true if the value of the type is equal to the value of
@@ -1607,7 +1751,58 @@ func (o *UserFunction) Call(args ...Object) (Object, error) {
 	return o.Value(args...)
 }
 
+// CallVM invokes a user function with the calling VM.
+func (o *UserFunction) CallVM(vm *VM, args ...Object) (Object, error) {
+	if o.VMValue != nil {
+		return o.VMValue(vm, args...)
+	}
+	return o.Value(args...)
+}
+
 // CanCall returns whether the Object can be Called.
 func (o *UserFunction) CanCall() bool {
 	return true
 }
+
+// VMFunction represents a user function that receives the VM calling it.
+type VMFunction struct {
+	ObjectImpl
+	Name  string
+	Value VMCallableFunc
+}
+
+// TypeName returns the name of the type.
+func (o *VMFunction) TypeName() string {
+	return "user-function:" + o.Name
+}
+
+func (o *VMFunction) String() string {
+	return "<user-function>"
+}
+
+// Copy returns a copy of the type.
+func (o *VMFunction) Copy() Object {
+	return &VMFunction{Name: o.Name, Value: o.Value}
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (o *VMFunction) Equals(_ Object) bool {
+	return false
+}
+
+// Call invokes a user function outside of a VM. The function receives a nil
+// VM.
+func (o *VMFunction) Call(args ...Object) (Object, error) {
+	return o.Value(nil, args...)
+}
+
+// CallVM invokes a user function with the calling VM.
+func (o *VMFunction) CallVM(vm *VM, args ...Object) (Object, error) {
+	return o.Value(vm, args...)
+}
+
+// CanCall returns whether the Object can be Called.
+func (o *VMFunction) CanCall() bool {
+	return true
+}
@@ -2,6 +2,7 @@ package tengo_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/d5/tengo/v2"
 	"github.com/d5/tengo/v2/require"
@@ -694,6 +695,65 @@ func TestMap_Index(t *testing.T) {
 	res, err := m.IndexGet(k)
 	require.NoError(t, err)
 	require.Equal(t, v, res)
+
+	// ints and chars are converted to strings, and the other keys are hashed
+	res, err = m.IndexGet(&tengo.String{Value: "1"})
+	require.NoError(t, err)
+	require.Equal(t, v, res)
+	require.NoError(t, m.IndexSet(&tengo.Char{Value: 'a'}, v))
+	res, err = m.IndexGet(&tengo.String{Value: "a"})
+	require.NoError(t, err)
+	require.Equal(t, v, res)
+	require.NoError(t, m.IndexSet(&tengo.Float{Value: 1.5}, v))
+	res, err = m.IndexGet(&tengo.String{Value: "1.5"})
+	require.NoError(t, err)
+	require.Equal(t, tengo.UndefinedValue, res)
+	require.Equal(t, 2, len(m.Value))
+	require.Equal(t, 1, m.Hashed.Len())
+
+	_, err = m.IndexGet(&tengo.Array{})
+	require.Equal(t, tengo.ErrInvalidIndexType, err)
+}
+
+func TestHashedMap(t *testing.T) {
+	m := tengo.NewHashedMap()
+	keys := []tengo.Hashable{
+		&tengo.Int{Value: 1},
+		&tengo.Float{Value: 1},
+		&tengo.Char{Value: 1},
+		tengo.TrueValue.(tengo.Hashable),
+		&tengo.Time{Value: time.Unix(1, 0)},
+		&tengo.ImmutableArray{Value: []tengo.Object{&tengo.Int{Value: 1}}},
+	}
+	for i, k := range keys {
+		m.Set(k, &tengo.Int{Value: int64(i)})
+	}
+	require.Equal(t, len(keys), m.Len())
+	for i, k := range keys {
+		v, ok := m.Get(k)
+		require.True(t, ok)
+		require.Equal(t, &tengo.Int{Value: int64(i)}, v)
+	}
+
+	m.Set(&tengo.Int{Value: 1}, &tengo.Int{Value: 10})
+	require.Equal(t, len(keys), m.Len())
+	m.Delete(&tengo.Int{Value: 1})
+	m.Delete(&tengo.Int{Value: 2})
+	require.Equal(t, len(keys)-1, m.Len())
+	_, ok := m.Get(&tengo.Int{Value: 1})
+	require.False(t, ok)
+
+	c := m.Copy()
+	require.True(t, c.Equals(m))
+	c.Set(&tengo.Int{Value: 1}, &tengo.Int{Value: 1})
+	require.False(t, c.Equals(m))
+
+	// nil HashedMap is empty
+	var n *tengo.HashedMap
+	require.Equal(t, 0, n.Len())
+	_, ok = n.Get(&tengo.Int{Value: 1})
+	require.False(t, ok)
+	require.True(t, n.Equals(tengo.NewHashedMap()))
 }
 
 func TestString_BinaryOp(t *testing.T) {
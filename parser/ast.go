@@ -24,6 +24,11 @@ type IdentList struct {
 	VarArgs bool
 	List    []*Ident
 	RParen  Pos
+
+	// Defaults has the default values of the parameters, and nil for the
+	// parameters without default values. It's nil if no parameter has a
+	// default value.
+	Defaults []Expr
 }
 
 // Pos returns the position of first character belonging to the node.
@@ -48,6 +53,20 @@ func (n *IdentList) End() Pos {
 	return NoPos
 }
 
+// NumDefaults returns the number of the parameters with default values.
+func (n *IdentList) NumDefaults() int {
+	if n == nil {
+		return 0
+	}
+	var count int
+	for _, def := range n.Defaults {
+		if def != nil {
+			count++
+		}
+	}
+	return count
+}
+
 // NumFields returns the number of fields.
 func (n *IdentList) NumFields() int {
 	if n == nil {
@@ -61,9 +80,55 @@ func (n *IdentList) String() string {
 	for i, e := range n.List {
 		if n.VarArgs && i == len(n.List)-1 {
 			list = append(list, "..."+e.String())
+		} else if i < len(n.Defaults) && n.Defaults[i] != nil {
+			list = append(list, e.String()+"="+n.Defaults[i].String())
 		} else {
 			list = append(list, e.String())
 		}
 	}
 	return "(" + strings.Join(list, ", ") + ")"
 }
+
+// Comment represents a single //-style or /*-style comment.
+type Comment struct {
+	Slash Pos    // position of "/" starting the comment
+	Text  string // comment text including "//" or "/*" and "*/"
+}
+
+// Pos returns the position of first character belonging to the node.
+func (c *Comment) Pos() Pos {
+	return c.Slash
+}
+
+// End returns the position of first character immediately after the node.
+func (c *Comment) End() Pos {
+	return c.Slash + Pos(len(c.Text))
+}
+
+func (c *Comment) String() string {
+	return c.Text
+}
+
+// CommentGroup represents a sequence of comments with no other tokens and no
+// empty lines between.
+type CommentGroup struct {
+	List []*Comment
+}
+
+// Pos returns the position of first character belonging to the node.
+func (g *CommentGroup) Pos() Pos {
+	return g.List[0].Pos()
+}
+
+// End returns the position of first character immediately after the node.
+func (g *CommentGroup) End() Pos {
+	return g.List[len(g.List)-1].End()
+}
+
+func (g *CommentGroup) String() string {
+	var list []string
+	for _, c := range g.List {
+		list = append(list, c.Text)
+	}
+	return strings.Join(list, "\n")
+}
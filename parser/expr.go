@@ -116,6 +116,7 @@ type CallExpr struct {
 	Args     []Expr
 	Ellipsis Pos
 	RParen   Pos
+	Keywords []*KeywordArg // keyword arguments after the positional ones
 }
 
 func (e *CallExpr) exprNode() {}
@@ -138,9 +139,36 @@ func (e *CallExpr) String() string {
 	if len(args) > 0 && e.Ellipsis.IsValid() {
 		args[len(args)-1] = args[len(args)-1] + "..."
 	}
+	for _, kw := range e.Keywords {
+		args = append(args, kw.String())
+	}
 	return e.Func.String() + "(" + strings.Join(args, ", ") + ")"
 }
 
+// KeywordArg represents a keyword argument of a call.
+type KeywordArg struct {
+	Name     string
+	NamePos  Pos
+	ColonPos Pos
+	Value    Expr
+}
+
+func (e *KeywordArg) exprNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (e *KeywordArg) Pos() Pos {
+	return e.NamePos
+}
+
+// End returns the position of first character immediately after the node.
+func (e *KeywordArg) End() Pos {
+	return e.Value.End()
+}
+
+func (e *KeywordArg) String() string {
+	return e.Name + ": " + e.Value.String()
+}
+
 // CharLit represents a character literal.
 type CharLit struct {
 	Value    rune
@@ -356,10 +384,11 @@ func (e *ImportExpr) String() string {
 
 // IndexExpr represents an index expression.
 type IndexExpr struct {
-	Expr   Expr
-	LBrack Pos
-	Index  Expr
-	RBrack Pos
+	Expr     Expr
+	LBrack   Pos
+	Index    Expr
+	RBrack   Pos
+	Optional bool // "?[": undefined if Expr is undefined or not indexable
 }
 
 func (e *IndexExpr) exprNode() {}
@@ -379,6 +408,9 @@ func (e *IndexExpr) String() string {
 	if e.Index != nil {
 		index = e.Index.String()
 	}
+	if e.Optional {
+		return e.Expr.String() + "?[" + index + "]"
+	}
 	return e.Expr.String() + "[" + index + "]"
 }
 
@@ -405,9 +437,61 @@ func (e *IntLit) String() string {
 	return e.Literal
 }
 
-// MapElementLit represents a map element.
+// InterpExpr represents a placeholder of an interpolated string literal.
+type InterpExpr struct {
+	Expr   Expr
+	Verb   string // formatting verb; or empty
+	LBrace Pos    // position of "${"
+	RBrace Pos
+}
+
+func (e *InterpExpr) exprNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (e *InterpExpr) Pos() Pos {
+	return e.LBrace
+}
+
+// End returns the position of first character immediately after the node.
+func (e *InterpExpr) End() Pos {
+	return e.RBrace + 1
+}
+
+func (e *InterpExpr) String() string {
+	if e.Verb != "" {
+		return "${" + e.Expr.String() + ":" + e.Verb + "}"
+	}
+	return "${" + e.Expr.String() + "}"
+}
+
+// InterpStringLit represents an interpolated string literal.
+type InterpStringLit struct {
+	Parts    []Expr // *StringLit or *InterpExpr
+	ValuePos Pos
+	Literal  string
+}
+
+func (e *InterpStringLit) exprNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (e *InterpStringLit) Pos() Pos {
+	return e.ValuePos
+}
+
+// End returns the position of first character immediately after the node.
+func (e *InterpStringLit) End() Pos {
+	return Pos(int(e.ValuePos) + len(e.Literal))
+}
+
+func (e *InterpStringLit) String() string {
+	return e.Literal
+}
+
+// MapElementLit represents a map element. KeyExpr is the expression of a
+// key other than an identifier or a string, e.g. "{1: a}" or "{(b): c}".
 type MapElementLit struct {
 	Key      string
+	KeyExpr  Expr
 	KeyPos   Pos
 	ColonPos Pos
 	Value    Expr
@@ -426,9 +510,31 @@ func (e *MapElementLit) End() Pos {
 }
 
 func (e *MapElementLit) String() string {
+	if e.IsSpread() {
+		return e.Value.String()
+	}
+	if e.IsShorthand() {
+		return e.Key
+	}
+	if e.KeyExpr != nil {
+		return e.KeyExpr.String() + ": " + e.Value.String()
+	}
 	return e.Key + ": " + e.Value.String()
 }
 
+// IsSpread returns true if the element spreads a map, e.g. "{m...}".
+func (e *MapElementLit) IsSpread() bool {
+	_, ok := e.Value.(*SpreadExpr)
+	return ok && !e.ColonPos.IsValid()
+}
+
+// IsShorthand returns true if the element is an identifier that is both the
+// key and the value, e.g. "{a}".
+func (e *MapElementLit) IsShorthand() bool {
+	_, ok := e.Value.(*Ident)
+	return ok && !e.ColonPos.IsValid()
+}
+
 // MapLit represents a map literal.
 type MapLit struct {
 	LBrace   Pos
@@ -481,8 +587,9 @@ func (e *ParenExpr) String() string {
 
 // SelectorExpr represents a selector expression.
 type SelectorExpr struct {
-	Expr Expr
-	Sel  Expr
+	Expr     Expr
+	Sel      Expr
+	Optional bool // "?.": undefined if Expr is undefined or not indexable
 }
 
 func (e *SelectorExpr) exprNode() {}
@@ -498,6 +605,9 @@ func (e *SelectorExpr) End() Pos {
 }
 
 func (e *SelectorExpr) String() string {
+	if e.Optional {
+		return e.Expr.String() + "?." + e.Sel.String()
+	}
 	return e.Expr.String() + "." + e.Sel.String()
 }
 
@@ -533,6 +643,30 @@ func (e *SliceExpr) String() string {
 	return e.Expr.String() + "[" + low + ":" + high + "]"
 }
 
+// SpreadExpr represents an expression that spreads the elements of an array
+// in an array literal, or the entries of a map in a map literal, e.g.
+// "[a..., 1]" or "{m..., b: 1}".
+type SpreadExpr struct {
+	Expr     Expr
+	Ellipsis Pos
+}
+
+func (e *SpreadExpr) exprNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (e *SpreadExpr) Pos() Pos {
+	return e.Expr.Pos()
+}
+
+// End returns the position of first character immediately after the node.
+func (e *SpreadExpr) End() Pos {
+	return e.Ellipsis + 3
+}
+
+func (e *SpreadExpr) String() string {
+	return e.Expr.String() + "..."
+}
+
 // StringLit represents a string literal.
 type StringLit struct {
 	Value    string
@@ -8,6 +8,7 @@ import (
 type File struct {
 	InputFile *SourceFile
 	Stmts     []Stmt
+	Comments  []*CommentGroup // comments in the source; or nil
 }
 
 // Pos returns the position of first character belonging to the node.
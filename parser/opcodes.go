@@ -47,6 +47,19 @@ const (
 	OpIteratorValue               // Iterator value
 	OpBinaryOp                    // Binary operation
 	OpSuspend                     // Suspend VM
+	OpTry                         // Set up error handler
+	OpTryEnd                      // Remove error handler
+	OpThrow                       // Re-raise error object
+	OpFormat                      // Format value
+	OpYield                       // Yield value of generator
+	OpRecord                      // Create record type
+	OpSpread                      // Spread array or map into literal
+	OpUnpack                      // Destructure array
+	OpUnpackMap                   // Destructure map
+	OpCallKw                      // Call function with keyword arguments
+	OpOptionalJump                // Jump if undefined
+	OpOptionalIndex               // Index operation of optional chain
+	OpCoalesceJump                // Jump if not undefined
 )
 
 // OpcodeNames are string representation of opcodes.
@@ -93,6 +106,19 @@ var OpcodeNames = [...]string{
 	OpIteratorValue: "ITVAL",
 	OpBinaryOp:      "BINARYOP",
 	OpSuspend:       "SUSPEND",
+	OpTry:           "TRY",
+	OpTryEnd:        "TRYEND",
+	OpThrow:         "THROW",
+	OpFormat:        "FORMAT",
+	OpYield:         "YIELD",
+	OpRecord:        "RECORD",
+	OpSpread:        "SPREAD",
+	OpUnpack:        "UNPACK",
+	OpUnpackMap:     "UNPACKM",
+	OpCallKw:        "CALLKW",
+	OpOptionalJump:  "OPTJMP",
+	OpOptionalIndex: "OPTINDEX",
+	OpCoalesceJump:  "COALJMP",
 }
 
 // OpcodeOperands is the number of operands.
@@ -139,6 +165,19 @@ var OpcodeOperands = [...][]int{
 	OpIteratorValue: {},
 	OpBinaryOp:      {1},
 	OpSuspend:       {},
+	OpTry:           {2},
+	OpTryEnd:        {},
+	OpThrow:         {},
+	OpFormat:        {2},
+	OpYield:         {},
+	OpRecord:        {2, 1},
+	OpSpread:        {},
+	OpUnpack:        {2},
+	OpUnpackMap:     {2},
+	OpCallKw:        {2, 1},
+	OpOptionalJump:  {2},
+	OpOptionalIndex: {2},
+	OpCoalesceJump:  {2},
 }
 
 // ReadOperands reads operands from the bytecode.
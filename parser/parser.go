@@ -5,6 +5,7 @@ import (
 	"io"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/d5/tengo/v2/token"
 )
@@ -17,7 +18,10 @@ var stmtStart = map[token.Token]bool{
 	token.For:      true,
 	token.If:       true,
 	token.Return:   true,
+	token.Yield:    true,
 	token.Export:   true,
+	token.Try:      true,
+	token.Switch:   true,
 }
 
 // Error represents a parser error.
@@ -89,12 +93,22 @@ func (p ErrorList) Err() error {
 	return p
 }
 
+// Mode represents a parser mode.
+type Mode int
+
+// List of parser modes.
+const (
+	// ParseComments makes the parser keep the comments in File.Comments.
+	ParseComments Mode = 1 << iota
+)
+
 // Parser parses the Tengo source files. It's based on Go's parser
 // implementation.
 type Parser struct {
 	file      *SourceFile
 	errors    ErrorList
 	scanner   *Scanner
+	comments  []*CommentGroup
 	pos       Pos
 	token     token.Token
 	tokenLit  string
@@ -108,15 +122,29 @@ type Parser struct {
 
 // NewParser creates a Parser.
 func NewParser(file *SourceFile, src []byte, trace io.Writer) *Parser {
+	return NewParserWithMode(file, src, trace, 0)
+}
+
+// NewParserWithMode creates a Parser with the parser mode.
+func NewParserWithMode(
+	file *SourceFile,
+	src []byte,
+	trace io.Writer,
+	mode Mode,
+) *Parser {
 	p := &Parser{
 		file:     file,
 		trace:    trace != nil,
 		traceOut: trace,
 	}
+	var scanMode ScanMode
+	if mode&ParseComments != 0 {
+		scanMode = ScanComments
+	}
 	p.scanner = NewScanner(p.file, src,
 		func(pos SourceFilePos, msg string) {
 			p.errors.Add(pos, msg)
-		}, 0)
+		}, scanMode)
 	p.next()
 	return p
 }
@@ -150,6 +178,7 @@ func (p *Parser) ParseFile() (file *File, err error) {
 	file = &File{
 		InputFile: p.file,
 		Stmts:     stmts,
+		Comments:  p.comments,
 	}
 	return
 }
@@ -225,32 +254,38 @@ func (p *Parser) parseUnaryExpr() Expr {
 			Expr:     x,
 		}
 	}
-	return p.parsePrimaryExpr()
+	return p.parsePrimaryExpr(nil)
 }
 
-func (p *Parser) parsePrimaryExpr() Expr {
+// parsePrimaryExpr parses a primary expression. If x is not nil, it's the
+// operand that is already parsed.
+func (p *Parser) parsePrimaryExpr(x Expr) Expr {
 	if p.trace {
 		defer untracep(tracep(p, "PrimaryExpression"))
 	}
 
-	x := p.parseOperand()
+	if x == nil {
+		x = p.parseOperand()
+	}
 
 L:
 	for {
 		switch p.token {
-		case token.Period:
+		case token.Period, token.QuestionPeriod:
+			optional := p.token == token.QuestionPeriod
 			p.next()
 
 			switch p.token {
 			case token.Ident:
 				x = p.parseSelector(x)
+				x.(*SelectorExpr).Optional = optional
 			default:
 				pos := p.pos
 				p.errorExpected(pos, "selector")
 				p.advance(stmtStart)
 				return &BadExpr{From: pos, To: p.pos}
 			}
-		case token.LBrack:
+		case token.LBrack, token.QuestionLBrack:
 			x = p.parseIndexOrSlice(x)
 		case token.LParen:
 			x = p.parseCall(x)
@@ -270,12 +305,27 @@ func (p *Parser) parseCall(x Expr) *CallExpr {
 	p.exprLevel++
 
 	var list []Expr
+	var keywords []*KeywordArg
 	var ellipsis Pos
 	for p.token != token.RParen && p.token != token.EOF && !ellipsis.IsValid() {
-		list = append(list, p.parseExpr())
-		if p.token == token.Ellipsis {
-			ellipsis = p.pos
+		arg := p.parseExpr()
+		if ident, ok := arg.(*Ident); ok && p.token == token.Colon {
+			colonPos := p.pos
 			p.next()
+			keywords = append(keywords, &KeywordArg{
+				Name:     ident.Name,
+				NamePos:  ident.NamePos,
+				ColonPos: colonPos,
+				Value:    p.parseExpr(),
+			})
+		} else if len(keywords) > 0 {
+			p.error(arg.Pos(), "positional argument after keyword argument")
+		} else {
+			list = append(list, arg)
+			if p.token == token.Ellipsis {
+				ellipsis = p.pos
+				p.next()
+			}
 		}
 		if !p.expectComma(token.RParen, "call argument") {
 			break
@@ -290,6 +340,7 @@ func (p *Parser) parseCall(x Expr) *CallExpr {
 		RParen:   rparen,
 		Ellipsis: ellipsis,
 		Args:     list,
+		Keywords: keywords,
 	}
 }
 
@@ -315,7 +366,14 @@ func (p *Parser) parseIndexOrSlice(x Expr) Expr {
 		defer untracep(tracep(p, "IndexOrSlice"))
 	}
 
-	lbrack := p.expect(token.LBrack)
+	var lbrack Pos
+	optional := p.token == token.QuestionLBrack
+	if optional {
+		lbrack = p.pos + 1
+		p.next()
+	} else {
+		lbrack = p.expect(token.LBrack)
+	}
 	p.exprLevel++
 
 	var index [2]Expr
@@ -336,6 +394,9 @@ func (p *Parser) parseIndexOrSlice(x Expr) Expr {
 	rbrack := p.expect(token.RBrack)
 
 	if numColons > 0 {
+		if optional {
+			p.error(lbrack, "slice not allowed in optional chain")
+		}
 		// slice expression
 		return &SliceExpr{
 			Expr:   x,
@@ -346,10 +407,11 @@ func (p *Parser) parseIndexOrSlice(x Expr) Expr {
 		}
 	}
 	return &IndexExpr{
-		Expr:   x,
-		LBrack: lbrack,
-		RBrack: rbrack,
-		Index:  index[0],
+		Expr:     x,
+		LBrack:   lbrack,
+		RBrack:   rbrack,
+		Index:    index[0],
+		Optional: optional,
 	}
 }
 
@@ -403,6 +465,8 @@ func (p *Parser) parseOperand() Expr {
 		}
 		p.next()
 		return x
+	case token.InterpString:
+		return p.parseInterpStringLit()
 	case token.True:
 		x := &BoolLit{
 			Value:    true,
@@ -526,7 +590,12 @@ func (p *Parser) parseArrayLit() Expr {
 
 	var elements []Expr
 	for p.token != token.RBrack && p.token != token.EOF {
-		elements = append(elements, p.parseExpr())
+		x := p.parseExpr()
+		if p.token == token.Ellipsis {
+			x = &SpreadExpr{Expr: x, Ellipsis: p.pos}
+			p.next()
+		}
+		elements = append(elements, x)
 
 		if !p.expectComma(token.RBrack, "array element") {
 			break
@@ -633,31 +702,41 @@ func (p *Parser) parseIdentList() *IdentList {
 	}
 
 	var params []*Ident
+	var defaults []Expr
 	lparen := p.expect(token.LParen)
 	isVarArgs := false
-	if p.token != token.RParen {
+	for p.token != token.RParen && p.token != token.EOF && !isVarArgs {
+		if len(params) > 0 && !p.expectComma(token.RParen, "parameter") {
+			break
+		}
 		if p.token == token.Ellipsis {
 			isVarArgs = true
 			p.next()
 		}
-
 		params = append(params, p.parseIdent())
-		for !isVarArgs && p.token == token.Comma {
+
+		// the parameters after a parameter with a default value must have
+		// default values
+		switch {
+		case p.token == token.Assign && !isVarArgs:
 			p.next()
-			if p.token == token.Ellipsis {
-				isVarArgs = true
-				p.next()
+			if defaults == nil {
+				defaults = make([]Expr, len(params)-1, cap(params))
 			}
-			params = append(params, p.parseIdent())
+			defaults = append(defaults, p.parseExpr())
+		case defaults != nil && !isVarArgs:
+			p.errorExpected(p.pos, "default value")
+			defaults = append(defaults, &BadExpr{From: p.pos, To: p.pos})
 		}
 	}
 
 	rparen := p.expect(token.RParen)
 	return &IdentList{
-		LParen:  lparen,
-		RParen:  rparen,
-		VarArgs: isVarArgs,
-		List:    params,
+		LParen:   lparen,
+		RParen:   rparen,
+		VarArgs:  isVarArgs,
+		List:     params,
+		Defaults: defaults,
 	}
 }
 
@@ -669,21 +748,27 @@ func (p *Parser) parseStmt() (stmt Stmt) {
 	switch p.token {
 	case // simple statements
 		token.Func, token.Error, token.Immutable, token.Ident, token.Int,
-		token.Float, token.Char, token.String, token.True, token.False,
-		token.Undefined, token.Import, token.LParen, token.LBrace,
-		token.LBrack, token.Add, token.Sub, token.Mul, token.And, token.Xor,
-		token.Not:
+		token.Float, token.Char, token.String, token.InterpString,
+		token.True, token.False, token.Undefined, token.Import,
+		token.LParen, token.LBrace, token.LBrack, token.Add, token.Sub,
+		token.Mul, token.And, token.Xor, token.Not:
 		s := p.parseSimpleStmt(false)
 		p.expectSemi()
 		return s
 	case token.Return:
 		return p.parseReturnStmt()
+	case token.Yield:
+		return p.parseYieldStmt()
 	case token.Export:
 		return p.parseExportStmt()
 	case token.If:
 		return p.parseIfStmt()
 	case token.For:
 		return p.parseForStmt()
+	case token.Try:
+		return p.parseTryStmt()
+	case token.Switch:
+		return p.parseSwitchStmt()
 	case token.Break, token.Continue:
 		return p.parseBranchStmt(p.token)
 	case token.Semicolon:
@@ -823,6 +908,103 @@ func (p *Parser) parseIfStmt() Stmt {
 	}
 }
 
+func (p *Parser) parseTryStmt() Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "TryStmt"))
+	}
+
+	pos := p.expect(token.Try)
+	stmt := &TryStmt{
+		TryPos: pos,
+		Body:   p.parseBlockStmt(),
+	}
+	if p.token == token.Catch {
+		stmt.CatchPos = p.pos
+		p.next()
+		if p.token == token.Ident {
+			stmt.CatchIdent = p.parseIdent()
+		}
+		stmt.Catch = p.parseBlockStmt()
+	}
+	if p.token == token.Finally {
+		stmt.FinallyPos = p.pos
+		p.next()
+		stmt.Finally = p.parseBlockStmt()
+	}
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.errorExpected(p.pos, "catch or finally")
+	}
+	p.expectSemi()
+	return stmt
+}
+
+func (p *Parser) parseSwitchStmt() Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "SwitchStmt"))
+	}
+
+	pos := p.expect(token.Switch)
+
+	var tag Expr
+	if p.token != token.LBrace {
+		outer := p.exprLevel
+		p.exprLevel = -1
+		tag = p.parseExpr()
+		p.exprLevel = outer
+	}
+
+	lbrace := p.expect(token.LBrace)
+	var cases []*CaseClause
+	hasDefault := false
+	for p.token == token.Case || p.token == token.Default {
+		clause := p.parseCaseClause()
+		if clause.List == nil {
+			if hasDefault {
+				p.error(clause.Pos(), "multiple defaults in switch")
+			}
+			hasDefault = true
+		}
+		cases = append(cases, clause)
+	}
+	rbrace := p.expect(token.RBrace)
+	p.expectSemi()
+	return &SwitchStmt{
+		SwitchPos: pos,
+		Tag:       tag,
+		LBrace:    lbrace,
+		Cases:     cases,
+		RBrace:    rbrace,
+	}
+}
+
+func (p *Parser) parseCaseClause() *CaseClause {
+	if p.trace {
+		defer untracep(tracep(p, "CaseClause"))
+	}
+
+	pos := p.pos
+	var list []Expr
+	if p.token == token.Case {
+		p.next()
+		list = p.parseExprList()
+	} else {
+		p.expect(token.Default)
+	}
+
+	colon := p.expect(token.Colon)
+	var body []Stmt
+	for p.token != token.Case && p.token != token.Default &&
+		p.token != token.RBrace && p.token != token.EOF {
+		body = append(body, p.parseStmt())
+	}
+	return &CaseClause{
+		Case:  pos,
+		List:  list,
+		Colon: colon,
+		Body:  body,
+	}
+}
+
 func (p *Parser) parseBlockStmt() *BlockStmt {
 	if p.trace {
 		defer untracep(tracep(p, "BlockStmt"))
@@ -911,6 +1093,78 @@ func (p *Parser) parseReturnStmt() Stmt {
 	}
 }
 
+func (p *Parser) parseYieldStmt() Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "YieldStmt"))
+	}
+
+	pos := p.pos
+	p.expect(token.Yield)
+
+	var x Expr
+	if p.token != token.Semicolon && p.token != token.RBrace {
+		x = p.parseExpr()
+	}
+	p.expectSemi()
+	return &YieldStmt{
+		YieldPos: pos,
+		Result:   x,
+	}
+}
+
+func (p *Parser) parseRecordStmt(pos Pos) Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "RecordStmt"))
+	}
+
+	name := p.parseIdent()
+	fields := p.parseIdentList()
+	if fields.VarArgs {
+		p.error(fields.List[len(fields.List)-1].Pos(),
+			"variadic field not allowed")
+	}
+	for _, def := range fields.Defaults {
+		if def != nil {
+			p.error(def.Pos(), "default value not allowed for field")
+			break
+		}
+	}
+	stmt := &RecordStmt{
+		RecordPos: pos,
+		Name:      name,
+		Fields:    fields,
+	}
+	if p.token != token.LBrace {
+		return stmt
+	}
+
+	stmt.LBrace = p.pos
+	p.next()
+	p.exprLevel++
+	for p.token != token.RBrace && p.token != token.EOF {
+		keyPos := p.pos
+		key := p.parseIdent()
+		colonPos := p.expect(token.Colon)
+		value := p.parseExpr()
+		if _, ok := value.(*FuncLit); !ok {
+			p.errorExpected(value.Pos(), "function literal")
+		}
+		stmt.Methods = append(stmt.Methods, &MapElementLit{
+			Key:      key.Name,
+			KeyPos:   keyPos,
+			ColonPos: colonPos,
+			Value:    value,
+		})
+
+		if !p.expectComma(token.RBrace, "method") {
+			break
+		}
+	}
+	p.exprLevel--
+	stmt.RBrace = p.expect(token.RBrace)
+	return stmt
+}
+
 func (p *Parser) parseExportStmt() Stmt {
 	if p.trace {
 		defer untracep(tracep(p, "ExportStmt"))
@@ -933,6 +1187,14 @@ func (p *Parser) parseSimpleStmt(forIn bool) Stmt {
 
 	x := p.parseExprList()
 
+	// "record" is not a keyword, and it starts a record declaration only if
+	// it's followed by the type name.
+	if len(x) == 1 && p.token == token.Ident {
+		if ident, ok := x[0].(*Ident); ok && ident.Name == "record" {
+			return p.parseRecordStmt(ident.NamePos)
+		}
+	}
+
 	switch p.token {
 	case token.Assign, token.Define: // assignment statement
 		pos, tok := p.pos, p.token
@@ -1021,32 +1283,166 @@ func (p *Parser) parseExprList() (list []Expr) {
 	return
 }
 
+func (p *Parser) parseInterpStringLit() Expr {
+	if p.trace {
+		defer untracep(tracep(p, "InterpStringLit"))
+	}
+
+	pos, lit := p.pos, p.tokenLit
+	if len(lit) < 3 || lit[len(lit)-1] != '"' {
+		// literal not terminated: error was reported by the scanner
+		p.next()
+		return &BadExpr{From: pos, To: p.pos}
+	}
+
+	x := &InterpStringLit{
+		ValuePos: pos,
+		Literal:  lit,
+	}
+	offs := p.file.Offset(pos)
+	start := 2 // skip 'f"'
+	var text []byte
+	addText := func(end int) {
+		if end > start {
+			v, _ := strconv.Unquote(`"` + string(text) + `"`)
+			x.Parts = append(x.Parts, &StringLit{
+				Value:    v,
+				ValuePos: pos + Pos(start),
+				Literal:  lit[start:end],
+			})
+		}
+		text = text[:0]
+	}
+	for i := start; i < len(lit)-1; {
+		switch {
+		case lit[i] == '\\' && lit[i+1] == '$':
+			text = append(text, '$')
+			i += 2
+		case lit[i] == '\\':
+			text = append(text, lit[i], lit[i+1])
+			i += 2
+		case lit[i] == '$' && lit[i+1] == '{':
+			addText(i)
+			var e *InterpExpr
+			e, i = p.parseInterpExpr(lit, offs, i+2)
+			x.Parts = append(x.Parts, e)
+			start = i
+		default:
+			text = append(text, lit[i])
+			i++
+		}
+	}
+	addText(len(lit) - 1)
+
+	p.next()
+	return x
+}
+
+// parseInterpExpr parses the placeholder starting at index i of the
+// interpolated string literal lit that is located at offset offs. It returns
+// the index immediately after the placeholder.
+func (p *Parser) parseInterpExpr(
+	lit string,
+	offs, i int,
+) (*InterpExpr, int) {
+	if p.trace {
+		defer untracep(tracep(p, "InterpExpr"))
+	}
+
+	// placeholder ends with the matching '}'
+	s := p.scanner.fork(offs + i)
+	s.skipInterpExpr()
+	end := s.offset - offs
+	x := &InterpExpr{
+		LBrace: p.file.FileSetPos(offs + i - 2),
+		RBrace: p.file.FileSetPos(offs + end - 1),
+	}
+
+	// parse the expression with the scanner reading the placeholder
+	scanner, tok, tokenLit, pos := p.scanner, p.token, p.tokenLit, p.pos
+	outer := p.exprLevel
+	p.scanner = p.scanner.fork(offs + i)
+	p.exprLevel = 0
+	p.next()
+	x.Expr = p.parseExpr()
+	switch {
+	case p.token == token.Colon:
+		x.Verb = lit[p.file.Offset(p.pos)-offs+1 : end-1]
+		if !strings.HasPrefix(x.Verb, "%") {
+			p.error(p.pos, "invalid format verb")
+		}
+	case p.token != token.RBrace || p.pos != x.RBrace:
+		p.errorExpected(p.pos, "'}'")
+	}
+	p.scanner, p.token, p.tokenLit, p.pos = scanner, tok, tokenLit, pos
+	p.exprLevel = outer
+	return x, end
+}
+
 func (p *Parser) parseMapElementLit() *MapElementLit {
 	if p.trace {
 		defer untracep(tracep(p, "MapElementLit"))
 	}
 
 	pos := p.pos
-	name := "_"
-	if p.token == token.Ident {
+	var name string
+	var keyExpr Expr
+	switch p.token {
+	case token.Ident:
 		name = p.tokenLit
-	} else if p.token == token.String {
+		p.next()
+		switch p.token {
+		case token.Comma, token.RBrace:
+			// shorthand: "{a}" is "{a: a}"
+			return &MapElementLit{
+				Key:    name,
+				KeyPos: pos,
+				Value:  &Ident{Name: name, NamePos: pos},
+			}
+		case token.Colon:
+		default:
+			x := p.parsePrimaryExpr(&Ident{Name: name, NamePos: pos})
+			if p.token == token.Ellipsis {
+				return p.parseMapSpread(x)
+			}
+		}
+	case token.String:
 		v, _ := strconv.Unquote(p.tokenLit)
 		name = v
-	} else {
+		p.next()
+	case token.Int, token.Float, token.Char, token.True, token.False,
+		token.Sub, token.LParen:
+		// non-string keys: literals, or any expression in parentheses
+		keyExpr = p.parseUnaryExpr()
+		if p.token == token.Ellipsis {
+			return p.parseMapSpread(keyExpr)
+		}
+	default:
+		name = "_"
 		p.errorExpected(pos, "map key")
+		p.next()
 	}
-	p.next()
 	colonPos := p.expect(token.Colon)
 	valueExpr := p.parseExpr()
 	return &MapElementLit{
 		Key:      name,
+		KeyExpr:  keyExpr,
 		KeyPos:   pos,
 		ColonPos: colonPos,
 		Value:    valueExpr,
 	}
 }
 
+// parseMapSpread parses the ellipsis of a map element that spreads the map x,
+// e.g. "{m..., a: 1}".
+func (p *Parser) parseMapSpread(x Expr) *MapElementLit {
+	ellipsis := p.expect(token.Ellipsis)
+	return &MapElementLit{
+		KeyPos: x.Pos(),
+		Value:  &SpreadExpr{Expr: x, Ellipsis: ellipsis},
+	}
+}
+
 func (p *Parser) parseMapLit() *MapLit {
 	if p.trace {
 		defer untracep(tracep(p, "MapLit"))
@@ -1158,7 +1554,31 @@ func (p *Parser) next() {
 			p.printTrace(s)
 		}
 	}
+	prev := p.pos
 	p.token, p.tokenLit, p.pos = p.scanner.Scan()
+	if p.token == token.Comment && prev.IsValid() &&
+		p.file.Position(p.pos).Line == p.file.Position(prev).Line {
+		// comments on the same line as the previous token
+		p.consumeCommentGroup(0)
+	}
+	for p.token == token.Comment {
+		p.consumeCommentGroup(1)
+	}
+}
+
+// consumeCommentGroup reads the comments that are at most n lines apart from
+// each other.
+func (p *Parser) consumeCommentGroup(n int) {
+	var list []*Comment
+	endLine := p.file.Position(p.pos).Line
+	for p.token == token.Comment &&
+		p.file.Position(p.pos).Line <= endLine+n {
+		comment := &Comment{Slash: p.pos, Text: p.tokenLit}
+		list = append(list, comment)
+		endLine = p.file.Position(comment.End()).Line
+		p.token, p.tokenLit, p.pos = p.scanner.Scan()
+	}
+	p.comments = append(p.comments, &CommentGroup{List: list})
 }
 
 func (p *Parser) printTrace(a ...interface{}) {
@@ -284,6 +284,27 @@ func TestParseBoolean(t *testing.T) {
 	})
 }
 
+func TestParseKeywordArgs(t *testing.T) {
+	expectParse(t, "f(1, b: 2)", func(p pfn) []Stmt {
+		call := callExpr(ident("f", p(1, 1)), p(1, 2), p(1, 10), NoPos,
+			intLit(1, p(1, 3)))
+		call.Keywords = []*KeywordArg{{
+			Name:     "b",
+			NamePos:  p(1, 6),
+			ColonPos: p(1, 7),
+			Value:    intLit(2, p(1, 9)),
+		}}
+		return stmts(exprStmt(call))
+	})
+
+	expectParseString(t, "f(a ? b : c, d: e ? f : g)",
+		"f((a ? b : c), d: (e ? f : g))")
+	expectParseString(t, "f(\n\ta: 1,\n\tb: 2)", "f(a: 1, b: 2)")
+	expectParseError(t, `f(a: 1, 2)`)
+	expectParseError(t, `f(a..., b: 1)`)
+	expectParseError(t, `f(a.b: 1)`)
+}
+
 func TestParseCall(t *testing.T) {
 	expectParse(t, "add(1, 2, 3)", func(p pfn) []Stmt {
 		return stmts(
@@ -733,6 +754,26 @@ func TestParseFunction(t *testing.T) {
 	})
 }
 
+func TestParseDefaultParams(t *testing.T) {
+	expectParse(t, "func(a, b=1) {}", func(p pfn) []Stmt {
+		params := identList(p(1, 5), p(1, 12), false,
+			ident("a", p(1, 6)),
+			ident("b", p(1, 9)))
+		params.Defaults = []Expr{nil, intLit(1, p(1, 11))}
+		return stmts(
+			exprStmt(
+				funcLit(
+					funcType(params, p(1, 1)),
+					blockStmt(p(1, 14), p(1, 15)))))
+	})
+
+	expectParseString(t, "func(a, b=a+1, ...c) {}",
+		"func(a, b=(a + 1), ...c) {}")
+	expectParseError(t, `func(a=1, b) {}`)
+	expectParseError(t, `func(...a=1) {}`)
+	expectParseError(t, `record P(x, y=1)`)
+}
+
 func TestParseVariadicFunction(t *testing.T) {
 	expectParse(t, "a = func(...args) { return args }", func(p pfn) []Stmt {
 		return stmts(
@@ -1263,6 +1304,74 @@ key2: 2,
 }`)
 }
 
+func TestParseMapKeys(t *testing.T) {
+	expectParse(t, "{1: a, (b): c}", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				mapLit(p(1, 1), p(1, 14),
+					&MapElementLit{
+						KeyExpr:  intLit(1, p(1, 2)),
+						KeyPos:   p(1, 2),
+						ColonPos: p(1, 3),
+						Value:    ident("a", p(1, 5)),
+					},
+					&MapElementLit{
+						KeyExpr: parenExpr(ident("b", p(1, 9)),
+							p(1, 8), p(1, 10)),
+						KeyPos:   p(1, 8),
+						ColonPos: p(1, 11),
+						Value:    ident("c", p(1, 13)),
+					})))
+	})
+
+	expectParseString(t, `{-1: a, 1.5: b, 'c': c, true: d, ([e, f]): g}`,
+		`{(-1): a, 1.5: b, 'c': c, true: d, ([e, f]): g}`)
+	expectParseError(t, `{[a]: b}`)
+	expectParseError(t, `{a + b: c}`)
+}
+
+func TestParseSpread(t *testing.T) {
+	expectParse(t, "[a..., 1]", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				arrayLit(p(1, 1), p(1, 9),
+					&SpreadExpr{
+						Expr:     ident("a", p(1, 2)),
+						Ellipsis: p(1, 3),
+					},
+					intLit(1, p(1, 8)))))
+	})
+	expectParse(t, "{a, m...}", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				mapLit(p(1, 1), p(1, 9),
+					&MapElementLit{
+						Key:    "a",
+						KeyPos: p(1, 2),
+						Value:  ident("a", p(1, 2)),
+					},
+					&MapElementLit{
+						KeyPos: p(1, 5),
+						Value: &SpreadExpr{
+							Expr:     ident("m", p(1, 5)),
+							Ellipsis: p(1, 6),
+						},
+					})))
+	})
+
+	expectParseString(t, `[a.b..., f()..., [1]...]`, `[a.b..., f()..., [1]...]`)
+	expectParseString(t, `{a: 1, b, m.c..., (x)...}`, `{a: 1, b, m.c..., (x)...}`)
+	expectParseError(t, `[...]`)
+	expectParseError(t, `{a: b...}`)
+}
+
+func TestParseDestructuring(t *testing.T) {
+	expectParseString(t, `a, b := f()`, `a, b := f()`)
+	expectParseString(t, `[x, _, z] := arr`, `[x, _, z] := arr`)
+	expectParseString(t, `{name, age: years} = m`, `{name, age: years} = m`)
+	expectParseString(t, `a.b, c[0] = [1, 2]`, `a.b, c[0] = [1, 2]`)
+}
+
 func TestParsePrecedence(t *testing.T) {
 	expectParseString(t, `a + b + c`, `((a + b) + c)`)
 	expectParseString(t, `a + b * c`, `(a + (b * c))`)
@@ -1505,6 +1614,310 @@ func TestParseString(t *testing.T) {
 	})
 }
 
+func TestParseTry(t *testing.T) {
+	expectParse(t, "try {} catch {}", func(p pfn) []Stmt {
+		return stmts(
+			tryStmt(
+				blockStmt(p(1, 5), p(1, 6)),
+				nil,
+				blockStmt(p(1, 14), p(1, 15)),
+				nil,
+				p(1, 1), p(1, 8), NoPos))
+	})
+
+	expectParse(t, "try { a() } catch err { b(err) } finally { c() }",
+		func(p pfn) []Stmt {
+			return stmts(
+				tryStmt(
+					blockStmt(p(1, 5), p(1, 11),
+						exprStmt(
+							callExpr(
+								ident("a", p(1, 7)),
+								p(1, 8), p(1, 9), NoPos))),
+					ident("err", p(1, 19)),
+					blockStmt(p(1, 23), p(1, 32),
+						exprStmt(
+							callExpr(
+								ident("b", p(1, 25)),
+								p(1, 26), p(1, 30), NoPos,
+								ident("err", p(1, 27))))),
+					blockStmt(p(1, 42), p(1, 48),
+						exprStmt(
+							callExpr(
+								ident("c", p(1, 44)),
+								p(1, 45), p(1, 46), NoPos))),
+					p(1, 1), p(1, 13), p(1, 34)))
+		})
+
+	expectParse(t, "try {} finally {}", func(p pfn) []Stmt {
+		return stmts(
+			tryStmt(
+				blockStmt(p(1, 5), p(1, 6)),
+				nil,
+				nil,
+				blockStmt(p(1, 16), p(1, 17)),
+				p(1, 1), NoPos, p(1, 8)))
+	})
+
+	expectParseString(t, "try { a() } catch err { b(err) } finally { c() }",
+		"try {a()} catch err {b(err)} finally {c()}")
+	expectParseString(t, "try { a() } catch { b() }",
+		"try {a()} catch {b()}")
+
+	expectParseError(t, `try {}`)
+	expectParseError(t, `try {} catch err`)
+	expectParseError(t, `try {} finally`)
+	expectParseError(t, `try {}
+catch {}`)
+}
+
+func TestParseSwitch(t *testing.T) {
+	expectParse(t, "switch x { case 1, 2: a(); default: }", func(p pfn) []Stmt {
+		return stmts(
+			switchStmt(
+				ident("x", p(1, 8)),
+				p(1, 1), p(1, 10), p(1, 37),
+				caseClause(
+					p(1, 12), p(1, 21),
+					exprs(intLit(1, p(1, 17)), intLit(2, p(1, 20))),
+					exprStmt(
+						callExpr(
+							ident("a", p(1, 23)),
+							p(1, 24), p(1, 25), NoPos))),
+				caseClause(p(1, 28), p(1, 35), nil)))
+	})
+
+	expectParse(t, `switch {
+case x > 1:
+	break
+}`, func(p pfn) []Stmt {
+		return stmts(
+			switchStmt(
+				nil,
+				p(1, 1), p(1, 8), p(4, 1),
+				caseClause(
+					p(2, 1), p(2, 11),
+					exprs(
+						binaryExpr(
+							ident("x", p(2, 6)),
+							intLit(1, p(2, 10)),
+							token.Greater,
+							p(2, 8))),
+					branchStmt(token.Break, nil, p(3, 2)))))
+	})
+
+	expectParse(t, "switch x {}", func(p pfn) []Stmt {
+		return stmts(
+			switchStmt(ident("x", p(1, 8)), p(1, 1), p(1, 10), p(1, 11)))
+	})
+
+	expectParseString(t, "switch x { case 1, 2: a(); b() \n default: c() }",
+		"switch x {case 1, 2: a(); b(); default: c()}")
+	expectParseString(t, `switch {
+case a == 1:
+case a > 2:
+	b()
+}`, "switch {case (a == 1):; case (a > 2): b()}")
+	expectParseString(t, "switch x {case 1, 2: a(); b(); default: c()}",
+		"switch x {case 1, 2: a(); b(); default: c()}")
+
+	expectParseError(t, `switch x { a() }`)
+	expectParseError(t, `switch x { case 1 }`)
+	expectParseError(t, `switch x { case: }`)
+	expectParseError(t, `switch x { default: default: }`)
+	expectParseError(t, `switch x`)
+}
+
+func TestParseInterpString(t *testing.T) {
+	expectParse(t, `f"a ${b} c"`, func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				interpStringLit(p(1, 1),
+					stringLit("a ", p(1, 3)),
+					interpExpr(ident("b", p(1, 7)), "", p(1, 5), p(1, 8)),
+					stringLit(" c", p(1, 9)))))
+	})
+
+	expectParse(t, `f"${a + 1:%05d}${b[0]}"`, func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				interpStringLit(p(1, 1),
+					interpExpr(
+						binaryExpr(
+							ident("a", p(1, 5)),
+							intLit(1, p(1, 9)),
+							token.Add,
+							p(1, 7)),
+						"%05d", p(1, 3), p(1, 15)),
+					interpExpr(
+						indexExpr(
+							ident("b", p(1, 18)),
+							intLit(0, p(1, 20)),
+							p(1, 19), p(1, 21)),
+						"", p(1, 16), p(1, 22)))))
+	})
+
+	expectParse(t, `f"" + f"\${a}\n"`, func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				binaryExpr(
+					interpStringLit(p(1, 1)),
+					interpStringLit(p(1, 7),
+						stringLit("${a}\n", p(1, 9))),
+					token.Add,
+					p(1, 5))))
+	})
+
+	expectParse(t, `f"${ {a: "}"}.a }${f"${x}"}"`, func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				interpStringLit(p(1, 1),
+					interpExpr(
+						selectorExpr(
+							mapLit(p(1, 6), p(1, 13),
+								mapElementLit(
+									"a", p(1, 7), p(1, 8),
+									stringLit("}", p(1, 10)))),
+							stringLit("a", p(1, 15))),
+						"", p(1, 3), p(1, 17)),
+					interpExpr(
+						interpStringLit(p(1, 20),
+							interpExpr(ident("x", p(1, 24)), "",
+								p(1, 22), p(1, 25))),
+						"", p(1, 18), p(1, 27)))))
+	})
+
+	expectParseString(t, `a := f"x ${y:%d} z"`, `a := f"x ${y:%d} z"`)
+
+	expectParseError(t, `f"${}"`)
+	expectParseError(t, `f"${a b}"`)
+	expectParseError(t, `f"${a:d}"`)
+	expectParseError(t, `f"${a"`)
+	expectParseError(t, `f"${a
+}"`)
+	expectParseError(t, `f"abc`)
+	expectParseError(t, `import(f"abc")`)
+}
+
+func TestParseYield(t *testing.T) {
+	expectParse(t, "func() { yield 1; yield }", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				funcLit(
+					funcType(identList(p(1, 5), p(1, 6), false), p(1, 1)),
+					blockStmt(p(1, 8), p(1, 25),
+						yieldStmt(p(1, 10), intLit(1, p(1, 16))),
+						yieldStmt(p(1, 19), nil)))))
+	})
+
+	expectParseString(t, "func() { yield\n yield a + 1 }",
+		"func() {yield; yield (a + 1)}")
+	expectParseError(t, `func() { yield a b }`)
+}
+
+func TestParseOptionalChain(t *testing.T) {
+	expectParse(t, "a?.b?[0]", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				&IndexExpr{
+					Expr: &SelectorExpr{
+						Expr:     ident("a", p(1, 1)),
+						Sel:      stringLit("b", p(1, 4)),
+						Optional: true,
+					},
+					Index:    intLit(0, p(1, 7)),
+					LBrack:   p(1, 6),
+					RBrack:   p(1, 8),
+					Optional: true,
+				}))
+	})
+	expectParse(t, "a ?? b", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				binaryExpr(
+					ident("a", p(1, 1)),
+					ident("b", p(1, 6)),
+					token.Coalesce,
+					p(1, 3))))
+	})
+
+	expectParseString(t, "a?.b.c?[d](e) ?? f", "(a?.b.c?[d](e) ?? f)")
+	expectParseString(t, "a || b ?? c", "((a || b) ?? c)")
+	expectParseString(t, "a ? .5 : b", "(a ? .5 : b)")
+	expectParseString(t, "a ? [1] : b", "(a ? [1] : b)")
+	expectParseError(t, `a?[1:2]`)
+	expectParseError(t, `a?.`)
+}
+
+func TestParseRecord(t *testing.T) {
+	expectParseString(t, "record Point(x, y)", "record Point(x, y)")
+	expectParseString(t, "record Point(x, y) {\n\tlen: func() { return self.x + self.y },\n\tinit: func(x) { self.x = x }\n}",
+		"record Point(x, y) {len: func() {return (self.x + self.y)}, init: func(x) {self.x = x}}")
+	expectParseString(t, "record Empty() {}", "record Empty() {}")
+	expectParseString(t, "record := 1", "record := 1")
+
+	expectParseError(t, `record Point(x, ...y)`)
+	expectParseError(t, `record Point(x) { len: 1 }`)
+	expectParseError(t, `record Point(x) { "len": func() {} }`)
+	expectParseError(t, `record Point`)
+}
+
+func TestInspect(t *testing.T) {
+	src := `
+a := func(b, c) {
+	for k, v in b {
+		try { c(k) } catch e { return f"${e.value}" }
+	}
+	switch b[a] { case c: x++ }
+}`
+	testFileSet := NewFileSet()
+	testFile := testFileSet.AddFile("test", -1, len(src))
+	p := NewParser(testFile, []byte(src), nil)
+	file, err := p.ParseFile()
+	require.NoError(t, err)
+
+	var idents []string
+	Inspect(file, func(node Node) bool {
+		if ident, ok := node.(*Ident); ok {
+			idents = append(idents, ident.Name)
+		}
+		_, isCall := node.(*CallExpr)
+		return !isCall
+	})
+	require.Equal(t, []string{"a", "b", "c", "k", "v", "b", "e", "e",
+		"b", "a", "c", "x"}, idents)
+}
+
+func TestParseComments(t *testing.T) {
+	src := `// doc
+// comment
+a := 1 // one
+/* two */ b := [
+	2 // three
+
+	// four
+]`
+	testFileSet := NewFileSet()
+	testFile := testFileSet.AddFile("test", -1, len(src))
+	p := NewParserWithMode(testFile, []byte(src), nil, ParseComments)
+	file, err := p.ParseFile()
+	require.NoError(t, err)
+	require.Equal(t, "a := 1; b := [2]", file.String())
+
+	var groups []string
+	for _, g := range file.Comments {
+		groups = append(groups, g.String())
+	}
+	require.Equal(t, []string{"// doc\n// comment", "// one", "/* two */",
+		"// three", "// four"}, groups)
+
+	// comments are not kept by default
+	p = NewParser(testFile, []byte(src), nil)
+	file, err = p.ParseFile()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(file.Comments))
+}
+
 type pfn func(int, int) Pos          // position conversion function
 type expectedFn func(pos pfn) []Stmt // callback function to return expected results
 
@@ -1622,6 +2035,10 @@ func returnStmt(pos Pos, result Expr) *ReturnStmt {
 	return &ReturnStmt{Result: result, ReturnPos: pos}
 }
 
+func yieldStmt(pos Pos, result Expr) *YieldStmt {
+	return &YieldStmt{Result: result, YieldPos: pos}
+}
+
 func forStmt(
 	init Stmt,
 	cond Expr,
@@ -1657,6 +2074,39 @@ func ifStmt(
 	}
 }
 
+func tryStmt(
+	body *BlockStmt,
+	catchIdent *Ident,
+	catchBlock *BlockStmt,
+	finallyBlock *BlockStmt,
+	pos, catchPos, finallyPos Pos,
+) *TryStmt {
+	return &TryStmt{
+		Body: body, CatchIdent: catchIdent, Catch: catchBlock,
+		Finally: finallyBlock, TryPos: pos, CatchPos: catchPos,
+		FinallyPos: finallyPos,
+	}
+}
+
+func switchStmt(
+	tag Expr,
+	pos, lbrace, rbrace Pos,
+	cases ...*CaseClause,
+) *SwitchStmt {
+	return &SwitchStmt{
+		Tag: tag, Cases: cases, SwitchPos: pos, LBrace: lbrace,
+		RBrace: rbrace,
+	}
+}
+
+func caseClause(pos, colon Pos, list []Expr, body ...Stmt) *CaseClause {
+	return &CaseClause{List: list, Body: body, Case: pos, Colon: colon}
+}
+
+func branchStmt(tok token.Token, label *Ident, pos Pos) *BranchStmt {
+	return &BranchStmt{Token: tok, Label: label, TokenPos: pos}
+}
+
 func incDecStmt(
 	expr Expr,
 	tok token.Token,
@@ -1731,6 +2181,14 @@ func stringLit(value string, pos Pos) *StringLit {
 	return &StringLit{Value: value, ValuePos: pos}
 }
 
+func interpStringLit(pos Pos, parts ...Expr) *InterpStringLit {
+	return &InterpStringLit{Parts: parts, ValuePos: pos}
+}
+
+func interpExpr(expr Expr, verb string, lbrace, rbrace Pos) *InterpExpr {
+	return &InterpExpr{Expr: expr, Verb: verb, LBrace: lbrace, RBrace: rbrace}
+}
+
 func charLit(value rune, pos Pos) *CharLit {
 	return &CharLit{
 		Value: value, ValuePos: pos, Literal: fmt.Sprintf("'%c'", value),
@@ -1879,6 +2337,40 @@ func equalStmt(t *testing.T, expected, actual Stmt) {
 			actual.(*ReturnStmt).Result)
 		require.Equal(t, expected.ReturnPos,
 			actual.(*ReturnStmt).ReturnPos)
+	case *YieldStmt:
+		equalExpr(t, expected.Result,
+			actual.(*YieldStmt).Result)
+		require.Equal(t, expected.YieldPos,
+			actual.(*YieldStmt).YieldPos)
+	case *TryStmt:
+		equalStmt(t, expected.Body, actual.(*TryStmt).Body)
+		equalExpr(t, expected.CatchIdent, actual.(*TryStmt).CatchIdent)
+		equalStmt(t, expected.Catch, actual.(*TryStmt).Catch)
+		equalStmt(t, expected.Finally, actual.(*TryStmt).Finally)
+		require.Equal(t, expected.TryPos, actual.(*TryStmt).TryPos)
+		require.Equal(t, expected.CatchPos, actual.(*TryStmt).CatchPos)
+		require.Equal(t, expected.FinallyPos,
+			actual.(*TryStmt).FinallyPos)
+	case *SwitchStmt:
+		equalExpr(t, expected.Tag, actual.(*SwitchStmt).Tag)
+		require.Equal(t, len(expected.Cases),
+			len(actual.(*SwitchStmt).Cases))
+		for i := 0; i < len(expected.Cases); i++ {
+			equalStmt(t, expected.Cases[i],
+				actual.(*SwitchStmt).Cases[i])
+		}
+		require.Equal(t, expected.SwitchPos,
+			actual.(*SwitchStmt).SwitchPos)
+		require.Equal(t, expected.LBrace, actual.(*SwitchStmt).LBrace)
+		require.Equal(t, expected.RBrace, actual.(*SwitchStmt).RBrace)
+	case *CaseClause:
+		equalExprs(t, expected.List, actual.(*CaseClause).List)
+		require.Equal(t, len(expected.Body), len(actual.(*CaseClause).Body))
+		for i := 0; i < len(expected.Body); i++ {
+			equalStmt(t, expected.Body[i], actual.(*CaseClause).Body[i])
+		}
+		require.Equal(t, expected.Case, actual.(*CaseClause).Case)
+		require.Equal(t, expected.Colon, actual.(*CaseClause).Colon)
 	case *BranchStmt:
 		equalExpr(t, expected.Label,
 			actual.(*BranchStmt).Label)
@@ -1930,6 +2422,15 @@ func equalExpr(t *testing.T, expected, actual Expr) {
 			actual.(*StringLit).Value)
 		require.Equal(t, int(expected.ValuePos),
 			int(actual.(*StringLit).ValuePos))
+	case *InterpStringLit:
+		equalExprs(t, expected.Parts, actual.(*InterpStringLit).Parts)
+		require.Equal(t, int(expected.ValuePos),
+			int(actual.(*InterpStringLit).ValuePos))
+	case *InterpExpr:
+		equalExpr(t, expected.Expr, actual.(*InterpExpr).Expr)
+		require.Equal(t, expected.Verb, actual.(*InterpExpr).Verb)
+		require.Equal(t, expected.LBrace, actual.(*InterpExpr).LBrace)
+		require.Equal(t, expected.RBrace, actual.(*InterpExpr).RBrace)
 	case *ArrayLit:
 		require.Equal(t, expected.LBrack,
 			actual.(*ArrayLit).LBrack)
@@ -1974,6 +2475,25 @@ func equalExpr(t *testing.T, expected, actual Expr) {
 			actual.(*CallExpr).RParen)
 		equalExprs(t, expected.Args,
 			actual.(*CallExpr).Args)
+		require.Equal(t, len(expected.Keywords),
+			len(actual.(*CallExpr).Keywords))
+		for i, kw := range expected.Keywords {
+			equalExpr(t, kw, actual.(*CallExpr).Keywords[i])
+		}
+	case *KeywordArg:
+		require.Equal(t, expected.Name,
+			actual.(*KeywordArg).Name)
+		require.Equal(t, expected.NamePos,
+			actual.(*KeywordArg).NamePos)
+		require.Equal(t, expected.ColonPos,
+			actual.(*KeywordArg).ColonPos)
+		equalExpr(t, expected.Value,
+			actual.(*KeywordArg).Value)
+	case *SpreadExpr:
+		equalExpr(t, expected.Expr,
+			actual.(*SpreadExpr).Expr)
+		require.Equal(t, expected.Ellipsis,
+			actual.(*SpreadExpr).Ellipsis)
 	case *ParenExpr:
 		equalExpr(t, expected.Expr,
 			actual.(*ParenExpr).Expr)
@@ -1990,6 +2510,8 @@ func equalExpr(t *testing.T, expected, actual Expr) {
 			actual.(*IndexExpr).LBrack)
 		require.Equal(t, expected.RBrack,
 			actual.(*IndexExpr).RBrack)
+		require.Equal(t, expected.Optional,
+			actual.(*IndexExpr).Optional)
 	case *SliceExpr:
 		equalExpr(t, expected.Expr,
 			actual.(*SliceExpr).Expr)
@@ -2006,6 +2528,8 @@ func equalExpr(t *testing.T, expected, actual Expr) {
 			actual.(*SelectorExpr).Expr)
 		equalExpr(t, expected.Sel,
 			actual.(*SelectorExpr).Sel)
+		require.Equal(t, expected.Optional,
+			actual.(*SelectorExpr).Optional)
 	case *ImportExpr:
 		require.Equal(t, expected.ModuleName,
 			actual.(*ImportExpr).ModuleName)
@@ -2042,6 +2566,7 @@ func equalFuncType(t *testing.T, expected, actual *FuncType) {
 	require.Equal(t, expected.Params.LParen, actual.Params.LParen)
 	require.Equal(t, expected.Params.RParen, actual.Params.RParen)
 	equalIdents(t, expected.Params.List, actual.Params.List)
+	equalExprs(t, expected.Params.Defaults, actual.Params.Defaults)
 }
 
 func equalIdents(t *testing.T, expected, actual []*Ident) {
@@ -2072,6 +2597,7 @@ func equalMapElements(
 	require.Equal(t, len(expected), len(actual))
 	for i := 0; i < len(expected); i++ {
 		require.Equal(t, expected[i].Key, actual[i].Key)
+		equalExpr(t, expected[i].KeyExpr, actual[i].KeyExpr)
 		require.Equal(t, expected[i].KeyPos, actual[i].KeyPos)
 		require.Equal(t, expected[i].ColonPos, actual[i].ColonPos)
 		equalExpr(t, expected[i].Value, actual[i].Value)
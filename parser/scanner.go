@@ -87,10 +87,18 @@ func (s *Scanner) Scan() (
 	switch ch := s.ch; {
 	case isLetter(ch):
 		literal = s.scanIdentifier()
+		if literal == "f" && s.ch == '"' {
+			s.next()
+			insertSemi = true
+			tok = token.InterpString
+			literal = s.scanInterpString()
+			break
+		}
 		tok = token.Lookup(literal)
 		switch tok {
 		case token.Ident, token.Break, token.Continue, token.Return,
-			token.Export, token.True, token.False, token.Undefined:
+			token.Yield, token.Export, token.True, token.False,
+			token.Undefined:
 			insertSemi = true
 		}
 	case '0' <= ch && ch <= '9':
@@ -139,7 +147,19 @@ func (s *Scanner) Scan() (
 		case ',':
 			tok = token.Comma
 		case '?':
-			tok = token.Question
+			switch {
+			case s.ch == '?':
+				s.next()
+				tok = token.Coalesce
+			case s.ch == '.' && !('0' <= s.peek() && s.peek() <= '9'):
+				s.next()
+				tok = token.QuestionPeriod
+			case s.ch == '[':
+				s.next()
+				tok = token.QuestionLBrack
+			default:
+				tok = token.Question
+			}
 		case ';':
 			tok = token.Semicolon
 			literal = ";"
@@ -568,6 +588,92 @@ func (s *Scanner) scanString() string {
 	return string(s.src[offs:s.offset])
 }
 
+func (s *Scanner) scanInterpString() string {
+	offs := s.offset - 2 // 'f"' opening already consumed
+
+	for {
+		ch := s.ch
+		if ch == '\n' || ch < 0 {
+			s.error(offs, "string literal not terminated")
+			break
+		}
+		s.next()
+		if ch == '"' {
+			break
+		}
+		if ch == '\\' {
+			if s.ch == '$' {
+				s.next()
+			} else {
+				s.scanEscape('"')
+			}
+		}
+		if ch == '$' && s.ch == '{' {
+			s.next()
+			if !s.skipInterpExpr() {
+				s.error(offs, "string literal not terminated")
+				break
+			}
+		}
+	}
+	return string(s.src[offs:s.offset])
+}
+
+// skipInterpExpr skips the placeholder expression of an interpolated string
+// literal including the closing '}'. It returns false if the placeholder is
+// not terminated in the same line.
+func (s *Scanner) skipInterpExpr() bool {
+	depth := 0
+	for {
+		ch := s.ch
+		if ch == '\n' || ch < 0 {
+			return false
+		}
+		s.next()
+		switch ch {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return true
+			}
+			depth--
+		case '"':
+			s.scanString()
+		case '\'':
+			s.scanRune()
+		case '`':
+			s.scanRawString()
+		default:
+			if isLetter(ch) {
+				offs := s.offset - 1
+				for isLetter(s.ch) || isDigit(s.ch) {
+					s.next()
+				}
+				if string(s.src[offs:s.offset]) == "f" && s.ch == '"' {
+					s.next()
+					s.scanInterpString()
+				}
+			}
+		}
+	}
+}
+
+// fork returns a new scanner that reads the same source from the offset.
+// Errors are not reported by the returned scanner.
+func (s *Scanner) fork(offset int) *Scanner {
+	f := &Scanner{
+		file:       s.file,
+		src:        s.src,
+		ch:         ' ',
+		readOffset: offset,
+		lineOffset: s.lineOffset,
+		mode:       (s.mode | DontInsertSemis) &^ ScanComments,
+	}
+	f.next()
+	return f
+}
+
 func (s *Scanner) scanRawString() string {
 	offs := s.offset - 1 // '`' opening already consumed
 
@@ -62,6 +62,10 @@ func TestScanner_Scan(t *testing.T) {
 		},
 		{token.String, "`\r`"},
 		{token.String, "`foo\r\nbar`"},
+		{token.InterpString, `f"foobar"`},
+		{token.InterpString, `f"foo ${bar} \${baz}"`},
+		{token.InterpString, `f"${ {a: "}"}.a } ${f"${'}'}"}"`},
+		{token.InterpString, `f"${x:%08.3f}"`},
 		{token.Add, "+"},
 		{token.Sub, "-"},
 		{token.Mul, "*"},
@@ -108,6 +112,10 @@ func TestScanner_Scan(t *testing.T) {
 		{token.RBrace, "}"},
 		{token.Semicolon, ";"},
 		{token.Colon, ":"},
+		{token.Question, "?"},
+		{token.QuestionPeriod, "?."},
+		{token.QuestionLBrack, "?["},
+		{token.Coalesce, "??"},
 		{token.Break, "break"},
 		{token.Continue, "continue"},
 		{token.Else, "else"},
@@ -116,6 +124,12 @@ func TestScanner_Scan(t *testing.T) {
 		{token.If, "if"},
 		{token.Return, "return"},
 		{token.Export, "export"},
+		{token.Try, "try"},
+		{token.Catch, "catch"},
+		{token.Finally, "finally"},
+		{token.Switch, "switch"},
+		{token.Case, "case"},
+		{token.Default, "default"},
 	}
 
 	// combine
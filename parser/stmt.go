@@ -347,3 +347,187 @@ func (s *ReturnStmt) String() string {
 	}
 	return "return"
 }
+
+// TryStmt represents a try statement.
+type TryStmt struct {
+	TryPos     Pos
+	Body       *BlockStmt
+	CatchPos   Pos
+	CatchIdent *Ident     // catch variable; or nil
+	Catch      *BlockStmt // catch block; or nil
+	FinallyPos Pos
+	Finally    *BlockStmt // finally block; or nil
+}
+
+func (s *TryStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *TryStmt) Pos() Pos {
+	return s.TryPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *TryStmt) End() Pos {
+	if s.Finally != nil {
+		return s.Finally.End()
+	}
+	if s.Catch != nil {
+		return s.Catch.End()
+	}
+	return s.Body.End()
+}
+
+func (s *TryStmt) String() string {
+	str := "try " + s.Body.String()
+	if s.Catch != nil {
+		str += " catch "
+		if s.CatchIdent != nil {
+			str += s.CatchIdent.String() + " "
+		}
+		str += s.Catch.String()
+	}
+	if s.Finally != nil {
+		str += " finally " + s.Finally.String()
+	}
+	return str
+}
+
+// SwitchStmt represents a switch statement.
+type SwitchStmt struct {
+	SwitchPos Pos
+	Tag       Expr // tag expression; or nil
+	LBrace    Pos
+	Cases     []*CaseClause
+	RBrace    Pos
+}
+
+func (s *SwitchStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *SwitchStmt) Pos() Pos {
+	return s.SwitchPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *SwitchStmt) End() Pos {
+	return s.RBrace + 1
+}
+
+func (s *SwitchStmt) String() string {
+	var list []string
+	for _, c := range s.Cases {
+		list = append(list, c.String())
+	}
+	var tag string
+	if s.Tag != nil {
+		tag = s.Tag.String() + " "
+	}
+	return "switch " + tag + "{" + strings.Join(list, "; ") + "}"
+}
+
+// CaseClause represents a case or default clause of a switch statement.
+type CaseClause struct {
+	Case  Pos
+	List  []Expr // list of expressions; nil means default clause
+	Colon Pos
+	Body  []Stmt
+}
+
+func (s *CaseClause) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *CaseClause) Pos() Pos {
+	return s.Case
+}
+
+// End returns the position of first character immediately after the node.
+func (s *CaseClause) End() Pos {
+	if n := len(s.Body); n > 0 {
+		return s.Body[n-1].End()
+	}
+	return s.Colon + 1
+}
+
+func (s *CaseClause) String() string {
+	str := "default:"
+	if s.List != nil {
+		var list []string
+		for _, e := range s.List {
+			list = append(list, e.String())
+		}
+		str = "case " + strings.Join(list, ", ") + ":"
+	}
+	var body []string
+	for _, e := range s.Body {
+		body = append(body, e.String())
+	}
+	if len(body) > 0 {
+		str += " " + strings.Join(body, "; ")
+	}
+	return str
+}
+
+// YieldStmt represents a yield statement.
+type YieldStmt struct {
+	YieldPos Pos
+	Result   Expr
+}
+
+func (s *YieldStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *YieldStmt) Pos() Pos {
+	return s.YieldPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *YieldStmt) End() Pos {
+	if s.Result != nil {
+		return s.Result.End()
+	}
+	return s.YieldPos + 5
+}
+
+func (s *YieldStmt) String() string {
+	if s.Result != nil {
+		return "yield " + s.Result.String()
+	}
+	return "yield"
+}
+
+// RecordStmt represents a record type declaration.
+type RecordStmt struct {
+	RecordPos Pos
+	Name      *Ident
+	Fields    *IdentList
+	LBrace    Pos              // position of "{"; or NoPos if there's no body
+	Methods   []*MapElementLit // methods with function literal values
+	RBrace    Pos
+}
+
+func (s *RecordStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *RecordStmt) Pos() Pos {
+	return s.RecordPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *RecordStmt) End() Pos {
+	if s.LBrace.IsValid() {
+		return s.RBrace + 1
+	}
+	return s.Fields.End()
+}
+
+func (s *RecordStmt) String() string {
+	str := "record " + s.Name.String() + s.Fields.String()
+	if s.LBrace.IsValid() {
+		var methods []string
+		for _, m := range s.Methods {
+			methods = append(methods, m.String())
+		}
+		str += " {" + strings.Join(methods, ", ") + "}"
+	}
+	return str
+}
@@ -0,0 +1,172 @@
+package parser
+
+// Inspect traverses the AST in depth-first order. It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node.
+func Inspect(node Node, f func(Node) bool) {
+	if !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *File:
+		inspectStmts(n.Stmts, f)
+	case *IdentList:
+		for i, ident := range n.List {
+			Inspect(ident, f)
+			if i < len(n.Defaults) && n.Defaults[i] != nil {
+				Inspect(n.Defaults[i], f)
+			}
+		}
+	case *ArrayLit:
+		inspectExprs(n.Elements, f)
+	case *BinaryExpr:
+		Inspect(n.LHS, f)
+		Inspect(n.RHS, f)
+	case *CallExpr:
+		Inspect(n.Func, f)
+		inspectExprs(n.Args, f)
+		for _, kw := range n.Keywords {
+			Inspect(kw, f)
+		}
+	case *CondExpr:
+		Inspect(n.Cond, f)
+		Inspect(n.True, f)
+		Inspect(n.False, f)
+	case *ErrorExpr:
+		Inspect(n.Expr, f)
+	case *FuncLit:
+		Inspect(n.Type, f)
+		Inspect(n.Body, f)
+	case *FuncType:
+		Inspect(n.Params, f)
+	case *ImmutableExpr:
+		Inspect(n.Expr, f)
+	case *KeywordArg:
+		Inspect(n.Value, f)
+	case *IndexExpr:
+		Inspect(n.Expr, f)
+		if n.Index != nil {
+			Inspect(n.Index, f)
+		}
+	case *InterpExpr:
+		Inspect(n.Expr, f)
+	case *InterpStringLit:
+		inspectExprs(n.Parts, f)
+	case *MapElementLit:
+		if n.KeyExpr != nil {
+			Inspect(n.KeyExpr, f)
+		}
+		Inspect(n.Value, f)
+	case *MapLit:
+		for _, elem := range n.Elements {
+			Inspect(elem, f)
+		}
+	case *ParenExpr:
+		Inspect(n.Expr, f)
+	case *SelectorExpr:
+		Inspect(n.Expr, f)
+		Inspect(n.Sel, f)
+	case *SpreadExpr:
+		Inspect(n.Expr, f)
+	case *SliceExpr:
+		Inspect(n.Expr, f)
+		if n.Low != nil {
+			Inspect(n.Low, f)
+		}
+		if n.High != nil {
+			Inspect(n.High, f)
+		}
+	case *UnaryExpr:
+		Inspect(n.Expr, f)
+	case *AssignStmt:
+		inspectExprs(n.LHS, f)
+		inspectExprs(n.RHS, f)
+	case *BlockStmt:
+		inspectStmts(n.Stmts, f)
+	case *BranchStmt:
+		if n.Label != nil {
+			Inspect(n.Label, f)
+		}
+	case *ExportStmt:
+		Inspect(n.Result, f)
+	case *ExprStmt:
+		Inspect(n.Expr, f)
+	case *ForInStmt:
+		Inspect(n.Key, f)
+		if n.Value != nil {
+			Inspect(n.Value, f)
+		}
+		Inspect(n.Iterable, f)
+		Inspect(n.Body, f)
+	case *ForStmt:
+		if n.Init != nil {
+			Inspect(n.Init, f)
+		}
+		if n.Cond != nil {
+			Inspect(n.Cond, f)
+		}
+		if n.Post != nil {
+			Inspect(n.Post, f)
+		}
+		Inspect(n.Body, f)
+	case *IfStmt:
+		if n.Init != nil {
+			Inspect(n.Init, f)
+		}
+		Inspect(n.Cond, f)
+		Inspect(n.Body, f)
+		if n.Else != nil {
+			Inspect(n.Else, f)
+		}
+	case *IncDecStmt:
+		Inspect(n.Expr, f)
+	case *ReturnStmt:
+		if n.Result != nil {
+			Inspect(n.Result, f)
+		}
+	case *YieldStmt:
+		if n.Result != nil {
+			Inspect(n.Result, f)
+		}
+	case *RecordStmt:
+		Inspect(n.Name, f)
+		Inspect(n.Fields, f)
+		for _, m := range n.Methods {
+			Inspect(m, f)
+		}
+	case *TryStmt:
+		Inspect(n.Body, f)
+		if n.CatchIdent != nil {
+			Inspect(n.CatchIdent, f)
+		}
+		if n.Catch != nil {
+			Inspect(n.Catch, f)
+		}
+		if n.Finally != nil {
+			Inspect(n.Finally, f)
+		}
+	case *SwitchStmt:
+		if n.Tag != nil {
+			Inspect(n.Tag, f)
+		}
+		for _, c := range n.Cases {
+			Inspect(c, f)
+		}
+	case *CaseClause:
+		inspectExprs(n.List, f)
+		inspectStmts(n.Body, f)
+	}
+}
+
+func inspectExprs(list []Expr, f func(Node) bool) {
+	for _, e := range list {
+		Inspect(e, f)
+	}
+}
+
+func inspectStmts(list []Stmt, f func(Node) bool) {
+	for _, s := range list {
+		Inspect(s, f)
+	}
+}
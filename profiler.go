@@ -0,0 +1,359 @@
+package tengo
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/d5/tengo/v2/parser"
+)
+
+// Profiler counts the instructions, the time and the object allocations of a
+// VM for each function and for each source line. The time spent in an
+// instruction, including the Go functions it calls, is measured until the
+// next instruction is executed.
+//
+// Profiler methods must not be called while the VM is running.
+type Profiler struct {
+	vm         *VM
+	root       *profileNode
+	nodes      []*profileNode // current node of each frame
+	last       *profileNode   // node of the last executed instruction
+	lastTime   time.Time
+	lastAllocs int64
+	start      time.Time
+	duration   time.Duration
+}
+
+// NewProfiler creates a Profiler attached to the VM. The profiling starts
+// when the VM runs.
+func NewProfiler(vm *VM) *Profiler {
+	p := &Profiler{vm: vm, root: &profileNode{}}
+	vm.profiler = p
+	return p
+}
+
+// ProfileEntry represents the profile of a function or a source line. The
+// values are the ones of the instructions in the function or at the source
+// line, not including the instructions of the called functions.
+type ProfileEntry struct {
+	Fn           *CompiledFunction
+	Pos          parser.SourceFilePos // position of the function or the line
+	Instructions int64
+	Duration     time.Duration
+	Allocs       int64
+}
+
+// Functions returns the profiles of the functions sorted by the duration in
+// descending order.
+func (p *Profiler) Functions() []*ProfileEntry {
+	return p.entries(false)
+}
+
+// Lines returns the profiles of the source lines sorted by the duration in
+// descending order.
+func (p *Profiler) Lines() []*ProfileEntry {
+	return p.entries(true)
+}
+
+// WriteProfile writes the profile in the gzip-compressed protocol buffer
+// format of pprof. The instruction counts, the durations and the allocation
+// counts are written as the sample values for each call stack, so the
+// profile can be viewed using "go tool pprof".
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(p.encodeProfile()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// trace is called by the VM before executing each instruction.
+func (p *Profiler) trace() {
+	v := p.vm
+	now := time.Now()
+	if p.last == nil {
+		p.start = now
+	} else {
+		p.last.duration += now.Sub(p.lastTime)
+		p.last.allocs += p.lastAllocs - v.allocs
+	}
+	p.lastTime, p.lastAllocs = now, v.allocs
+
+	depth := v.framesIndex - 1
+	for len(p.nodes) <= depth {
+		p.nodes = append(p.nodes, nil)
+	}
+	parent := p.root
+	if depth > 0 && p.nodes[depth-1] != nil {
+		parent = p.nodes[depth-1]
+	}
+	fn := v.curFrame.fn
+	pos := fn.SourcePos(v.ip)
+	node := p.nodes[depth]
+	if node == nil || node.parent != parent || node.fn != fn ||
+		node.pos != pos {
+		if pos == parser.NoPos {
+			node = parent // entry frame of VM.RunCompiled
+		} else {
+			node = parent.child(fn, pos)
+		}
+		p.nodes[depth] = node
+	}
+	node.instructions++
+	p.last = node
+}
+
+// stop is called by the VM when the execution ends.
+func (p *Profiler) stop() {
+	if p.last != nil {
+		p.last.duration += time.Since(p.lastTime)
+		p.last.allocs += p.lastAllocs - p.vm.allocs
+		p.duration += time.Since(p.start)
+	}
+	p.last = nil
+}
+
+// entries returns the profiles aggregated by the functions or by the source
+// lines.
+func (p *Profiler) entries(byLine bool) []*ProfileEntry {
+	var entries []*ProfileEntry
+	indexes := make(map[interface{}]int)
+	p.root.walk(func(n *profileNode) {
+		var key interface{} = n.fn
+		if byLine {
+			key = sourceLine(p.vm.fileSet.Position(n.pos))
+		}
+		i, ok := indexes[key]
+		if !ok {
+			i = len(entries)
+			indexes[key] = i
+			pos := n.pos
+			if !byLine {
+				pos = p.funcPos(n.fn)
+			}
+			entries = append(entries, &ProfileEntry{
+				Fn:  n.fn,
+				Pos: sourceLine(p.vm.fileSet.Position(pos)),
+			})
+		}
+		e := entries[i]
+		e.Instructions += n.instructions
+		e.Duration += n.duration
+		e.Allocs += n.allocs
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Duration > entries[j].Duration
+	})
+	return entries
+}
+
+// funcPos returns the source position of the first instruction of the
+// function.
+func (p *Profiler) funcPos(fn *CompiledFunction) parser.Pos {
+	pos := parser.NoPos
+	for _, v := range fn.SourceMap {
+		if pos == parser.NoPos || v < pos {
+			pos = v
+		}
+	}
+	return pos
+}
+
+// funcName returns the name of the function used in the profile.
+func (p *Profiler) funcName(fn *CompiledFunction) string {
+	if fn == p.vm.frames[0].fn {
+		return "main"
+	}
+	pos := p.vm.fileSet.Position(p.funcPos(fn))
+	return fmt.Sprintf("func@%s:%d", pos.Filename, pos.Line)
+}
+
+// encodeProfile encodes the profile in the protocol buffer format of pprof.
+// See https://github.com/google/pprof/blob/master/proto/profile.proto.
+func (p *Profiler) encodeProfile() []byte {
+	strs := map[string]int{"": 0}
+	strTable := []string{""}
+	str := func(s string) uint64 {
+		i, ok := strs[s]
+		if !ok {
+			i = len(strTable)
+			strs[s] = i
+			strTable = append(strTable, s)
+		}
+		return uint64(i)
+	}
+	valueType := func(typ, unit string) []byte {
+		var b protoBuffer
+		b.uint64(1, str(typ))
+		b.uint64(2, str(unit))
+		return b.data
+	}
+
+	var b protoBuffer
+	b.bytes(1, valueType("instructions", "count"))
+	b.bytes(1, valueType("cpu", "nanoseconds"))
+	b.bytes(1, valueType("alloc_objects", "count"))
+
+	// samples for the call stacks
+	funcs := make(map[*CompiledFunction]uint64)
+	var funcList []*CompiledFunction
+	type location struct {
+		fn   uint64
+		line int
+	}
+	locs := make(map[location]uint64)
+	var locList []location
+	var stack []uint64
+	var sample func(n *profileNode)
+	sample = func(n *profileNode) {
+		if n != p.root {
+			fid, ok := funcs[n.fn]
+			if !ok {
+				funcList = append(funcList, n.fn)
+				fid = uint64(len(funcList))
+				funcs[n.fn] = fid
+			}
+			loc := location{fid, p.vm.fileSet.Position(n.pos).Line}
+			lid, ok := locs[loc]
+			if !ok {
+				locList = append(locList, loc)
+				lid = uint64(len(locList))
+				locs[loc] = lid
+			}
+			stack = append(stack, lid)
+
+			var s protoBuffer
+			var ids protoBuffer
+			for i := len(stack) - 1; i >= 0; i-- {
+				ids.varint(stack[i]) // leaf first
+			}
+			s.bytes(1, ids.data)
+			var values protoBuffer
+			values.varint(uint64(n.instructions))
+			values.varint(uint64(n.duration))
+			values.varint(uint64(n.allocs))
+			s.bytes(2, values.data)
+			b.bytes(2, s.data)
+		}
+		for _, c := range n.children {
+			sample(c)
+		}
+		if n != p.root {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	sample(p.root)
+
+	for i, loc := range locList {
+		var line protoBuffer
+		line.uint64(1, loc.fn)
+		line.uint64(2, uint64(loc.line))
+		var l protoBuffer
+		l.uint64(1, uint64(i+1))
+		l.bytes(4, line.data)
+		b.bytes(4, l.data)
+	}
+	for i, fn := range funcList {
+		pos := p.vm.fileSet.Position(p.funcPos(fn))
+		name := p.funcName(fn)
+		var f protoBuffer
+		f.uint64(1, uint64(i+1))
+		f.uint64(2, str(name))
+		f.uint64(3, str(name))
+		f.uint64(4, str(pos.Filename))
+		f.uint64(5, uint64(pos.Line))
+		b.bytes(5, f.data)
+	}
+
+	// string table is encoded after all strings are added
+	timeNanos := p.start.UnixNano()
+	durationNanos := int64(p.duration)
+	periodType := valueType("cpu", "nanoseconds")
+	defaultType := str("cpu")
+	for _, s := range strTable {
+		b.bytes(6, []byte(s))
+	}
+	b.uint64(9, uint64(timeNanos))
+	b.uint64(10, uint64(durationNanos))
+	b.bytes(11, periodType)
+	b.uint64(12, 1)
+	b.uint64(14, defaultType)
+	return b.data
+}
+
+// profileNode is a node of the tree of the call stacks. Each node represents
+// an instruction position in a function called from the parent node.
+type profileNode struct {
+	parent       *profileNode
+	fn           *CompiledFunction
+	pos          parser.Pos
+	children     []*profileNode
+	index        map[profileKey]*profileNode
+	instructions int64
+	duration     time.Duration
+	allocs       int64
+}
+
+type profileKey struct {
+	fn  *CompiledFunction
+	pos parser.Pos
+}
+
+func (n *profileNode) child(
+	fn *CompiledFunction,
+	pos parser.Pos,
+) *profileNode {
+	key := profileKey{fn, pos}
+	if c, ok := n.index[key]; ok {
+		return c
+	}
+	if n.index == nil {
+		n.index = make(map[profileKey]*profileNode)
+	}
+	c := &profileNode{parent: n, fn: fn, pos: pos}
+	n.index[key] = c
+	n.children = append(n.children, c)
+	return c
+}
+
+// walk calls f for the node and all its descendants except the root.
+func (n *profileNode) walk(f func(n *profileNode)) {
+	if n.parent != nil {
+		f(n)
+	}
+	for _, c := range n.children {
+		c.walk(f)
+	}
+}
+
+// protoBuffer encodes the protocol buffer messages.
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+// uint64 encodes a varint field. Zero values are omitted.
+func (b *protoBuffer) uint64(tag int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.varint(uint64(tag)<<3 | 0)
+	b.varint(x)
+}
+
+// bytes encodes a length-delimited field.
+func (b *protoBuffer) bytes(tag int, data []byte) {
+	b.varint(uint64(tag)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
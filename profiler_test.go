@@ -0,0 +1,93 @@
+package tengo_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/require"
+)
+
+const profileSrc = `
+f := func(x) {
+	return [x, x]
+}
+a := 0
+for i := 0; i < 10; i++ {
+	a += len(f(i))
+}
+`
+
+func TestProfiler(t *testing.T) {
+	v, p := runProfile(t, profileSrc)
+
+	lines := make(map[int]*tengo.ProfileEntry)
+	for _, e := range p.Lines() {
+		require.Equal(t, "test", e.Pos.Filename)
+		lines[e.Pos.Line] = e
+	}
+	require.Equal(t, 5, len(lines))
+	require.Equal(t, int64(10), lines[3].Allocs) // array literals
+	require.True(t, lines[3].Instructions >= 40)
+	require.True(t, lines[7].Instructions >= 60)
+	require.True(t, lines[7].Fn != lines[3].Fn)
+
+	funcs := p.Functions()
+	require.Equal(t, 2, len(funcs))
+	var total int64
+	for _, e := range funcs {
+		total += e.Instructions
+	}
+	for _, e := range p.Lines() {
+		total -= e.Instructions
+	}
+	require.Equal(t, int64(0), total)
+	for _, e := range funcs {
+		if e.Fn == lines[3].Fn {
+			require.Equal(t, 3, e.Pos.Line)
+			require.Equal(t, lines[3].Instructions, e.Instructions)
+		}
+	}
+
+	// running again accumulates the profile
+	require.NoError(t, v.Run())
+	for _, e := range p.Lines() {
+		if e.Pos.Line == 7 {
+			require.Equal(t, 2*lines[7].Instructions, e.Instructions)
+		}
+	}
+}
+
+func TestProfiler_WriteProfile(t *testing.T) {
+	_, p := runProfile(t, profileSrc)
+
+	var buf bytes.Buffer
+	require.NoError(t, p.WriteProfile(&buf))
+	r, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	for _, s := range []string{"instructions", "cpu", "nanoseconds",
+		"alloc_objects", "main", "func@test:3"} {
+		require.True(t, bytes.Contains(data, []byte(s)), s)
+	}
+}
+
+func runProfile(t *testing.T, src string) (*tengo.VM, *tengo.Profiler) {
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("test", -1, len(src))
+	p := parser.NewParser(srcFile, []byte(src), nil)
+	file, err := p.ParseFile()
+	require.NoError(t, err)
+
+	c := tengo.NewCompiler(srcFile, nil, nil, nil, nil)
+	require.NoError(t, c.Compile(file))
+
+	v := tengo.NewVM(c.Bytecode(), nil, -1)
+	profiler := tengo.NewProfiler(v)
+	require.NoError(t, v.Run())
+	return v, profiler
+}
@@ -0,0 +1,247 @@
+package tengo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RecordType represents a record type declared by a record statement. It's
+// called to create a record: the arguments are passed to the "init" method if
+// the type has one, or assigned to the fields in the declared order
+// otherwise.
+type RecordType struct {
+	ObjectImpl
+	Name    string
+	Fields  []string
+	Methods map[string]*CompiledFunction
+	index   map[string]int // field name to slot index
+}
+
+// NewRecordType creates a record type with the fields and the methods. The
+// methods take the receiver record as the first parameter.
+func NewRecordType(
+	name string,
+	fields []string,
+	methods map[string]*CompiledFunction,
+) *RecordType {
+	index := make(map[string]int, len(fields))
+	for i, f := range fields {
+		index[f] = i
+	}
+	return &RecordType{
+		Name:    name,
+		Fields:  fields,
+		Methods: methods,
+		index:   index,
+	}
+}
+
+// TypeName returns the name of the type.
+func (o *RecordType) TypeName() string {
+	return "record-type:" + o.Name
+}
+
+func (o *RecordType) String() string {
+	return "<record-type:" + o.Name + ">"
+}
+
+// Copy returns the record type itself. Record types are immutable.
+func (o *RecordType) Copy() Object {
+	return o
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (o *RecordType) Equals(x Object) bool {
+	return o == x
+}
+
+// CanCall returns whether the Object can be Called.
+func (o *RecordType) CanCall() bool {
+	return true
+}
+
+// Call creates a record outside of a VM. It fails if the type has an "init"
+// method, which can only run in a VM.
+func (o *RecordType) Call(args ...Object) (Object, error) {
+	return o.CallVM(nil, args...)
+}
+
+// CallVM creates a record, and runs its "init" method if the type has one.
+func (o *RecordType) CallVM(vm *VM, args ...Object) (Object, error) {
+	r := &Record{
+		Type:   o,
+		Values: make([]Object, len(o.Fields)),
+	}
+	init := o.Methods["init"]
+	if init == nil {
+		if len(args) > len(o.Fields) {
+			return nil, ErrWrongNumArguments
+		}
+		copy(r.Values, args)
+		for i := len(args); i < len(r.Values); i++ {
+			r.Values[i] = UndefinedValue
+		}
+		return r, nil
+	}
+
+	for i := range r.Values {
+		r.Values[i] = UndefinedValue
+	}
+	if vm == nil {
+		return nil, fmt.Errorf("%s: init called outside of a VM", o.Name)
+	}
+	max := init.NumParameters - 1 // receiver
+	min := max - init.NumDefaults
+	if init.VarArgs {
+		min--
+	}
+	if len(args) < min || (!init.VarArgs && len(args) > max) {
+		return nil, wrongNumArgs(init, len(args), 1)
+	}
+	if _, err := vm.RunCompiled(init, append([]Object{r}, args...)...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// New creates a record of the type with the field values in the declared
+// order. Missing values are undefined. It doesn't run the "init" method.
+func (o *RecordType) New(values ...Object) (*Record, error) {
+	if len(values) > len(o.Fields) {
+		return nil, ErrWrongNumArguments
+	}
+	r := &Record{
+		Type:   o,
+		Values: make([]Object, len(o.Fields)),
+	}
+	copy(r.Values, values)
+	for i := len(values); i < len(r.Values); i++ {
+		r.Values[i] = UndefinedValue
+	}
+	return r, nil
+}
+
+// Record represents a value of a record type. The values of the fields are
+// stored in fixed slots in the order of the declared fields.
+type Record struct {
+	ObjectImpl
+	Type   *RecordType
+	Values []Object
+}
+
+// TypeName returns the name of the record type.
+func (o *Record) TypeName() string {
+	return o.Type.Name
+}
+
+func (o *Record) String() string {
+	var pairs []string
+	for i, f := range o.Type.Fields {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", f, o.Values[i].String()))
+	}
+	return o.Type.Name + "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Copy returns a copy of the record with copies of the field values.
+func (o *Record) Copy() Object {
+	values := make([]Object, len(o.Values))
+	for i, v := range o.Values {
+		values[i] = v.Copy()
+	}
+	return &Record{Type: o.Type, Values: values}
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (o *Record) Equals(x Object) bool {
+	t, ok := x.(*Record)
+	if !ok || o.Type != t.Type {
+		return false
+	}
+	for i, v := range o.Values {
+		if !v.Equals(t.Values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Field returns the value of the field.
+func (o *Record) Field(name string) (Object, bool) {
+	i, ok := o.Type.index[name]
+	if !ok {
+		return nil, false
+	}
+	return o.Values[i], true
+}
+
+// SetField sets the value of the field. It returns ErrUnknownField error if
+// the record doesn't have the field.
+func (o *Record) SetField(name string, value Object) error {
+	i, ok := o.Type.index[name]
+	if !ok {
+		return fmt.Errorf("%w '%s' in %s", ErrUnknownField, name, o.Type.Name)
+	}
+	o.Values[i] = value
+	return nil
+}
+
+// IndexGet returns the value of the field, or the method bound to the record.
+func (o *Record) IndexGet(index Object) (Object, error) {
+	name, ok := index.(*String)
+	if !ok {
+		return nil, ErrInvalidIndexType
+	}
+	if i, ok := o.Type.index[name.Value]; ok {
+		return o.Values[i], nil
+	}
+	if fn, ok := o.Type.Methods[name.Value]; ok {
+		return &BoundMethod{Fn: fn, Recv: o}, nil
+	}
+	return nil, fmt.Errorf("%w '%s' in %s",
+		ErrUnknownField, name.Value, o.Type.Name)
+}
+
+// IndexSet sets the value of the field.
+func (o *Record) IndexSet(index, value Object) error {
+	name, ok := index.(*String)
+	if !ok {
+		return ErrInvalidIndexType
+	}
+	return o.SetField(name.Value, value)
+}
+
+// BoundMethod represents a method of a record bound to the record. The VM
+// calls the method function with the record as the first argument.
+type BoundMethod struct {
+	ObjectImpl
+	Fn   *CompiledFunction
+	Recv Object
+}
+
+// TypeName returns the name of the type.
+func (o *BoundMethod) TypeName() string {
+	return "bound-method"
+}
+
+func (o *BoundMethod) String() string {
+	return "<bound-method>"
+}
+
+// Copy returns a copy of the type.
+func (o *BoundMethod) Copy() Object {
+	return &BoundMethod{Fn: o.Fn, Recv: o.Recv}
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (o *BoundMethod) Equals(x Object) bool {
+	t, ok := x.(*BoundMethod)
+	return ok && o.Fn == t.Fn && o.Recv == t.Recv
+}
+
+// CanCall returns whether the Object can be Called.
+func (o *BoundMethod) CanCall() bool {
+	return true
+}
@@ -0,0 +1,641 @@
+package tengo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	typeObject   = reflect.TypeOf((*Object)(nil)).Elem()
+	typeError    = reflect.TypeOf((*error)(nil)).Elem()
+	typeTime     = reflect.TypeOf(time.Time{})
+	typeDuration = reflect.TypeOf(time.Duration(0))
+	typeBigInt   = reflect.TypeOf((*big.Int)(nil))
+	typeDecimal  = reflect.TypeOf((*big.Rat)(nil))
+
+	// structInfos is the cache of the metadata of the struct types.
+	structInfos sync.Map // reflect.Type -> *structInfo
+)
+
+// GoStruct represents a Go struct value. The fields of the struct can be
+// read and written by the index operators, and the exported methods can be
+// called as functions. The field values and the arguments of the methods are
+// converted between the objects and the Go values automatically.
+//
+// A field is named by the "tengo" struct tag or by the field name, and a
+// field with "-" tag is not exposed. The fields of an embedded struct are
+// promoted as if they were the fields of the outer struct.
+type GoStruct struct {
+	ObjectImpl
+	Value reflect.Value // pointer to the struct
+}
+
+// NewGoStruct creates a GoStruct of a struct or a pointer to a struct. If v
+// is a struct, the GoStruct holds a copy of it.
+func NewGoStruct(v interface{}) (*GoStruct, error) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Kind() == reflect.Struct:
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		rv = p
+	case rv.Kind() != reflect.Ptr || rv.Type().Elem().Kind() != reflect.Struct:
+		return nil, fmt.Errorf("not a struct: %T", v)
+	case rv.IsNil():
+		return nil, fmt.Errorf("nil pointer: %T", v)
+	}
+	return &GoStruct{Value: rv}, nil
+}
+
+// TypeName returns the name of the struct type.
+func (o *GoStruct) TypeName() string {
+	return o.Value.Type().Elem().String()
+}
+
+// String returns the result of String method if the struct implements
+// fmt.Stringer; or the string representation of the fields otherwise.
+func (o *GoStruct) String() string {
+	if s, ok := o.Value.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	info := structInfoOf(o.Value.Type().Elem())
+	var pairs []string
+	for _, name := range info.names {
+		f := o.Value.Elem().FieldByIndex(info.fields[name])
+		var s string
+		if v, err := FromInterface(f.Interface()); err == nil {
+			s = v.String()
+		} else {
+			s = fmt.Sprint(f.Interface())
+		}
+		pairs = append(pairs, fmt.Sprintf("%s: %s", name, s))
+	}
+	return o.TypeName() + "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Copy returns a shallow copy of the struct.
+func (o *GoStruct) Copy() Object {
+	p := reflect.New(o.Value.Type().Elem())
+	p.Elem().Set(o.Value.Elem())
+	return &GoStruct{Value: p}
+}
+
+// Equals returns true if the other object is a GoStruct of the same pointer.
+func (o *GoStruct) Equals(x Object) bool {
+	t, ok := x.(*GoStruct)
+	return ok && o.Value.Pointer() == t.Value.Pointer() &&
+		o.Value.Type() == t.Value.Type()
+}
+
+// IndexGet returns the value of the field, or the method bound to the
+// struct. The value of a struct field is a GoStruct that points to the field
+// so that the changes to it are made to the outer struct, while the values of
+// slice and map fields are copies.
+func (o *GoStruct) IndexGet(index Object) (Object, error) {
+	name, ok := index.(*String)
+	if !ok {
+		return nil, ErrInvalidIndexType
+	}
+	info := structInfoOf(o.Value.Type().Elem())
+	if idx, ok := info.fields[name.Value]; ok {
+		f := o.Value.Elem().FieldByIndex(idx)
+		if f.Kind() == reflect.Struct && f.Type() != typeTime {
+			return &GoStruct{Value: f.Addr()}, nil
+		}
+		return FromInterface(f.Interface())
+	}
+	if m := o.Value.MethodByName(name.Value); m.IsValid() {
+		return &UserFunction{Name: name.Value, Value: goFunc(m)}, nil
+	}
+	return nil, fmt.Errorf("%w '%s' in %s",
+		ErrUnknownField, name.Value, o.TypeName())
+}
+
+// IndexSet sets the value of the field. The value is converted to the type
+// of the field.
+func (o *GoStruct) IndexSet(index, value Object) error {
+	name, ok := index.(*String)
+	if !ok {
+		return ErrInvalidIndexType
+	}
+	info := structInfoOf(o.Value.Type().Elem())
+	idx, ok := info.fields[name.Value]
+	if !ok {
+		return fmt.Errorf("%w '%s' in %s",
+			ErrUnknownField, name.Value, o.TypeName())
+	}
+	f := o.Value.Elem().FieldByIndex(idx)
+	v, ok := toReflect(value, f.Type())
+	if !ok {
+		return argTypeError(name.Value, value, f.Type())
+	}
+	f.Set(v)
+	return nil
+}
+
+// structInfo is the metadata of a struct type.
+type structInfo struct {
+	names  []string         // field names in the declared order
+	fields map[string][]int // field name to field index sequence
+}
+
+func structInfoOf(t reflect.Type) *structInfo {
+	if info, ok := structInfos.Load(t); ok {
+		return info.(*structInfo)
+	}
+	info := &structInfo{fields: make(map[string][]int)}
+	info.add(t, nil)
+	actual, _ := structInfos.LoadOrStore(t, info)
+	return actual.(*structInfo)
+}
+
+// add adds the exported fields of the struct type t, and then the promoted
+// fields of its embedded structs that are not shadowed.
+func (info *structInfo) add(t reflect.Type, index []int) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("tengo")
+		if tag == "-" {
+			continue
+		}
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			tag = tag[:j] // the options are ignored
+		}
+		f.Index = append(append([]int{}, index...), i)
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, f)
+			continue
+		}
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		name := f.Name
+		if tag != "" {
+			name = tag
+		}
+		if _, ok := info.fields[name]; !ok {
+			info.names = append(info.names, name)
+			info.fields[name] = f.Index
+		}
+	}
+	for _, f := range embedded {
+		info.add(f.Type, f.Index)
+	}
+}
+
+// WrapFunc returns a CallableFunc that calls the Go function fn of any
+// signature. The signature is inspected once, and the arguments are converted
+// to the parameter types on each call: a value that can't be converted is
+// ErrInvalidArgumentType error that names the argument, or the element of an
+// array or a map argument. An int or a duration string (e.g. "1.5s") can be
+// passed for a time.Duration parameter.
+//
+// If the last result of fn is an error, a non-nil error is returned as an
+// Error object. The other results are returned as a value, or as an array if
+// there are more than one. It panics if fn is not a function.
+func WrapFunc(fn interface{}) CallableFunc {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		panic(fmt.Sprintf("tengo: WrapFunc of non-function %T", fn))
+	}
+	return goFunc(v)
+}
+
+func goFunc(fn reflect.Value) CallableFunc {
+	t := fn.Type()
+	params := make([]reflect.Type, t.NumIn())
+	for i := range params {
+		params[i] = t.In(i)
+	}
+	variadic := t.IsVariadic()
+	if variadic {
+		params[len(params)-1] = params[len(params)-1].Elem()
+	}
+	numOut := t.NumOut()
+	errOut := numOut > 0 && t.Out(numOut-1) == typeError
+	return func(args ...Object) (Object, error) {
+		if variadic {
+			if len(args) < len(params)-1 {
+				return nil, ErrWrongNumArguments
+			}
+		} else if len(args) != len(params) {
+			return nil, ErrWrongNumArguments
+		}
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			pt := params[len(params)-1]
+			if i < len(params) {
+				pt = params[i]
+			}
+			v, ok := toReflect(arg, pt)
+			if !ok {
+				return nil, argTypeError(argName(i), arg, pt)
+			}
+			in[i] = v
+		}
+		out := fn.Call(in)
+		if errOut {
+			if err := out[numOut-1]; !err.IsNil() {
+				return FromInterface(err.Interface())
+			}
+			out = out[:numOut-1]
+		}
+		switch len(out) {
+		case 0:
+			return UndefinedValue, nil
+		case 1:
+			return FromInterface(out[0].Interface())
+		}
+		arr := make([]Object, len(out))
+		for i, v := range out {
+			o, err := FromInterface(v.Interface())
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = o
+		}
+		return &Array{Value: arr}, nil
+	}
+}
+
+// argTypeError returns the error of the value o of the argument name that
+// can't be converted to the type t. If o is an array or a map, the error
+// names the first element that can't be converted.
+func argTypeError(name string, o Object, t reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		var elems []Object
+		switch o := o.(type) {
+		case *Array:
+			elems = o.Value
+		case *ImmutableArray:
+			elems = o.Value
+		}
+		for i, e := range elems {
+			if _, ok := toReflect(e, t.Elem()); !ok {
+				return argTypeError(fmt.Sprintf("%s[%d]", name, i), e,
+					t.Elem())
+			}
+		}
+	case reflect.Map, reflect.Struct:
+		var kv map[string]Object
+		switch o := o.(type) {
+		case *Map:
+			kv = o.Value
+		case *ImmutableMap:
+			kv = o.Value
+		}
+		for key, e := range kv {
+			et := t
+			if t.Kind() == reflect.Map {
+				et = t.Elem()
+			} else if idx, ok := structInfoOf(t).fields[key]; ok {
+				et = t.FieldByIndex(idx).Type
+			} else {
+				return fmt.Errorf("%w '%s.%s' in %s",
+					ErrUnknownField, name, key, t)
+			}
+			if _, ok := toReflect(e, et); !ok {
+				return argTypeError(fmt.Sprintf("%s.%s", name, key), e, et)
+			}
+		}
+	}
+	return ErrInvalidArgumentType{
+		Name:     name,
+		Expected: goTypeName(t),
+		Found:    o.TypeName(),
+	}
+}
+
+var argNames = []string{"first", "second", "third", "fourth", "fifth",
+	"sixth", "seventh", "eighth", "ninth", "tenth"}
+
+func argName(i int) string {
+	if i < len(argNames) {
+		return argNames[i]
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// fromReflect converts the Go value v to an object. It's used by
+// FromInterface for the values of the types that it doesn't handle directly.
+func fromReflect(v reflect.Value) (Object, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return &Int{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		if u := v.Uint(); u > 1<<63-1 {
+			return &BigInt{Value: new(big.Int).SetUint64(u)}, nil
+		}
+		return &Int{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.Bool:
+		if v.Bool() {
+			return TrueValue, nil
+		}
+		return FalseValue, nil
+	case reflect.String:
+		return FromInterface(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return UndefinedValue, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return FromInterface(b)
+		}
+		arr := make([]Object, v.Len())
+		for i := range arr {
+			o, err := FromInterface(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = o
+		}
+		return &Array{Value: arr}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return UndefinedValue, nil
+		}
+		m := &Map{Value: make(map[string]Object, v.Len())}
+		iter := v.MapRange()
+		for iter.Next() {
+			val, err := FromInterface(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			if iter.Key().Kind() == reflect.String {
+				m.Value[iter.Key().String()] = val
+				continue
+			}
+			key, err := FromInterface(iter.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+			if err := m.IndexSet(key, val); err != nil {
+				return nil, fmt.Errorf("cannot convert to map key: %s",
+					iter.Key().Type())
+			}
+		}
+		return m, nil
+	case reflect.Struct:
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		return &GoStruct{Value: p}, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return UndefinedValue, nil
+		}
+		return &UserFunction{Value: goFunc(v)}, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return UndefinedValue, nil
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return &GoStruct{Value: v}, nil
+		}
+		return FromInterface(v.Elem().Interface())
+	}
+	return nil, fmt.Errorf("cannot convert to object: %s", v.Type())
+}
+
+// toReflect converts the object o to a Go value of the type t. It returns
+// false if o can't be converted.
+func toReflect(o Object, t reflect.Type) (reflect.Value, bool) {
+	switch t {
+	case typeTime:
+		v, ok := ToTime(o)
+		return reflect.ValueOf(v), ok
+	case typeDuration:
+		v, ok := ToDuration(o)
+		return reflect.ValueOf(v), ok
+	case typeBigInt:
+		v, ok := ToBigInt(o)
+		return reflect.ValueOf(v), ok
+	case typeDecimal:
+		v, ok := ToDecimal(o)
+		return reflect.ValueOf(v), ok
+	}
+	if reflect.TypeOf(o).AssignableTo(t) &&
+		(t.Kind() != reflect.Interface || t.Implements(typeObject)) {
+		return reflect.ValueOf(o), true
+	}
+	if s, ok := o.(*GoStruct); ok {
+		switch {
+		case s.Value.Type().AssignableTo(t):
+			return s.Value, true
+		case s.Value.Type().Elem().AssignableTo(t):
+			return s.Value.Elem(), true
+		}
+	}
+	v := reflect.New(t).Elem()
+	if o == UndefinedValue {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			return v, true // nil
+		}
+		return v, false
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		i, ok := ToStrictInt64(o)
+		if !ok || v.OverflowInt(i) {
+			return v, false
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		i, ok := ToBigInt(o)
+		if !ok || hasFraction(o) || !i.IsUint64() ||
+			v.OverflowUint(i.Uint64()) {
+			return v, false
+		}
+		v.SetUint(i.Uint64())
+	case reflect.Float32, reflect.Float64:
+		f, ok := ToFloat64(o)
+		if !ok {
+			return v, false
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, ok := ToBool(o)
+		if !ok {
+			return v, false
+		}
+		v.SetBool(b)
+	case reflect.String:
+		s, ok := ToStrictString(o)
+		if !ok {
+			return v, false
+		}
+		v.SetString(s)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			if b, ok := ToByteSlice(o); ok {
+				if t.Kind() == reflect.Slice {
+					v.Set(reflect.MakeSlice(t, len(b), len(b)))
+				} else if len(b) != t.Len() {
+					return v, false
+				}
+				reflect.Copy(v, reflect.ValueOf(b))
+				return v, true
+			}
+		}
+		var elems []Object
+		switch o := o.(type) {
+		case *Array:
+			elems = o.Value
+		case *ImmutableArray:
+			elems = o.Value
+		default:
+			return v, false
+		}
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, len(elems), len(elems)))
+		} else if len(elems) != t.Len() {
+			return v, false
+		}
+		for i, e := range elems {
+			ev, ok := toReflect(e, t.Elem())
+			if !ok {
+				return v, false
+			}
+			v.Index(i).Set(ev)
+		}
+	case reflect.Map:
+		var kv map[string]Object
+		var hashed *HashedMap
+		switch o := o.(type) {
+		case *Map:
+			kv, hashed = o.Value, o.Hashed
+		case *ImmutableMap:
+			kv, hashed = o.Value, o.Hashed
+		default:
+			return v, false
+		}
+		entries := hashed.Entries()
+		for key, value := range kv {
+			entries = append(entries,
+				&MapEntry{Key: &String{Value: key}, Value: value})
+		}
+		v.Set(reflect.MakeMapWithSize(t, len(entries)))
+		for _, e := range entries {
+			kv, ok := toReflect(e.Key, t.Key())
+			if !ok {
+				return v, false
+			}
+			ev, ok := toReflect(e.Value, t.Elem())
+			if !ok {
+				return v, false
+			}
+			v.SetMapIndex(kv, ev)
+		}
+	case reflect.Struct:
+		var kv map[string]Object
+		switch o := o.(type) {
+		case *Map:
+			kv = o.Value
+		case *ImmutableMap:
+			kv = o.Value
+		default:
+			return v, false
+		}
+		info := structInfoOf(t)
+		for name, e := range kv {
+			idx, ok := info.fields[name]
+			if !ok {
+				return v, false
+			}
+			f := v.FieldByIndex(idx)
+			fv, ok := toReflect(e, f.Type())
+			if !ok {
+				return v, false
+			}
+			f.Set(fv)
+		}
+	case reflect.Ptr:
+		ev, ok := toReflect(o, t.Elem())
+		if !ok {
+			return v, false
+		}
+		v.Set(reflect.New(t.Elem()))
+		v.Elem().Set(ev)
+	case reflect.Interface:
+		var i interface{}
+		if e, ok := o.(*Error); ok {
+			i = errors.New(e.Value.String())
+		} else {
+			i = ToInterface(o)
+		}
+		if i == nil || !reflect.TypeOf(i).AssignableTo(t) {
+			return v, false
+		}
+		v.Set(reflect.ValueOf(i))
+	default:
+		return v, false
+	}
+	return v, true
+}
+
+// hasFraction returns true if the number o has a fractional part, which is
+// lost when it's converted to an integer.
+func hasFraction(o Object) bool {
+	switch o := o.(type) {
+	case *Float:
+		return o.Value != math.Trunc(o.Value)
+	case *Decimal:
+		return !o.Value.IsInt()
+	}
+	return false
+}
+
+// goTypeName returns the name of the object type that is converted to the Go
+// type t.
+func goTypeName(t reflect.Type) string {
+	switch t {
+	case typeTime:
+		return "time"
+	case typeDuration:
+		return "int/string"
+	case typeBigInt:
+		return "bigint"
+	case typeDecimal:
+		return "decimal"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		return "int"
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return "int(" + t.Kind().String() + ")"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+		return "array"
+	case reflect.Map:
+		return "map"
+	case reflect.Ptr:
+		return goTypeName(t.Elem())
+	}
+	return t.String()
+}
@@ -160,9 +160,13 @@ func Equal(
 	case *tengo.Map:
 		equalObjectMap(t, expected.Value,
 			actual.(*tengo.Map).Value, msg...)
+		equalHashedMap(t, expected.Hashed,
+			actual.(*tengo.Map).Hashed, msg...)
 	case *tengo.ImmutableMap:
 		equalObjectMap(t, expected.Value,
 			actual.(*tengo.ImmutableMap).Value, msg...)
+		equalHashedMap(t, expected.Hashed,
+			actual.(*tengo.ImmutableMap).Hashed, msg...)
 	case *tengo.CompiledFunction:
 		equalCompiledFunction(t, expected,
 			actual.(*tengo.CompiledFunction), msg...)
@@ -295,6 +299,21 @@ func equalObjectMap(
 	}
 }
 
+func equalHashedMap(
+	t *testing.T,
+	expected, actual *tengo.HashedMap,
+	msg ...interface{},
+) {
+	Equal(t, expected.Len(), actual.Len(), msg...)
+	for _, e := range expected.Entries() {
+		actualVal, ok := actual.Get(e.Key)
+		True(t, ok, msg...)
+		if ok {
+			Equal(t, e.Value, actualVal, msg...)
+		}
+	}
+}
+
 func equalCompiledFunction(
 	t *testing.T,
 	expected, actual tengo.Object,
@@ -15,9 +15,19 @@ type Script struct {
 	modules          *ModuleMap
 	input            []byte
 	maxAllocs        int64
+	maxInsts         int64
+	instCosts        *InstructionCosts
+	maxStack         int
+	maxFrames        int
+	maxMemory        int64
 	maxConstObjects  int
+	checked          bool
 	enableFileImport bool
 	importDir        string
+	fsImportDir      string // import directory in fs
+	fs               FS
+	caps             []string
+	auditHook        func(AuditEvent)
 }
 
 // NewScript creates a Script instance with an input script.
@@ -26,6 +36,10 @@ func NewScript(input []byte) *Script {
 		variables:       make(map[string]*Variable),
 		input:           input,
 		maxAllocs:       -1,
+		maxInsts:        -1,
+		maxStack:        StackSize,
+		maxFrames:       MaxFrames,
+		maxMemory:       -1,
 		maxConstObjects: -1,
 	}
 }
@@ -59,7 +73,10 @@ func (s *Script) SetImports(modules *ModuleMap) {
 }
 
 // SetImportDir sets the initial import directory for script files.
+// If the script has a file system (see SetFS), dir is a path in the file
+// system.
 func (s *Script) SetImportDir(dir string) error {
+	s.fsImportDir = dir
 	dir, err := filepath.Abs(dir)
 	if err != nil {
 		return err
@@ -75,18 +92,85 @@ func (s *Script) SetMaxAllocs(n int64) {
 	s.maxAllocs = n
 }
 
+// SetMaxInstructions sets the maximum total cost of the instructions executed
+// during the run time. Compiled script will return ErrInstructionLimit error
+// if it exceeds this limit. Each instruction costs 1 unless the costs are set
+// using SetInstructionCosts.
+func (s *Script) SetMaxInstructions(n int64) {
+	s.maxInsts = n
+}
+
+// SetInstructionCosts sets the costs of the instructions counted against the
+// instruction limit.
+func (s *Script) SetInstructionCosts(costs *InstructionCosts) {
+	s.instCosts = costs
+}
+
+// SetMaxMemory sets the maximum number of bytes allocated during the run time.
+// The sizes of strings, bytes, arrays and maps are estimated when they're
+// created. Compiled script will return ErrMemoryLimit error if it exceeds this
+// limit.
+func (s *Script) SetMaxMemory(n int64) {
+	s.maxMemory = n
+}
+
+// SetMaxStackSize sets the maximum number of objects in the stack of the VM.
+// The stack grows as needed up to this size, and compiled script will return
+// ErrStackOverflow error if it needs more.
+func (s *Script) SetMaxStackSize(n int) {
+	s.maxStack = n
+}
+
+// SetMaxFrames sets the maximum number of function frames of the VM, which
+// limits the depth of the function calls. Compiled script will return
+// ErrStackOverflow error if it exceeds this limit.
+func (s *Script) SetMaxFrames(n int) {
+	s.maxFrames = n
+}
+
 // SetMaxConstObjects sets the maximum number of objects in the compiled
 // constants.
 func (s *Script) SetMaxConstObjects(n int) {
 	s.maxConstObjects = n
 }
 
+// SetCheckedArithmetic enables or disables the checked arithmetic mode.
+// Compiled script will return ErrIntegerOverflow error if an int operation
+// overflows, and ErrDivisionByZero error if an int is divided by zero.
+func (s *Script) SetCheckedArithmetic(enable bool) {
+	s.checked = enable
+}
+
 // EnableFileImport enables or disables module loading from local files. Local
 // file modules are disabled by default.
 func (s *Script) EnableFileImport(enable bool) {
 	s.enableFileImport = enable
 }
 
+// SetFS sets the file system that the script accesses instead of the host's
+// file system: the file imports and the functions of the "os" module read and
+// write the files of fsys. The functions of the "os" module that fsys doesn't
+// support fail with a permission error. See DirFS, MemFS and ReadOnlyFS.
+func (s *Script) SetFS(fsys FS) {
+	s.fs = fsys
+}
+
+// SetCapabilities restricts the privileged functions of the standard library,
+// such as "os.exec", to the granted capabilities. The denied functions return
+// a permission error. A capability also grants the capabilities under it,
+// e.g. "os" grants "os.env.read" and "os.exec". The privileged functions are
+// unrestricted by default.
+func (s *Script) SetCapabilities(caps ...string) {
+	s.caps = append([]string{}, caps...)
+}
+
+// SetAuditHook sets a function that's called with every call of the
+// privileged functions, whether it's allowed or not. It may be called
+// concurrently if the script spawns goroutines.
+func (s *Script) SetAuditHook(hook func(event AuditEvent)) {
+	s.auditHook = hook
+}
+
 // Compile compiles the script with all the defined variables, and, returns
 // Compiled object.
 func (s *Script) Compile() (*Compiled, error) {
@@ -106,6 +190,10 @@ func (s *Script) Compile() (*Compiled, error) {
 	c := NewCompiler(srcFile, symbolTable, nil, s.modules, nil)
 	c.EnableFileImport(s.enableFileImport)
 	c.SetImportDir(s.importDir)
+	if s.fs != nil {
+		c.SetFS(s.fs)
+		c.SetImportDir(s.fsImportDir)
+	}
 	if err := c.Compile(file); err != nil {
 		return nil, err
 	}
@@ -138,6 +226,15 @@ func (s *Script) Compile() (*Compiled, error) {
 		bytecode:      bytecode,
 		globals:       globals,
 		maxAllocs:     s.maxAllocs,
+		maxInsts:      s.maxInsts,
+		instCosts:     s.instCosts,
+		maxStack:      s.maxStack,
+		maxFrames:     s.maxFrames,
+		maxMemory:     s.maxMemory,
+		checked:       s.checked,
+		fs:            s.fs,
+		caps:          s.caps,
+		auditHook:     s.auditHook,
 	}, nil
 }
 
@@ -199,6 +296,16 @@ type Compiled struct {
 	bytecode      *Bytecode
 	globals       []Object
 	maxAllocs     int64
+	maxInsts      int64
+	instCosts     *InstructionCosts
+	insts         int64
+	maxStack      int
+	maxFrames     int
+	maxMemory     int64
+	checked       bool
+	fs            FS
+	caps          []string
+	auditHook     func(AuditEvent)
 	lock          sync.RWMutex
 }
 
@@ -207,8 +314,10 @@ func (c *Compiled) Run() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	v := NewVM(c.bytecode, c.globals, c.maxAllocs)
-	return v.Run()
+	v := c.newVM()
+	err := v.Run()
+	c.insts = v.InstructionCount()
+	return err
 }
 
 // RunContext is like Run but includes a context.
@@ -216,7 +325,7 @@ func (c *Compiled) RunContext(ctx context.Context) (err error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	v := NewVM(c.bytecode, c.globals, c.maxAllocs)
+	v := c.newVM()
 	ch := make(chan error, 1)
 	go func() {
 		ch <- v.Run()
@@ -229,9 +338,35 @@ func (c *Compiled) RunContext(ctx context.Context) (err error) {
 		err = ctx.Err()
 	case err = <-ch:
 	}
+	c.insts = v.InstructionCount()
 	return
 }
 
+func (c *Compiled) newVM() *VM {
+	v := NewVM(c.bytecode, c.globals, c.maxAllocs)
+	v.SetMaxInstructions(c.maxInsts)
+	v.SetMaxStackSize(c.maxStack)
+	v.SetMaxFrames(c.maxFrames)
+	v.SetMaxMemory(c.maxMemory)
+	v.SetCheckedArithmetic(c.checked)
+	v.SetFS(c.fs)
+	v.SetCapabilities(c.caps)
+	v.SetAuditHook(c.auditHook)
+	if c.instCosts != nil {
+		v.SetInstructionCosts(c.instCosts)
+	}
+	return v
+}
+
+// InstructionCount returns the total cost of the instructions executed in the
+// last run.
+func (c *Compiled) InstructionCount() int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.insts
+}
+
 // Clone creates a new copy of Compiled. Cloned copies are safe for concurrent
 // use by multiple goroutines.
 func (c *Compiled) Clone() *Compiled {
@@ -243,6 +378,15 @@ func (c *Compiled) Clone() *Compiled {
 		bytecode:      c.bytecode,
 		globals:       make([]Object, len(c.globals)),
 		maxAllocs:     c.maxAllocs,
+		maxInsts:      c.maxInsts,
+		instCosts:     c.instCosts,
+		maxStack:      c.maxStack,
+		maxFrames:     c.maxFrames,
+		maxMemory:     c.maxMemory,
+		checked:       c.checked,
+		fs:            c.fs,
+		caps:          c.caps,
+		auditHook:     c.auditHook,
 	}
 	// copy global objects
 	for idx, g := range c.globals {
@@ -4,13 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
 	"github.com/d5/tengo/v2/require"
 	"github.com/d5/tengo/v2/stdlib"
 	"github.com/d5/tengo/v2/token"
@@ -40,6 +45,30 @@ func TestScript_Add(t *testing.T) {
 	require.Equal(t, int64(6), c.Get("d").Value())
 }
 
+func TestScript_AddStruct(t *testing.T) {
+	type account struct {
+		Owner   string `tengo:"owner"`
+		Balance int64  `tengo:"balance"`
+		History []int64
+	}
+	acc := &account{Owner: "foo", Balance: 10}
+	s := tengo.NewScript([]byte(`
+acc.balance += 5
+acc.History = [10, 5]
+{owner} := acc
+name := owner + ":" + type_name(acc)
+err := undefined
+try { acc.balance = "x" } catch e { err = e }`))
+	require.NoError(t, s.Add("acc", acc))
+	c, err := s.Run()
+	require.NoError(t, err)
+	require.Equal(t, int64(15), acc.Balance)
+	require.Equal(t, 2, len(acc.History))
+	require.Equal(t, "foo:tengo_test.account", c.Get("name").String())
+	require.False(t, c.Get("err").IsUndefined())
+	require.True(t, c.Get("acc").Value() == acc)
+}
+
 func TestScript_Remove(t *testing.T) {
 	s := tengo.NewScript([]byte(`a := b`))
 	err := s.Add("b", 5)
@@ -136,6 +165,382 @@ func TestScript_SetMaxConstObjects(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestScript_SetMaxInstructions(t *testing.T) {
+	s := tengo.NewScript([]byte(`for {}`))
+	s.SetMaxInstructions(1000)
+	c, err := s.Compile()
+	require.NoError(t, err)
+	err = c.Run()
+	require.True(t, errors.Is(err, tengo.ErrInstructionLimit))
+	require.Equal(t, int64(1001), c.InstructionCount())
+
+	// the limit error cannot be caught
+	s = tengo.NewScript([]byte(`a := 0; try { for {} } catch e { a = 1 }`))
+	s.SetMaxInstructions(1000)
+	c, err = s.Compile()
+	require.NoError(t, err)
+	err = c.Run()
+	require.True(t, errors.Is(err, tengo.ErrInstructionLimit))
+
+	// count is available after a successful run
+	s = tengo.NewScript([]byte(`a := 0; for i := 0; i < 10; i++ { a += i }`))
+	s.SetMaxInstructions(1000)
+	c, err = s.Compile()
+	require.NoError(t, err)
+	require.NoError(t, c.Run())
+	count := c.InstructionCount()
+	require.True(t, count > 10 && count <= 1000)
+
+	// no limit set
+	c, err = tengo.NewScript([]byte(`a := 0; for i := 0; i < 10000; i++ {}`)).
+		Compile()
+	require.NoError(t, err)
+	require.NoError(t, c.Run())
+	require.True(t, c.InstructionCount() > 10000)
+}
+
+func TestScript_SetInstructionCosts(t *testing.T) {
+	src := []byte(`a := 0; for i := 0; i < 10; i++ { a += len([i]) }`)
+	run := func(costs *tengo.InstructionCosts) (int64, error) {
+		s := tengo.NewScript(src)
+		s.SetMaxInstructions(1000)
+		if costs != nil {
+			s.SetInstructionCosts(costs)
+		}
+		c, err := s.Compile()
+		require.NoError(t, err)
+		err = c.Run()
+		return c.InstructionCount(), err
+	}
+
+	base, err := run(nil)
+	require.NoError(t, err)
+
+	// 10 builtin function calls
+	count, err := run(&tengo.InstructionCosts{GoCall: 10})
+	require.NoError(t, err)
+	require.Equal(t, base+100, count)
+
+	// 10 array literals cost 5 instead of 1
+	count, err = run(&tengo.InstructionCosts{
+		Opcodes: map[parser.Opcode]int64{parser.OpArray: 5},
+	})
+	require.NoError(t, err)
+	require.Equal(t, base+40, count)
+
+	_, err = run(&tengo.InstructionCosts{GoCall: 100})
+	require.True(t, errors.Is(err, tengo.ErrInstructionLimit))
+	require.Equal(t, "Runtime Error: instruction limit exceeded\n\tat (main):1:40",
+		err.Error())
+}
+
+func TestScript_SetMaxMemory(t *testing.T) {
+	run := func(src string, maxMemory int64) error {
+		s := tengo.NewScript([]byte(src))
+		s.SetImports(stdlib.GetModuleMap("text"))
+		s.SetMaxMemory(maxMemory)
+		c, err := s.Compile()
+		require.NoError(t, err)
+		return c.Run()
+	}
+	expectLimit := func(src string, maxMemory int64) {
+		require.NoError(t, run(src, -1), src)
+		err := run(src, maxMemory)
+		require.True(t, errors.Is(err, tengo.ErrMemoryLimit), src)
+	}
+
+	expectLimit(`a := bytes(1000)`, 999)
+	require.NoError(t, run(`a := bytes(1000)`, 1000))
+	expectLimit(`a := string(bytes(600))`, 1000)
+	expectLimit(`a := "x"; for i := 0; i < 10; i++ { a += a }`, 1000)
+	expectLimit(`a := []; for i := 0; i < 100; i++ { a = append(a, i) }`,
+		1000)
+	expectLimit(`a := [1, 2, 3]; b := splice(a, 0, 1, 4, 5)`, 50)
+	expectLimit(`a := [0, 0, 0, 0, 0, 0, 0, 0, 0, 0]`, 100)
+	expectLimit(`a := {foo: 1, bar: 2}`, 50)
+	expectLimit(`a := f"${1:%200d}"`, 100)
+	expectLimit(`a := import("text").repeat("x", 1000)`, 999)
+	expectLimit(`a := import("text").join(["x", "y", "z"], "--")`, 6)
+
+	// memory limit error cannot be caught
+	err := run(`a := 0; try { b := bytes(1000) } catch e { a = 1 }`, 100)
+	require.True(t, errors.Is(err, tengo.ErrMemoryLimit))
+	require.Equal(t,
+		"Runtime Error: memory limit exceeded\n\tat (main):1:20",
+		err.Error())
+}
+
+func TestScript_SetMaxFrames(t *testing.T) {
+	src := []byte(`f := func(n) { return n == 0 ? 0 : f(n-1) + 1 }; a := f(n)`)
+	run := func(n, maxFrames int) error {
+		s := tengo.NewScript(src)
+		require.NoError(t, s.Add("n", n))
+		s.SetMaxFrames(maxFrames)
+		c, err := s.Compile()
+		require.NoError(t, err)
+		return c.Run()
+	}
+
+	require.NoError(t, run(10, 20))
+	require.True(t, errors.Is(run(30, 20), tengo.ErrStackOverflow))
+	// limits below the initial number of frames
+	require.NoError(t, run(3, 5))
+	require.True(t, errors.Is(run(10, 5), tengo.ErrStackOverflow))
+	require.True(t, errors.Is(run(2000, 0), tengo.ErrStackOverflow))
+
+	s := tengo.NewScript(src)
+	require.NoError(t, s.Add("n", 2000))
+	s.SetMaxFrames(5000)
+	s.SetMaxStackSize(10000)
+	c, err := s.Compile()
+	require.NoError(t, err)
+	require.NoError(t, c.Run())
+	require.Equal(t, int64(2000), c.Get("a").Int64())
+}
+
+func TestScript_SetMaxStackSize(t *testing.T) {
+	src := []byte(`
+f := func(...a) { return len(a) }
+a := f(arr...)`)
+	run := func(n, maxStack int) error {
+		s := tengo.NewScript(src)
+		require.NoError(t, s.Add("arr", make([]interface{}, n)))
+		s.SetMaxStackSize(maxStack)
+		c, err := s.Compile()
+		require.NoError(t, err)
+		return c.Run()
+	}
+
+	require.NoError(t, run(100, 200))
+	require.True(t, errors.Is(run(300, 200), tengo.ErrStackOverflow))
+	// limits below the initial stack size
+	require.NoError(t, run(5, 20))
+	require.True(t, errors.Is(run(20, 10), tengo.ErrStackOverflow))
+	require.NoError(t, run(3000, 5000))
+	require.True(t, errors.Is(run(3000, 0), tengo.ErrStackOverflow))
+}
+
+func TestScript_SetCheckedArithmetic(t *testing.T) {
+	run := func(src string, checked bool) (*tengo.Compiled, error) {
+		s := tengo.NewScript([]byte(src))
+		s.SetCheckedArithmetic(checked)
+		c, err := s.Compile()
+		require.NoError(t, err)
+		return c, c.Run()
+	}
+	expectOverflow := func(src string) {
+		_, err := run(src, false)
+		require.NoError(t, err, src)
+		_, err = run(src, true)
+		require.True(t, errors.Is(err, tengo.ErrIntegerOverflow), src)
+	}
+
+	expectOverflow(`a := 9223372036854775807; a++`)
+	expectOverflow(`a := -9223372036854775807 - 1; a -= 1`)
+	expectOverflow(`a := 4611686018427387904 * 2`)
+	expectOverflow(`a := -9223372036854775807 - 1; b := a * -1`)
+	expectOverflow(`a := -9223372036854775807 - 1; b := -1 * a`)
+	expectOverflow(`a := -9223372036854775807 - 1; b := a / -1`)
+	expectOverflow(`a := -9223372036854775807 - 1; b := -a`)
+	expectOverflow(`a := 1 << 63`)
+	expectOverflow(`a := 3 << 62`)
+
+	_, err := run(`a := 0; b := 1 % a`, true)
+	require.True(t, errors.Is(err, tengo.ErrDivisionByZero))
+
+	c, err := run(`
+a := 9223372036854775807 - 1 + 1
+b := -9223372036854775807 - 1
+c := -1 << 63
+d := 4611686018427387904 * -2
+e := 0 << 100
+f := bigint(9223372036854775807) + 1
+g := 0
+try { g = 9223372036854775807 + 1 } catch err { g = string(err) }`, true)
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), c.Get("a").Int64())
+	require.Equal(t, int64(math.MinInt64), c.Get("b").Int64())
+	require.Equal(t, int64(math.MinInt64), c.Get("c").Int64())
+	require.Equal(t, int64(math.MinInt64), c.Get("d").Int64())
+	require.Equal(t, int64(0), c.Get("e").Int64())
+	require.Equal(t, "9223372036854775808", c.Get("f").String())
+	require.Equal(t, `error: "integer overflow"`, c.Get("g").String())
+}
+
+func TestScript_SetFS(t *testing.T) {
+	fsys := tengo.NewMemFS()
+	require.NoError(t, fsys.WriteFile("lib/a.tengo",
+		[]byte(`export import("./b") + 1`), 0644))
+	require.NoError(t, fsys.WriteFile("lib/b.tengo",
+		[]byte(`export 41`), 0644))
+	require.NoError(t, fsys.WriteFile("data.txt", []byte("foo"), 0644))
+
+	run := func(src string, fsys tengo.FS) (*tengo.Compiled, error) {
+		s := tengo.NewScript([]byte(src))
+		s.SetImports(stdlib.GetModuleMap("os"))
+		s.EnableFileImport(true)
+		s.SetFS(fsys)
+		return s.Run()
+	}
+
+	c, err := run(`
+os := import("os")
+a := import("./lib/a")
+b := string(os.read_file("/data.txt"))
+c := string(os.read_file("../../data.txt"))
+d := string(os.remove("data.txt"))`, tengo.ReadOnlyFS(fsys))
+	require.NoError(t, err)
+	require.Equal(t, int64(42), c.Get("a").Int64())
+	require.Equal(t, "foo", c.Get("b").String())
+	require.Equal(t, "foo", c.Get("c").String())
+	require.Equal(t,
+		`error: "remove data.txt: permission denied"`, c.Get("d").String())
+
+	_, err = run(`import("../secret")`, fsys)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(),
+		"open /secret.tengo: file does not exist"), err.Error())
+
+	// import directory in the file system
+	s := tengo.NewScript([]byte(`a := import("./b")`))
+	s.EnableFileImport(true)
+	s.SetFS(fsys)
+	require.NoError(t, s.SetImportDir("lib"))
+	c, err = s.Run()
+	require.NoError(t, err)
+	require.Equal(t, int64(41), c.Get("a").Int64())
+
+	// the file system is shared with the clones
+	s = tengo.NewScript([]byte(`
+f := import("os").create("out.txt")
+f.write_string(text)
+f.close()`))
+	s.SetImports(stdlib.GetModuleMap("os"))
+	s.SetFS(fsys)
+	require.NoError(t, s.Add("text", "foo"))
+	c, err = s.Compile()
+	require.NoError(t, err)
+	clone := c.Clone()
+	require.NoError(t, clone.Set("text", "bar"))
+	require.NoError(t, clone.Run())
+	b, err := fsys.Open("out.txt")
+	require.NoError(t, err)
+	data := make([]byte, 10)
+	n, _ := b.Read(data)
+	require.Equal(t, "bar", string(data[:n]))
+
+	// the imports in the modules don't fall back to the host's file system
+	dir, err := ioutil.TempDir("", "tengo")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "host.tengo"),
+		[]byte(`export 1`), 0644))
+	mods := tengo.NewModuleMap()
+	mods.AddSourceModule("mod", []byte(`export import("./host")`))
+	require.NoError(t, fsys.WriteFile(filepath.Join(dir, "lib/c.tengo"),
+		[]byte(`export import("../host")`), 0644))
+	for _, src := range []string{
+		`a := import("./host")`,
+		`a := import("mod")`,
+		`a := import("./lib/c")`,
+	} {
+		s = tengo.NewScript([]byte(src))
+		s.SetImports(mods)
+		s.EnableFileImport(true)
+		s.SetFS(fsys)
+		require.NoError(t, s.SetImportDir(dir))
+		_, err = s.Run()
+		require.True(t, err != nil && strings.Contains(err.Error(),
+			"host.tengo: file does not exist"), src, err)
+	}
+}
+
+func TestScript_SetCapabilities(t *testing.T) {
+	run := func(
+		src string,
+		hook func(tengo.AuditEvent),
+		caps ...string,
+	) *tengo.Compiled {
+		s := tengo.NewScript([]byte(src))
+		s.SetImports(stdlib.GetModuleMap("os", "times"))
+		if caps != nil {
+			s.SetCapabilities(caps...)
+		}
+		s.SetAuditHook(hook)
+		c, err := s.Run()
+		require.NoError(t, err)
+		return c
+	}
+	src := `
+os := import("os")
+a := os.getenv("TENGO_TEST_CAPS")
+b := is_error(os.setenv("TENGO_TEST_CAPS", "bar"))
+c := import("times").sleep(0)
+c = is_error(c) ? string(c) : "ok"`
+	require.NoError(t, os.Setenv("TENGO_TEST_CAPS", "foo"))
+	defer func() { _ = os.Unsetenv("TENGO_TEST_CAPS") }()
+
+	// unrestricted by default
+	c := run(src, nil)
+	require.Equal(t, "foo", c.Get("a").String())
+	require.False(t, c.Get("b").Bool())
+	require.Equal(t, "ok", c.Get("c").String())
+
+	// a capability grants the capabilities under it
+	var events []string
+	hook := func(e tengo.AuditEvent) {
+		var args []string
+		for _, arg := range e.Args {
+			args = append(args, arg.String())
+		}
+		events = append(events, fmt.Sprintf("%s(%s) %s %v", e.Function,
+			strings.Join(args, ", "), e.Capability, e.Allowed))
+	}
+	c = run(src, hook, "os.env", "times.now")
+	require.Equal(t, "bar", c.Get("a").String())
+	require.False(t, c.Get("b").Bool())
+	require.Equal(t, `error: "permission denied: times.sleep requires `+
+		`capability 'times.sleep'"`, c.Get("c").String())
+	require.Equal(t, `os.getenv("TENGO_TEST_CAPS") os.env.read true
+os.setenv("TENGO_TEST_CAPS", "bar") os.env.write true
+times.sleep(0) times.sleep false`, strings.Join(events, "\n"))
+
+	// no capabilities
+	c = run(src, nil, []string{}...)
+	require.Equal(t, `error: "permission denied: os.getenv requires `+
+		`capability 'os.env.read'"`, c.Get("a").Object().String())
+	require.True(t, c.Get("b").Bool())
+	require.Equal(t, "bar", os.Getenv("TENGO_TEST_CAPS"))
+
+	// the functions called outside of a VM have no capabilities set, and the
+	// functions called with the VM are checked
+	s := tengo.NewScript([]byte(`
+os := import("os")
+a := call(os.getenv, "TENGO_TEST_CAPS")
+b := call_vm(os.getenv, "TENGO_TEST_CAPS")`))
+	s.SetImports(stdlib.GetModuleMap("os"))
+	require.NoError(t, s.Add("call", &tengo.UserFunction{
+		Value: func(args ...tengo.Object) (tengo.Object, error) {
+			return args[0].Call(args[1:]...)
+		},
+	}))
+	require.NoError(t, s.Add("call_vm", &tengo.VMFunction{
+		Value: func(vm *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
+			return args[0].(tengo.VMCallable).CallVM(vm, args[1:]...)
+		},
+	}))
+	s.SetCapabilities("times")
+	events = nil
+	s.SetAuditHook(hook)
+	c, err := s.Run()
+	require.NoError(t, err)
+	require.Equal(t, "bar", c.Get("a").String())
+	require.Equal(t, `error: "permission denied: os.getenv requires `+
+		`capability 'os.env.read'"`, c.Get("b").Object().String())
+	require.Equal(t, `os.getenv("TENGO_TEST_CAPS") os.env.read false`,
+		strings.Join(events, "\n"))
+}
+
 func TestScriptConcurrency(t *testing.T) {
 	solve := func(a, b, c int) (d, e int) {
 		a += 2
@@ -409,6 +814,16 @@ func TestCompiled_Get(t *testing.T) {
 	compiledGet(t, c, "a", nil)             // a = undefined; because it's before Compiled.Run()
 	compiledRun(t, c)                       // Compiled.Run()
 	compiledGet(t, c, "a", "foo")           // a = "foo"
+
+	// records are converted to maps of the fields
+	c = compile(t, `record P(x, y); a := P(1, "b")`, nil)
+	compiledRun(t, c)
+	m := c.Get("a").Map()
+	require.Equal(t, 2, len(m))
+	require.Equal(t, int64(1), m["x"])
+	require.Equal(t, "b", m["y"])
+	m = c.Get("a").Value().(map[string]interface{})
+	require.Equal(t, "b", m["y"])
 }
 
 func TestCompiled_GetAll(t *testing.T) {
@@ -477,6 +892,23 @@ func TestCompiled_RunContext(t *testing.T) {
 	defer cancel()
 	err = c.RunContext(ctx)
 	require.Equal(t, context.DeadlineExceeded, err)
+
+	// timeout inside a script function called from Go
+	c = compile(t, `apply(func() { for true {} })`, M{
+		"apply": func(
+			vm *tengo.VM,
+			args ...tengo.Object,
+		) (tengo.Object, error) {
+			ret, err := vm.RunCompiled(args[0].(*tengo.CompiledFunction))
+			require.Equal(t, tengo.ErrVMAborted, err)
+			return ret, err
+		},
+	})
+	ctx, cancel = context.WithTimeout(context.Background(),
+		1*time.Millisecond)
+	defer cancel()
+	err = c.RunContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
 }
 
 func compile(t *testing.T, input string, vars M) *tengo.Compiled {
@@ -0,0 +1,575 @@
+package stdlib
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/d5/tengo/v2"
+)
+
+var (
+	errNotInVM          = errors.New("not called by a VM")
+	errClosedChannel    = errors.New("send on closed channel")
+	errCloseClosedChan  = errors.New("close of closed channel")
+	errUnlockedMutex    = errors.New("unlock of unlocked mutex")
+	errNegativeWGCount  = errors.New("negative wait group counter")
+	errInvalidSelectArg = errors.New("invalid select case")
+)
+
+var concurrencyModule = map[string]tengo.Object{
+	"go":         &tengo.VMFunction{Name: "go", Value: concurrencyGo},
+	"wait":       &tengo.VMFunction{Name: "wait", Value: concurrencyWait},
+	"chan":       &tengo.UserFunction{Name: "chan", Value: concurrencyChan},
+	"select":     &tengo.VMFunction{Name: "select", Value: concurrencySelect},
+	"mutex":      &tengo.UserFunction{Name: "mutex", Value: concurrencyMutex},
+	"wait_group": &tengo.UserFunction{Name: "wait_group", Value: concurrencyWaitGroup},
+}
+
+// go(fn, args...) => task
+func concurrencyGo(vm *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
+	if len(args) < 1 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	fn, ok := args[0].(*tengo.CompiledFunction)
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "function",
+			Found:    args[0].TypeName(),
+		}
+	}
+	if vm == nil {
+		return nil, errNotInVM
+	}
+	t := &task{done: make(chan struct{})}
+	err := vm.Spawn(fn, args[1:], func(ret tengo.Object, err error) {
+		if err != nil {
+			ret = wrapError(err)
+		}
+		t.ret = ret
+		close(t.done)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// wait(tasks...) => array
+func concurrencyWait(vm *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
+	results := make([]tengo.Object, 0, len(args))
+	for _, arg := range args {
+		t, ok := arg.(*task)
+		if !ok {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     "tasks",
+				Expected: "task",
+				Found:    arg.TypeName(),
+			}
+		}
+		ret, err := t.wait(vm)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ret)
+	}
+	return &tengo.Array{Value: results}, nil
+}
+
+// chan(size) => chan
+func concurrencyChan(args ...tengo.Object) (tengo.Object, error) {
+	var size int
+	switch len(args) {
+	case 0:
+	case 1:
+		n, ok := tengo.ToInt(args[0])
+		if !ok || n < 0 {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     "first",
+				Expected: "non-negative int",
+				Found:    args[0].TypeName(),
+			}
+		}
+		size = n
+	default:
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return &channel{
+		ch:     make(chan tengo.Object, size),
+		closed: make(chan struct{}),
+	}, nil
+}
+
+// select(cases...) => [index, value]
+//
+// A case is a chan to receive a value from, or an array of a chan and a value
+// to send. It blocks until one of the cases can proceed, and returns the index
+// of the case and the received value.
+func concurrencySelect(
+	vm *tengo.VM,
+	args ...tengo.Object,
+) (tengo.Object, error) {
+	if len(args) == 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+
+	// each case waits on the channel and on the close of the channel
+	cases := make([]reflect.SelectCase, 0, 2*len(args)+1)
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *channel:
+			cases = append(cases, reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(arg.ch),
+			})
+			cases = append(cases, reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(arg.closed),
+			})
+		case *tengo.Array:
+			c, err := selectSendCase(arg.Value)
+			if err != nil {
+				return nil, err
+			}
+			cases = append(cases, c...)
+		case *tengo.ImmutableArray:
+			c, err := selectSendCase(arg.Value)
+			if err != nil {
+				return nil, err
+			}
+			cases = append(cases, c...)
+		default:
+			return nil, errInvalidSelectArg
+		}
+	}
+	if aborted := vm.Aborted(); aborted != nil {
+		cases = append(cases, reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(aborted),
+		})
+	}
+
+	chosen, recv, _ := reflect.Select(cases)
+	idx := chosen / 2
+	if idx == len(args) {
+		return nil, tengo.ErrVMAborted
+	}
+	value := tengo.Object(tengo.UndefinedValue)
+	if c, isRecv := args[idx].(*channel); isRecv {
+		if chosen%2 == 0 {
+			value = recv.Interface().(tengo.Object)
+		} else {
+			value = c.drain()
+		}
+	} else if chosen%2 == 1 {
+		return wrapError(errClosedChannel), nil
+	}
+	return &tengo.Array{
+		Value: []tengo.Object{&tengo.Int{Value: int64(idx)}, value},
+	}, nil
+}
+
+func selectSendCase(arr []tengo.Object) ([]reflect.SelectCase, error) {
+	if len(arr) != 2 {
+		return nil, errInvalidSelectArg
+	}
+	c, ok := arr[0].(*channel)
+	if !ok {
+		return nil, errInvalidSelectArg
+	}
+	value, err := tengo.DeepCopy(arr[1])
+	if err != nil {
+		return nil, err
+	}
+	return []reflect.SelectCase{
+		{
+			Dir:  reflect.SelectSend,
+			Chan: reflect.ValueOf(c.ch),
+			Send: reflect.ValueOf(&value).Elem(),
+		},
+		{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(c.closed),
+		},
+	}, nil
+}
+
+// mutex() => mutex
+func concurrencyMutex(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return &mutex{ch: make(chan struct{}, 1)}, nil
+}
+
+// wait_group() => wait group
+func concurrencyWaitGroup(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return &waitGroup{}, nil
+}
+
+// task represents a function running in a child VM.
+type task struct {
+	tengo.ObjectImpl
+	done chan struct{}
+	ret  tengo.Object
+}
+
+func (o *task) TypeName() string {
+	return "task"
+}
+
+func (o *task) String() string {
+	return "<task>"
+}
+
+func (o *task) Equals(x tengo.Object) bool {
+	return o == x
+}
+
+func (o *task) Copy() tengo.Object {
+	return o
+}
+
+// IndexGet returns the methods of the task:
+//
+//	wait() => the return value of the function, or an error
+func (o *task) IndexGet(index tengo.Object) (tengo.Object, error) {
+	switch name, _ := tengo.ToString(index); name {
+	case "wait":
+		return &tengo.VMFunction{
+			Name: "wait",
+			Value: func(
+				vm *tengo.VM,
+				args ...tengo.Object,
+			) (tengo.Object, error) {
+				if len(args) != 0 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				return o.wait(vm)
+			},
+		}, nil
+	}
+	return tengo.UndefinedValue, nil
+}
+
+func (o *task) wait(vm *tengo.VM) (tengo.Object, error) {
+	select {
+	case <-o.done:
+		return o.ret, nil
+	case <-vm.Aborted():
+		return nil, tengo.ErrVMAborted
+	}
+}
+
+// channel represents a channel of objects. Unlike Go channels, sending to a
+// closed channel returns an error instead of panicking.
+type channel struct {
+	tengo.ObjectImpl
+	ch       chan tengo.Object
+	closed   chan struct{}
+	mu       sync.Mutex
+	isClosed bool
+}
+
+func (o *channel) TypeName() string {
+	return "chan"
+}
+
+func (o *channel) String() string {
+	return "<chan>"
+}
+
+func (o *channel) Equals(x tengo.Object) bool {
+	return o == x
+}
+
+func (o *channel) Copy() tengo.Object {
+	return o
+}
+
+// IndexGet returns the methods of the channel:
+//
+//	send(value) => true/error
+//	recv() => value (undefined if the channel is closed)
+//	close() => true/error
+//	len() => int
+//	cap() => int
+func (o *channel) IndexGet(index tengo.Object) (tengo.Object, error) {
+	switch name, _ := tengo.ToString(index); name {
+	case "send":
+		return &tengo.VMFunction{
+			Name: "send",
+			Value: func(
+				vm *tengo.VM,
+				args ...tengo.Object,
+			) (tengo.Object, error) {
+				if len(args) != 1 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				return o.send(vm, args[0])
+			},
+		}, nil
+	case "recv":
+		return &tengo.VMFunction{
+			Name: "recv",
+			Value: func(
+				vm *tengo.VM,
+				args ...tengo.Object,
+			) (tengo.Object, error) {
+				if len(args) != 0 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				return o.recv(vm)
+			},
+		}, nil
+	case "close":
+		return &tengo.UserFunction{
+			Name: "close",
+			Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) != 0 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				o.mu.Lock()
+				defer o.mu.Unlock()
+				if o.isClosed {
+					return wrapError(errCloseClosedChan), nil
+				}
+				o.isClosed = true
+				close(o.closed)
+				return tengo.TrueValue, nil
+			},
+		}, nil
+	case "len":
+		return &tengo.UserFunction{
+			Name: "len",
+			Value: FuncARI(func() int {
+				return len(o.ch)
+			}),
+		}, nil
+	case "cap":
+		return &tengo.UserFunction{
+			Name: "cap",
+			Value: FuncARI(func() int {
+				return cap(o.ch)
+			}),
+		}, nil
+	}
+	return tengo.UndefinedValue, nil
+}
+
+func (o *channel) send(vm *tengo.VM, value tengo.Object) (tengo.Object, error) {
+	select {
+	case <-o.closed:
+		return wrapError(errClosedChannel), nil
+	default:
+	}
+	// the receiver may run on another VM
+	value, err := tengo.DeepCopy(value)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case o.ch <- value:
+		return tengo.TrueValue, nil
+	case <-o.closed:
+		return wrapError(errClosedChannel), nil
+	case <-vm.Aborted():
+		return nil, tengo.ErrVMAborted
+	}
+}
+
+func (o *channel) recv(vm *tengo.VM) (tengo.Object, error) {
+	select {
+	case value := <-o.ch:
+		return value, nil
+	case <-o.closed:
+		return o.drain(), nil
+	case <-vm.Aborted():
+		return nil, tengo.ErrVMAborted
+	}
+}
+
+// drain returns a value left in the closed channel, or undefined if there's
+// none.
+func (o *channel) drain() tengo.Object {
+	select {
+	case value := <-o.ch:
+		return value
+	default:
+		return tengo.UndefinedValue
+	}
+}
+
+// mutex represents a mutual exclusion lock.
+type mutex struct {
+	tengo.ObjectImpl
+	ch chan struct{}
+}
+
+func (o *mutex) TypeName() string {
+	return "mutex"
+}
+
+func (o *mutex) String() string {
+	return "<mutex>"
+}
+
+func (o *mutex) Equals(x tengo.Object) bool {
+	return o == x
+}
+
+func (o *mutex) Copy() tengo.Object {
+	return o
+}
+
+// IndexGet returns the methods of the mutex:
+//
+//	lock()
+//	unlock() => true/error
+func (o *mutex) IndexGet(index tengo.Object) (tengo.Object, error) {
+	switch name, _ := tengo.ToString(index); name {
+	case "lock":
+		return &tengo.VMFunction{
+			Name: "lock",
+			Value: func(
+				vm *tengo.VM,
+				args ...tengo.Object,
+			) (tengo.Object, error) {
+				if len(args) != 0 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				select {
+				case o.ch <- struct{}{}:
+					return tengo.UndefinedValue, nil
+				case <-vm.Aborted():
+					return nil, tengo.ErrVMAborted
+				}
+			},
+		}, nil
+	case "unlock":
+		return &tengo.UserFunction{
+			Name: "unlock",
+			Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) != 0 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				select {
+				case <-o.ch:
+					return tengo.TrueValue, nil
+				default:
+					return wrapError(errUnlockedMutex), nil
+				}
+			},
+		}, nil
+	}
+	return tengo.UndefinedValue, nil
+}
+
+// waitGroup represents a counter of the tasks to wait for.
+type waitGroup struct {
+	tengo.ObjectImpl
+	mu   sync.Mutex
+	n    int
+	zero chan struct{} // closed when n becomes 0; or nil
+}
+
+func (o *waitGroup) TypeName() string {
+	return "wait-group"
+}
+
+func (o *waitGroup) String() string {
+	return "<wait-group>"
+}
+
+func (o *waitGroup) Equals(x tengo.Object) bool {
+	return o == x
+}
+
+func (o *waitGroup) Copy() tengo.Object {
+	return o
+}
+
+// IndexGet returns the methods of the wait group:
+//
+//	add(delta) => true/error
+//	done() => true/error
+//	wait()
+func (o *waitGroup) IndexGet(index tengo.Object) (tengo.Object, error) {
+	switch name, _ := tengo.ToString(index); name {
+	case "add":
+		return &tengo.UserFunction{
+			Name: "add",
+			Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) != 1 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				delta, ok := tengo.ToInt(args[0])
+				if !ok {
+					return nil, tengo.ErrInvalidArgumentType{
+						Name:     "first",
+						Expected: "int(compatible)",
+						Found:    args[0].TypeName(),
+					}
+				}
+				return wrapError(o.add(delta)), nil
+			},
+		}, nil
+	case "done":
+		return &tengo.UserFunction{
+			Name: "done",
+			Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) != 0 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				return wrapError(o.add(-1)), nil
+			},
+		}, nil
+	case "wait":
+		return &tengo.VMFunction{
+			Name: "wait",
+			Value: func(
+				vm *tengo.VM,
+				args ...tengo.Object,
+			) (tengo.Object, error) {
+				if len(args) != 0 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				o.mu.Lock()
+				if o.n == 0 {
+					o.mu.Unlock()
+					return tengo.UndefinedValue, nil
+				}
+				zero := o.zero
+				o.mu.Unlock()
+				select {
+				case <-zero:
+					return tengo.UndefinedValue, nil
+				case <-vm.Aborted():
+					return nil, tengo.ErrVMAborted
+				}
+			},
+		}, nil
+	}
+	return tengo.UndefinedValue, nil
+}
+
+func (o *waitGroup) add(delta int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.n+delta < 0 {
+		return errNegativeWGCount
+	}
+	if o.n == 0 && delta > 0 {
+		o.zero = make(chan struct{})
+	}
+	o.n += delta
+	if o.n == 0 && o.zero != nil {
+		close(o.zero)
+		o.zero = nil
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package stdlib_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/require"
+	"github.com/d5/tengo/v2/stdlib"
+)
+
+func TestConcurrency(t *testing.T) {
+	expect(t, `
+c := import("concurrency")
+sum := func(a, b) { return a + b }
+out := c.wait(c.go(sum, 1, 2), c.go(sum, 3, 4))
+`, ARR{3, 7})
+
+	expect(t, `
+c := import("concurrency")
+t := c.go(func() { return 1 + "a" })
+out := is_error(t.wait())
+`, true)
+
+	// channels
+	expect(t, `
+c := import("concurrency")
+ch := c.chan()
+for i := 0; i < 3; i++ {
+	c.go(func(i) { ch.send(i * 10) }, i)
+}
+out := 0
+for i := 0; i < 3; i++ {
+	out += ch.recv()
+}
+`, 30)
+
+	expect(t, `
+c := import("concurrency")
+ch := c.chan(2)
+ch.send(1)
+ch.send(2)
+ch.close()
+out := [ch.cap(), ch.len(), ch.recv(), ch.recv(), ch.recv(),
+	is_error(ch.send(3)), is_error(ch.close())]
+`, ARR{2, 2, 1, 2, tengo.UndefinedValue, true, true})
+
+	expect(t, `
+c := import("concurrency")
+ch := c.chan()
+done := c.chan()
+c.go(func() {
+	for v := ch.recv(); v != undefined; v = ch.recv() {
+		done.send(v * 2)
+	}
+	done.close()
+})
+out := []
+for i := 0; i < 3; i++ {
+	ch.send(i)
+	out = append(out, done.recv())
+}
+ch.close()
+out = append(out, done.recv())
+`, ARR{0, 2, 4, tengo.UndefinedValue})
+
+	// select
+	expect(t, `
+c := import("concurrency")
+a := c.chan()
+b := c.chan(1)
+c.go(func() { a.send("x") })
+out := [c.select(a, [b, 1]), c.select(a, b), c.select(a)]
+`, ARR{ARR{1, tengo.UndefinedValue}, ARR{1, 1}, ARR{0, "x"}})
+
+	expect(t, `
+c := import("concurrency")
+a := c.chan()
+a.close()
+out := [c.select(a), is_error(c.select([a, 1]))]
+`, ARR{ARR{0, tengo.UndefinedValue}, true})
+
+	// mutex and wait group
+	expect(t, `
+c := import("concurrency")
+m := c.mutex()
+wg := c.wait_group()
+ch := c.chan(10)
+for i := 0; i < 10; i++ {
+	wg.add(1)
+	c.go(func(i) {
+		m.lock()
+		ch.send(i)
+		m.unlock()
+		wg.done()
+	}, i)
+}
+wg.wait()
+ch.close()
+out := 0
+for v := ch.recv(); v != undefined; v = ch.recv() { out += v }
+`, 45)
+
+	expect(t, `
+c := import("concurrency")
+wg := c.wait_group()
+out := [is_error(c.mutex().unlock()), is_error(wg.done()), wg.wait()]
+`, ARR{true, true, tengo.UndefinedValue})
+}
+
+func TestConcurrency_Isolation(t *testing.T) {
+	// child VMs write to their own copies of the globals, the free variables
+	// and the arguments
+	expect(t, `
+c := import("concurrency")
+m := {}
+a := [0]
+f := func(arg) {
+	local := {}
+	tasks := []
+	for i := 0; i < 8; i++ {
+		tasks = append(tasks, c.go(func(i) {
+			for j := 0; j < 100; j++ {
+				m[string(j)] = i
+				a[0]++
+				local[string(j)] = i
+				arg.x = i
+			}
+			return [len(m), a[0], len(local)]
+		}, i))
+	}
+	return [c.wait(tasks...), len(m), a[0], len(local), arg]
+}
+out := f({})
+`, ARR{
+		ARR{
+			ARR{100, 100, 100}, ARR{100, 100, 100},
+			ARR{100, 100, 100}, ARR{100, 100, 100},
+			ARR{100, 100, 100}, ARR{100, 100, 100},
+			ARR{100, 100, 100}, ARR{100, 100, 100},
+		},
+		0, 0, 0, MAP{},
+	})
+
+	// the values sent to channels are copied
+	expect(t, `
+c := import("concurrency")
+ch := c.chan()
+done := c.chan()
+c.go(func() {
+	v := ch.recv()
+	v.a[0] = 2
+	done.send(v)
+})
+v := {a: [1]}
+ch.send(v)
+out := [v, done.recv()]
+`, ARR{MAP{"a": ARR{1}}, MAP{"a": ARR{2}}})
+
+	expect(t, `
+c := import("concurrency")
+ch := c.chan()
+c.go(func() {
+	v := ch.recv()
+	v.a[0] = 2
+	ch.send(undefined)
+})
+v := {a: [1]}
+c.select([ch, v])
+ch.recv()
+out := v
+`, MAP{"a": ARR{1}})
+
+	// the references between the copied values are kept
+	expect(t, `
+c := import("concurrency")
+a := [1]
+out := c.go(func(x, y) { x[0] = 2; return y[0] }, a, a).wait()
+`, 2)
+
+	// generators can't be copied
+	for _, src := range []string{
+		`c.go(func(g) {}, g)`,
+		`c.go(func() { g() })`,
+		`c.chan(1).send([g])`,
+	} {
+		s := tengo.NewScript([]byte(`
+c := import("concurrency")
+g := func() { yield 1 }()
+` + src))
+		s.SetImports(stdlib.GetModuleMap("concurrency"))
+		_, err := s.Run()
+		require.True(t, errors.Is(err, tengo.ErrNotCopyable), err)
+	}
+}
+
+func TestConcurrency_Limits(t *testing.T) {
+	// child VMs inherit the allocation limit
+	s := tengo.NewScript([]byte(`
+c := import("concurrency")
+out := c.go(func() {
+	a := []
+	for { a = append(a, [1]) }
+}).wait()
+`))
+	s.SetImports(stdlib.GetModuleMap("concurrency"))
+	s.SetMaxAllocs(1000)
+	c, err := s.Run()
+	require.NoError(t, err)
+	out, ok := c.Get("out").Object().(*tengo.Error)
+	require.True(t, ok)
+	require.True(t, strings.Contains(out.String(),
+		tengo.ErrObjectAllocLimit.Error()))
+
+	// the limits apply to the total of the script and the child VMs
+	src := `
+c := import("concurrency")
+work := func(n) {
+	a := []
+	for i := 0; i < n; i++ { a = append(a, [i]) }
+}
+tasks := []
+for i := 0; i < num; i++ { tasks = append(tasks, c.go(work, n)) }
+out := 0
+for t in tasks {
+	if is_error(t.wait()) { out++ }
+}`
+	run := func(num, n int, limit func(*tengo.Script)) *tengo.Compiled {
+		s := tengo.NewScript([]byte(src))
+		s.SetImports(stdlib.GetModuleMap("concurrency"))
+		require.NoError(t, s.Add("num", num))
+		require.NoError(t, s.Add("n", n))
+		limit(s)
+		c, err := s.Compile()
+		require.NoError(t, err)
+		return c
+	}
+	for _, limit := range []struct {
+		set func(*tengo.Script)
+		err error
+	}{
+		{func(s *tengo.Script) { s.SetMaxInstructions(100000) },
+			tengo.ErrInstructionLimit},
+		{func(s *tengo.Script) { s.SetMaxAllocs(1000) },
+			tengo.ErrObjectAllocLimit},
+	} {
+		c := run(5, 20, limit.set)
+		require.NoError(t, c.Run())
+		require.Equal(t, int64(0), c.Get("out").Int64())
+
+		// either the script or some child VMs fail
+		c = run(50, 4000, limit.set)
+		if err := c.Run(); err != nil {
+			require.True(t, errors.Is(err, limit.err), err)
+		} else {
+			require.True(t, c.Get("out").Int64() > 0)
+		}
+	}
+	c = run(50, 4000, func(s *tengo.Script) { s.SetMaxInstructions(100000) })
+	_ = c.Run()
+	// each VM may exceed the limit by the instruction that fails
+	require.True(t, c.InstructionCount() <= 100000+51, c.InstructionCount())
+
+	// aborting the script aborts the child VMs
+	s = tengo.NewScript([]byte(`
+c := import("concurrency")
+ch := c.chan()
+c.go(func() { for {} })
+c.go(func() { ch.recv() })
+c.go(func() { c.go(func() { c.mutex().lock() }).wait() })
+ch.recv()
+`))
+	s.SetImports(stdlib.GetModuleMap("concurrency"))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = s.RunContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// child VMs are aborted when the script finishes
+	expect(t, `
+c := import("concurrency")
+c.go(func() { for {} })
+out := 1
+`, 1)
+}
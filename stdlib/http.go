@@ -0,0 +1,335 @@
+package stdlib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/d5/tengo/v2"
+)
+
+var httpModule = HTTPModule(nil)
+
+// HTTPModule returns the "http" module that sends the requests with the
+// transport. The host can restrict the requests, e.g. to the allowed hosts,
+// with a transport that rejects the others. It uses http.DefaultTransport if
+// transport is nil.
+func HTTPModule(transport http.RoundTripper) map[string]tengo.Object {
+	m := &httpClient{client: &http.Client{Transport: transport}}
+	return map[string]tengo.Object{
+		"get": privileged("http.request", &tengo.VMFunction{
+			Name:  "get",
+			Value: m.get,
+		}), // get(url, options) => response/error
+		"post": privileged("http.request", &tengo.VMFunction{
+			Name:  "post",
+			Value: m.post,
+		}), // post(url, body, options) => response/error
+		"request": privileged("http.request", &tengo.VMFunction{
+			Name:  "request",
+			Value: m.request,
+		}), // request(method, url, options) => response/error
+	}
+}
+
+// HandlerTransport returns a transport that serves the requests with the
+// handler in-process, which is useful to test the scripts that use the
+// "http" module without a network. A request fails if its context is done
+// when the handler returns.
+func HandlerTransport(handler http.Handler) http.RoundTripper {
+	return handlerTransport{handler: handler}
+}
+
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.RequestURI = req.URL.RequestURI()
+	r.RemoteAddr = "192.0.2.1:1234"
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	w := &responseRecorder{header: make(http.Header)}
+	t.handler.ServeHTTP(w, r)
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.code, http.StatusText(w.code)),
+		StatusCode:    w.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          ioutil.NopCloser(&w.body),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// responseRecorder is the http.ResponseWriter of handlerTransport.
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *responseRecorder) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+type httpClient struct {
+	client *http.Client
+}
+
+// get(url, options) => response/error
+func (m *httpClient) get(
+	vm *tengo.VM,
+	args ...tengo.Object,
+) (tengo.Object, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return m.do(vm, http.MethodGet, args[0], nil, args[1:])
+}
+
+// post(url, body, options) => response/error
+func (m *httpClient) post(
+	vm *tengo.VM,
+	args ...tengo.Object,
+) (tengo.Object, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return m.do(vm, http.MethodPost, args[0], args[1], args[2:])
+}
+
+// request(method, url, options) => response/error
+func (m *httpClient) request(
+	vm *tengo.VM,
+	args ...tengo.Object,
+) (tengo.Object, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	method, ok := tengo.ToString(args[0])
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "string(compatible)",
+			Found:    args[0].TypeName(),
+		}
+	}
+	return m.do(vm, strings.ToUpper(method), args[1], nil, args[2:])
+}
+
+// do sends the request of the method to the URL, and returns the response.
+// The body is the body of the request, or nil if the body is given by the
+// options. opts is an empty slice, or the options map.
+func (m *httpClient) do(
+	vm *tengo.VM,
+	method string,
+	urlArg, bodyArg tengo.Object,
+	opts []tengo.Object,
+) (tengo.Object, error) {
+	url, ok := tengo.ToString(urlArg)
+	if !ok {
+		return nil, tengo.ErrInvalidArgumentType{
+			Name:     "url",
+			Expected: "string(compatible)",
+			Found:    urlArg.TypeName(),
+		}
+	}
+	var options map[string]tengo.Object
+	if len(opts) > 0 && opts[0] != tengo.UndefinedValue {
+		switch o := opts[0].(type) {
+		case *tengo.Map:
+			options = o.Value
+		case *tengo.ImmutableMap:
+			options = o.Value
+		default:
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     "options",
+				Expected: "map",
+				Found:    o.TypeName(),
+			}
+		}
+	}
+
+	header := make(http.Header)
+	var timeout time.Duration
+	for name, value := range options {
+		switch name {
+		case "headers":
+			if err := httpHeader(header, value); err != nil {
+				return nil, err
+			}
+		case "body":
+			if bodyArg != nil {
+				return nil, fmt.Errorf("invalid option '%s'", name)
+			}
+			bodyArg = value
+		case "timeout":
+			if timeout, ok = tengo.ToDuration(value); !ok {
+				return nil, tengo.ErrInvalidArgumentType{
+					Name:     "timeout",
+					Expected: "int/string",
+					Found:    value.TypeName(),
+				}
+			}
+		default:
+			return nil, fmt.Errorf("invalid option '%s'", name)
+		}
+	}
+
+	var body io.Reader
+	if bodyArg != nil && bodyArg != tengo.UndefinedValue {
+		b, ok := tengo.ToByteSlice(bodyArg)
+		if !ok {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     "body",
+				Expected: "string/bytes",
+				Found:    bodyArg.TypeName(),
+			}
+		}
+		body = bytes.NewReader(b)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		defer cancelTimeout()
+	}
+	aborted := vm.Aborted()
+	if aborted != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-aborted:
+				cancel()
+			case <-done:
+			}
+		}()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return wrapError(err), nil
+	}
+	req.Header = header
+	resp, err := m.client.Do(req)
+	if err != nil {
+		select {
+		case <-aborted:
+			return nil, tengo.ErrVMAborted
+		default:
+		}
+		return wrapError(err), nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := ioutil.ReadAll(io.LimitReader(
+		&allocReader{r: resp.Body, vm: vm}, int64(tengo.MaxBytesLen)+1))
+	if err == tengo.ErrMemoryLimit {
+		return nil, err
+	} else if err != nil {
+		select {
+		case <-aborted:
+			return nil, tengo.ErrVMAborted
+		default:
+		}
+		return wrapError(err), nil
+	}
+	if len(respBody) > tengo.MaxBytesLen {
+		return nil, tengo.ErrBytesLimit
+	}
+
+	headers := make(map[string]tengo.Object, len(resp.Header))
+	for name, values := range resp.Header {
+		headers[name] = &tengo.String{Value: strings.Join(values, ", ")}
+	}
+	return &tengo.Map{Value: map[string]tengo.Object{
+		"status":  &tengo.Int{Value: int64(resp.StatusCode)},
+		"headers": &tengo.Map{Value: headers},
+		"body":    &tengo.Bytes{Value: respBody},
+	}}, nil
+}
+
+// httpHeader adds the headers of the map o to header. A header value is a
+// string, or an array of strings for a header of multiple values.
+func httpHeader(header http.Header, o tengo.Object) error {
+	var kv map[string]tengo.Object
+	switch o := o.(type) {
+	case *tengo.Map:
+		kv = o.Value
+	case *tengo.ImmutableMap:
+		kv = o.Value
+	default:
+		return tengo.ErrInvalidArgumentType{
+			Name:     "headers",
+			Expected: "map",
+			Found:    o.TypeName(),
+		}
+	}
+	for name, value := range kv {
+		values := []tengo.Object{value}
+		switch v := value.(type) {
+		case *tengo.Array:
+			values = v.Value
+		case *tengo.ImmutableArray:
+			values = v.Value
+		}
+		for _, v := range values {
+			s, ok := tengo.ToString(v)
+			if !ok {
+				return tengo.ErrInvalidArgumentType{
+					Name:     "headers." + name,
+					Expected: "string(compatible)",
+					Found:    v.TypeName(),
+				}
+			}
+			header.Add(name, s)
+		}
+	}
+	return nil
+}
+
+// allocReader counts the bytes read from r against the memory limit of vm.
+type allocReader struct {
+	r  io.Reader
+	vm *tengo.VM
+}
+
+func (r *allocReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if allocErr := r.vm.Allocate(int64(n)); allocErr != nil {
+		return n, allocErr
+	}
+	return n, err
+}
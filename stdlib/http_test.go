@@ -0,0 +1,212 @@
+package stdlib_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/require"
+	"github.com/d5/tengo/v2/stdlib"
+)
+
+// echoHandler responds with the method, the URL, the headers and the body of
+// the request.
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	var headers []string
+	for name, values := range r.Header {
+		if strings.HasPrefix(name, "X-") {
+			headers = append(headers, name+"="+strings.Join(values, ","))
+		}
+	}
+	sort.Strings(headers)
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Add("X-Values", "a")
+	w.Header().Add("X-Values", "b")
+	if r.URL.Path == "/missing" {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	_, _ = fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.RequestURI(),
+		strings.Join(headers, ";"), body)
+})
+
+func TestHTTP(t *testing.T) {
+	transport := stdlib.HandlerTransport(echoHandler)
+	expectHTTP(t, transport, `
+http := import("http")
+res := http.get("http://example.com/a?b=c")
+out := [res.status, res.headers["Content-Type"], res.headers["X-Values"],
+	string(res.body)]
+`, ARR{200, "text/plain", "a, b", "GET /a?b=c  "})
+	expectHTTP(t, transport, `
+http := import("http")
+res := http.post("http://example.com/", "foo", {
+	headers: {"x-a": "1", "X-B": [2, "3"]}
+})
+out := string(res.body)
+`, "POST / X-A=1;X-B=2,3 foo")
+	expectHTTP(t, transport, `
+http := import("http")
+res := http.request("put", "http://example.com/missing", {
+	body: bytes("bar"),
+	timeout: "1s"
+})
+out := [res.status, string(res.body)]
+`, ARR{404, "PUT /missing  bar"})
+
+	// errors
+	expectHTTP(t, transport, `
+out := import("http").get("://")
+out = is_error(out)
+`, true)
+	expectHTTPError(t, transport, `import("http").get()`,
+		"wrong number of arguments")
+	expectHTTPError(t, transport, `import("http").get("/", {header: {}})`,
+		"invalid option 'header'")
+	expectHTTPError(t, transport, `import("http").post("/", "", {body: ""})`,
+		"invalid option 'body'")
+	expectHTTPError(t, transport, `import("http").get("/", {timeout: []})`,
+		"invalid type for argument 'timeout'")
+	expectHTTPError(t, transport,
+		`import("http").post("/", {})`,
+		"invalid type for argument 'body'")
+	expectHTTPError(t, transport,
+		`import("http").get("/", {headers: {a: [undefined]}})`,
+		"invalid type for argument 'headers.a'")
+}
+
+func TestHTTP_Server(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	expectHTTP(t, srv.Client().Transport, `
+http := import("http")
+out := string(http.get(url + "/foo", {headers: {"X-A": "b"}}).body)
+`, "GET /foo X-A=b ", "url", srv.URL)
+}
+
+func TestHTTP_Transport(t *testing.T) {
+	// a transport that allows only the requests to example.com
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host != "example.com" {
+			return nil, errors.New("host not allowed")
+		}
+		return stdlib.HandlerTransport(echoHandler).RoundTrip(r)
+	})
+	expectHTTP(t, transport, `
+http := import("http")
+out := [http.get("http://example.com/").status,
+	string(http.get("http://example.org/"))]
+`, ARR{200, `error: "Get \"http://example.org/\": host not allowed"`})
+}
+
+func TestHTTP_Cancel(t *testing.T) {
+	canceled := make(chan struct{})
+	transport := stdlib.HandlerTransport(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			close(canceled)
+		}))
+
+	// timeout option
+	expectHTTP(t, transport, `
+out := import("http").get("http://example.com/", {timeout: 10000000})
+out = string(out)
+`, `error: "Get \"http://example.com/\": context deadline exceeded"`)
+	<-canceled
+
+	// aborted run
+	canceled = make(chan struct{})
+	s := tengo.NewScript([]byte(`import("http").get("http://example.com/")`))
+	mods := tengo.NewModuleMap()
+	mods.AddBuiltinModule("http", stdlib.HTTPModule(transport))
+	s.SetImports(mods)
+	c, err := s.Compile()
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, c.RunContext(ctx))
+	<-canceled
+}
+
+func TestHTTP_Capabilities(t *testing.T) {
+	var functions []string
+	s := tengo.NewScript([]byte(`
+http := import("http")
+res := [http.get("http://example.com/"), http.post("http://example.com/", "")]
+out := [is_error(res[0]) ? string(res[0]) : res[0].status, res[1].status]`))
+	mods := tengo.NewModuleMap()
+	mods.AddBuiltinModule("http", stdlib.HTTPModule(
+		stdlib.HandlerTransport(echoHandler)))
+	s.SetImports(mods)
+	s.SetCapabilities("http")
+	s.SetAuditHook(func(e tengo.AuditEvent) {
+		functions = append(functions, e.Function)
+	})
+	c, err := s.Run()
+	require.NoError(t, err)
+	require.Equal(t, object(ARR{200, 200}), c.Get("out").Object())
+	require.Equal(t, "http.get,http.post", strings.Join(functions, ","))
+
+	s = tengo.NewScript([]byte(`
+out := string(import("http").get("http://example.com/"))`))
+	s.SetImports(mods)
+	s.SetCapabilities("os", "times")
+	c, err = s.Run()
+	require.NoError(t, err)
+	require.Equal(t, `error: "permission denied: http.get requires `+
+		`capability 'http.request'"`, c.Get("out").String())
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func runHTTP(
+	t *testing.T,
+	transport http.RoundTripper,
+	input string,
+	vars ...interface{},
+) (*tengo.Compiled, error) {
+	s := tengo.NewScript([]byte(input))
+	mods := tengo.NewModuleMap()
+	mods.AddBuiltinModule("http", stdlib.HTTPModule(transport))
+	s.SetImports(mods)
+	for i := 0; i < len(vars); i += 2 {
+		require.NoError(t, s.Add(vars[i].(string), vars[i+1]))
+	}
+	return s.Run()
+}
+
+func expectHTTP(
+	t *testing.T,
+	transport http.RoundTripper,
+	input string,
+	expected interface{},
+	vars ...interface{},
+) {
+	c, err := runHTTP(t, transport, input, vars...)
+	require.NoError(t, err, input)
+	require.Equal(t, object(expected), c.Get("out").Object(), input)
+}
+
+func expectHTTPError(
+	t *testing.T,
+	transport http.RoundTripper,
+	input, expected string,
+) {
+	_, err := runHTTP(t, transport, input)
+	require.Error(t, err, input)
+	require.True(t, strings.Contains(err.Error(), expected), err.Error())
+}
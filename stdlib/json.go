@@ -28,13 +28,18 @@ var jsonModule = map[string]tengo.Object{
 }
 
 func jsonDecode(args ...tengo.Object) (ret tengo.Object, err error) {
-	if len(args) != 1 {
+	if len(args) != 1 && len(args) != 2 {
 		return nil, tengo.ErrWrongNumArguments
 	}
 
+	decode := json.Decode
+	if len(args) == 2 && !args[1].IsFalsy() {
+		decode = json.DecodeExact
+	}
+
 	switch o := args[0].(type) {
 	case *tengo.Bytes:
-		v, err := json.Decode(o.Value)
+		v, err := decode(o.Value)
 		if err != nil {
 			return &tengo.Error{
 				Value: &tengo.String{Value: err.Error()},
@@ -42,7 +47,7 @@ func jsonDecode(args ...tengo.Object) (ret tengo.Object, err error) {
 		}
 		return v, nil
 	case *tengo.String:
-		v, err := json.Decode([]byte(o.Value))
+		v, err := decode([]byte(o.Value))
 		if err != nil {
 			return &tengo.Error{
 				Value: &tengo.String{Value: err.Error()},
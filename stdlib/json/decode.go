@@ -7,7 +7,10 @@
 package json
 
 import (
+	"errors"
+	"math/big"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf16"
 	"unicode/utf8"
@@ -15,9 +18,21 @@ import (
 	"github.com/d5/tengo/v2"
 )
 
-// Decode parses the JSON-encoded data and returns the result object.
+// Decode parses the JSON-encoded data and returns the result object. The
+// numbers are decoded as floats.
 func Decode(data []byte) (tengo.Object, error) {
-	var d decodeState
+	return decode(data, false)
+}
+
+// DecodeExact is like Decode, but it decodes the numbers without losing
+// precision: integers as ints, or bigints if they overflow an int, and the
+// numbers with a fraction or an exponent as decimals.
+func DecodeExact(data []byte) (tengo.Object, error) {
+	return decode(data, true)
+}
+
+func decode(data []byte, exact bool) (tengo.Object, error) {
+	d := decodeState{exact: exact}
 	err := checkValid(data, &d.scan)
 	if err != nil {
 		return nil, err
@@ -34,6 +49,7 @@ type decodeState struct {
 	off    int // next read offset in data
 	opcode int // last read result
 	scan   scanner
+	exact  bool // decodes the numbers without losing precision
 }
 
 // readIndex returns the position of the last byte read.
@@ -210,11 +226,31 @@ func (d *decodeState) literal() (tengo.Object, error) {
 		if c != '-' && (c < '0' || c > '9') {
 			panic(phasePanicMsg)
 		}
+		if d.exact {
+			return exactNumber(string(item))
+		}
 		n, _ := strconv.ParseFloat(string(item), 10)
 		return &tengo.Float{Value: n}, nil
 	}
 }
 
+// exactNumber returns the number s as an int, or a bigint if it overflows an
+// int, or a decimal if it has a fraction or an exponent.
+func exactNumber(s string) (tengo.Object, error) {
+	if strings.ContainsAny(s, ".eE") {
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, errors.New("number out of range: " + s)
+		}
+		return &tengo.Decimal{Value: r}, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return &tengo.Int{Value: n}, nil
+	}
+	n, _ := new(big.Int).SetString(s, 10)
+	return &tengo.BigInt{Value: n}, nil
+}
+
 // getu4 decodes \uXXXX from the beginning of s, returning the hex value,
 // or it returns -1.
 func getu4(s []byte) rune {
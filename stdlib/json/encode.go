@@ -158,41 +158,9 @@ func Encode(o tengo.Object) ([]byte, error) {
 		}
 		b = append(b, ']')
 	case *tengo.Map:
-		b = append(b, '{')
-		len1 := len(o.Value) - 1
-		idx := 0
-		for key, value := range o.Value {
-			b = encodeString(b, key)
-			b = append(b, ':')
-			eb, err := Encode(value)
-			if err != nil {
-				return nil, err
-			}
-			b = append(b, eb...)
-			if idx < len1 {
-				b = append(b, ',')
-			}
-			idx++
-		}
-		b = append(b, '}')
+		return encodeMap(b, o.Value, o.Hashed)
 	case *tengo.ImmutableMap:
-		b = append(b, '{')
-		len1 := len(o.Value) - 1
-		idx := 0
-		for key, value := range o.Value {
-			b = encodeString(b, key)
-			b = append(b, ':')
-			eb, err := Encode(value)
-			if err != nil {
-				return nil, err
-			}
-			b = append(b, eb...)
-			if idx < len1 {
-				b = append(b, ',')
-			}
-			idx++
-		}
-		b = append(b, '}')
+		return encodeMap(b, o.Value, o.Hashed)
 	case *tengo.Bool:
 		if o.IsFalsy() {
 			b = strconv.AppendBool(b, false)
@@ -208,6 +176,10 @@ func Encode(o tengo.Object) ([]byte, error) {
 		b = append(b, '"')
 	case *tengo.Char:
 		b = strconv.AppendInt(b, int64(o.Value), 10)
+	case *tengo.BigInt:
+		b = append(b, o.Value.String()...)
+	case *tengo.Decimal:
+		b = append(b, o.String()...)
 	case *tengo.Float:
 		var y []byte
 
@@ -256,6 +228,69 @@ func Encode(o tengo.Object) ([]byte, error) {
 	return b, nil
 }
 
+// encodeMap encodes the map entries as a JSON object. The keys other than
+// strings are encoded as the strings of their JSON values. It fails if two
+// keys are encoded as the same string, e.g. 1 and "1".
+func encodeMap(
+	b []byte,
+	kv map[string]tengo.Object,
+	hashed *tengo.HashedMap,
+) ([]byte, error) {
+	b = append(b, '{')
+	len1 := len(kv) + hashed.Len() - 1
+	idx := 0
+	var seen map[string]bool // encoded keys; only if keys may collide
+	if hashed.Len() > 0 {
+		seen = make(map[string]bool, len1+1)
+	}
+	for key, value := range kv {
+		start := len(b)
+		b = encodeString(b, key)
+		if seen != nil {
+			seen[string(b[start:])] = true
+		}
+		b = append(b, ':')
+		eb, err := Encode(value)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, eb...)
+		if idx < len1 {
+			b = append(b, ',')
+		}
+		idx++
+	}
+	for _, e := range hashed.Entries() {
+		kb, err := Encode(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		start := len(b)
+		if len(kb) > 0 && kb[0] == '"' {
+			b = append(b, kb...)
+		} else {
+			b = encodeString(b, string(kb))
+		}
+		key := string(b[start:])
+		if seen[key] {
+			return nil, errors.New("duplicate key " + key)
+		}
+		seen[key] = true
+		b = append(b, ':')
+		eb, err := Encode(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, eb...)
+		if idx < len1 {
+			b = append(b, ',')
+		}
+		idx++
+	}
+	b = append(b, '}')
+	return b, nil
+}
+
 // encodeString encodes given string as JSON string according to
 // https://www.json.org/img/string.png
 // Implementation is inspired by https://github.com/json-iterator/go
@@ -2,7 +2,9 @@ package json_test
 
 import (
 	gojson "encoding/json"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/d5/tengo/v2"
 	"github.com/d5/tengo/v2/require"
@@ -57,6 +59,40 @@ func TestJSON(t *testing.T) {
 		"arr": ARR{1, 2, 3, "four"}})
 	testJSONEncodeDecode(t, MAP{"a": 0, "b": "bee",
 		"arr": ARR{1, 2, 3, MAP{"a": false, "b": 109.4}}})
+
+	// keys other than strings are encoded as strings
+	testJSONEncodeKey(t, &tengo.Int{Value: -1}, `"-1"`)
+	testJSONEncodeKey(t, &tengo.Float{Value: 1.5}, `"1.5"`)
+	testJSONEncodeKey(t, &tengo.Char{Value: 'a'}, `"a"`)
+	testJSONEncodeKey(t, tengo.TrueValue, `"true"`)
+	testJSONEncodeKey(t, &tengo.Time{Value: time.Date(2009, 11, 10, 23, 0, 0,
+		0, time.UTC)}, `"2009-11-10T23:00:00Z"`)
+	testJSONEncodeKey(t, &tengo.ImmutableArray{Value: []tengo.Object{
+		&tengo.Int{Value: 1}, &tengo.String{Value: "a"}}}, `"[1,\"a\"]"`)
+
+	// keys encoded as the same string are errors
+	testJSONEncodeDuplicateKeys(t, &tengo.Float{Value: 1.5},
+		&tengo.String{Value: "1.5"})
+	testJSONEncodeDuplicateKeys(t, tengo.TrueValue,
+		&tengo.String{Value: "true"})
+}
+
+func testJSONEncodeDuplicateKeys(t *testing.T, keys ...tengo.Object) {
+	m := &tengo.Map{Value: map[string]tengo.Object{}}
+	for _, key := range keys {
+		require.NoError(t, m.IndexSet(key, &tengo.Int{Value: 1}))
+	}
+	_, err := json.Encode(m)
+	require.Error(t, err)
+	require.Equal(t, `duplicate key "`+keys[0].String()+`"`, err.Error())
+}
+
+func testJSONEncodeKey(t *testing.T, key tengo.Object, expected string) {
+	m := &tengo.Map{Value: map[string]tengo.Object{}}
+	require.NoError(t, m.IndexSet(key, &tengo.Int{Value: 1}))
+	b, err := json.Encode(m)
+	require.NoError(t, err)
+	require.Equal(t, "{"+expected+":1}", string(b))
 }
 
 func TestDecode(t *testing.T) {
@@ -83,6 +119,39 @@ func TestDecode(t *testing.T) {
 	testDecodeError(t, `{"a":"b":"c"}`)
 }
 
+func TestDecodeExact(t *testing.T) {
+	testDecodeExact(t, `1984`, "int", "1984")
+	testDecodeExact(t, `-9223372036854775808`, "int", "-9223372036854775808")
+	testDecodeExact(t, `9223372036854775808`, "bigint",
+		"9223372036854775808")
+	testDecodeExact(t, `-123456789012345678901234567890`, "bigint",
+		"-123456789012345678901234567890")
+	testDecodeExact(t, `0.1`, "decimal", "0.1")
+	testDecodeExact(t, `-19.840`, "decimal", "-19.84")
+	testDecodeExact(t, `1e3`, "decimal", "1000")
+	testDecodeExact(t, `1.5E-3`, "decimal", "0.0015")
+	testDecodeExact(t, `12345678901234567890.12345678901234567890`, "decimal",
+		"12345678901234567890.1234567890123456789")
+
+	_, err := json.DecodeExact([]byte(`1e1000000000`))
+	require.Error(t, err)
+
+	// encoded exactly
+	b, err := json.Encode(&tengo.Array{Value: []tengo.Object{
+		&tengo.BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)},
+		&tengo.Decimal{Value: big.NewRat(-1, 8)},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, `[18446744073709551616,-0.125]`, string(b))
+}
+
+func testDecodeExact(t *testing.T, input, typeName, expected string) {
+	o, err := json.DecodeExact([]byte(input))
+	require.NoError(t, err, input)
+	require.Equal(t, typeName, o.TypeName(), input)
+	require.Equal(t, expected, o.String(), input)
+}
+
 func testDecodeError(t *testing.T, input string) {
 	_, err := json.Decode([]byte(input))
 	require.Error(t, err)
@@ -1,6 +1,11 @@
 package stdlib_test
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/d5/tengo/v2"
+)
 
 func TestJSON(t *testing.T) {
 	module(t, "json").call("encode", 5).
@@ -63,6 +68,8 @@ func TestJSON(t *testing.T) {
 		expect(MAP{"foo": "bar"})
 	module(t, "json").call("decode", `{"foo":[1,2,3,"bar"]}`).
 		expect(MAP{"foo": ARR{1.0, 2.0, 3.0, "bar"}})
+	module(t, "json").call("decode", `{"foo":[1,2.5]}`, true).
+		expect(MAP{"foo": ARR{1, &tengo.Decimal{Value: big.NewRat(5, 2)}}})
 
 	module(t, "json").
 		call("indent", []byte("{\"foo\":[\"bar\",1,1.8,56,true]}"), "", "  ").
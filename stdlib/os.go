@@ -39,168 +39,167 @@ var osModule = map[string]tengo.Object{
 	"seek_set":            &tengo.Int{Value: int64(io.SeekStart)},
 	"seek_cur":            &tengo.Int{Value: int64(io.SeekCurrent)},
 	"seek_end":            &tengo.Int{Value: int64(io.SeekEnd)},
-	"args": &tengo.UserFunction{
+	"args": privileged("os.info", &tengo.UserFunction{
 		Name:  "args",
 		Value: osArgs,
-	}, // args() => array(string)
-	"chdir": &tengo.UserFunction{
-		Name:  "chdir",
-		Value: FuncASRE(os.Chdir),
-	}, // chdir(dir string) => error
-	"chmod": osFuncASFmRE("chmod", os.Chmod), // chmod(name string, mode int) => error
-	"chown": &tengo.UserFunction{
-		Name:  "chown",
-		Value: FuncASIIRE(os.Chown),
-	}, // chown(name string, uid int, gid int) => error
-	"clearenv": &tengo.UserFunction{
+	}), // args() => array(string)
+	// chdir(dir string) => error
+	"chdir": privileged("os.fs.write",
+		osPathFunc("chdir", FuncASRE(os.Chdir), nil)),
+	// chmod(name string, mode int) => error
+	"chmod": privileged("os.fs.write",
+		osFuncASFmRE("chmod", os.Chmod, nil)),
+	// chown(name string, uid int, gid int) => error
+	"chown": privileged("os.fs.write",
+		osPathFunc("chown", FuncASIIRE(os.Chown), nil)),
+	"clearenv": privileged("os.env.write", &tengo.UserFunction{
 		Name:  "clearenv",
 		Value: FuncAR(os.Clearenv),
-	}, // clearenv()
-	"environ": &tengo.UserFunction{
+	}), // clearenv()
+	"environ": privileged("os.env.read", &tengo.UserFunction{
 		Name:  "environ",
 		Value: FuncARSs(os.Environ),
-	}, // environ() => array(string)
-	"exit": &tengo.UserFunction{
+	}), // environ() => array(string)
+	"exit": privileged("os.exit", &tengo.UserFunction{
 		Name:  "exit",
 		Value: FuncAIR(os.Exit),
-	}, // exit(code int)
-	"expand_env": &tengo.UserFunction{
+	}), // exit(code int)
+	"expand_env": privileged("os.env.read", &tengo.UserFunction{
 		Name:  "expand_env",
 		Value: osExpandEnv,
-	}, // expand_env(s string) => string
-	"getegid": &tengo.UserFunction{
+	}), // expand_env(s string) => string
+	"getegid": privileged("os.info", &tengo.UserFunction{
 		Name:  "getegid",
 		Value: FuncARI(os.Getegid),
-	}, // getegid() => int
-	"getenv": &tengo.UserFunction{
+	}), // getegid() => int
+	"getenv": privileged("os.env.read", &tengo.UserFunction{
 		Name:  "getenv",
 		Value: FuncASRS(os.Getenv),
-	}, // getenv(s string) => string
-	"geteuid": &tengo.UserFunction{
+	}), // getenv(s string) => string
+	"geteuid": privileged("os.info", &tengo.UserFunction{
 		Name:  "geteuid",
 		Value: FuncARI(os.Geteuid),
-	}, // geteuid() => int
-	"getgid": &tengo.UserFunction{
+	}), // geteuid() => int
+	"getgid": privileged("os.info", &tengo.UserFunction{
 		Name:  "getgid",
 		Value: FuncARI(os.Getgid),
-	}, // getgid() => int
-	"getgroups": &tengo.UserFunction{
+	}), // getgid() => int
+	"getgroups": privileged("os.info", &tengo.UserFunction{
 		Name:  "getgroups",
 		Value: FuncARIsE(os.Getgroups),
-	}, // getgroups() => array(string)/error
-	"getpagesize": &tengo.UserFunction{
+	}), // getgroups() => array(string)/error
+	"getpagesize": privileged("os.info", &tengo.UserFunction{
 		Name:  "getpagesize",
 		Value: FuncARI(os.Getpagesize),
-	}, // getpagesize() => int
-	"getpid": &tengo.UserFunction{
+	}), // getpagesize() => int
+	"getpid": privileged("os.info", &tengo.UserFunction{
 		Name:  "getpid",
 		Value: FuncARI(os.Getpid),
-	}, // getpid() => int
-	"getppid": &tengo.UserFunction{
+	}), // getpid() => int
+	"getppid": privileged("os.info", &tengo.UserFunction{
 		Name:  "getppid",
 		Value: FuncARI(os.Getppid),
-	}, // getppid() => int
-	"getuid": &tengo.UserFunction{
+	}), // getppid() => int
+	"getuid": privileged("os.info", &tengo.UserFunction{
 		Name:  "getuid",
 		Value: FuncARI(os.Getuid),
-	}, // getuid() => int
-	"getwd": &tengo.UserFunction{
+	}), // getuid() => int
+	"getwd": privileged("os.info", &tengo.UserFunction{
 		Name:  "getwd",
 		Value: FuncARSE(os.Getwd),
-	}, // getwd() => string/error
-	"hostname": &tengo.UserFunction{
+	}), // getwd() => string/error
+	"hostname": privileged("os.info", &tengo.UserFunction{
 		Name:  "hostname",
 		Value: FuncARSE(os.Hostname),
-	}, // hostname() => string/error
-	"lchown": &tengo.UserFunction{
-		Name:  "lchown",
-		Value: FuncASIIRE(os.Lchown),
-	}, // lchown(name string, uid int, gid int) => error
-	"link": &tengo.UserFunction{
-		Name:  "link",
-		Value: FuncASSRE(os.Link),
-	}, // link(oldname string, newname string) => error
-	"lookup_env": &tengo.UserFunction{
+	}), // hostname() => string/error
+	// lchown(name string, uid int, gid int) => error
+	"lchown": privileged("os.fs.write",
+		osPathFunc("lchown", FuncASIIRE(os.Lchown), nil)),
+	// link(oldname string, newname string) => error
+	"link": privileged("os.fs.write",
+		osPathFunc("link", FuncASSRE(os.Link), nil)),
+	"lookup_env": privileged("os.env.read", &tengo.UserFunction{
 		Name:  "lookup_env",
 		Value: osLookupEnv,
-	}, // lookup_env(key string) => string/false
-	"mkdir":     osFuncASFmRE("mkdir", os.Mkdir),        // mkdir(name string, perm int) => error
-	"mkdir_all": osFuncASFmRE("mkdir_all", os.MkdirAll), // mkdir_all(name string, perm int) => error
-	"readlink": &tengo.UserFunction{
-		Name:  "readlink",
-		Value: FuncASRSE(os.Readlink),
-	}, // readlink(name string) => string/error
-	"remove": &tengo.UserFunction{
-		Name:  "remove",
-		Value: FuncASRE(os.Remove),
-	}, // remove(name string) => error
-	"remove_all": &tengo.UserFunction{
-		Name:  "remove_all",
-		Value: FuncASRE(os.RemoveAll),
-	}, // remove_all(name string) => error
-	"rename": &tengo.UserFunction{
-		Name:  "rename",
-		Value: FuncASSRE(os.Rename),
-	}, // rename(oldpath string, newpath string) => error
-	"setenv": &tengo.UserFunction{
+	}), // lookup_env(key string) => string/false
+	// mkdir(name string, perm int) => error
+	"mkdir": privileged("os.fs.write",
+		osFuncASFmRE("mkdir", os.Mkdir, tengo.WritableFS.Mkdir)),
+	// mkdir_all(name string, perm int) => error
+	"mkdir_all": privileged("os.fs.write",
+		osFuncASFmRE("mkdir_all", os.MkdirAll, fsMkdirAll)),
+	// readlink(name string) => string/error
+	"readlink": privileged("os.fs.read",
+		osPathFunc("readlink", FuncASRSE(os.Readlink), nil)),
+	// remove(name string) => error
+	"remove": privileged("os.fs.write",
+		osPathFunc("remove", FuncASRE(os.Remove), tengo.WritableFS.Remove)),
+	// remove_all(name string) => error
+	"remove_all": privileged("os.fs.write",
+		osPathFunc("remove_all", FuncASRE(os.RemoveAll), nil)),
+	// rename(oldpath string, newpath string) => error
+	"rename": privileged("os.fs.write",
+		osPathFunc("rename", FuncASSRE(os.Rename), nil)),
+	"setenv": privileged("os.env.write", &tengo.UserFunction{
 		Name:  "setenv",
 		Value: FuncASSRE(os.Setenv),
-	}, // setenv(key string, value string) => error
-	"symlink": &tengo.UserFunction{
-		Name:  "symlink",
-		Value: FuncASSRE(os.Symlink),
-	}, // symlink(oldname string newname string) => error
-	"temp_dir": &tengo.UserFunction{
+	}), // setenv(key string, value string) => error
+	// symlink(oldname string newname string) => error
+	"symlink": privileged("os.fs.write",
+		osPathFunc("symlink", FuncASSRE(os.Symlink), nil)),
+	"temp_dir": privileged("os.info", &tengo.UserFunction{
 		Name:  "temp_dir",
 		Value: FuncARS(os.TempDir),
-	}, // temp_dir() => string
-	"truncate": &tengo.UserFunction{
-		Name:  "truncate",
-		Value: FuncASI64RE(os.Truncate),
-	}, // truncate(name string, size int) => error
-	"unsetenv": &tengo.UserFunction{
+	}), // temp_dir() => string
+	// truncate(name string, size int) => error
+	"truncate": privileged("os.fs.write",
+		osPathFunc("truncate", FuncASI64RE(os.Truncate), nil)),
+	"unsetenv": privileged("os.env.write", &tengo.UserFunction{
 		Name:  "unsetenv",
 		Value: FuncASRE(os.Unsetenv),
-	}, // unsetenv(key string) => error
-	"create": &tengo.UserFunction{
+	}), // unsetenv(key string) => error
+	"create": privileged("os.fs.write", &tengo.VMFunction{
 		Name:  "create",
 		Value: osCreate,
-	}, // create(name string) => imap(file)/error
-	"open": &tengo.UserFunction{
+	}), // create(name string) => imap(file)/error
+	"open": privileged("os.fs.read", &tengo.VMFunction{
 		Name:  "open",
 		Value: osOpen,
-	}, // open(name string) => imap(file)/error
-	"open_file": &tengo.UserFunction{
+	}), // open(name string) => imap(file)/error
+	"open_file": &tengo.VMFunction{
 		Name:  "open_file",
 		Value: osOpenFile,
 	}, // open_file(name string, flag int, perm int) => imap(file)/error
-	"find_process": &tengo.UserFunction{
+	"find_process": privileged("os.exec", &tengo.UserFunction{
 		Name:  "find_process",
 		Value: osFindProcess,
-	}, // find_process(pid int) => imap(process)/error
-	"start_process": &tengo.UserFunction{
+	}), // find_process(pid int) => imap(process)/error
+	"start_process": privileged("os.exec", &tengo.UserFunction{
 		Name:  "start_process",
 		Value: osStartProcess,
-	}, // start_process(name string, argv array(string), dir string, env array(string)) => imap(process)/error
-	"exec_look_path": &tengo.UserFunction{
+	}), // start_process(name string, argv array(string), dir string, env array(string)) => imap(process)/error
+	"exec_look_path": privileged("os.exec", &tengo.UserFunction{
 		Name:  "exec_look_path",
 		Value: FuncASRSE(exec.LookPath),
-	}, // exec_look_path(file) => string/error
-	"exec": &tengo.UserFunction{
+	}), // exec_look_path(file) => string/error
+	"exec": privileged("os.exec", &tengo.UserFunction{
 		Name:  "exec",
 		Value: osExec,
-	}, // exec(name, args...) => command
-	"stat": &tengo.UserFunction{
+	}), // exec(name, args...) => command
+	"stat": privileged("os.fs.read", &tengo.VMFunction{
 		Name:  "stat",
 		Value: osStat,
-	}, // stat(name) => imap(fileinfo)/error
-	"read_file": &tengo.UserFunction{
+	}), // stat(name) => imap(fileinfo)/error
+	"read_file": privileged("os.fs.read", &tengo.VMFunction{
 		Name:  "read_file",
 		Value: osReadFile,
-	}, // readfile(name) => array(byte)/error
+	}), // readfile(name) => array(byte)/error
 }
 
-func osReadFile(args ...tengo.Object) (ret tengo.Object, err error) {
+func osReadFile(
+	vm *tengo.VM,
+	args ...tengo.Object,
+) (ret tengo.Object, err error) {
 	if len(args) != 1 {
 		return nil, tengo.ErrWrongNumArguments
 	}
@@ -212,7 +211,28 @@ func osReadFile(args ...tengo.Object) (ret tengo.Object, err error) {
 			Found:    args[0].TypeName(),
 		}
 	}
-	bytes, err := ioutil.ReadFile(fname)
+	fsys := vm.FS()
+	var stat os.FileInfo
+	if fsys != nil {
+		stat, err = fsys.Stat(fname)
+	} else {
+		stat, err = os.Stat(fname)
+	}
+	if err != nil {
+		return wrapError(err), nil
+	}
+	if stat.Size() > int64(tengo.MaxBytesLen) {
+		return nil, tengo.ErrBytesLimit
+	}
+	if err := vm.Allocate(stat.Size()); err != nil {
+		return nil, err
+	}
+	var bytes []byte
+	if fsys != nil {
+		bytes, err = fsReadFile(fsys, fname)
+	} else {
+		bytes, err = ioutil.ReadFile(fname)
+	}
 	if err != nil {
 		return wrapError(err), nil
 	}
@@ -222,7 +242,10 @@ func osReadFile(args ...tengo.Object) (ret tengo.Object, err error) {
 	return &tengo.Bytes{Value: bytes}, nil
 }
 
-func osStat(args ...tengo.Object) (ret tengo.Object, err error) {
+func osStat(
+	vm *tengo.VM,
+	args ...tengo.Object,
+) (ret tengo.Object, err error) {
 	if len(args) != 1 {
 		return nil, tengo.ErrWrongNumArguments
 	}
@@ -234,10 +257,20 @@ func osStat(args ...tengo.Object) (ret tengo.Object, err error) {
 			Found:    args[0].TypeName(),
 		}
 	}
-	stat, err := os.Stat(fname)
+	var stat os.FileInfo
+	if fsys := vm.FS(); fsys != nil {
+		stat, err = fsys.Stat(fname)
+	} else {
+		stat, err = os.Stat(fname)
+	}
 	if err != nil {
 		return wrapError(err), nil
 	}
+	return makeFileInfo(stat), nil
+}
+
+// makeFileInfo returns the immutable map of the file info.
+func makeFileInfo(stat os.FileInfo) *tengo.ImmutableMap {
 	fstat := &tengo.ImmutableMap{
 		Value: map[string]tengo.Object{
 			"name":  &tengo.String{Value: stat.Name()},
@@ -251,10 +284,10 @@ func osStat(args ...tengo.Object) (ret tengo.Object, err error) {
 	} else {
 		fstat.Value["directory"] = tengo.FalseValue
 	}
-	return fstat, nil
+	return fstat
 }
 
-func osCreate(args ...tengo.Object) (tengo.Object, error) {
+func osCreate(vm *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
 	if len(args) != 1 {
 		return nil, tengo.ErrWrongNumArguments
 	}
@@ -266,6 +299,10 @@ func osCreate(args ...tengo.Object) (tengo.Object, error) {
 			Found:    args[0].TypeName(),
 		}
 	}
+	if vm.FS() != nil {
+		return fsOpenFile(vm, "create", s1,
+			os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	}
 	res, err := os.Create(s1)
 	if err != nil {
 		return wrapError(err), nil
@@ -273,7 +310,7 @@ func osCreate(args ...tengo.Object) (tengo.Object, error) {
 	return makeOSFile(res), nil
 }
 
-func osOpen(args ...tengo.Object) (tengo.Object, error) {
+func osOpen(vm *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
 	if len(args) != 1 {
 		return nil, tengo.ErrWrongNumArguments
 	}
@@ -285,6 +322,13 @@ func osOpen(args ...tengo.Object) (tengo.Object, error) {
 			Found:    args[0].TypeName(),
 		}
 	}
+	if fsys := vm.FS(); fsys != nil {
+		file, err := fsys.Open(s1)
+		if err != nil {
+			return wrapError(err), nil
+		}
+		return makeFSFile(s1, file), nil
+	}
 	res, err := os.Open(s1)
 	if err != nil {
 		return wrapError(err), nil
@@ -292,7 +336,7 @@ func osOpen(args ...tengo.Object) (tengo.Object, error) {
 	return makeOSFile(res), nil
 }
 
-func osOpenFile(args ...tengo.Object) (tengo.Object, error) {
+func osOpenFile(vm *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
 	if len(args) != 3 {
 		return nil, tengo.ErrWrongNumArguments
 	}
@@ -320,6 +364,18 @@ func osOpenFile(args ...tengo.Object) (tengo.Object, error) {
 			Found:    args[2].TypeName(),
 		}
 	}
+	// opening a file only for reading requires the read capability
+	capability := "os.fs.read"
+	if i2&osWriteFlags != 0 {
+		capability = "os.fs.write"
+	}
+	err := vm.CheckCapability(capability, "os.open_file", args)
+	if err != nil {
+		return wrapError(err), nil
+	}
+	if vm.FS() != nil {
+		return fsOpenFile(vm, "open", s1, i2, os.FileMode(i3))
+	}
 	res, err := os.OpenFile(s1, i2, os.FileMode(i3))
 	if err != nil {
 		return wrapError(err), nil
@@ -341,13 +397,17 @@ func osArgs(args ...tengo.Object) (tengo.Object, error) {
 	return arr, nil
 }
 
+// osFuncASFmRE returns the function name that calls fn with a file name and
+// a file mode. If the VM has a file system, it calls fsFn instead, or fails
+// if fsFn is nil or the file system is read-only.
 func osFuncASFmRE(
 	name string,
 	fn func(string, os.FileMode) error,
-) *tengo.UserFunction {
-	return &tengo.UserFunction{
+	fsFn func(tengo.WritableFS, string, os.FileMode) error,
+) *tengo.VMFunction {
+	return &tengo.VMFunction{
 		Name: name,
-		Value: func(args ...tengo.Object) (tengo.Object, error) {
+		Value: func(vm *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
 			if len(args) != 2 {
 				return nil, tengo.ErrWrongNumArguments
 			}
@@ -367,6 +427,13 @@ func osFuncASFmRE(
 					Found:    args[1].TypeName(),
 				}
 			}
+			if vm.FS() != nil {
+				fsys, err := writableFS(vm, name, s1, fsFn != nil)
+				if err != nil {
+					return wrapError(err), nil
+				}
+				return wrapError(fsFn(fsys, s1, os.FileMode(i2))), nil
+			}
 			return wrapError(fn(s1, os.FileMode(i2))), nil
 		},
 	}
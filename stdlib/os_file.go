@@ -1,6 +1,7 @@
 package stdlib
 
 import (
+	"io"
 	"os"
 
 	"github.com/d5/tengo/v2"
@@ -73,35 +74,7 @@ func makeOSFile(file *os.File) *tengo.ImmutableMap {
 				},
 			},
 			// seek(offset int, whence int) => int/error
-			"seek": &tengo.UserFunction{
-				Name: "seek",
-				Value: func(args ...tengo.Object) (tengo.Object, error) {
-					if len(args) != 2 {
-						return nil, tengo.ErrWrongNumArguments
-					}
-					i1, ok := tengo.ToInt64(args[0])
-					if !ok {
-						return nil, tengo.ErrInvalidArgumentType{
-							Name:     "first",
-							Expected: "int(compatible)",
-							Found:    args[0].TypeName(),
-						}
-					}
-					i2, ok := tengo.ToInt(args[1])
-					if !ok {
-						return nil, tengo.ErrInvalidArgumentType{
-							Name:     "second",
-							Expected: "int(compatible)",
-							Found:    args[1].TypeName(),
-						}
-					}
-					res, err := file.Seek(i1, i2)
-					if err != nil {
-						return wrapError(err), nil
-					}
-					return &tengo.Int{Value: res}, nil
-				},
-			},
+			"seek": fileSeekFunc(file),
 			// stat() => imap(fileinfo)/error
 			"stat": &tengo.UserFunction{
 				Name: "stat",
@@ -109,9 +82,46 @@ func makeOSFile(file *os.File) *tengo.ImmutableMap {
 					if len(args) != 0 {
 						return nil, tengo.ErrWrongNumArguments
 					}
-					return osStat(&tengo.String{Value: file.Name()})
+					stat, err := file.Stat()
+					if err != nil {
+						return wrapError(err), nil
+					}
+					return makeFileInfo(stat), nil
 				},
 			},
 		},
 	}
 }
+
+// fileSeekFunc returns the seek function of the file.
+func fileSeekFunc(file io.Seeker) *tengo.UserFunction {
+	return &tengo.UserFunction{
+		Name: "seek",
+		Value: func(args ...tengo.Object) (tengo.Object, error) {
+			if len(args) != 2 {
+				return nil, tengo.ErrWrongNumArguments
+			}
+			i1, ok := tengo.ToInt64(args[0])
+			if !ok {
+				return nil, tengo.ErrInvalidArgumentType{
+					Name:     "first",
+					Expected: "int(compatible)",
+					Found:    args[0].TypeName(),
+				}
+			}
+			i2, ok := tengo.ToInt(args[1])
+			if !ok {
+				return nil, tengo.ErrInvalidArgumentType{
+					Name:     "second",
+					Expected: "int(compatible)",
+					Found:    args[1].TypeName(),
+				}
+			}
+			res, err := file.Seek(i1, i2)
+			if err != nil {
+				return wrapError(err), nil
+			}
+			return &tengo.Int{Value: res}, nil
+		},
+	}
+}
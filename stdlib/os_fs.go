@@ -0,0 +1,183 @@
+package stdlib
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/d5/tengo/v2"
+)
+
+// osWriteFlags are the flags of opening a file that may modify it.
+const osWriteFlags = os.O_WRONLY | os.O_RDWR | os.O_APPEND | os.O_CREATE |
+	os.O_TRUNC
+
+// osPathFunc returns the function name that calls fn with the arguments, the
+// first of which is a file name. If the VM has a file system, it calls fsFn
+// with the name instead, or fails if fsFn is nil or the file system is
+// read-only.
+func osPathFunc(
+	name string,
+	fn tengo.CallableFunc,
+	fsFn func(tengo.WritableFS, string) error,
+) *tengo.VMFunction {
+	return &tengo.VMFunction{
+		Name: name,
+		Value: func(vm *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
+			if vm.FS() == nil {
+				return fn(args...)
+			}
+			if len(args) == 0 || (fsFn != nil && len(args) != 1) {
+				return nil, tengo.ErrWrongNumArguments
+			}
+			s1, ok := tengo.ToString(args[0])
+			if !ok {
+				return nil, tengo.ErrInvalidArgumentType{
+					Name:     "first",
+					Expected: "string(compatible)",
+					Found:    args[0].TypeName(),
+				}
+			}
+			fsys, err := writableFS(vm, name, s1, fsFn != nil)
+			if err != nil {
+				return wrapError(err), nil
+			}
+			return wrapError(fsFn(fsys, s1)), nil
+		},
+	}
+}
+
+// writableFS returns the file system of the VM if it's writable and the
+// operation op is supported. Otherwise, it returns a permission error.
+func writableFS(
+	vm *tengo.VM,
+	op, name string,
+	supported bool,
+) (tengo.WritableFS, error) {
+	if supported {
+		if fsys, ok := vm.FS().(tengo.WritableFS); ok {
+			return fsys, nil
+		}
+	}
+	return nil, &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+}
+
+// fsOpenFile opens the named file of the file system of the VM with the flag
+// and the permission bits. It requires a writable file system unless the
+// file is opened only for reading.
+func fsOpenFile(
+	vm *tengo.VM,
+	op, name string,
+	flag int,
+	perm os.FileMode,
+) (tengo.Object, error) {
+	var file tengo.File
+	if flag&osWriteFlags == 0 {
+		f, err := vm.FS().Open(name)
+		if err != nil {
+			return wrapError(err), nil
+		}
+		file = f
+	} else {
+		fsys, err := writableFS(vm, op, name, true)
+		if err != nil {
+			return wrapError(err), nil
+		}
+		if file, err = fsys.OpenFile(name, flag, perm); err != nil {
+			return wrapError(err), nil
+		}
+	}
+	return makeFSFile(name, file), nil
+}
+
+// fsReadFile reads the named file of the file system.
+func fsReadFile(fsys tengo.FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return ioutil.ReadAll(f)
+}
+
+// fsMkdirAll creates the named directory of the file system and its parent
+// directories that don't exist.
+func fsMkdirAll(fsys tengo.WritableFS, name string, perm os.FileMode) error {
+	if fi, err := fsys.Stat(name); err == nil {
+		if fi.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: name,
+			Err: errors.New("not a directory")}
+	}
+	if parent := path.Dir(path.Clean("/" + name)); parent != "/" {
+		if err := fsMkdirAll(fsys, parent, perm); err != nil {
+			return err
+		}
+	}
+	return fsys.Mkdir(name, perm)
+}
+
+// makeFSFile returns the immutable map of the file opened from a file system.
+// It has the functions of the file that the file supports.
+func makeFSFile(name string, file tengo.File) *tengo.ImmutableMap {
+	m := map[string]tengo.Object{
+		// close() => error
+		"close": &tengo.UserFunction{
+			Name:  "close",
+			Value: FuncARE(file.Close),
+		}, //
+		// name() => string
+		"name": &tengo.UserFunction{
+			Name:  "name",
+			Value: FuncARS(func() string { return name }),
+		}, //
+		// read(bytes) => int/error
+		"read": &tengo.UserFunction{
+			Name:  "read",
+			Value: FuncAYRIE(file.Read),
+		}, //
+		// stat() => imap(fileinfo)/error
+		"stat": &tengo.UserFunction{
+			Name: "stat",
+			Value: func(args ...tengo.Object) (tengo.Object, error) {
+				if len(args) != 0 {
+					return nil, tengo.ErrWrongNumArguments
+				}
+				stat, err := file.Stat()
+				if err != nil {
+					return wrapError(err), nil
+				}
+				return makeFileInfo(stat), nil
+			},
+		},
+	}
+	if w, ok := file.(io.Writer); ok {
+		// write(bytes) => int/error
+		m["write"] = &tengo.UserFunction{
+			Name:  "write",
+			Value: FuncAYRIE(w.Write),
+		}
+		// write_string(string) => int/error
+		m["write_string"] = &tengo.UserFunction{
+			Name: "write_string",
+			Value: FuncASRIE(func(s string) (int, error) {
+				return w.Write([]byte(s))
+			}),
+		}
+	}
+	if s, ok := file.(io.Seeker); ok {
+		// seek(offset int, whence int) => int/error
+		m["seek"] = fileSeekFunc(s)
+	}
+	if s, ok := file.(interface{ Sync() error }); ok {
+		// sync() => error
+		m["sync"] = &tengo.UserFunction{
+			Name:  "sync",
+			Value: FuncARE(s.Sync),
+		}
+	}
+	return &tengo.ImmutableMap{Value: m}
+}
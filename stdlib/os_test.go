@@ -3,10 +3,12 @@ package stdlib_test
 import (
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/d5/tengo/v2"
 	"github.com/d5/tengo/v2/require"
+	"github.com/d5/tengo/v2/stdlib"
 )
 
 func TestReadFile(t *testing.T) {
@@ -106,3 +108,94 @@ func TestOSExpandEnv(t *testing.T) {
 	_ = os.Setenv("TENGO", "123456")
 	module(t, "os").call("expand_env", "${TENGO} ${TENGO}").expectError()
 }
+
+func TestOSFS(t *testing.T) {
+	fsys := tengo.NewMemFS()
+	require.NoError(t, fsys.WriteFile("a.txt", []byte("foo"), 0644))
+
+	expectFS(t, fsys, `
+os := import("os")
+out := [string(os.read_file("a.txt")), os.stat("/a.txt").size,
+	os.stat("b.txt").value]
+`, ARR{"foo", 3, "stat b.txt: file does not exist"})
+	expectFS(t, fsys, `
+os := import("os")
+f := os.open("a.txt")
+b := bytes(5)
+n := f.read(b)
+f.close()
+out := [n, string(b[:n]), f.name(), is_undefined(f.chdir)]
+`, ARR{3, "foo", "a.txt", true})
+
+	// writes
+	expectFS(t, fsys, `
+os := import("os")
+os.mkdir_all("x/y", 0755)
+f := os.create("x/y/b.txt")
+f.write_string("bar")
+f.seek(0, os.seek_set)
+b := bytes(3)
+f.read(b)
+f.close()
+f = os.open_file("x/y/b.txt", os.o_append|os.o_wronly, 0)
+f.write(bytes("!"))
+f.close()
+out := [string(b), string(os.read_file("x/y/b.txt")),
+	os.stat("x").directory, os.remove("x"), os.remove("x/y/b.txt")]
+`, ARR{"bar", "bar!", true,
+		&tengo.Error{Value: &tengo.String{
+			Value: "remove x: directory not empty"}},
+		tengo.TrueValue})
+	require.Equal(t, "/a.txt,/x,/x/y", strings.Join(fsys.Names(), ","))
+
+	// unsupported functions and a read-only file system
+	expectFS(t, fsys, `
+os := import("os")
+out := [string(os.chmod("a.txt", 0777)), string(os.rename("a.txt", "b")),
+	string(os.remove_all("x"))]
+`, ARR{`error: "chmod a.txt: permission denied"`,
+		`error: "rename a.txt: permission denied"`,
+		`error: "remove_all x: permission denied"`})
+	expectFS(t, tengo.ReadOnlyFS(fsys), `
+os := import("os")
+out := [string(os.create("b.txt")), string(os.mkdir("z", 0755)),
+	string(os.open_file("a.txt", os.o_rdonly, 0).name())]
+`, ARR{`error: "create b.txt: permission denied"`,
+		`error: "mkdir z: permission denied"`, "a.txt"})
+}
+
+func TestOSOpenFileCapabilities(t *testing.T) {
+	fsys := tengo.NewMemFS()
+	require.NoError(t, fsys.WriteFile("a.txt", []byte("foo"), 0644))
+	var capabilities []string
+	s := tengo.NewScript([]byte(`
+os := import("os")
+out := [os.open_file("a.txt", os.o_rdonly, 0).name(),
+	string(os.open_file("a.txt", os.o_wronly, 0)),
+	string(os.open_file("b.txt", os.o_rdonly|os.o_create, 0644))]`))
+	s.SetImports(stdlib.GetModuleMap("os"))
+	s.SetFS(fsys)
+	s.SetCapabilities("os.fs.read")
+	s.SetAuditHook(func(e tengo.AuditEvent) {
+		capabilities = append(capabilities, e.Capability)
+	})
+	c, err := s.Run()
+	require.NoError(t, err)
+	require.Equal(t, object(ARR{"a.txt",
+		`error: "permission denied: os.open_file requires capability ` +
+			`'os.fs.write'"`,
+		`error: "permission denied: os.open_file requires capability ` +
+			`'os.fs.write'"`,
+	}), c.Get("out").Object())
+	require.Equal(t, "os.fs.read,os.fs.write,os.fs.write",
+		strings.Join(capabilities, ","))
+}
+
+func expectFS(t *testing.T, fsys tengo.FS, input string, expected interface{}) {
+	s := tengo.NewScript([]byte(input))
+	s.SetImports(stdlib.GetModuleMap("os"))
+	s.SetFS(fsys)
+	c, err := s.Run()
+	require.NoError(t, err, input)
+	require.Equal(t, object(expected), c.Get("out").Object(), input)
+}
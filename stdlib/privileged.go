@@ -0,0 +1,46 @@
+package stdlib
+
+import (
+	"strings"
+
+	"github.com/d5/tengo/v2"
+)
+
+// privileged returns the function fn that requires the capability. The
+// module name of the function is the first element of the capability. The
+// denied calls return the permission error. See VM.CheckCapability.
+func privileged(capability string, fn tengo.Object) *tengo.UserFunction {
+	var name string
+	var call tengo.VMCallableFunc
+	switch fn := fn.(type) {
+	case *tengo.UserFunction:
+		name = fn.Name
+		call = func(_ *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
+			return fn.Value(args...)
+		}
+	case *tengo.VMFunction:
+		name = fn.Name
+		call = fn.Value
+	default:
+		panic("privileged: not a function")
+	}
+	module := capability
+	if i := strings.IndexByte(capability, '.'); i >= 0 {
+		module = capability[:i]
+	}
+	fullName := module + "." + name
+	callVM := func(vm *tengo.VM, args ...tengo.Object) (tengo.Object, error) {
+		err := vm.CheckCapability(capability, fullName, args)
+		if err != nil {
+			return wrapError(err), nil
+		}
+		return call(vm, args...)
+	}
+	return &tengo.UserFunction{
+		Name: name,
+		Value: func(args ...tengo.Object) (tengo.Object, error) {
+			return callVM(nil, args...)
+		},
+		VMValue: callVM,
+	}
+}
@@ -71,7 +71,7 @@ var textModule = map[string]tengo.Object{
 		Name:  "index_any",
 		Value: FuncASSRI(strings.IndexAny),
 	}, // index_any(s, chars) => int
-	"join": &tengo.UserFunction{
+	"join": &tengo.VMFunction{
 		Name:  "join",
 		Value: textJoin,
 	}, // join(arr, sep) => string
@@ -83,7 +83,7 @@ var textModule = map[string]tengo.Object{
 		Name:  "last_index_any",
 		Value: FuncASSRI(strings.LastIndexAny),
 	}, // last_index_any(s, chars) => int
-	"repeat": &tengo.UserFunction{
+	"repeat": &tengo.VMFunction{
 		Name:  "repeat",
 		Value: textRepeat,
 	}, // repeat(s, count) => string
@@ -711,7 +711,10 @@ func textPadRight(args ...tengo.Object) (ret tengo.Object, err error) {
 	return
 }
 
-func textRepeat(args ...tengo.Object) (ret tengo.Object, err error) {
+func textRepeat(
+	vm *tengo.VM,
+	args ...tengo.Object,
+) (ret tengo.Object, err error) {
 	if len(args) != 2 {
 		return nil, tengo.ErrWrongNumArguments
 	}
@@ -737,11 +740,17 @@ func textRepeat(args ...tengo.Object) (ret tengo.Object, err error) {
 	if len(s1)*i2 > tengo.MaxStringLen {
 		return nil, tengo.ErrStringLimit
 	}
+	if err := vm.Allocate(int64(len(s1) * i2)); err != nil {
+		return nil, err
+	}
 
 	return &tengo.String{Value: strings.Repeat(s1, i2)}, nil
 }
 
-func textJoin(args ...tengo.Object) (ret tengo.Object, err error) {
+func textJoin(
+	vm *tengo.VM,
+	args ...tengo.Object,
+) (ret tengo.Object, err error) {
 	if len(args) != 2 {
 		return nil, tengo.ErrWrongNumArguments
 	}
@@ -793,9 +802,13 @@ func textJoin(args ...tengo.Object) (ret tengo.Object, err error) {
 	}
 
 	// make sure output length does not exceed the limit
-	if slen+len(s2)*(len(ss1)-1) > tengo.MaxStringLen {
+	size := slen + len(s2)*(len(ss1)-1)
+	if size > tengo.MaxStringLen {
 		return nil, tengo.ErrStringLimit
 	}
+	if err := vm.Allocate(int64(size)); err != nil {
+		return nil, err
+	}
 
 	return &tengo.String{Value: strings.Join(ss1, s2)}, nil
 }
@@ -40,10 +40,10 @@ var timesModule = map[string]tengo.Object{
 	"october":             &tengo.Int{Value: int64(time.October)},
 	"november":            &tengo.Int{Value: int64(time.November)},
 	"december":            &tengo.Int{Value: int64(time.December)},
-	"sleep": &tengo.UserFunction{
+	"sleep": privileged("times.sleep", &tengo.UserFunction{
 		Name:  "sleep",
 		Value: timesSleep,
-	}, // sleep(int)
+	}), // sleep(int)
 	"parse_duration": &tengo.UserFunction{
 		Name:  "parse_duration",
 		Value: timesParseDuration,
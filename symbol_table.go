@@ -174,6 +174,19 @@ func (t *SymbolTable) updateMaxDefs(numDefs int) {
 	}
 }
 
+// original returns the symbol that a symbol resolved in the current scope
+// refers to. It's the symbol itself unless the symbol is a free variable.
+func (t *SymbolTable) original(symbol *Symbol) *Symbol {
+	for symbol.Scope == ScopeFree {
+		for t.block {
+			t = t.parent
+		}
+		symbol = t.freeSymbols[symbol.Index]
+		t = t.parent
+	}
+	return symbol
+}
+
 func (t *SymbolTable) defineFree(original *Symbol) *Symbol {
 	// TODO: should we check duplicates?
 	t.freeSymbols = append(t.freeSymbols, original)
@@ -3,6 +3,9 @@ package tengo
 import (
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"reflect"
 	"strconv"
 	"time"
 )
@@ -21,16 +24,22 @@ const (
 	// GlobalsSize is the maximum number of global variables for a VM.
 	GlobalsSize = 1024
 
-	// StackSize is the maximum stack size for a VM.
+	// StackSize is the default maximum stack size for a VM. See
+	// VM.SetMaxStackSize.
 	StackSize = 2048
 
-	// MaxFrames is the maximum number of function frames for a VM.
+	// MaxFrames is the default maximum number of function frames for a VM.
+	// See VM.SetMaxFrames.
 	MaxFrames = 1024
 )
 
 // CallableFunc is a function signature for the callable functions.
 type CallableFunc = func(args ...Object) (ret Object, err error)
 
+// VMCallableFunc is a function signature for the callable functions that
+// receive the VM calling them.
+type VMCallableFunc = func(vm *VM, args ...Object) (ret Object, err error)
+
 // CountObjects returns the number of objects that a given object o contains.
 // For scalar value types, it will always be 1. For compound value types,
 // this will include its elements and all of their elements recursively.
@@ -49,10 +58,16 @@ func CountObjects(o Object) (c int) {
 		for _, v := range o.Value {
 			c += CountObjects(v)
 		}
+		for _, e := range o.Hashed.Entries() {
+			c += CountObjects(e.Value)
+		}
 	case *ImmutableMap:
 		for _, v := range o.Value {
 			c += CountObjects(v)
 		}
+		for _, e := range o.Hashed.Entries() {
+			c += CountObjects(e.Value)
+		}
 	case *Error:
 		c += CountObjects(o.Value)
 	}
@@ -73,12 +88,27 @@ func ToString(o Object) (v string, ok bool) {
 	return
 }
 
+// ToStrictString returns the value of the string object o. Unlike ToString,
+// it returns false for the other objects. The functions wrapped by WrapFunc
+// convert their string arguments with it.
+func ToStrictString(o Object) (v string, ok bool) {
+	if str, isStr := o.(*String); isStr {
+		return str.Value, true
+	}
+	return
+}
+
 // ToInt will try to convert object o to int value.
 func ToInt(o Object) (v int, ok bool) {
 	switch o := o.(type) {
 	case *Int:
 		v = int(o.Value)
 		ok = true
+	case *BigInt, *Decimal:
+		var i int64
+		if i, ok = ToInt64(o); ok {
+			v = int(i)
+		}
 	case *Float:
 		v = int(o.Value)
 		ok = true
@@ -106,6 +136,16 @@ func ToInt64(o Object) (v int64, ok bool) {
 	case *Int:
 		v = o.Value
 		ok = true
+	case *BigInt:
+		if o.Value.IsInt64() {
+			v = o.Value.Int64()
+			ok = true
+		}
+	case *Decimal:
+		if i, isInt := ToBigInt(o); isInt && i.IsInt64() {
+			v = i.Int64()
+			ok = true
+		}
 	case *Float:
 		v = int64(o.Value)
 		ok = true
@@ -127,6 +167,31 @@ func ToInt64(o Object) (v int64, ok bool) {
 	return
 }
 
+// ToStrictInt is like ToStrictInt64, but also returns false if the value is
+// out of the range of int.
+func ToStrictInt(o Object) (v int, ok bool) {
+	i, ok := ToStrictInt64(o)
+	if !ok || int64(int(i)) != i {
+		return 0, false
+	}
+	return int(i), true
+}
+
+// ToStrictInt64 is like ToInt64, but returns false if o is a float or a
+// decimal that has a fractional part or is out of the range of int64, instead
+// of truncating it. The functions wrapped by WrapFunc convert their integer
+// arguments with it.
+func ToStrictInt64(o Object) (v int64, ok bool) {
+	if f, isFloat := o.(*Float); isFloat &&
+		!(f.Value >= math.MinInt64 && f.Value < math.MaxInt64) {
+		return
+	}
+	if hasFraction(o) {
+		return
+	}
+	return ToInt64(o)
+}
+
 // ToFloat64 will try to convert object o to float64 value.
 func ToFloat64(o Object) (v float64, ok bool) {
 	switch o := o.(type) {
@@ -136,6 +201,12 @@ func ToFloat64(o Object) (v float64, ok bool) {
 	case *Float:
 		v = o.Value
 		ok = true
+	case *BigInt:
+		v = bigIntToFloat(o.Value)
+		ok = true
+	case *Decimal:
+		v, _ = o.Value.Float64()
+		ok = true
 	case *String:
 		c, err := strconv.ParseFloat(o.Value, 64)
 		if err == nil {
@@ -146,6 +217,60 @@ func ToFloat64(o Object) (v float64, ok bool) {
 	return
 }
 
+// ToBigInt will try to convert object o to *big.Int value. Floats and
+// decimals are truncated toward zero.
+func ToBigInt(o Object) (v *big.Int, ok bool) {
+	switch o := o.(type) {
+	case *BigInt:
+		v = o.Value
+		ok = true
+	case *Int:
+		v = big.NewInt(o.Value)
+		ok = true
+	case *Float:
+		if !math.IsInf(o.Value, 0) && !math.IsNaN(o.Value) {
+			v, _ = big.NewFloat(o.Value).Int(nil)
+			ok = true
+		}
+	case *Decimal:
+		v = new(big.Int).Quo(o.Value.Num(), o.Value.Denom())
+		ok = true
+	case *Char:
+		v = big.NewInt(int64(o.Value))
+		ok = true
+	case *Bool:
+		v = new(big.Int)
+		if o == TrueValue {
+			v.SetInt64(1)
+		}
+		ok = true
+	case *String:
+		v, ok = new(big.Int).SetString(o.Value, 10)
+	}
+	return
+}
+
+// ToDecimal will try to convert object o to *big.Rat value of a decimal.
+// Floats are converted to the shortest decimals that represent them.
+func ToDecimal(o Object) (v *big.Rat, ok bool) {
+	switch o := o.(type) {
+	case *Decimal:
+		v = o.Value
+		ok = true
+	case *Int:
+		v = new(big.Rat).SetInt64(o.Value)
+		ok = true
+	case *BigInt:
+		v = new(big.Rat).SetInt(o.Value)
+		ok = true
+	case *Float:
+		v, ok = floatToDecimal(o.Value)
+	case *String:
+		v, ok = parseDecimal(o.Value)
+	}
+	return
+}
+
 // ToBool will try to convert object o to bool value.
 func ToBool(o Object) (v bool, ok bool) {
 	ok = true
@@ -192,11 +317,32 @@ func ToTime(o Object) (v time.Time, ok bool) {
 	return
 }
 
-// ToInterface attempts to convert an object o to an interface{} value
+// ToDuration will try to convert object o to time.Duration value. An int is
+// the number of nanoseconds, and a string is parsed by time.ParseDuration.
+func ToDuration(o Object) (v time.Duration, ok bool) {
+	if s, isStr := o.(*String); isStr {
+		var err error
+		v, err = time.ParseDuration(s.Value)
+		ok = err == nil
+		return
+	}
+	var i int64
+	i, ok = ToInt64(o)
+	v = time.Duration(i)
+	return
+}
+
+// ToInterface attempts to convert an object o to an interface{} value. A map
+// whose keys can't be converted to distinct Go values, e.g. an immutable array
+// key [1] and a string key "[1]", is converted to an error value.
 func ToInterface(o Object) (res interface{}) {
 	switch o := o.(type) {
 	case *Int:
 		res = o.Value
+	case *BigInt:
+		res = o.Value
+	case *Decimal:
+		res = o.Value
 	case *String:
 		res = o.Value
 	case *Float:
@@ -218,17 +364,18 @@ func ToInterface(o Object) (res interface{}) {
 			res.([]interface{})[i] = ToInterface(val)
 		}
 	case *Map:
-		res = make(map[string]interface{})
-		for key, v := range o.Value {
-			res.(map[string]interface{})[key] = ToInterface(v)
-		}
+		res = mapToInterface(o.Value, o.Hashed)
 	case *ImmutableMap:
+		res = mapToInterface(o.Value, o.Hashed)
+	case *Record:
 		res = make(map[string]interface{})
-		for key, v := range o.Value {
-			res.(map[string]interface{})[key] = ToInterface(v)
+		for i, f := range o.Type.Fields {
+			res.(map[string]interface{})[f] = ToInterface(o.Values[i])
 		}
 	case *Time:
 		res = o.Value
+	case *GoStruct:
+		res = o.Value.Interface()
 	case *Error:
 		res = errors.New(o.String())
 	case *Undefined:
@@ -264,6 +411,10 @@ func FromInterface(v interface{}) (Object, error) {
 		return &Char{Value: rune(v)}, nil
 	case float64:
 		return &Float{Value: v}, nil
+	case *big.Int:
+		return &BigInt{Value: v}, nil
+	case *big.Rat:
+		return &Decimal{Value: decimalValue(v)}, nil
 	case []byte:
 		if len(v) > MaxBytesLen {
 			return nil, ErrBytesLimit
@@ -283,6 +434,27 @@ func FromInterface(v interface{}) (Object, error) {
 			kv[vk] = vo
 		}
 		return &Map{Value: kv}, nil
+	case map[interface{}]interface{}:
+		m := &Map{Value: make(map[string]Object)}
+		for vk, vv := range v {
+			ko, err := FromInterface(vk)
+			if err != nil {
+				return nil, err
+			}
+			vo, err := FromInterface(vv)
+			if err != nil {
+				return nil, err
+			}
+			n := m.Len()
+			if err := m.IndexSet(ko, vo); err != nil {
+				return nil, fmt.Errorf("cannot convert to map key: %T", vk)
+			}
+			if m.Len() == n { // e.g. int64(1) and "1"
+				key, _ := ToString(ko)
+				return nil, fmt.Errorf("duplicate key %q", key)
+			}
+		}
+		return m, nil
 	case []Object:
 		return &Array{Value: v}, nil
 	case []interface{}:
@@ -301,6 +473,40 @@ func FromInterface(v interface{}) (Object, error) {
 		return v, nil
 	case CallableFunc:
 		return &UserFunction{Value: v}, nil
+	case VMCallableFunc:
+		return &VMFunction{Value: v}, nil
+	}
+	return fromReflect(reflect.ValueOf(v))
+}
+
+// mapToInterface returns map[string]interface{} value of the map entries; or
+// map[interface{}]interface{} value if the map has keys other than strings.
+// Immutable array keys are converted to their string representations because
+// Go slices can't be map keys, and it returns an error value if such a key is
+// the same as another key.
+func mapToInterface(kv map[string]Object, hashed *HashedMap) interface{} {
+	if hashed.Len() == 0 {
+		res := make(map[string]interface{}, len(kv))
+		for key, v := range kv {
+			res[key] = ToInterface(v)
+		}
+		return res
+	}
+	res := make(map[interface{}]interface{}, len(kv)+hashed.Len())
+	for key, v := range kv {
+		res[key] = ToInterface(v)
+	}
+	for _, e := range hashed.Entries() {
+		var key interface{}
+		if arr, ok := e.Key.(*ImmutableArray); ok {
+			key = arr.String()
+			if _, ok := res[key]; ok {
+				return fmt.Errorf("duplicate key %q", key)
+			}
+		} else {
+			key = ToInterface(e.Key)
+		}
+		res[key] = ToInterface(e.Value)
 	}
-	return nil, fmt.Errorf("cannot convert to object: %T", v)
+	return res
 }
@@ -1,6 +1,7 @@
 package tengo_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -135,3 +136,290 @@ func makeInstruction(
 	inst := tengo.MakeInstruction(opcode, operands...)
 	require.Equal(t, expected, inst)
 }
+
+func TestToInterface_Map(t *testing.T) {
+	m := &tengo.Map{Value: map[string]tengo.Object{
+		"a": &tengo.Int{Value: 1},
+	}}
+	require.True(t, tengo.ToInterface(m).(map[string]interface{})["a"] ==
+		int64(1))
+
+	require.NoError(t, m.IndexSet(&tengo.Float{Value: 2}, &tengo.Int{Value: 3}))
+	require.NoError(t, m.IndexSet(&tengo.ImmutableArray{
+		Value: []tengo.Object{&tengo.Int{Value: 4}},
+	}, &tengo.Int{Value: 5}))
+	v := tengo.ToInterface(m).(map[interface{}]interface{})
+	require.Equal(t, 3, len(v))
+	require.True(t, v["a"] == int64(1))
+	require.True(t, v[float64(2)] == int64(3))
+	require.True(t, v["[4]"] == int64(5))
+
+	// an immutable array key can't overwrite a string key
+	require.NoError(t, m.IndexSet(&tengo.String{Value: "[4]"},
+		&tengo.Int{Value: 6}))
+	err, ok := tengo.ToInterface(m).(error)
+	require.True(t, ok)
+	require.Equal(t, `duplicate key "[4]"`, err.Error())
+
+	o, err := tengo.FromInterface(map[interface{}]interface{}{
+		"a":      1,
+		int64(2): 3,
+		true:     "b",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, o.(*tengo.Map).Len())
+	res, err := o.IndexGet(tengo.TrueValue)
+	require.NoError(t, err)
+	require.Equal(t, "b", res.(*tengo.String).Value)
+
+	_, err = tengo.FromInterface(map[interface{}]interface{}{
+		"a": 1, struct{}{}: 2,
+	})
+	require.Error(t, err)
+
+	// ints are converted to string keys
+	_, err = tengo.FromInterface(map[interface{}]interface{}{
+		"1": 1, int64(1): 2,
+	})
+	require.Error(t, err)
+	require.Equal(t, `duplicate key "1"`, err.Error())
+}
+
+type testEmbedded struct {
+	ID int
+}
+
+type testPoint struct {
+	testEmbedded
+	X, Y   float64
+	Label  string `tengo:"label"`
+	Note   string `tengo:"note,omitempty"`
+	Hidden int    `tengo:"-"`
+	Tags   []string
+	Attrs  map[string]int
+	Inner  struct{ Z int8 }
+	hidden int
+}
+
+func (p *testPoint) Move(dx, dy float64) {
+	p.X += dx
+	p.Y += dy
+}
+
+func (p testPoint) Sum(values ...int) (int, error) {
+	if len(values) == 0 {
+		return 0, errors.New("no values")
+	}
+	var sum int
+	for _, v := range values {
+		sum += v
+	}
+	return sum, nil
+}
+
+func TestFromInterface_Struct(t *testing.T) {
+	p := &testPoint{X: 1, Label: "a", Tags: []string{"b"}}
+	o, err := tengo.FromInterface(p)
+	require.NoError(t, err)
+	s := o.(*tengo.GoStruct)
+	require.Equal(t, "tengo_test.testPoint", s.TypeName())
+	require.True(t, tengo.ToInterface(s) == p)
+
+	get := func(name string) tengo.Object {
+		v, err := s.IndexGet(&tengo.String{Value: name})
+		require.NoError(t, err, name)
+		return v
+	}
+	require.Equal(t, &tengo.Float{Value: 1}, get("X"))
+	require.Equal(t, &tengo.String{Value: "a"}, get("label"))
+	require.Equal(t, &tengo.Int{Value: 0}, get("ID"))
+	require.Equal(t, &tengo.Array{Value: []tengo.Object{
+		&tengo.String{Value: "b"}}}, get("Tags"))
+	require.Equal(t, tengo.UndefinedValue, get("Attrs"))
+	for _, name := range []string{"Label", "Hidden", "hidden", "nope"} {
+		_, err := s.IndexGet(&tengo.String{Value: name})
+		require.True(t, errors.Is(err, tengo.ErrUnknownField), name)
+	}
+
+	// fields are converted to the field types
+	require.NoError(t, s.IndexSet(&tengo.String{Value: "Y"},
+		&tengo.Int{Value: 2}))
+	require.NoError(t, s.IndexSet(&tengo.String{Value: "ID"},
+		&tengo.Int{Value: 3}))
+	require.NoError(t, s.IndexSet(&tengo.String{Value: "Attrs"},
+		&tengo.Map{Value: map[string]tengo.Object{
+			"c": &tengo.Int{Value: 4}}}))
+	require.Equal(t, 2.0, p.Y)
+	require.Equal(t, 3, p.ID)
+	require.Equal(t, 4, p.Attrs["c"])
+	require.Error(t, s.IndexSet(&tengo.String{Value: "X"},
+		&tengo.String{Value: "d"}))
+	require.Error(t, s.IndexSet(&tengo.String{Value: "Tags"},
+		&tengo.Int{Value: 1}))
+	require.NoError(t, s.IndexSet(&tengo.String{Value: "note"},
+		&tengo.String{Value: "e"}))
+	require.Equal(t, "e", p.Note)
+
+	// lossy conversions are errors
+	require.NoError(t, s.IndexSet(&tengo.String{Value: "ID"},
+		&tengo.Float{Value: 4}))
+	require.Equal(t, 4, p.ID)
+	err = s.IndexSet(&tengo.String{Value: "ID"}, &tengo.Float{Value: 1.9})
+	require.Equal(t, "invalid type for argument 'ID': expected int, "+
+		"found float", err.Error())
+	require.Error(t, s.IndexSet(&tengo.String{Value: "ID"},
+		&tengo.Float{Value: 1e19}))
+	err = s.IndexSet(&tengo.String{Value: "label"}, &tengo.Int{Value: 1})
+	require.Equal(t, "invalid type for argument 'label': expected string, "+
+		"found int", err.Error())
+	require.Equal(t, 4, p.ID)
+	require.Equal(t, "a", p.Label)
+
+	// slices are copied
+	tags := get("Tags").(*tengo.Array)
+	tags.Value[0] = &tengo.String{Value: "c"}
+	require.Equal(t, "b", p.Tags[0])
+
+	// nested structs are referenced
+	inner := get("Inner")
+	require.NoError(t, inner.IndexSet(&tengo.String{Value: "Z"},
+		&tengo.Int{Value: 5}))
+	require.Equal(t, 5, int(p.Inner.Z))
+	require.Error(t, inner.IndexSet(&tengo.String{Value: "Z"},
+		&tengo.Int{Value: 128}))
+
+	// methods
+	res, err := get("Move").Call(&tengo.Int{Value: 1}, &tengo.Float{Value: 2})
+	require.NoError(t, err)
+	require.Equal(t, tengo.UndefinedValue, res)
+	require.Equal(t, 2.0, p.X)
+	require.Equal(t, 4.0, p.Y)
+	_, err = get("Move").Call(&tengo.Int{Value: 1})
+	require.Equal(t, tengo.ErrWrongNumArguments, err)
+	_, err = get("Move").Call(&tengo.Int{Value: 1}, tengo.TrueValue)
+	require.Equal(t, "invalid type for argument 'second': expected float, "+
+		"found bool", err.Error())
+	res, err = get("Sum").Call(&tengo.Int{Value: 1}, &tengo.Int{Value: 2})
+	require.NoError(t, err)
+	require.Equal(t, &tengo.Int{Value: 3}, res)
+	_, err = get("Sum").Call(&tengo.Float{Value: 1.5})
+	require.Equal(t, "invalid type for argument 'first': expected int, "+
+		"found float", err.Error())
+	res, err = get("Sum").Call()
+	require.NoError(t, err)
+	require.Equal(t, &tengo.Error{Value: &tengo.String{Value: "no values"}},
+		res)
+
+	// a struct value is copied
+	o, err = tengo.FromInterface(testPoint{X: 1})
+	require.NoError(t, err)
+	require.NoError(t, o.IndexSet(&tengo.String{Value: "X"},
+		&tengo.Int{Value: 2}))
+	require.Equal(t, 2.0, tengo.ToInterface(o).(*testPoint).X)
+
+	// other Go types
+	o, err = tengo.FromInterface([]uint8{1, 2})
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2}, o.(*tengo.Bytes).Value)
+	o, err = tengo.FromInterface(map[int]float32{1: 0.5})
+	require.NoError(t, err)
+	res, err = o.IndexGet(&tengo.Int{Value: 1})
+	require.NoError(t, err)
+	require.Equal(t, &tengo.Float{Value: 0.5}, res)
+	o, err = tengo.FromInterface(uint64(1 << 63))
+	require.NoError(t, err)
+	require.Equal(t, "9223372036854775808", o.String())
+	_, err = tengo.FromInterface(make(chan int))
+	require.Error(t, err)
+}
+
+func TestWrapFunc(t *testing.T) {
+	call := func(fn interface{}, args ...tengo.Object) (tengo.Object, error) {
+		return tengo.WrapFunc(fn)(args...)
+	}
+	ints := func(values ...int64) *tengo.Array {
+		arr := &tengo.Array{}
+		for _, v := range values {
+			arr.Value = append(arr.Value, &tengo.Int{Value: v})
+		}
+		return arr
+	}
+
+	res, err := call(strings.Repeat, &tengo.String{Value: "ab"},
+		&tengo.Int{Value: 2})
+	require.NoError(t, err)
+	require.Equal(t, &tengo.String{Value: "abab"}, res)
+	_, err = call(strings.Repeat, &tengo.String{Value: "ab"})
+	require.Equal(t, tengo.ErrWrongNumArguments, err)
+	_, err = call(strings.Repeat, &tengo.String{Value: "ab"},
+		&tengo.Array{})
+	require.Equal(t, "invalid type for argument 'second': expected int, "+
+		"found array", err.Error())
+	_, err = call(strings.Repeat, &tengo.Int{Value: 1}, &tengo.Int{Value: 2})
+	require.Equal(t, "invalid type for argument 'first': expected string, "+
+		"found int", err.Error())
+
+	// variadic, slices and maps
+	sum := func(scale uint8, values ...[]int) map[string]int {
+		res := map[string]int{}
+		for _, v := range values {
+			for _, e := range v {
+				res["sum"] += e * int(scale)
+			}
+		}
+		return res
+	}
+	res, err = call(sum, &tengo.Int{Value: 2}, ints(1, 2), ints(3))
+	require.NoError(t, err)
+	require.Equal(t, &tengo.Map{Value: map[string]tengo.Object{
+		"sum": &tengo.Int{Value: 12}}}, res)
+	res, err = call(sum, &tengo.Int{Value: 2})
+	require.NoError(t, err)
+	require.Equal(t, &tengo.Map{Value: map[string]tengo.Object{}}, res)
+	_, err = call(sum, &tengo.Int{Value: 256})
+	require.Equal(t, "invalid type for argument 'first': expected "+
+		"int(uint8), found int", err.Error())
+	_, err = call(sum, &tengo.Int{Value: 1}, ints(1),
+		&tengo.Array{Value: []tengo.Object{
+			&tengo.Int{Value: 1}, &tengo.String{Value: "a"}}})
+	require.Equal(t, "invalid type for argument 'third[1]': expected int, "+
+		"found string", err.Error())
+	_, err = call(func(m map[string][]float64) {},
+		&tengo.Map{Value: map[string]tengo.Object{
+			"a": &tengo.Array{Value: []tengo.Object{tengo.TrueValue}}}})
+	require.Equal(t, "invalid type for argument 'first.a[0]': expected "+
+		"float, found bool", err.Error())
+
+	// durations and multiple results
+	div := func(d time.Duration, n int) (time.Duration, time.Duration, error) {
+		if n == 0 {
+			return 0, 0, errors.New("division by zero")
+		}
+		return d / time.Duration(n), d % time.Duration(n), nil
+	}
+	res, err = call(div, &tengo.String{Value: "1m"}, &tengo.Int{Value: 7})
+	require.NoError(t, err)
+	require.Equal(t, ints(int64(time.Minute/7), int64(time.Minute%7)), res)
+	res, err = call(div, &tengo.Int{Value: 10}, &tengo.Int{Value: 3})
+	require.NoError(t, err)
+	require.Equal(t, ints(3, 1), res)
+	res, err = call(div, &tengo.Int{Value: 10}, &tengo.Int{Value: 0})
+	require.NoError(t, err)
+	require.Equal(t, &tengo.Error{Value: &tengo.String{
+		Value: "division by zero"}}, res)
+	_, err = call(div, &tengo.String{Value: "1x"}, &tengo.Int{Value: 1})
+	require.Equal(t, "invalid type for argument 'first': expected "+
+		"int/string, found string", err.Error())
+
+	// Go functions are wrapped by FromInterface
+	o, err := tengo.FromInterface(strings.ToUpper)
+	require.NoError(t, err)
+	res, err = o.Call(&tengo.String{Value: "a"})
+	require.NoError(t, err)
+	require.Equal(t, &tengo.String{Value: "A"}, res)
+
+	defer func() {
+		require.True(t, recover() != nil)
+	}()
+	tengo.WrapFunc(1)
+}
@@ -84,7 +84,20 @@ const (
 	In
 	Undefined
 	Import
+	Try
+	Catch
+	Finally
+	Switch
+	Case
+	Default
 	_keywordEnd
+	// tokens added later are appended to keep the values of the others
+	// which are encoded in the compiled bytecode.
+	InterpString
+	Yield
+	QuestionPeriod // ?.
+	QuestionLBrack // ?[
+	Coalesce       // ??
 )
 
 var tokens = [...]string{
@@ -158,6 +171,18 @@ var tokens = [...]string{
 	In:           "in",
 	Undefined:    "undefined",
 	Import:       "import",
+	Try:          "try",
+	Catch:        "catch",
+	Finally:      "finally",
+	Switch:       "switch",
+	Case:         "case",
+	Default:      "default",
+	InterpString: "INTERP_STRING",
+	Yield:        "yield",
+
+	QuestionPeriod: "?.",
+	QuestionLBrack: "?[",
+	Coalesce:       "??",
 }
 
 func (tok Token) String() string {
@@ -180,7 +205,7 @@ const LowestPrec = 0
 // Precedence returns the precedence for the operator token.
 func (tok Token) Precedence() int {
 	switch tok {
-	case LOr:
+	case LOr, Coalesce:
 		return 1
 	case LAnd:
 		return 2
@@ -196,17 +221,18 @@ func (tok Token) Precedence() int {
 
 // IsLiteral returns true if the token is a literal.
 func (tok Token) IsLiteral() bool {
-	return _literalBeg < tok && tok < _literalEnd
+	return _literalBeg < tok && tok < _literalEnd || tok == InterpString
 }
 
 // IsOperator returns true if the token is an operator.
 func (tok Token) IsOperator() bool {
-	return _operatorBeg < tok && tok < _operatorEnd
+	return _operatorBeg < tok && tok < _operatorEnd ||
+		QuestionPeriod <= tok && tok <= Coalesce
 }
 
 // IsKeyword returns true if the token is a keyword.
 func (tok Token) IsKeyword() bool {
-	return _keywordBeg < tok && tok < _keywordEnd
+	return _keywordBeg < tok && tok < _keywordEnd || tok == Yield
 }
 
 // Lookup returns corresponding keyword if ident is a keyword.
@@ -222,4 +248,5 @@ func init() {
 	for i := _keywordBeg + 1; i < _keywordEnd; i++ {
 		keywords[tokens[i]] = i
 	}
+	keywords[tokens[Yield]] = Yield
 }
@@ -86,8 +86,10 @@ func (v *Variable) Array() []interface{} {
 	return nil
 }
 
-// Map returns map[string]interface{} value of the variable value. It returns
-// 0 if the value is not convertible to map[string]interface{}.
+// Map returns map[string]interface{} value of the variable value. The fields
+// of a record are returned as a map, and the entries of the map keys other
+// than strings are not included. It returns 0 if the value is not
+// convertible to map[string]interface{}.
 func (v *Variable) Map() map[string]interface{} {
 	switch val := v.value.(type) {
 	case *Map:
@@ -96,6 +98,8 @@ func (v *Variable) Map() map[string]interface{} {
 			kv[mk] = ToInterface(mv)
 		}
 		return kv
+	case *Record:
+		return ToInterface(val).(map[string]interface{})
 	}
 	return nil
 }
@@ -1,7 +1,12 @@
 package tengo
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/d5/tengo/v2/parser"
@@ -14,26 +19,109 @@ type frame struct {
 	freeVars    []*ObjectPtr
 	ip          int
 	basePointer int
+	gen         *Generator // generator running in the frame; or nil
+}
+
+// errorHandler represents an error handler set up by a try statement.
+type errorHandler struct {
+	framesIndex int
+	sp          int
+	ip          int
+}
+
+// thrownError is a runtime error re-raised from an Error object.
+type thrownError struct {
+	obj *Error
+}
+
+func (e *thrownError) Error() string {
+	return e.obj.cause.Error()
+}
+
+func (e *thrownError) Unwrap() error {
+	return e.obj.cause
 }
 
 // VM is a virtual machine that executes the bytecode compiled by Compiler.
 type VM struct {
 	constants   []Object
-	stack       [StackSize]Object
+	stack       []Object
 	sp          int
 	globals     []Object
 	fileSet     *parser.SourceFileSet
-	frames      [MaxFrames]frame
+	frames      []frame
+	maxStack    int
+	maxFrames   int
 	framesIndex int
 	curFrame    *frame
 	curInsts    []byte
 	ip          int
+	handlers    []errorHandler
+	debugger    *Debugger
+	profiler    *Profiler
 	aborting    int64
 	maxAllocs   int64
 	allocs      int64
+	maxInsts    int64
+	instLimit   int64 // maxInsts or its lease, or math.MaxInt64 if no limit
+	insts       int64 // total cost of the executed instructions
+	instCosts   *[256]int64
+	goCallCost  int64
+	maxMemory   int64
+	memLimit    int64   // maxMemory or its lease, or math.MaxInt64 if no limit
+	memory      int64   // estimated bytes allocated
+	childInsts  int64   // insts of the finished child VMs (atomic)
+	childMemory int64   // memory of the finished child VMs (atomic)
+	checked     bool    // checked arithmetic mode
+	instBudget  *budget // limits shared with the child VMs; nil if not shared
+	allocBudget *budget
+	memBudget   *budget
+	fs          FS
+	caps        map[string]bool // granted capabilities; nil if unrestricted
+	auditHook   func(AuditEvent)
 	err         error
+
+	// mu guards the abort channel and the child VMs spawned by Spawn.
+	mu       sync.Mutex
+	abortCh  chan struct{}
+	children map[*VM]struct{}
+	tasks    sync.WaitGroup
+}
+
+// InstructionCosts represents the costs of the instructions that are counted
+// against the instruction limit of the VM.
+type InstructionCosts struct {
+	// Opcodes are the costs of the instructions by their opcodes. An opcode
+	// not in the map costs 1.
+	Opcodes map[parser.Opcode]int64
+
+	// GoCall is the additional cost of calling a Go function, such as a
+	// builtin function or a UserFunction, on top of the cost of OpCall.
+	GoCall int64
 }
 
+const (
+	// initialStackSize is the initial stack size of a VM. The stack grows as
+	// needed up to the maximum stack size.
+	initialStackSize = 64
+
+	// initialFrames is the initial number of function frames of a VM.
+	initialFrames = 16
+
+	// stackReserve is the number of stack slots that must be available before
+	// executing an instruction. It covers the objects pushed by any single
+	// instruction except the calls, which check the stack by themselves.
+	stackReserve = 4
+)
+
+// defaultInstCosts are the instruction costs where every instruction costs 1.
+var defaultInstCosts = func() (costs [256]int64) {
+	for i := range costs {
+		costs[i] = 1
+	}
+	return
+}()
+
 // NewVM creates a VM.
 func NewVM(
 	bytecode *Bytecode,
@@ -45,12 +133,19 @@ func NewVM(
 	}
 	v := &VM{
 		constants:   bytecode.Constants,
+		stack:       make([]Object, initialStackSize),
 		sp:          0,
 		globals:     globals,
 		fileSet:     bytecode.FileSet,
+		frames:      make([]frame, initialFrames),
+		maxStack:    StackSize,
+		maxFrames:   MaxFrames,
 		framesIndex: 1,
 		ip:          -1,
 		maxAllocs:   maxAllocs,
+		maxInsts:    -1,
+		instCosts:   &defaultInstCosts,
+		maxMemory:   -1,
 	}
 	v.frames[0].fn = bytecode.MainFunction
 	v.frames[0].ip = -1
@@ -59,9 +154,576 @@ func NewVM(
 	return v
 }
 
-// Abort aborts the execution.
+// Abort aborts the execution. It also aborts the child VMs spawned by Spawn.
 func (v *VM) Abort() {
-	atomic.StoreInt64(&v.aborting, 1)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !atomic.CompareAndSwapInt64(&v.aborting, 0, 1) {
+		return
+	}
+	if v.abortCh != nil {
+		close(v.abortCh)
+	}
+	for child := range v.children {
+		child.Abort()
+	}
+}
+
+// Aborted returns a channel that's closed when the VM is aborted. Go functions
+// called by the VM (see VMCallable) that may block should stop waiting when
+// the channel is closed. It returns nil if v is nil, which is the case for the
+// functions called outside of a VM.
+func (v *VM) Aborted() <-chan struct{} {
+	if v == nil {
+		return nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.abortCh == nil {
+		v.abortCh = make(chan struct{})
+		if atomic.LoadInt64(&v.aborting) != 0 {
+			close(v.abortCh)
+		}
+	}
+	return v.abortCh
+}
+
+// resetAbort clears the abort state after a run.
+func (v *VM) resetAbort() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	atomic.StoreInt64(&v.aborting, 0)
+	v.abortCh = nil
+}
+
+// Spawn calls the compiled function fn with the given arguments in a new
+// goroutine on a child VM, and calls done with the return value or the
+// runtime error when the function returns. It must be called while the VM is
+// running, e.g. by a Go function called by the VM.
+//
+// The child VM shares the constants with v, and runs on deep copies of the
+// global variables of v, fn and the arguments (see DeepCopy), so that the
+// VMs don't share mutable values other than the objects that are meant to
+// be shared, e.g. the channels of the "concurrency" module. It returns
+// ErrNotCopyable error if they hold a generator. The child VM inherits the
+// stack, frames and instruction cost settings of v, and shares the
+// allocation, instruction and memory limits of v: the limits apply to the
+// total of v and all its child VMs, and the instruction count and the memory
+// usage of v include the child VMs that finished. The VMs lease small parts
+// of the limits as they run, so a VM may fail slightly before the total
+// reaches a limit while the others hold unused parts.
+// Aborting v aborts the child VM, and the child VMs are aborted when the run
+// of v finishes. Run returns only after all child VMs stop.
+func (v *VM) Spawn(
+	fn *CompiledFunction,
+	args []Object,
+	done func(ret Object, err error),
+) error {
+	c := newCopier()
+	globals := make([]Object, len(v.globals))
+	if err := c.copyAll(globals, v.globals); err != nil {
+		return err
+	}
+	fnCopy, err := c.copy(fn)
+	if err != nil {
+		return err
+	}
+	fn = fnCopy.(*CompiledFunction)
+	args = append([]Object(nil), args...)
+	if err := c.copyAll(args, args); err != nil {
+		return err
+	}
+
+	child := v.fork(globals)
+	v.mu.Lock()
+	if v.children == nil {
+		v.children = make(map[*VM]struct{})
+	}
+	v.children[child] = struct{}{}
+	aborting := atomic.LoadInt64(&v.aborting) != 0
+	v.tasks.Add(1)
+	v.mu.Unlock()
+	if aborting {
+		child.Abort()
+	}
+
+	go func() {
+		defer v.tasks.Done()
+		ret, err := child.RunCompiled(fn, args...)
+		child.stopChildren()
+		v.join(child)
+		v.mu.Lock()
+		delete(v.children, child)
+		v.mu.Unlock()
+		done(ret, err)
+	}()
+	return nil
+}
+
+// fork creates a child VM that runs on the constants of v and the globals.
+// The child VM shares the limits of v, and leases its parts as it runs.
+func (v *VM) fork(globals []Object) *VM {
+	v.shareBudgets()
+	stackSize := initialSize(initialStackSize, v.maxStack)
+	numFrames := initialSize(initialFrames, v.maxFrames)
+	child := &VM{
+		constants:   v.constants,
+		stack:       make([]Object, stackSize),
+		globals:     globals,
+		fileSet:     v.fileSet,
+		frames:      make([]frame, numFrames),
+		maxStack:    v.maxStack,
+		maxFrames:   v.maxFrames,
+		framesIndex: 1,
+		ip:          -1,
+		maxAllocs:   v.maxAllocs,
+		maxInsts:    v.maxInsts,
+		instLimit:   math.MaxInt64,
+		instCosts:   v.instCosts,
+		goCallCost:  v.goCallCost,
+		maxMemory:   v.maxMemory,
+		memLimit:    math.MaxInt64,
+		instBudget:  v.instBudget,
+		allocBudget: v.allocBudget,
+		memBudget:   v.memBudget,
+		checked:     v.checked,
+		fs:          v.fs,
+		caps:        v.caps,
+		auditHook:   v.auditHook,
+	}
+	child.frames[0].fn = v.frames[0].fn
+	child.frames[0].ip = -1
+	child.curFrame = &child.frames[0]
+	child.curInsts = child.curFrame.fn.Instructions
+	// the child VM starts with empty leases
+	if child.instBudget != nil {
+		child.instLimit = 0
+	}
+	if child.allocBudget != nil {
+		child.allocs = 1
+	}
+	if child.memBudget != nil {
+		child.memLimit = 0
+	}
+	return child
+}
+
+const (
+	// instLease, allocLease and memoryLease are the parts of the shared
+	// limits that a VM leases at a time.
+	instLease   = 1 << 10
+	allocLease  = 1 << 6
+	memoryLease = 1 << 16
+)
+
+// budget is the unleased part of a limit shared by a VM and its child VMs.
+// Each VM counts its usage against its own lease without synchronization,
+// and leases more from the budget when the lease is used up.
+type budget struct {
+	remaining int64 // accessed atomically
+}
+
+// lease takes up to n from the budget, and returns the amount taken.
+func (b *budget) lease(n int64) int64 {
+	for {
+		remaining := atomic.LoadInt64(&b.remaining)
+		if remaining <= 0 {
+			return 0
+		}
+		if n > remaining {
+			n = remaining
+		}
+		if atomic.CompareAndSwapInt64(&b.remaining, remaining, remaining-n) {
+			return n
+		}
+	}
+}
+
+// release returns n to the budget.
+func (b *budget) release(n int64) {
+	if n > 0 {
+		atomic.AddInt64(&b.remaining, n)
+	}
+}
+
+// shareBudgets moves the unused parts of the limits of v to the budgets
+// shared with its child VMs.
+func (v *VM) shareBudgets() {
+	if v.maxInsts >= 0 && v.instBudget == nil {
+		v.instBudget = &budget{}
+	}
+	if v.maxAllocs >= 0 && v.allocBudget == nil {
+		v.allocBudget = &budget{}
+	}
+	if v.maxMemory >= 0 && v.memBudget == nil {
+		v.memBudget = &budget{}
+	}
+	v.releaseLeases()
+}
+
+// releaseLeases returns the unused parts of the leases of v to the shared
+// budgets.
+func (v *VM) releaseLeases() {
+	if v.instBudget != nil && v.insts < v.instLimit {
+		v.instBudget.release(v.instLimit - v.insts)
+		v.instLimit = v.insts
+	}
+	if v.allocBudget != nil && v.allocs > 1 {
+		v.allocBudget.release(v.allocs - 1)
+		v.allocs = 1
+	}
+	if v.memBudget != nil && v.memory < v.memLimit {
+		v.memBudget.release(v.memLimit - v.memory)
+		v.memLimit = v.memory
+	}
+}
+
+// leaseInsts leases the instructions executed beyond the lease of v and more
+// from the shared budget. It returns false if the budget is used up.
+func (v *VM) leaseInsts() bool {
+	if v.instBudget == nil {
+		return false
+	}
+	v.instLimit += v.instBudget.lease(v.insts - v.instLimit + instLease)
+	return v.insts <= v.instLimit
+}
+
+// leaseAllocs leases more allocations from the shared budget when v used up
+// its lease. It returns false if the budget is used up.
+func (v *VM) leaseAllocs() bool {
+	if v.allocBudget == nil {
+		return false
+	}
+	v.allocs += v.allocBudget.lease(allocLease)
+	return v.allocs > 0
+}
+
+// leaseMemory leases the memory allocated beyond the lease of v and more from
+// the shared budget. It returns false if the budget is used up.
+func (v *VM) leaseMemory() bool {
+	if v.memBudget == nil {
+		return false
+	}
+	v.memLimit += v.memBudget.lease(v.memory - v.memLimit + memoryLease)
+	return v.memory <= v.memLimit
+}
+
+// join returns the unused leases of the finished child VM to the shared
+// budgets, and counts its usage in v.
+func (v *VM) join(child *VM) {
+	child.releaseLeases()
+	atomic.AddInt64(&v.childInsts, child.InstructionCount())
+	atomic.AddInt64(&v.childMemory, child.MemoryUsage())
+}
+
+// stopChildren aborts the child VMs and waits for them to stop.
+func (v *VM) stopChildren() {
+	v.mu.Lock()
+	for child := range v.children {
+		child.Abort()
+	}
+	v.mu.Unlock()
+	v.tasks.Wait()
+}
+
+// SetMaxStackSize sets the maximum number of objects in the stack. The stack
+// grows as needed up to this size, and the VM returns ErrStackOverflow error
+// if it needs more. It's StackSize by default.
+func (v *VM) SetMaxStackSize(n int) {
+	if n <= 0 {
+		n = StackSize
+	}
+	v.maxStack = n
+	if len(v.stack) > n {
+		stack := make([]Object, n)
+		copy(stack, v.stack)
+		v.stack = stack
+	}
+}
+
+// SetMaxFrames sets the maximum number of function frames, which limits the
+// depth of the function calls. The VM returns ErrStackOverflow error if it
+// exceeds this limit. It's MaxFrames by default.
+func (v *VM) SetMaxFrames(n int) {
+	if n <= 0 {
+		n = MaxFrames
+	}
+	v.maxFrames = n
+	if len(v.frames) > n && v.framesIndex <= n {
+		frames := make([]frame, n)
+		copy(frames, v.frames)
+		v.frames = frames
+		v.curFrame = &v.frames[v.framesIndex-1]
+	}
+}
+
+// SetMaxInstructions sets the maximum total cost of the instructions executed
+// in a run. The VM stops with ErrInstructionLimit error if the limit is
+// exceeded. A negative value means no limit.
+func (v *VM) SetMaxInstructions(n int64) {
+	v.maxInsts = n
+}
+
+// SetInstructionCosts sets the costs of the instructions counted against the
+// instruction limit. By default, every instruction costs 1.
+func (v *VM) SetInstructionCosts(costs *InstructionCosts) {
+	instCosts := defaultInstCosts
+	for op, cost := range costs.Opcodes {
+		instCosts[op] = cost
+	}
+	v.instCosts = &instCosts
+	v.goCallCost = costs.GoCall
+}
+
+// InstructionCount returns the total cost of the instructions executed in the
+// last run, including the child VMs spawned by Spawn that finished.
+func (v *VM) InstructionCount() int64 {
+	return v.insts + atomic.LoadInt64(&v.childInsts)
+}
+
+// SetMaxMemory sets the maximum number of bytes allocated in a run. The sizes
+// of strings, bytes, arrays and maps are estimated when they're created by the
+// VM, the builtin functions, or the Go functions calling Allocate. The VM
+// stops with ErrMemoryLimit error if the limit is exceeded. A negative value
+// means no limit.
+func (v *VM) SetMaxMemory(n int64) {
+	v.maxMemory = n
+}
+
+// SetCheckedArithmetic enables or disables the checked arithmetic mode. In
+// this mode, the VM stops with ErrIntegerOverflow error if an int operation
+// overflows instead of wrapping around, and with ErrDivisionByZero error if
+// an int is divided by zero.
+func (v *VM) SetCheckedArithmetic(enable bool) {
+	v.checked = enable
+}
+
+// SetFS sets the file system that the Go functions called by the VM, such as
+// the functions of the "os" module, access instead of the host's file system.
+func (v *VM) SetFS(fsys FS) {
+	v.fs = fsys
+}
+
+// FS returns the file system set by SetFS, or nil if the scripts access the
+// host's file system. It returns nil if v is nil, which is the case for the
+// functions called outside of a VM.
+func (v *VM) FS() FS {
+	if v == nil {
+		return nil
+	}
+	return v.fs
+}
+
+// SetCapabilities restricts the privileged Go functions, such as the
+// functions of the "os" module that run the commands, to the capabilities.
+// The capabilities are dot-separated names, and granting a capability also
+// grants the capabilities under it, e.g. "os" grants "os.exec". A nil caps
+// removes the restriction, which is the default.
+func (v *VM) SetCapabilities(caps []string) {
+	if caps == nil {
+		v.caps = nil
+		return
+	}
+	v.caps = make(map[string]bool, len(caps))
+	for _, c := range caps {
+		v.caps[c] = true
+	}
+}
+
+// SetAuditHook sets a function that's called with every call of the
+// privileged Go functions, whether it's allowed or not. It may be called
+// concurrently by the child VMs spawned by Spawn.
+func (v *VM) SetAuditHook(hook func(event AuditEvent)) {
+	v.auditHook = hook
+}
+
+// CheckCapability reports the call of the privileged Go function name with
+// the arguments to the audit hook, and returns ErrPermissionDenied error if
+// the capability is not granted. Privileged Go functions called by the VM
+// (see VMCallable) should call it before doing anything. It returns nil if v
+// is nil, which is the case for the functions called outside of a VM, e.g. by
+// Object.Call, as no capabilities are set for them.
+func (v *VM) CheckCapability(capability, name string, args []Object) error {
+	if v == nil {
+		return nil
+	}
+	allowed := v.caps == nil
+	for c := capability; !allowed; {
+		if v.caps[c] {
+			allowed = true
+		} else if i := strings.LastIndexByte(c, '.'); i >= 0 {
+			c = c[:i]
+		} else {
+			break
+		}
+	}
+	if v.auditHook != nil {
+		v.auditHook(AuditEvent{
+			Capability: capability,
+			Function:   name,
+			Args:       args,
+			Allowed:    allowed,
+		})
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s requires capability '%s'",
+			ErrPermissionDenied, name, capability)
+	}
+	return nil
+}
+
+// AuditEvent is a call of a privileged Go function reported to the audit hook
+// of the VM.
+type AuditEvent struct {
+	Capability string   // capability that the function requires
+	Function   string   // name of the function, e.g. "os.exec"
+	Args       []Object // arguments of the call
+	Allowed    bool     // whether the capability is granted
+}
+
+// Allocate counts size bytes against the memory limit of the VM. Go functions
+// called by the VM (see VMCallable) should call it before allocating large
+// values. It returns ErrMemoryLimit error if the limit is exceeded. It does
+// nothing if v is nil, which is the case for the functions called outside of
+// a VM.
+func (v *VM) Allocate(size int64) error {
+	if v == nil {
+		return nil
+	}
+	v.memory += size
+	if v.memory > v.memLimit && !v.leaseMemory() {
+		return ErrMemoryLimit
+	}
+	return nil
+}
+
+// MemoryUsage returns the estimated number of bytes allocated in the last
+// run, including the child VMs spawned by Spawn that finished.
+func (v *VM) MemoryUsage() int64 {
+	return v.memory + atomic.LoadInt64(&v.childMemory)
+}
+
+const (
+	// objectRefSize is the size of an Object interface value, e.g. an element
+	// of an array.
+	objectRefSize = 16
+
+	// mapEntrySize is the estimated size of a map entry not including the
+	// bytes of the key.
+	mapEntrySize = 2 * objectRefSize
+
+	// bigWordSize is the size of a word of a big number.
+	bigWordSize = 8
+)
+
+// objectSize returns the estimated size of the value of a String, Bytes,
+// Array, Map, BigInt or Decimal object in bytes. Other objects are only
+// counted by the allocation limit.
+func objectSize(o Object) int64 {
+	switch o := o.(type) {
+	case *BigInt:
+		return bigWordSize * int64(len(o.Value.Bits()))
+	case *Decimal:
+		return bigWordSize * int64(len(o.Value.Num().Bits())+
+			len(o.Value.Denom().Bits()))
+	case *String:
+		return int64(len(o.Value))
+	case *Bytes:
+		return int64(len(o.Value))
+	case *Array:
+		return objectRefSize * int64(len(o.Value))
+	case *ImmutableArray:
+		return objectRefSize * int64(len(o.Value))
+	case *Map:
+		return mapSize(o.Value, o.Hashed)
+	case *ImmutableMap:
+		return mapSize(o.Value, o.Hashed)
+	}
+	return 0
+}
+
+func mapSize(m map[string]Object, hashed *HashedMap) int64 {
+	size := mapEntrySize * int64(len(m)+hashed.Len())
+	for k := range m {
+		size += int64(len(k))
+	}
+	return size
+}
+
+// RunCompiled calls the compiled function fn with the given arguments and
+// returns its return value. It is meant to be used by Go functions called by
+// the VM (see VMCallable) to call back into script functions. The call shares
+// the stack, the allocation limit and the abort state of the VM, and runtime
+// errors carry the source positions of the script function.
+func (v *VM) RunCompiled(fn *CompiledFunction, args ...Object) (Object, error) {
+	// entry frame calls fn and suspends the VM when fn returns
+	return v.runEntry(
+		append(MakeInstruction(parser.OpCall, len(args), 0), parser.OpSuspend),
+		append([]Object{fn}, args...)...)
+}
+
+// runEntry runs the instructions in a new entry frame with the objects pushed
+// to the stack, and returns the object on top of the stack when the
+// instructions suspend the VM.
+func (v *VM) runEntry(insts []byte, objs ...Object) (Object, error) {
+	if !v.growStack(v.sp+len(objs)+stackReserve) || !v.growFrames() {
+		return nil, ErrStackOverflow
+	}
+
+	// save the states of the caller
+	sp, ip := v.sp, v.ip
+	curInsts := v.curInsts
+	framesIndex := v.framesIndex
+	handlers := v.handlers
+	defer func() {
+		// frames may have been reallocated in the call
+		v.sp, v.ip = sp, ip
+		v.curFrame, v.curInsts = &v.frames[framesIndex-1], curInsts
+		v.framesIndex = framesIndex
+		v.handlers = handlers
+	}()
+
+	// errors in the entry frame must not be handled by the error handlers
+	// of the caller
+	v.handlers = handlers[len(handlers):]
+
+	entry := &CompiledFunction{Instructions: insts}
+	v.curFrame.ip = v.ip
+	v.curFrame = &v.frames[v.framesIndex]
+	v.curFrame.fn = entry
+	v.curFrame.freeVars = nil
+	v.curFrame.basePointer = v.sp
+	v.curFrame.gen = nil
+	v.curInsts = entry.Instructions
+	v.ip = -1
+	v.framesIndex++
+
+	for _, obj := range objs {
+		v.stack[v.sp] = obj
+		v.sp++
+	}
+
+	v.run()
+
+	if err := v.err; err != nil {
+		v.err = nil
+		ip := v.ip
+		for v.framesIndex > framesIndex+1 {
+			filePos := v.fileSet.Position(v.curFrame.fn.SourcePos(ip - 1))
+			err = fmt.Errorf("%w\n\tat %s", err, filePos)
+			if v.curFrame.gen != nil {
+				v.curFrame.gen.finish()
+			}
+			v.framesIndex--
+			v.curFrame = &v.frames[v.framesIndex-1]
+			ip = v.curFrame.ip
+		}
+		return nil, err
+	}
+	if atomic.LoadInt64(&v.aborting) != 0 {
+		return nil, ErrVMAborted
+	}
+	return v.stack[v.sp-1], nil
 }
 
 // Run starts the execution.
@@ -72,10 +734,27 @@ func (v *VM) Run() (err error) {
 	v.curInsts = v.curFrame.fn.Instructions
 	v.framesIndex = 1
 	v.ip = -1
+	v.handlers = v.handlers[:0]
 	v.allocs = v.maxAllocs + 1
+	v.insts = 0
+	v.instLimit = v.maxInsts
+	if v.instLimit < 0 {
+		v.instLimit = math.MaxInt64
+	}
+	v.memory = 0
+	v.memLimit = v.maxMemory
+	if v.memLimit < 0 {
+		v.memLimit = math.MaxInt64
+	}
+	v.instBudget, v.allocBudget, v.memBudget = nil, nil, nil
+	v.childInsts, v.childMemory = 0, 0
 
 	v.run()
-	atomic.StoreInt64(&v.aborting, 0)
+	v.stopChildren()
+	if v.profiler != nil {
+		v.profiler.stop()
+	}
+	v.resetAbort()
 	err = v.err
 	if err != nil {
 		filePos := v.fileSet.Position(
@@ -83,6 +762,9 @@ func (v *VM) Run() (err error) {
 		err = fmt.Errorf("Runtime Error: %w\n\tat %s",
 			err, filePos)
 		for v.framesIndex > 1 {
+			if v.curFrame.gen != nil {
+				v.curFrame.gen.finish()
+			}
 			v.framesIndex--
 			v.curFrame = &v.frames[v.framesIndex-1]
 			filePos = v.fileSet.Position(
@@ -94,9 +776,234 @@ func (v *VM) Run() (err error) {
 	return nil
 }
 
+// initialSize returns the initial size of the stack or the frames, which
+// can't exceed the maximum size.
+func initialSize(size, max int) int {
+	if size > max {
+		return max
+	}
+	return size
+}
+
+// growStack grows the stack to have at least n slots. It returns false if n
+// exceeds the maximum stack size.
+func (v *VM) growStack(n int) bool {
+	if n <= len(v.stack) {
+		return true
+	}
+	if n > v.maxStack {
+		return false
+	}
+	size := 2 * len(v.stack)
+	if size < n {
+		size = n
+	}
+	if size > v.maxStack {
+		size = v.maxStack
+	}
+	stack := make([]Object, size)
+	copy(stack, v.stack)
+	v.stack = stack
+	return true
+}
+
+// growFrames grows the frames to have room for a new frame. It returns false
+// if the number of frames exceeds the maximum.
+func (v *VM) growFrames() bool {
+	if v.framesIndex < len(v.frames) {
+		return true
+	}
+	if v.framesIndex >= v.maxFrames {
+		return false
+	}
+	size := 2 * len(v.frames)
+	if size > v.maxFrames {
+		size = v.maxFrames
+	}
+	frames := make([]frame, size)
+	copy(frames, v.frames)
+	v.frames = frames
+	v.curFrame = &v.frames[v.framesIndex-1]
+	return true
+}
+
+// bindArgs binds the keyword arguments on top of the stack to the parameters
+// of the function, and fills the omitted parameters that have default values
+// with undefined. It returns the number of the arguments after binding.
+func (v *VM) bindArgs(
+	fn *CompiledFunction,
+	numArgs int,
+	names []Object,
+) (int, error) {
+	numPos := numArgs - len(names)
+	fixed := fn.NumParameters
+	if fn.VarArgs {
+		fixed--
+	}
+	required := fixed - fn.NumDefaults
+	if len(names) == 0 && (numPos >= fixed || numPos < required) {
+		return numArgs, nil
+	}
+
+	base := v.sp - numArgs
+	kwargs := append([]Object{}, v.stack[base+numPos:v.sp]...)
+	v.sp = base + numPos
+	if numPos < fixed {
+		if !v.growStack(base + fixed + stackReserve) {
+			return 0, ErrStackOverflow
+		}
+		for i := numPos; i < fixed; i++ {
+			v.stack[base+i] = nil
+		}
+		v.sp = base + fixed
+	}
+	for i, name := range names {
+		name := name.(*String).Value
+		idx := -1
+		for j := 0; j < fixed && j < len(fn.ParamNames); j++ {
+			if fn.ParamNames[j] == name {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			return 0, fmt.Errorf("unexpected keyword argument '%s'", name)
+		}
+		if idx < numPos || v.stack[base+idx] != nil {
+			return 0, fmt.Errorf("multiple values for argument '%s'", name)
+		}
+		v.stack[base+idx] = kwargs[i]
+	}
+	for i := numPos; i < fixed; i++ {
+		if v.stack[base+i] != nil {
+			continue
+		}
+		if i < required {
+			return 0, fmt.Errorf("missing argument '%s'", fn.ParamNames[i])
+		}
+		v.stack[base+i] = UndefinedValue
+	}
+	return v.sp - base, nil
+}
+
+// wrongNumArgs returns the error of calling the function with the wrong
+// number of arguments, excluding the receiver if recv is 1.
+func wrongNumArgs(fn *CompiledFunction, numArgs, recv int) error {
+	want := fn.NumParameters - recv
+	switch {
+	case fn.VarArgs:
+		return fmt.Errorf("wrong number of arguments: want>=%d, got=%d",
+			want-1-fn.NumDefaults, numArgs)
+	case fn.NumDefaults > 0:
+		return fmt.Errorf("wrong number of arguments: want=%d..%d, got=%d",
+			want-fn.NumDefaults, want, numArgs)
+	}
+	return fmt.Errorf("wrong number of arguments: want=%d, got=%d",
+		want, numArgs)
+}
+
+// appendKwArgs replaces the keyword arguments at the end of the arguments
+// with a map of them.
+func appendKwArgs(args []Object, names []Object) []Object {
+	numPos := len(args) - len(names)
+	kwargs := &Map{Value: make(map[string]Object, len(names))}
+	for i, name := range names {
+		kwargs.Value[name.(*String).Value] = args[numPos+i]
+	}
+	return append(args[:numPos], kwargs)
+}
+
 func (v *VM) run() {
+	for {
+		v.execute()
+		if v.err == nil || !v.handleError() {
+			return
+		}
+	}
+}
+
+// handleError unwinds the call frames to the innermost error handler and
+// pushes the runtime error as an Error object. It returns false if there's no
+// error handler or the error cannot be handled.
+func (v *VM) handleError() bool {
+	n := len(v.handlers)
+	if n == 0 ||
+		errors.Is(v.err, ErrObjectAllocLimit) ||
+		errors.Is(v.err, ErrInstructionLimit) ||
+		errors.Is(v.err, ErrMemoryLimit) ||
+		errors.Is(v.err, ErrStackOverflow) ||
+		errors.Is(v.err, ErrVMAborted) {
+		return false
+	}
+
+	var e *Error
+	if thrown, ok := v.err.(*thrownError); ok {
+		e = &Error{Value: thrown.obj.Value, Pos: thrown.obj.Pos}
+		v.err = thrown.obj.cause
+	} else {
+		msg := v.err.Error()
+		if idx := strings.Index(msg, "\n\tat "); idx >= 0 {
+			msg = msg[:idx] // source positions of a nested call
+		}
+		e = &Error{
+			Value: &String{Value: msg},
+			Pos:   v.fileSet.Position(v.curFrame.fn.SourcePos(v.ip - 1)),
+		}
+	}
+	v.allocs--
+	if v.allocs == 0 && !v.leaseAllocs() {
+		v.err = ErrObjectAllocLimit
+		return false
+	}
+
+	// source positions of the unwound frames are kept in the cause so
+	// that re-raised error reports them.
+	h := v.handlers[n-1]
+	v.handlers = v.handlers[:n-1]
+	cause, ip := v.err, v.ip
+	for v.framesIndex > h.framesIndex {
+		filePos := v.fileSet.Position(v.curFrame.fn.SourcePos(ip - 1))
+		cause = fmt.Errorf("%w\n\tat %s", cause, filePos)
+		if v.curFrame.gen != nil {
+			v.curFrame.gen.finish()
+		}
+		v.framesIndex--
+		v.curFrame = &v.frames[v.framesIndex-1]
+		ip = v.curFrame.ip
+	}
+	e.cause, e.ip = cause, ip
+
+	v.err = nil
+	v.curInsts = v.curFrame.fn.Instructions
+	v.ip = h.ip - 1
+	v.sp = h.sp
+	v.stack[v.sp] = e
+	v.sp++
+	return true
+}
+
+func (v *VM) execute() {
 	for atomic.LoadInt64(&v.aborting) == 0 {
 		v.ip++
+		if v.debugger != nil {
+			v.debugger.trace()
+		}
+		if v.profiler != nil {
+			v.profiler.trace()
+		}
+
+		v.insts += v.instCosts[v.curInsts[v.ip]]
+		if v.insts > v.instLimit && !v.leaseInsts() {
+			v.ip++ // for the source position of the instruction
+			v.err = ErrInstructionLimit
+			return
+		}
+		if v.sp+stackReserve > len(v.stack) &&
+			!v.growStack(v.sp+stackReserve) {
+			v.ip++ // for the source position of the instruction
+			v.err = ErrStackOverflow
+			return
+		}
 
 		switch v.curInsts[v.ip] {
 		case parser.OpConstant:
@@ -113,7 +1020,13 @@ func (v *VM) run() {
 			right := v.stack[v.sp-1]
 			left := v.stack[v.sp-2]
 			tok := token.Token(v.curInsts[v.ip])
-			res, e := left.BinaryOp(tok, right)
+			var res Object
+			var e error
+			if v.checked {
+				res, e = checkedBinaryOp(left, tok, right)
+			} else {
+				res, e = left.BinaryOp(tok, right)
+			}
 			if e != nil {
 				v.sp -= 2
 				if e == ErrInvalidOperator {
@@ -126,10 +1039,14 @@ func (v *VM) run() {
 			}
 
 			v.allocs--
-			if v.allocs == 0 {
+			if v.allocs == 0 && !v.leaseAllocs() {
 				v.err = ErrObjectAllocLimit
 				return
 			}
+			if v.Allocate(objectSize(res)) != nil {
+				v.err = ErrMemoryLimit
+				return
+			}
 
 			v.stack[v.sp-2] = res
 			v.sp--
@@ -178,7 +1095,16 @@ func (v *VM) run() {
 			case *Int:
 				var res Object = &Int{Value: ^x.Value}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
+					v.err = ErrObjectAllocLimit
+					return
+				}
+				v.stack[v.sp] = res
+				v.sp++
+			case *BigInt:
+				var res Object = &BigInt{Value: new(big.Int).Not(x.Value)}
+				v.allocs--
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
@@ -195,9 +1121,13 @@ func (v *VM) run() {
 
 			switch x := operand.(type) {
 			case *Int:
+				if v.checked && x.Value == math.MinInt64 {
+					v.err = ErrIntegerOverflow
+					return
+				}
 				var res Object = &Int{Value: -x.Value}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
@@ -206,7 +1136,25 @@ func (v *VM) run() {
 			case *Float:
 				var res Object = &Float{Value: -x.Value}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
+					v.err = ErrObjectAllocLimit
+					return
+				}
+				v.stack[v.sp] = res
+				v.sp++
+			case *BigInt:
+				var res Object = &BigInt{Value: new(big.Int).Neg(x.Value)}
+				v.allocs--
+				if v.allocs == 0 && !v.leaseAllocs() {
+					v.err = ErrObjectAllocLimit
+					return
+				}
+				v.stack[v.sp] = res
+				v.sp++
+			case *Decimal:
+				var res Object = &Decimal{Value: new(big.Rat).Neg(x.Value)}
+				v.allocs--
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
@@ -240,6 +1188,20 @@ func (v *VM) run() {
 				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
 				v.ip = pos - 1
 			}
+		case parser.OpOptionalJump:
+			v.ip += 2
+			if v.stack[v.sp-1] == UndefinedValue {
+				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+				v.ip = pos - 1
+			}
+		case parser.OpCoalesceJump:
+			v.ip += 2
+			if v.stack[v.sp-1] == UndefinedValue {
+				v.sp--
+			} else {
+				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+				v.ip = pos - 1
+			}
 		case parser.OpJump:
 			pos := int(v.curInsts[v.ip+2]) | int(v.curInsts[v.ip+1])<<8
 			v.ip = pos - 1
@@ -283,10 +1245,14 @@ func (v *VM) run() {
 
 			var arr Object = &Array{Value: elements}
 			v.allocs--
-			if v.allocs == 0 {
+			if v.allocs == 0 && !v.leaseAllocs() {
 				v.err = ErrObjectAllocLimit
 				return
 			}
+			if v.Allocate(objectRefSize*int64(numElements)) != nil {
+				v.err = ErrMemoryLimit
+				return
+			}
 
 			v.stack[v.sp] = arr
 			v.sp++
@@ -294,28 +1260,148 @@ func (v *VM) run() {
 			v.ip += 2
 			numElements := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
 			kv := make(map[string]Object)
+			var hashed *HashedMap
 			for i := v.sp - numElements; i < v.sp; i += 2 {
 				key := v.stack[i]
 				value := v.stack[i+1]
-				kv[key.(*String).Value] = value
+				str, h, ok := mapKey(key)
+				if !ok {
+					v.err = fmt.Errorf("invalid map key type: %s",
+						key.TypeName())
+					return
+				}
+				if h == nil {
+					kv[str] = value
+					continue
+				}
+				if hashed == nil {
+					hashed = NewHashedMap()
+				}
+				hashed.Set(h, value)
 			}
 			v.sp -= numElements
 
-			var m Object = &Map{Value: kv}
+			var m Object = &Map{Value: kv, Hashed: hashed}
 			v.allocs--
-			if v.allocs == 0 {
+			if v.allocs == 0 && !v.leaseAllocs() {
 				v.err = ErrObjectAllocLimit
 				return
 			}
+			if v.Allocate(mapSize(kv, hashed)) != nil {
+				v.err = ErrMemoryLimit
+				return
+			}
 			v.stack[v.sp] = m
 			v.sp++
+		case parser.OpSpread:
+			src := v.stack[v.sp-1]
+			v.sp--
+
+			switch dst := v.stack[v.sp-1].(type) {
+			case *Array:
+				var elements []Object
+				switch src := src.(type) {
+				case *Array:
+					elements = src.Value
+				case *ImmutableArray:
+					elements = src.Value
+				default:
+					v.err = fmt.Errorf("cannot spread %s in array",
+						src.TypeName())
+					return
+				}
+				if v.Allocate(objectRefSize*int64(len(elements))) != nil {
+					v.err = ErrMemoryLimit
+					return
+				}
+				dst.Value = append(dst.Value, elements...)
+			case *Map:
+				var kv map[string]Object
+				var hashed *HashedMap
+				switch src := src.(type) {
+				case *Map:
+					kv, hashed = src.Value, src.Hashed
+				case *ImmutableMap:
+					kv, hashed = src.Value, src.Hashed
+				default:
+					v.err = fmt.Errorf("cannot spread %s in map",
+						src.TypeName())
+					return
+				}
+				if v.Allocate(mapSize(kv, hashed)) != nil {
+					v.err = ErrMemoryLimit
+					return
+				}
+				for k, e := range kv {
+					dst.Value[k] = e
+				}
+				for _, e := range hashed.Entries() {
+					_ = dst.IndexSet(e.Key, e.Value)
+				}
+			}
+		case parser.OpUnpack:
+			v.ip += 2
+			numTargets := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+			value := v.stack[v.sp-1]
+			v.sp--
+
+			var elements []Object
+			switch value := value.(type) {
+			case *Array:
+				elements = value.Value
+			case *ImmutableArray:
+				elements = value.Value
+			default:
+				v.err = fmt.Errorf("cannot destructure %s: not an array",
+					value.TypeName())
+				return
+			}
+			if len(elements) != numTargets {
+				v.err = fmt.Errorf(
+					"wrong number of values to destructure: want=%d, got=%d",
+					numTargets, len(elements))
+				return
+			}
+			if !v.growStack(v.sp + numTargets + stackReserve) {
+				v.err = ErrStackOverflow
+				return
+			}
+			// the first element is on top
+			for i := numTargets - 1; i >= 0; i-- {
+				v.stack[v.sp] = elements[i]
+				v.sp++
+			}
+		case parser.OpUnpackMap:
+			v.ip += 2
+			numKeys := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+			keys := append([]Object{}, v.stack[v.sp-numKeys:v.sp]...)
+			value := v.stack[v.sp-numKeys-1]
+			v.sp -= numKeys + 1
+
+			switch value.(type) {
+			case *Map, *ImmutableMap, *Record, *GoStruct:
+			default:
+				v.err = fmt.Errorf("cannot destructure %s: not a map",
+					value.TypeName())
+				return
+			}
+			// the value of the first key is on top
+			for i := numKeys - 1; i >= 0; i-- {
+				val, err := value.IndexGet(keys[i])
+				if err != nil {
+					v.err = err
+					return
+				}
+				v.stack[v.sp] = val
+				v.sp++
+			}
 		case parser.OpError:
 			value := v.stack[v.sp-1]
 			var e Object = &Error{
 				Value: value,
 			}
 			v.allocs--
-			if v.allocs == 0 {
+			if v.allocs == 0 && !v.leaseAllocs() {
 				v.err = ErrObjectAllocLimit
 				return
 			}
@@ -328,28 +1414,40 @@ func (v *VM) run() {
 					Value: value.Value,
 				}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
 				v.stack[v.sp-1] = immutableArray
 			case *Map:
 				var immutableMap Object = &ImmutableMap{
-					Value: value.Value,
+					Value:  value.Value,
+					Hashed: value.Hashed,
 				}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
 				v.stack[v.sp-1] = immutableMap
 			}
-		case parser.OpIndex:
+		case parser.OpIndex, parser.OpOptionalIndex:
 			index := v.stack[v.sp-1]
 			left := v.stack[v.sp-2]
 			v.sp -= 2
 
+			optional := v.curInsts[v.ip] == parser.OpOptionalIndex
+			if optional {
+				v.ip += 2
+			}
+
 			val, err := left.IndexGet(index)
+			if optional && err == ErrNotIndexable {
+				// optional link ends the chain with undefined
+				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+				v.ip = pos - 1
+				val, err = UndefinedValue, nil
+			}
 			if err != nil {
 				if err == ErrNotIndexable {
 					v.err = fmt.Errorf("not indexable: %s", index.TypeName())
@@ -417,7 +1515,7 @@ func (v *VM) run() {
 					Value: left.Value[lowIdx:highIdx],
 				}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
@@ -454,7 +1552,7 @@ func (v *VM) run() {
 					Value: left.Value[lowIdx:highIdx],
 				}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
@@ -491,7 +1589,7 @@ func (v *VM) run() {
 					Value: left.Value[lowIdx:highIdx],
 				}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
@@ -528,17 +1626,26 @@ func (v *VM) run() {
 					Value: left.Value[lowIdx:highIdx],
 				}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
 				v.stack[v.sp] = val
 				v.sp++
 			}
-		case parser.OpCall:
-			numArgs := int(v.curInsts[v.ip+1])
-			spread := int(v.curInsts[v.ip+2])
-			v.ip += 2
+		case parser.OpCall, parser.OpCallKw:
+			var numArgs, spread int
+			var names []Object // names of the keyword arguments
+			if v.curInsts[v.ip] == parser.OpCall {
+				numArgs = int(v.curInsts[v.ip+1])
+				spread = int(v.curInsts[v.ip+2])
+				v.ip += 2
+			} else {
+				cidx := int(v.curInsts[v.ip+2]) | int(v.curInsts[v.ip+1])<<8
+				names = v.constants[cidx].(*ImmutableArray).Value
+				numArgs = int(v.curInsts[v.ip+3])
+				v.ip += 3
+			}
 
 			value := v.stack[v.sp-1-numArgs]
 			if !value.CanCall() {
@@ -548,26 +1655,51 @@ func (v *VM) run() {
 
 			if spread == 1 {
 				v.sp--
+				var items []Object
 				switch arr := v.stack[v.sp].(type) {
 				case *Array:
-					for _, item := range arr.Value {
-						v.stack[v.sp] = item
-						v.sp++
-					}
-					numArgs += len(arr.Value) - 1
+					items = arr.Value
 				case *ImmutableArray:
-					for _, item := range arr.Value {
-						v.stack[v.sp] = item
-						v.sp++
-					}
-					numArgs += len(arr.Value) - 1
+					items = arr.Value
 				default:
 					v.err = fmt.Errorf("not an array: %s", arr.TypeName())
 					return
 				}
+				if !v.growStack(v.sp + len(items)) {
+					v.err = ErrStackOverflow
+					return
+				}
+				for _, item := range items {
+					v.stack[v.sp] = item
+					v.sp++
+				}
+				numArgs += len(items) - 1
+			}
+
+			// a bound method is called with the receiver as the first
+			// argument
+			var recv int
+			if method, ok := value.(*BoundMethod); ok {
+				if !v.growStack(v.sp + 1 + stackReserve) {
+					v.err = ErrStackOverflow
+					return
+				}
+				args := v.stack[v.sp-numArgs : v.sp+1]
+				copy(args[1:], args)
+				args[0] = method.Recv
+				v.sp++
+				numArgs++
+				recv = 1
+				value = method.Fn
 			}
 
 			if callee, ok := value.(*CompiledFunction); ok {
+				if len(names) > 0 || callee.NumDefaults > 0 {
+					numArgs, v.err = v.bindArgs(callee, numArgs, names)
+					if v.err != nil {
+						return
+					}
+				}
 				if callee.VarArgs {
 					// if the closure is variadic,
 					// roll up all variadic parameters into an array
@@ -585,18 +1717,36 @@ func (v *VM) run() {
 					}
 				}
 				if numArgs != callee.NumParameters {
-					if callee.VarArgs {
-						v.err = fmt.Errorf(
-							"wrong number of arguments: want>=%d, got=%d",
-							callee.NumParameters-1, numArgs)
-					} else {
-						v.err = fmt.Errorf(
-							"wrong number of arguments: want=%d, got=%d",
-							callee.NumParameters, numArgs)
-					}
+					v.err = wrongNumArgs(callee, numArgs-recv, recv)
 					return
 				}
 
+				// generator function returns a generator with the
+				// arguments, which runs when it's iterated
+				if callee.Generator {
+					locals := make([]Object, callee.NumLocals)
+					copy(locals, v.stack[v.sp-numArgs:v.sp])
+					for i := numArgs; i < len(locals); i++ {
+						locals[i] = UndefinedValue
+					}
+					v.sp -= numArgs + 1
+					v.allocs--
+					if v.allocs == 0 && !v.leaseAllocs() {
+						v.err = ErrObjectAllocLimit
+						return
+					}
+					v.stack[v.sp] = &Generator{
+						vm:    v,
+						fn:    callee,
+						stack: locals,
+						ip:    -1,
+						key:   -1,
+						value: UndefinedValue,
+					}
+					v.sp++
+					continue
+				}
+
 				// test if it's tail-call
 				if callee == v.curFrame.fn { // recursion
 					nextOp := v.curInsts[v.ip+1]
@@ -612,7 +1762,8 @@ func (v *VM) run() {
 						continue
 					}
 				}
-				if v.framesIndex >= MaxFrames {
+				if !v.growFrames() || !v.growStack(
+					v.sp-numArgs+callee.NumLocals+stackReserve) {
 					v.err = ErrStackOverflow
 					return
 				}
@@ -623,14 +1774,35 @@ func (v *VM) run() {
 				v.curFrame.fn = callee
 				v.curFrame.freeVars = callee.Free
 				v.curFrame.basePointer = v.sp - numArgs
+				v.curFrame.gen = nil
 				v.curInsts = callee.Instructions
 				v.ip = -1
 				v.framesIndex++
 				v.sp = v.sp - numArgs + callee.NumLocals
 			} else {
+				v.insts += v.goCallCost
+				if v.insts > v.instLimit && !v.leaseInsts() {
+					v.err = ErrInstructionLimit
+					return
+				}
+
 				var args []Object
 				args = append(args, v.stack[v.sp-numArgs:v.sp]...)
-				ret, e := value.Call(args...)
+				if fn, ok := value.(*UserFunction); ok && fn.KwArgs {
+					args = appendKwArgs(args, names)
+				} else if len(names) > 0 {
+					v.err = fmt.Errorf(
+						"keyword arguments not supported in call to '%s'",
+						value.TypeName())
+					return
+				}
+				var ret Object
+				var e error
+				if callee, ok := value.(VMCallable); ok {
+					ret, e = callee.CallVM(v, args...)
+				} else {
+					ret, e = value.Call(args...)
+				}
 				v.sp -= numArgs + 1
 
 				// runtime error
@@ -657,7 +1829,7 @@ func (v *VM) run() {
 					ret = UndefinedValue
 				}
 				v.allocs--
-				if v.allocs == 0 {
+				if v.allocs == 0 && !v.leaseAllocs() {
 					v.err = ErrObjectAllocLimit
 					return
 				}
@@ -672,6 +1844,11 @@ func (v *VM) run() {
 			} else {
 				retVal = UndefinedValue
 			}
+			if v.curFrame.gen != nil {
+				// generator returns false to the iteration
+				v.curFrame.gen.finish()
+				retVal = FalseValue
+			}
 			//v.sp--
 			v.framesIndex--
 			v.curFrame = &v.frames[v.framesIndex-1]
@@ -767,10 +1944,16 @@ func (v *VM) run() {
 				NumLocals:     fn.NumLocals,
 				NumParameters: fn.NumParameters,
 				VarArgs:       fn.VarArgs,
+				SourceMap:     fn.SourceMap,
 				Free:          free,
+				Locals:        fn.Locals,
+				FreeNames:     fn.FreeNames,
+				Generator:     fn.Generator,
+				NumDefaults:   fn.NumDefaults,
+				ParamNames:    fn.ParamNames,
 			}
 			v.allocs--
-			if v.allocs == 0 {
+			if v.allocs == 0 && !v.leaseAllocs() {
 				v.err = ErrObjectAllocLimit
 				return
 			}
@@ -835,7 +2018,7 @@ func (v *VM) run() {
 			}
 			iterator = dst.Iterate()
 			v.allocs--
-			if v.allocs == 0 {
+			if v.allocs == 0 && !v.leaseAllocs() {
 				v.err = ErrObjectAllocLimit
 				return
 			}
@@ -843,6 +2026,12 @@ func (v *VM) run() {
 			v.sp++
 		case parser.OpIteratorNext:
 			iterator := v.stack[v.sp-1]
+			if g, ok := iterator.(*Generator); ok && g.vm == v {
+				if !v.resume(g) {
+					return
+				}
+				continue
+			}
 			v.sp--
 			hasMore := iterator.(Iterator).Next()
 			if hasMore {
@@ -863,6 +2052,68 @@ func (v *VM) run() {
 			val := iterator.(Iterator).Value()
 			v.stack[v.sp] = val
 			v.sp++
+		case parser.OpTry:
+			v.ip += 2
+			pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+			v.handlers = append(v.handlers, errorHandler{
+				framesIndex: v.framesIndex,
+				sp:          v.sp,
+				ip:          pos,
+			})
+		case parser.OpTryEnd:
+			v.handlers = v.handlers[:len(v.handlers)-1]
+		case parser.OpThrow:
+			e := v.stack[v.sp-1].(*Error)
+			v.sp--
+			v.ip = e.ip
+			v.err = &thrownError{obj: e}
+			return
+		case parser.OpFormat:
+			v.ip += 2
+			cidx := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+			format := v.constants[cidx].(*String)
+			s, err := Format(format.Value, v.stack[v.sp-1])
+			if err != nil {
+				v.err = err
+				return
+			}
+			if len(s) > MaxStringLen {
+				v.err = ErrStringLimit
+				return
+			}
+
+			v.allocs--
+			if v.allocs == 0 && !v.leaseAllocs() {
+				v.err = ErrObjectAllocLimit
+				return
+			}
+			if v.Allocate(int64(len(s))) != nil {
+				v.err = ErrMemoryLimit
+				return
+			}
+
+			v.stack[v.sp-1] = &String{Value: s}
+		case parser.OpRecord:
+			v.ip += 3
+			constIndex := int(v.curInsts[v.ip-1]) | int(v.curInsts[v.ip-2])<<8
+			numMethods := int(v.curInsts[v.ip])
+			typ := v.constants[constIndex].(*RecordType)
+			methods := make(map[string]*CompiledFunction, numMethods)
+			for i := v.sp - 2*numMethods; i < v.sp; i += 2 {
+				name := v.stack[i].(*String).Value
+				methods[name] = v.stack[i+1].(*CompiledFunction)
+			}
+			v.sp -= 2 * numMethods
+
+			v.allocs--
+			if v.allocs == 0 && !v.leaseAllocs() {
+				v.err = ErrObjectAllocLimit
+				return
+			}
+			v.stack[v.sp] = NewRecordType(typ.Name, typ.Fields, methods)
+			v.sp++
+		case parser.OpYield:
+			v.yield()
 		case parser.OpSuspend:
 			return
 		default:
@@ -898,6 +2149,10 @@ func indexAssign(dst, src Object, selectors []Object) error {
 		if err == ErrNotIndexAssignable {
 			return fmt.Errorf("not index-assignable: %s", dst.TypeName())
 		}
+		if err == ErrInvalidIndexType {
+			return fmt.Errorf("invalid index type: %s",
+				selectors[0].TypeName())
+		}
 		if err == ErrInvalidIndexValueType {
 			return fmt.Errorf("invaid index value type: %s", src.TypeName())
 		}
@@ -905,3 +2160,58 @@ func indexAssign(dst, src Object, selectors []Object) error {
 	}
 	return nil
 }
+
+// checkedBinaryOp is like left.BinaryOp, but it returns ErrIntegerOverflow
+// error if an int operation overflows, and ErrDivisionByZero error if an int
+// is divided by zero.
+func checkedBinaryOp(left Object, op token.Token, right Object) (Object, error) {
+	l, ok := left.(*Int)
+	if !ok {
+		return left.BinaryOp(op, right)
+	}
+	r, ok := right.(*Int)
+	if !ok {
+		return left.BinaryOp(op, right)
+	}
+	x, y := l.Value, r.Value
+	var res int64
+	switch op {
+	case token.Add:
+		res = x + y
+		if (y > 0 && res < x) || (y < 0 && res > x) {
+			return nil, ErrIntegerOverflow
+		}
+	case token.Sub:
+		res = x - y
+		if (y > 0 && res > x) || (y < 0 && res < x) {
+			return nil, ErrIntegerOverflow
+		}
+	case token.Mul:
+		res = x * y
+		if x != 0 && (res/x != y || (x == -1 && y == math.MinInt64)) {
+			return nil, ErrIntegerOverflow
+		}
+	case token.Quo, token.Rem:
+		if y == 0 {
+			return nil, ErrDivisionByZero
+		}
+		if op == token.Quo && x == math.MinInt64 && y == -1 {
+			return nil, ErrIntegerOverflow
+		}
+		return left.BinaryOp(op, right)
+	case token.Shl:
+		if y < 0 || y >= 64 {
+			if x != 0 {
+				return nil, ErrIntegerOverflow
+			}
+			return left, nil
+		}
+		res = x << uint64(y)
+		if res>>uint64(y) != x {
+			return nil, ErrIntegerOverflow
+		}
+	default:
+		return left.BinaryOp(op, right)
+	}
+	return &Int{Value: res}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
 	"reflect"
 	_runtime "runtime"
@@ -448,6 +449,105 @@ a := {
 a.x.e = "bar"`, nil, "not index-assignable")
 }
 
+func TestBigInt(t *testing.T) {
+	expectRun(t, `out = bigint("123456789012345678901234567890") * 10`,
+		nil, bigIntObject("1234567890123456789012345678900"))
+	expectRun(t, `out = bigint(9223372036854775807) + 1`,
+		nil, bigIntObject("9223372036854775808"))
+	expectRun(t, `out = 1 - bigint(9223372036854775807) - 3`,
+		nil, bigIntObject("-9223372036854775809"))
+	expectRun(t, `out = [bigint(7) / 2, bigint(-7) % 2, -bigint(3), ^bigint(0)]`,
+		nil, ARR{bigIntObject("3"), bigIntObject("-1"), bigIntObject("-3"),
+			bigIntObject("-1")})
+	expectRun(t, `out = bigint(1) << 64 >> 62`, nil, bigIntObject("4"))
+	expectRun(t, `out = [bigint(6) & 3, bigint(6) | 3, bigint(6) ^ 3, bigint(6) &^ 3]`,
+		nil, ARR{bigIntObject("2"), bigIntObject("7"), bigIntObject("5"),
+			bigIntObject("4")})
+	expectRun(t, `out = bigint(1) + 0.5`, nil, 1.5)
+	expectRun(t, `out = 0.5 + bigint(1)`, nil, 1.5)
+	expectRun(t, `out = bigint(1) + decimal("0.5")`, nil,
+		decimalObject("1.5"))
+	expectRun(t, `out = [bigint(5) == 5, 5 == bigint(5), bigint(5) != 6]`,
+		nil, ARR{true, true, true})
+	expectRun(t, `out = [bigint(5) < 6, 4 < bigint(5), bigint(5) >= 5.5]`,
+		nil, ARR{true, true, false})
+	expectRun(t, `m := {}; m[5] = 1; m[bigint(5)] += 1; out = [len(m), m[5]]`,
+		nil, ARR{1, 2})
+	expectRun(t, `out = bigint(0) ? 1 : 2`, nil, 2)
+	expectRun(t, `out = string(bigint(2) << 70)`, nil,
+		"2361183241434822606848")
+	expectRun(t, `out = [int(bigint(5)), int(bigint(1) << 64), float(bigint(2))]`,
+		nil, ARR{5, tengo.UndefinedValue, 2.0})
+	expectRun(t, `out = [bigint(2.9), bigint(-2.9), bigint('a'), bigint(true)]`,
+		nil, ARR{bigIntObject("2"), bigIntObject("-2"), bigIntObject("97"),
+			bigIntObject("1")})
+	expectRun(t, `out = [bigint("1.5"), bigint("x", 0)]`, nil,
+		ARR{tengo.UndefinedValue, 0})
+	expectRun(t, `out = [is_bigint(bigint(1)), is_bigint(1), type_name(bigint(1))]`,
+		nil, ARR{true, false, "bigint"})
+	expectRun(t, `out = format("%d %x %5d", bigint(1) << 64, bigint(255), bigint(7))`,
+		nil, "18446744073709551616 ff     7")
+
+	expectError(t, `bigint(1) / 0`, nil, "Runtime Error: division by zero")
+	expectError(t, `bigint(1) % bigint(0)`, nil,
+		"Runtime Error: division by zero")
+	expectError(t, `bigint(1) << -1`, nil,
+		"Runtime Error: invalid operation: bigint << int")
+	expectError(t, `bigint(1) + "a"`, nil,
+		"Runtime Error: invalid operation: bigint + string")
+}
+
+func TestDecimal(t *testing.T) {
+	expectRun(t, `out = decimal("0.1") + decimal("0.2")`, nil,
+		decimalObject("0.3"))
+	expectRun(t, `out = decimal("0.1") + decimal("0.2") == decimal("0.3")`,
+		nil, true)
+	expectRun(t, `out = decimal("19.99") * 3`, nil, decimalObject("59.97"))
+	expectRun(t, `out = [decimal(10) / 4, decimal(1) / 3, decimal(2) / 3]`,
+		nil, ARR{decimalObject("2.5"), decimalObject("0.3333333333333333"),
+			decimalObject("0.6666666666666667")})
+	expectRun(t, `out = [decimal("7.5") % 2, decimal("-7.5") % 2]`, nil,
+		ARR{decimalObject("1.5"), decimalObject("-1.5")})
+	expectRun(t, `out = [decimal("0.1") + 0.2, 0.2 + decimal("0.1")]`, nil,
+		ARR{decimalObject("0.3"), decimalObject("0.3")})
+	expectRun(t, `out = [1 + decimal("0.5"), decimal("0.5") - bigint(1)]`,
+		nil, ARR{decimalObject("1.5"), decimalObject("-0.5")})
+	expectRun(t, `out = -decimal("1.5")`, nil, decimalObject("-1.5"))
+	expectRun(t, `out = [decimal(2) == 2, decimal("2.5") == 2, decimal(2) == 2.0]`,
+		nil, ARR{true, false, false})
+	expectRun(t, `out = [decimal("1.5") < 2, 1.25 > decimal("1.5")]`, nil,
+		ARR{true, false})
+	expectRun(t, `out = [string(decimal("1.50")), string(decimal("1e3")), string(decimal("-0.05"))]`,
+		nil, ARR{"1.5", "1000", "-0.05"})
+	expectRun(t, `out = [decimal(0.1), decimal(bigint(1) << 64), decimal("1/3")]`,
+		nil, ARR{decimalObject("0.1"), decimalObject("18446744073709551616"),
+			tengo.UndefinedValue})
+	expectRun(t, `out = [int(decimal("-2.5")), float(decimal("2.5"))]`, nil,
+		ARR{-2, 2.5})
+	expectRun(t, `m := {}; m[decimal("2.0")] = 1; out = [m[2], m[decimal("2.5")]]`,
+		nil, ARR{1, tengo.UndefinedValue})
+	expectRun(t, `out = [is_decimal(decimal(1)), is_decimal(1.0), type_name(decimal(1))]`,
+		nil, ARR{true, false, "decimal"})
+	expectRun(t, `out = format("%v %.2f %8.3f", decimal("2.5"), decimal("2.345"), decimal(1) / 3)`,
+		nil, "2.5 2.35    0.333")
+
+	expectError(t, `decimal(1) / 0`, nil, "Runtime Error: division by zero")
+	expectError(t, `decimal(1) % decimal(0)`, nil,
+		"Runtime Error: division by zero")
+	expectError(t, `decimal(1) & 1`, nil,
+		"Runtime Error: invalid operation: decimal & int")
+}
+
+func bigIntObject(s string) *tengo.BigInt {
+	v, _ := new(big.Int).SetString(s, 10)
+	return &tengo.BigInt{Value: v}
+}
+
+func decimalObject(s string) *tengo.Decimal {
+	v, _ := new(big.Rat).SetString(s)
+	return &tengo.Decimal{Value: v}
+}
+
 func TestBitwise(t *testing.T) {
 	expectRun(t, `out = 1 & 1`, nil, 1)
 	expectRun(t, `out = 1 & 0`, nil, 0)
@@ -752,7 +852,6 @@ func TestBuiltinFunction(t *testing.T) {
 		`invalid type for argument 'first'`)
 	expectError(t, `delete([], "")`, nil, `invalid type for argument 'first'`)
 	expectError(t, `delete({}, 1)`, nil, `invalid type for argument 'second'`)
-	expectError(t, `delete({}, 1.0)`, nil, `invalid type for argument 'second'`)
 	expectError(t, `delete({}, undefined)`, nil,
 		`invalid type for argument 'second'`)
 	expectError(t, `delete({}, [])`, nil, `invalid type for argument 'second'`)
@@ -763,11 +862,9 @@ func TestBuiltinFunction(t *testing.T) {
 		`invalid type for argument 'second'`)
 	expectError(t, `delete({}, char(35))`, nil,
 		`invalid type for argument 'second'`)
-	expectError(t, `delete({}, time(1257894000))`, nil,
-		`invalid type for argument 'second'`)
 	expectError(t, `delete({}, immutable({}))`, nil,
 		`invalid type for argument 'second'`)
-	expectError(t, `delete({}, immutable([]))`, nil,
+	expectError(t, `delete({}, immutable([[]]))`, nil,
 		`invalid type for argument 'second'`)
 
 	expectRun(t, `out = delete({}, "")`, nil, tengo.UndefinedValue)
@@ -776,6 +873,12 @@ func TestBuiltinFunction(t *testing.T) {
 		MAP{"key2": "2"})
 	expectRun(t, `out = [1, "2", {a: "b", c: 10}]; delete(out[2], "c")`, nil,
 		ARR{1, "2", MAP{"a": "b"}})
+	expectRun(t, `
+out = {1: "a", 1.5: "b", 'c': "c", "2": "d", true: "e"}
+k := immutable([1, 2])
+out[k] = "f"
+delete(out, "1"); delete(out, 1.5); delete(out, "c"); delete(out, k)
+delete(out, true); delete(out, time(1257894000))`, nil, MAP{"2": "d"})
 
 	// splice
 	expectError(t, `splice()`, nil, tengo.ErrWrongNumArguments.Error())
@@ -1168,6 +1271,253 @@ func TestVMErrorUnwrap(t *testing.T) {
 		"expected error as:%v, got:%v", wrapUserErr, asErr2)
 }
 
+func TestTry(t *testing.T) {
+	expectRun(t, `out = 0; try { out = 1 } catch { out = 2 }`, nil, 1)
+	expectRun(t, `out = 0; try { out = 1 + "a" } catch { out = 2 }`, nil, 2)
+	expectRun(t, `try { out = [1, 2][5] + 1 } catch err { out = err.value }`,
+		nil, "invalid operation: undefined + int")
+	expectRun(t, `a := [1, 2]; try { a[5] = 3 } catch err { out = err.value }`,
+		nil, "index out of bounds")
+	expectRun(t, `try { 1 + "a" } catch err { out = is_error(err) }`, nil, true)
+	expectRun(t, `try { 1 + "a" } catch _ { out = 5 }`, nil, 5)
+	expectRun(t, `out = ""
+try {
+	out += "a"
+	1 + "a"
+	out += "b"
+} catch err {
+	out += "c"
+} finally {
+	out += "d"
+}`, nil, "acd")
+	expectRun(t, `out = ""
+try { out += "a" } finally { out += "b" }`, nil, "ab")
+
+	// errors from nested function calls
+	expectRun(t, `
+f := func(x) { return x + "a" }
+g := func(x) { return f(x) * 2 }
+try { g(1) } catch err { out = err.value }`, nil,
+		"invalid operation: int + string")
+	expectRun(t, `
+f := func(x) {
+	try {
+		return x + "a"
+	} catch err {
+		return "caught: " + err.value
+	}
+}
+out = f(1)`, nil, "caught: invalid operation: int + string")
+
+	// nested try statements
+	expectRun(t, `out = ""
+try {
+	try {
+		1 + "a"
+	} catch {
+		out += "a"
+		2 + "b"
+	} finally {
+		out += "b"
+	}
+} catch {
+	out += "c"
+}`, nil, "abc")
+	expectRun(t, `out = ""
+try {
+	try {
+		1 + "a"
+	} finally {
+		out += "a"
+	}
+} catch err {
+	out += "b:" + err.value
+}`, nil, "ab:invalid operation: int + string")
+	expectRun(t, `out = ""
+try {
+	try {} catch { out += "a" }
+	1 + "a"
+} catch {
+	out += "b"
+}`, nil, "b")
+
+	// finally with return, break and continue
+	expectRun(t, `out = ""
+f := func() {
+	try {
+		return "a"
+	} finally {
+		out += "b"
+	}
+}
+r := f()
+out += r`, nil, "ba")
+	expectRun(t, `out = ""
+f := func() {
+	try {
+		1 + "a"
+	} catch {
+		return "a"
+	} finally {
+		out += "b"
+	}
+}
+r := f()
+out += r`, nil, "ba")
+	expectRun(t, `out = ""
+for i := 0; i < 5; i++ {
+	try {
+		if i == 1 { continue }
+		if i == 3 { break }
+		out += string(i)
+	} finally {
+		out += "."
+	}
+}`, nil, "0..2..")
+	expectRun(t, `out = ""
+for x in [1, "a", 3] {
+	try {
+		out += string(x * 2)
+	} catch {
+		continue
+	}
+}`, nil, "26")
+	expectRun(t, `out = 0
+for x in [1, 2, 3] {
+	try {
+		try { x + "a" } finally { if x == 2 { break } }
+	} catch {
+		out += x
+	}
+}`, nil, 1)
+
+	// tail call inside try statement
+	expectRun(t, `
+f := func(n) {
+	try {
+		if n == 0 { return 1 + "a" }
+		return f(n - 1)
+	} catch err {
+		return n
+	}
+}
+out = f(10)`, nil, 0)
+
+	// user function errors
+	expectRun(t, `try { user_func() } catch err { out = err.value }`,
+		Opts().Symbol("user_func", &tengo.UserFunction{
+			Value: func(args ...tengo.Object) (tengo.Object, error) {
+				return nil, errors.New("user error")
+			},
+		}).Skip2ndPass(), "user error")
+
+	// source position of the error
+	expectRun(t, `try {
+	a := 1
+	a += "b"
+} catch err { out = err.pos }`, Opts().Skip2ndPass(), "test:3:2")
+	expectRun(t, `f := func() {
+	return 1 + "a"
+}
+try { f() } catch err { out = err.pos }`, Opts().Skip2ndPass(), "test:2:9")
+	expectRun(t, `out = error(1).pos`, nil, tengo.UndefinedValue)
+
+	// uncaught errors
+	expectError(t, `try { 1 + "a" } finally { a := 1 }`, nil,
+		"Runtime Error: invalid operation: int + string\n\tat test:1:7")
+	expectError(t, `try { 1 + "a" } catch { 2 + "b" }`, nil,
+		"Runtime Error: invalid operation: int + string\n\tat test:1:25")
+	expectError(t, `
+f := func() { return 1 + "a" }
+try { f() } finally {}`, nil,
+		"Runtime Error: invalid operation: int + string\n\tat test:2:22\n"+
+			"\tat test:3:7")
+	expectErrorIs(t, `try { user_func() } finally {}`,
+		Opts().Symbol("user_func", &tengo.UserFunction{
+			Value: func(args ...tengo.Object) (tengo.Object, error) {
+				return nil, tengo.ErrIndexOutOfBounds
+			},
+		}), tengo.ErrIndexOutOfBounds)
+	expectError(t, `try { a := [1]; b := [2]; c := [3] } catch {}`,
+		Opts().MaxAllocs(2).Skip2ndPass(), "allocation limit exceeded")
+	expectError(t, `f := func() { return 1 + f() }; try { f() } catch {}`, nil,
+		"stack overflow")
+
+	expectError(t, `try { err := 1 } catch { err + 1 }`, nil,
+		"unresolved reference 'err'")
+	expectError(t, `try {} catch err {}; err`, nil,
+		"unresolved reference 'err'")
+}
+
+func TestVMCallback(t *testing.T) {
+	apply := &tengo.VMFunction{
+		Name: "apply",
+		Value: func(
+			vm *tengo.VM,
+			args ...tengo.Object,
+		) (tengo.Object, error) {
+			fn, ok := args[0].(*tengo.CompiledFunction)
+			if !ok {
+				return nil, tengo.ErrInvalidArgumentType{
+					Name:     "first",
+					Expected: "compiled-function",
+					Found:    args[0].TypeName(),
+				}
+			}
+			return vm.RunCompiled(fn, args[1:]...)
+		},
+	}
+	opts := Opts().Symbol("apply", apply).Skip2ndPass()
+
+	expectRun(t, `out = apply(func() { return 5 })`, opts, 5)
+	expectRun(t, `out = apply(func(a, b) { return a + b }, 1, 2)`, opts, 3)
+	expectRun(t, `out = apply(func(a, ...b) { return [a, b] }, 1, 2, 3)`,
+		opts, ARR{1, ARR{2, 3}})
+	expectRun(t, `
+a := 10
+f := func() {
+	b := 5
+	return apply(func(x) { a += x; return a * b }, 2)
+}
+out = [f(), a]`, opts, ARR{60, 12})
+	expectRun(t, `
+out = apply(func(x) {
+	return apply(func(y) { return x + y }, 2) * 10
+}, 1)`, opts, 30)
+	expectRun(t, `
+out = 0
+for i := 0; i < 5; i++ {
+	out += apply(func(x) { return x * x }, i)
+}`, opts, 30)
+	expectRun(t, `
+fact := func(n) {
+	if n < 2 { return 1 }
+	return n * apply(fact, n - 1)
+}
+out = fact(10)`, opts, 3628800)
+	expectRun(t, `
+sum := func(n) {
+	if n < 1 { return 0 }
+	return n + apply(sum, n - 1)
+}
+out = sum(300)`, opts, 45150)
+
+	expectError(t, `apply(func(a) {}, 1, 2)`, opts,
+		"Runtime Error: wrong number of arguments: want=1, got=2")
+	expectError(t, `apply(func() {
+	return 5 + "foo"
+})`, opts,
+		"Runtime Error: invalid operation: int + string\n\tat test:2:9\n"+
+			"\tat test:1:1")
+	expectError(t, `apply(func() {
+	return apply(func(){ return 5 + "foo" })
+})`, opts,
+		"Runtime Error: invalid operation: int + string\n\tat test:2:30\n"+
+			"\tat test:2:9\n\tat test:1:1")
+	expectError(t, `apply(func() { a := [1]; b := [2]; c := [3] })`,
+		opts.MaxAllocs(2), "allocation limit exceeded")
+}
+
 func TestError(t *testing.T) {
 	expectRun(t, `out = error(1)`, nil, errorObject(1))
 	expectRun(t, `out = error(1).value`, nil, 1)
@@ -1226,6 +1576,237 @@ func TestForIn(t *testing.T) {
 		nil, "abde")
 }
 
+func TestGenerator(t *testing.T) {
+	expectRun(t, `
+gen := func(n) { for i := 0; i < n; i++ { yield i * i } }
+out = []
+for x in gen(4) { out = append(out, x) }`, nil, ARR{0, 1, 4, 9})
+	expectRun(t, `
+gen := func(...a) { for x in a { yield x + 1 } }
+out = 0
+for i, x in gen(10, 20, 30) { out += i * x }`, nil, 83)
+	expectRun(t, `
+gen := func() { yield 1; yield; return 3; yield 4 }
+out = []
+for x in gen() { out = append(out, x) }`, nil, ARR{1, tengo.UndefinedValue})
+
+	// infinite generator
+	expectRun(t, `
+nat := func() { i := 0; for { yield i; i++ } }
+out = 0
+for x in nat() { if x > 10 { break }; out += x }`, nil, 55)
+
+	// generator is iterated once
+	expectRun(t, `
+g := func() { yield 1; yield 2 }()
+out = 0
+for x in g { out += x; break }
+for x in g { out += x * 10 }`, nil, 21)
+
+	// closures and nested generators
+	expectRun(t, `
+base := 10
+gen := func(n) {
+	f := func(x) { return base + x }
+	for i := 0; i < n; i++ { yield f(i) }
+	base = 100
+}
+out = []
+for x in gen(2) { out = append(out, x) }
+out = append(out, base)`, nil, ARR{10, 11, 100})
+	expectRun(t, `
+flatten := func(arr) {
+	for x in arr {
+		if is_array(x) {
+			for y in flatten(x) { yield y }
+		} else {
+			yield x
+		}
+	}
+}
+out = []
+for x in flatten([1, [2, [3, 4]], 5]) { out = append(out, x) }`,
+		nil, ARR{1, 2, 3, 4, 5})
+	expectRun(t, `
+gen := func() { for i := 0; i < 3; i++ { yield func() { return i } } }
+out = []
+for f in gen() { out = append(out, f()) }`, nil, ARR{0, 1, 2})
+
+	// generators suspended in the middle of expressions
+	expectRun(t, `
+gen := func() { yield 1; yield 2; yield 3 }
+first := func(g) { for x in g { return x } }
+g := gen()
+out = [first(g), [first(g), first(g)], first(g)]`,
+		nil, ARR{1, ARR{2, 3}, tengo.UndefinedValue})
+
+	// error handlers in generators
+	expectRun(t, `
+gen := func() {
+	try {
+		yield 1
+		yield 1 + "a"
+	} catch e {
+		yield "caught"
+	}
+	yield 2
+}
+out = []
+for x in gen() { out = append(out, x) }`, nil, ARR{1, "caught", 2})
+	expectRun(t, `
+gen := func() { yield 1; a := 1 + "a"; yield 2 }
+g := gen()
+out = []
+try {
+	for x in g { out = append(out, x) }
+} catch e {
+	out = append(out, e.value)
+}
+for x in g { out = append(out, x) }`,
+		nil, ARR{1, "invalid operation: int + string"})
+	expectRun(t, `
+gen := func() { try { yield 1 } catch e { yield 2 } }
+out = 0
+for x in gen() { try { out += x } catch e {} }
+try { out += 1 + "a" } catch e { out += 10 }`, nil, 11)
+
+	// finally blocks run only if the generator is resumed until it finishes,
+	// and not when it's abandoned
+	expectRun(t, `
+out = []
+gen := func() {
+	try { yield 1; yield 2 } finally { out = append(out, "finally") }
+}
+for x in gen() { out = append(out, x) }
+for x in gen() { out = append(out, x); break }
+g := gen()
+for x in g { out = append(out, x); break }
+for x in g { out = append(out, x) }`,
+		nil, ARR{1, 2, "finally", 1, 1, 2, "finally"})
+
+	expectError(t, `
+g := func() { yield 1; yield 1 + "a" }()
+for x in g {}`, nil, "Runtime Error: invalid operation: int + string\n"+
+		"\tat test:2:30\n\tat test:3:1")
+	expectError(t, `
+g := undefined
+g = func() { for x in g {}; yield 1 }()
+for x in g {}`, nil, "generator already running")
+	expectError(t, `yield 1`, nil, "yield not allowed outside function")
+	expectError(t, `if true { yield 1 }`, nil,
+		"yield not allowed outside function")
+
+	// generators iterated by Go functions
+	collect := &tengo.VMFunction{
+		Name: "collect",
+		Value: func(
+			vm *tengo.VM,
+			args ...tengo.Object,
+		) (tengo.Object, error) {
+			it := args[0].Iterate()
+			arr := &tengo.Array{}
+			for it.Next() {
+				arr.Value = append(arr.Value, it.Value())
+			}
+			if g, ok := it.(*tengo.Generator); ok && g.Err() != nil {
+				return nil, g.Err()
+			}
+			return arr, nil
+		},
+	}
+	opts := Opts().Symbol("collect", collect).Skip2ndPass()
+	expectRun(t, `out = collect(func() { yield 1; yield 2 }())`,
+		opts, ARR{1, 2})
+	expectRun(t, `
+gen := func(n) { for i := 0; i < n; i++ { yield collect(gen(i)) } }
+out = collect(gen(3))`, opts, ARR{ARR{}, ARR{ARR{}}, ARR{ARR{}, ARR{ARR{}}}})
+	expectError(t, `collect(func() { yield 1; yield 1 + "a" }())`, opts,
+		"invalid operation: int + string")
+}
+
+func TestRecord(t *testing.T) {
+	expectRun(t, `
+record Point(x, y)
+p := Point(1, 2)
+out = [p.x, p.y, p["x"], Point(1).y]`,
+		nil, ARR{1, 2, 1, tengo.UndefinedValue})
+	expectRun(t, `
+record Point(x, y)
+p := Point(1, 2)
+p.x = 10
+p["y"] += 5
+out = [p.x, p.y, string(p), type_name(p), type_name(Point)]`,
+		nil, ARR{10, 7, "Point{x: 10, y: 7}", "Point", "record-type:Point"})
+
+	// methods and init
+	expectRun(t, `
+record Vec(x, y) {
+	add: func(o) { return Vec(self.x + o.x, self.y + o.y) },
+	scale: func(k) { self.x *= k; self.y *= k; return self }
+}
+v := Vec(1, 2).add(Vec(3, 4))
+f := v.scale
+f(2)
+out = [v.x, v.y]`, nil, ARR{8, 12})
+	expectRun(t, `
+record Counter(n, step) {
+	init: func(step) { self.n = 0; self.step = step },
+	inc: func() { self.n += self.step; return self.n }
+}
+c := Counter(5)
+c.inc()
+out = c.inc()`, nil, 10)
+	expectRun(t, `
+record Node(value, next) {
+	sum: func() {
+		if self.next == undefined { return self.value }
+		return self.value + self.next.sum()
+	}
+}
+out = Node(1, Node(2, Node(3))).sum()`, nil, 6)
+
+	// copies and equality
+	expectRun(t, `
+record Point(x, y)
+p := Point(1, [2])
+q := copy(p)
+q.y[0] = 3
+out = [p == Point(1, [2]), p == q, p.y[0], p == {x: 1, y: [2]}]`,
+		nil, ARR{true, false, 2, false})
+
+	// local record types
+	expectRun(t, `
+f := func(a) {
+	record Pair(a, b) { swap: func() { return Pair(self.b, self.a) } }
+	return Pair(a, a * 2).swap()
+}
+p := f(3)
+out = [p.a, p.b, type_name(p)]`, nil, ARR{6, 3, "Pair"})
+
+	expectRun(t, `
+record Point(x, y)
+record Other(x, y)
+out = [is_record(Point(1, 2)), is_record({}), is_record(Point(), Point),
+	is_record(Other(), Point)]`, nil, ARR{true, false, true, false})
+
+	// record is not a reserved word
+	expectRun(t, `record := 1; out = record`, nil, 1)
+
+	expectError(t, `record Point(x, y); p := Point(); p.z = 1`, nil,
+		"unknown field 'z' in Point")
+	expectError(t, `record Point(x, y); p := Point(); a := p.z`, nil,
+		"unknown field 'z' in Point")
+	expectError(t, `record Point(x); p := Point(1, 2)`, nil,
+		"wrong number of arguments")
+	expectError(t, `record P(x) { init: func(x) {} }; p := P()`, nil,
+		"wrong number of arguments: want=1, got=0")
+	expectError(t, `record Point(x, x)`, nil, "duplicate field 'x'")
+	expectError(t, `record P(x) { x: func() {} }`, nil,
+		"duplicate field or method 'x'")
+	expectError(t, `a := 1; record a(x)`, nil, "'a' redeclared in this block")
+	expectError(t, `is_record(1, 2)`, nil, "invalid type for argument 'second'")
+}
+
 func TestFor(t *testing.T) {
 	expectRun(t, `
 	out = 0
@@ -1804,6 +2385,97 @@ out = (func() {
 	`, nil, 2)
 }
 
+func TestDefaultParams(t *testing.T) {
+	expectRun(t, `f := func(a, b=10) { return [a, b] }; out = [f(1), f(1, 2)]`,
+		nil, ARR{ARR{1, 10}, ARR{1, 2}})
+	expectRun(t, `f := func(a, b=a*2, c=b+1) { return [a, b, c] }; out = f(1)`,
+		nil, ARR{1, 2, 3})
+	expectRun(t, `f := func(a=[]) { a = append(a, 1); return a }; f(); out = f()`,
+		nil, ARR{1})
+	expectRun(t, `f := func(a=1) { return a }; out = f(undefined)`, nil, 1)
+	expectRun(t, `f := func(a, b=1, ...c) { return [a, b, c] }
+out = [f(1), f(1, 2, 3, 4)]`, nil, ARR{ARR{1, 1, ARR{}}, ARR{1, 2, ARR{3, 4}}})
+	expectRun(t, `
+n := 0
+f := func(a=func() { n++ }()) {}
+f(); f(1); f()
+out = n`, nil, 2)
+	expectRun(t, `
+f := func(n, acc=0) {
+	if n == 0 { return acc }
+	return f(n - 1, acc + n)
+}
+out = f(100)`, nil, 5050)
+	expectRun(t, `
+g := func(n=2) { for i := 0; i < n; i++ { yield i } }
+out = []
+for v in g() { out = append(out, v) }`, nil, ARR{0, 1})
+	expectRun(t, `
+record P(x, y) {
+	init: func(x, y=0) { self.x = x; self.y = y },
+	scale: func(k=2) { return self.x * k }
+}
+p := P(3)
+out = [p.y, p.scale(), p.scale(3)]`, nil, ARR{0, 6, 9})
+
+	expectError(t, `func(a, b=1) {}()`, nil,
+		"Runtime Error: wrong number of arguments: want=1..2, got=0")
+	expectError(t, `func(a, b=1) {}(1, 2, 3)`, nil,
+		"Runtime Error: wrong number of arguments: want=1..2, got=3")
+	expectError(t, `func(a, b=1, ...c) {}()`, nil,
+		"Runtime Error: wrong number of arguments: want>=1, got=0")
+	expectError(t, `record P(x) { init: func(x, y=1) {} }; P()`, nil,
+		"Runtime Error: wrong number of arguments: want=1..2, got=0")
+}
+
+func TestKeywordArgs(t *testing.T) {
+	expectRun(t, `f := func(a, b, c) { return [a, b, c] }; out = f(1, c: 3, b: 2)`,
+		nil, ARR{1, 2, 3})
+	expectRun(t, `f := func(a, b=2, c=3) { return [a, b, c] }; out = f(c: 4, a: 1)`,
+		nil, ARR{1, 2, 4})
+	expectRun(t, `f := func(a, ...b) { return [a, b] }; out = f(a: 1)`,
+		nil, ARR{1, ARR{}})
+	expectRun(t, `
+f := func(n, acc=0) {
+	if n == 0 { return acc }
+	return f(n - 1, acc: acc + n)
+}
+out = f(100)`, nil, 5050)
+	expectRun(t, `
+record P(x) { scale: func(k=2, d=0) { return self.x * k + d } }
+out = P(3).scale(d: 1)`, nil, 7)
+	expectRun(t, `out = f(1, b: 2)`,
+		Opts().Symbol("f", &tengo.UserFunction{
+			KwArgs: true,
+			Value: func(args ...tengo.Object) (tengo.Object, error) {
+				kwargs := args[len(args)-1].(*tengo.Map)
+				return &tengo.Array{Value: []tengo.Object{
+					args[0], kwargs.Value["b"],
+				}}, nil
+			},
+		}).Skip2ndPass(), ARR{1, 2})
+	expectRun(t, `out = f(1)`,
+		Opts().Symbol("f", &tengo.UserFunction{
+			KwArgs: true,
+			Value: func(args ...tengo.Object) (tengo.Object, error) {
+				return &tengo.Int{Value: int64(len(args))}, nil
+			},
+		}).Skip2ndPass(), 2)
+
+	expectError(t, `func(a, b) {}(1, a: 2)`, nil,
+		"Runtime Error: multiple values for argument 'a'")
+	expectError(t, `func(a) {}(b: 1)`, nil,
+		"Runtime Error: unexpected keyword argument 'b'")
+	expectError(t, `func(a, b=1) {}(b: 1)`, nil,
+		"Runtime Error: missing argument 'a'")
+	expectError(t, `func(a, ...b) {}(1, 2, a: 3)`, nil,
+		"Runtime Error: multiple values for argument 'a'")
+	expectError(t, `len(a: 1)`, nil,
+		"Runtime Error: keyword arguments not supported in call to 'builtin-function:len'")
+	expectError(t, `f := func(a) {}; f(a: 1, a: 2)`, nil,
+		"Compile Error: keyword argument 'a' repeated")
+}
+
 func TestBlocksInGlobalScope(t *testing.T) {
 	expectRun(t, `
 f := undefined
@@ -2459,6 +3131,34 @@ func (o *StringArray) CanIterate() bool {
 	return true
 }
 
+func TestInterpString(t *testing.T) {
+	expectRun(t, `out = f""`, nil, "")
+	expectRun(t, `out = f"foo"`, nil, "foo")
+	expectRun(t, `a := 1; out = f"${a}"`, nil, "1")
+	expectRun(t, `a := "b"; out = f"a${a}c"`, nil, "abc")
+	expectRun(t, `a := 1; b := 2; out = f"${a} + ${b} = ${a + b}"`,
+		nil, "1 + 2 = 3")
+	expectRun(t, `out = f"${[1, "a"]} ${{a: true}} ${'c'} ${undefined}"`,
+		nil, `[1, "a"] {a: true} c <undefined>`)
+	expectRun(t, `out = f"\${a} \t${"\""}"`, nil, "${a} \t\"")
+	expectRun(t, `a := "x"; out = f"${f"${a}${a}"}y"`, nil, "xxy")
+	expectRun(t, `f := func(x) { return x * 2 }; out = f"${f(2)}"`,
+		nil, "4")
+	expectRun(t, `out = f"${true ? "a" : "b"}"`, nil, "a")
+
+	// formatting verbs
+	expectRun(t, `out = f"${3.14159:%08.3f}"`, nil, "0003.142")
+	expectRun(t, `a := 10; out = f"[${a:%-4d}] [${a:%x}]"`, nil, "[10  ] [a]")
+	expectRun(t, `a := "s"; out = f"${a:%q}"`, nil, `"s"`)
+
+	expectError(t, `f"${a}"`, nil, "unresolved reference 'a'")
+	expectError(t, `a := 1
+f"abc ${a + "b" * 2}"`, nil,
+		"Runtime Error: invalid operation: string * int\n\tat test:2:13")
+	expectError(t, `a := f"${[1]}${[2]}"`,
+		Opts().MaxAllocs(2).Skip2ndPass(), "allocation limit exceeded")
+}
+
 func TestIterable(t *testing.T) {
 	strArr := func() *StringArray {
 		return &StringArray{Value: []string{"one", "two", "three"}}
@@ -2563,6 +3263,51 @@ out = m["foo"](2) + m["foo"](3)
 		nil, 5)
 	expectRun(t, `func() { m1 := {k1: 1, k2: "foo"}; m2 := m1; m2.k1 = 3; out = m1.k1 }()`,
 		nil, 3)
+
+	// non-string keys; ints and chars are converted to strings
+	expectRun(t, `
+m := {1: "a", "1": "b", 1.5: "c", 'x': "d", true: "e", -2: "f"}
+out = [m[1], m["1"], m[1.5], m["1.5"], m['x'], m["x"], m[true], m[-2],
+	m[2], m[false], len(m)]`,
+		nil, ARR{"b", "b", "c", tengo.UndefinedValue, "d", "d", "e", "f",
+			tengo.UndefinedValue, tengo.UndefinedValue, 5})
+	expectRun(t, `
+counts := {}
+for id in [3, 1, 3, 2, 3] { counts[id] = (counts[id] || 0) + 1 }
+out = [counts[1], counts[2], counts[3], counts["3"]]
+for k, _ in counts { out = append(out, is_string(k)) }`,
+		nil, ARR{1, 1, 3, 3, true, true, true})
+	expectRun(t, `
+cache := {}
+key := func(a, b) { return immutable([a, b]) }
+cache[key(1, "x")] = 10
+cache[key(1, "x")] += 5
+k := 2
+out = [cache[key(1, "x")], cache[key("x", 1)], {(k * 2): "four"}[4],
+	{(key(1, 2)): 3}[immutable([1, 2])]]`,
+		nil, ARR{15, tengo.UndefinedValue, "four", 3})
+	expectRun(t, `
+t := time(1257894000)
+out = {(t): 1}[time(1257894000)]`, nil, 1)
+	expectRun(t, `
+m := {1.5: "a", b: 2}
+out = []
+for k, v in m { if is_float(k) { out = append(out, v) } }
+out = append(out, m == {b: 2, 1.5: "a"}, m == {b: 2, "1.5": "a"})
+n := copy(m); n[1.5] = "c"
+out = append(out, m[1.5], immutable(m)[1.5])`,
+		nil, ARR{"a", true, false, "a", "a"})
+	expectRun(t, `out = {0.0: 1}[-0.0]`, nil, 1)
+	expectRun(t, `out = {1: 1}[1.0]`, nil, tengo.UndefinedValue)
+	expectRun(t, `out = {1: 1}[bigint(1)]`, nil, 1)
+
+	expectError(t, `m := {}; m[[1]] = 1`, nil, "invalid index type: array")
+	expectError(t, `m := {}; a := m[{}]`, nil, "invalid index type: map")
+	expectError(t, `m := {}; m[immutable([[1]])] = 1`, nil,
+		"invalid index type: immutable-array")
+	expectError(t, `m := {([1]): 1}`, nil, "invalid map key type: array")
+	expectError(t, `m := {(undefined): 1}`, nil,
+		"invalid map key type: undefined")
 }
 
 func TestBuiltin(t *testing.T) {
@@ -3355,6 +4100,153 @@ out = x.at([1, 2, 3], 0)
 `, Opts().Stdlib(), 1)
 }
 
+func TestSwitch(t *testing.T) {
+	expectRun(t, `switch 2 { case 1: out = 10; case 2: out = 20; case 3: out = 30 }`,
+		nil, 20)
+	expectRun(t, `switch 4 { case 1: out = 10; case 2: out = 20 }`,
+		nil, tengo.UndefinedValue)
+	expectRun(t, `switch 4 { case 1: out = 10; default: out = 30; case 2: out = 20 }`,
+		nil, 30)
+	expectRun(t, `switch 2 { default: out = 30; case 1, 2: out = 20 }`,
+		nil, 20)
+	expectRun(t, `switch 3 { case 1, 2, 3: out = 10; case 3: out = 20 }`,
+		nil, 10)
+	expectRun(t, `switch "b" { case "a": out = 1; case "b": out = 2 }`,
+		nil, 2)
+	expectRun(t, `switch type_name([]) { case "int": out = 1; case "array": out = 2 }`,
+		nil, 2)
+	expectRun(t, `switch {}; out = 1`, nil, 1)
+	expectRun(t, `switch 1 {}; out = 1`, nil, 1)
+	expectRun(t, `switch 1 { case 1: }; out = 1`, nil, 1)
+
+	// no fallthrough
+	expectRun(t, `
+out = 0
+switch 1 {
+case 1:
+	out += 1
+case 2:
+	out += 2
+default:
+	out += 3
+}`, nil, 1)
+
+	// expression-less switch
+	expectRun(t, `
+x := 5
+switch {
+case x < 3:
+	out = "small"
+case x < 10:
+	out = "medium"
+default:
+	out = "large"
+}`, nil, "medium")
+	expectRun(t, `x := 20; switch { case x < 3: out = 1; default: out = 3 }`,
+		nil, 3)
+	expectRun(t, `switch { case 0, "": out = 1; case [1]: out = 2 }`,
+		nil, 2)
+
+	// tag is evaluated once, case values in order until matched
+	expectRun(t, `
+n := 0
+f := func(v) { n++; return v }
+switch f(2) {
+case f(1), f(2), f(3):
+	out = n
+}`, nil, 3)
+
+	// break
+	expectRun(t, `
+out = 0
+switch 1 {
+case 1:
+	out = 1
+	if true { break }
+	out = 2
+}`, nil, 1)
+	expectRun(t, `
+out = ""
+for i := 0; i < 5; i++ {
+	switch {
+	case i == 1:
+		continue
+	case i == 3:
+		break
+	}
+	out += string(i)
+}`, nil, "0234")
+	expectRun(t, `
+out = 0
+for {
+	switch out {
+	case 3:
+		break
+	default:
+		out++
+		continue
+	}
+	break
+}`, nil, 3)
+	expectRun(t, `
+out = ""
+for x in [1, 2, 3] {
+	switch x {
+	case 2:
+		out += "two"
+	default:
+		for y in [1, 2] {
+			switch y {
+			case 2:
+				break
+			}
+			out += string(x * 10 + y)
+		}
+	}
+}`, nil, "1112two3132")
+
+	// local scopes
+	expectRun(t, `
+f := func(x) {
+	a := 1
+	switch x {
+	case 1:
+		a := 10
+		return a
+	case 2:
+		b := 20
+		return a + b
+	}
+	return a
+}
+out = [f(1), f(2), f(3)]`, nil, ARR{10, 21, 1})
+	expectRun(t, `
+f := func(x) {
+	switch x {
+	case 1, 2:
+		return "a"
+	default:
+		return "b"
+	}
+}
+out = f(2) + f(3)`, nil, "ab")
+	expectRun(t, `
+a := 1
+switch a {
+case 1:
+	a := 2
+	a = 3
+}
+out = a`, nil, 1)
+
+	expectError(t, `switch 1 { case 1: continue }`, nil,
+		"continue not allowed outside loop")
+	expectError(t, `switch 1 { case 1: a := 1 }; a`, nil,
+		"unresolved reference 'a'")
+	expectError(t, `switch a { case 1: }`, nil,
+		"unresolved reference 'a'")
+}
+
 func TestVMStackOverflow(t *testing.T) {
 	expectError(t, `f := func() { return f() + 1 }; f()`,
 		nil, "stack overflow")
@@ -3623,6 +4515,109 @@ func TestSpread(t *testing.T) {
 		"Runtime Error: wrong number of arguments: want=1, got=2")
 	expectError(t, `func(a, b, c) {}([1, 2]...)`, nil,
 		"Runtime Error: wrong number of arguments: want=3, got=2")
+
+	// spread in array and map literals
+	expectRun(t, `a := [2, 3]; out = [1, a..., 4, a...]`, nil,
+		ARR{1, 2, 3, 4, 2, 3})
+	expectRun(t, `out = [[]..., immutable([1])..., []...]`, nil, ARR{1})
+	expectRun(t, `a := [1]; b := [a...]; b[0] = 2; out = a`, nil, ARR{1})
+	expectRun(t, `m := {a: 1, b: 2}; out = {m..., b: 3, c: 4}`, nil,
+		MAP{"a": 1, "b": 3, "c": 4})
+	expectRun(t, `m := {b: 2}; out = {a: 1, b: 0, m...}`, nil,
+		MAP{"a": 1, "b": 2})
+	expectRun(t, `a := immutable({a: 1}); b := {1: 2}; out = {a..., b...}[1]`,
+		nil, 2)
+	expectRun(t, `f := func(x) { return {x, y: [x...]} }; out = f([1])`,
+		nil, MAP{"x": ARR{1}, "y": ARR{1}})
+	expectError(t, `a := 1; b := [a...]`, nil,
+		"Runtime Error: cannot spread int in array")
+	expectError(t, `a := [1]; b := {a...}`, nil,
+		"Runtime Error: cannot spread array in map")
+}
+
+func TestDestructuring(t *testing.T) {
+	expectRun(t, `a, b := [1, 2]; out = [a, b]`, nil, ARR{1, 2})
+	expectRun(t, `f := func() { return [1, "x"] }; a, b := f(); out = b`,
+		nil, "x")
+	expectRun(t, `[x, _, z] := immutable([1, 2, 3]); out = x + z`, nil, 4)
+	expectRun(t, `a := 1; b := 2; a, b = [b, a]; out = [a, b]`, nil,
+		ARR{2, 1})
+	expectRun(t, `{name, age: years} := {name: "a", age: 3}
+out = [name, years]`, nil, ARR{"a", 3})
+	expectRun(t, `{x} := {}; out = x`, nil, tengo.UndefinedValue)
+	expectRun(t, `m := {}; a := [0]; m.x, a[0] = [1, 2]; out = [m, a]`,
+		nil, ARR{MAP{"x": 1}, ARR{2}})
+	expectRun(t, `
+f := func(arr) {
+	x, y := arr
+	g := func() { return x + y }
+	return g()
+}
+out = f([1, 2])`, nil, 3)
+	expectRun(t, `
+out = 0
+for p in [[1, 2], [3, 4]] {
+	a, b := p
+	out += a * b
+}`, nil, 14)
+	expectRun(t, `
+record Point(x, y)
+{x, y} := Point(1, 2)
+out = x + y`, nil, 3)
+
+	expectError(t, `a, b := [1, 2, 3]`, nil,
+		"Runtime Error: wrong number of values to destructure: want=2, got=3")
+	expectError(t, `[a] := []`, nil,
+		"Runtime Error: wrong number of values to destructure: want=1, got=0")
+	expectError(t, `a, b := 1`, nil,
+		"Runtime Error: cannot destructure int: not an array")
+	expectError(t, `{a} := [1]`, nil,
+		"Runtime Error: cannot destructure array: not a map")
+}
+
+func TestOptionalChaining(t *testing.T) {
+	expectRun(t, `cfg := {server: {port: 80}}; out = cfg?.server?.port ?? 8080`,
+		nil, 80)
+	expectRun(t, `cfg := {}; out = cfg?.server?.port ?? 8080`, nil, 8080)
+	expectRun(t, `cfg := undefined; out = cfg?.server?.port ?? 8080`,
+		nil, 8080)
+	expectRun(t, `out = [1?.a, "ab"?[0], [1]?[5], undefined?[0]]`, nil,
+		ARR{tengo.UndefinedValue, 'a', tengo.UndefinedValue,
+			tengo.UndefinedValue})
+	expectRun(t, `a := undefined; out = a?.b.c[0].d`, nil,
+		tengo.UndefinedValue)
+	expectRun(t, `a := undefined; out = a?.b(1)`, nil, tengo.UndefinedValue)
+	expectRun(t, `a := {f: func(x) { return x * 2 }}; out = a?.f(3)`, nil, 6)
+	expectRun(t, `a := {b: [1, 2]}; out = a?.b[1:]`, nil, ARR{2})
+	expectRun(t, `
+n := 0
+f := func() { n++; return 0 }
+a := undefined
+a?[f()]
+out = n`, nil, 0)
+
+	// chains do not leak into nested expressions
+	expectRun(t, `a := {}; b := [a?.x, 1]; out = b[1]`, nil, 1)
+	expectRun(t, `a := undefined; out = [a?.b][0] ?? 2`, nil, 2)
+	expectRun(t, `a := {b: undefined}; out = len([a?.b?.c])`, nil, 1)
+
+	// coalescing
+	expectRun(t, `out = 0 ?? 1`, nil, 0)
+	expectRun(t, `out = false ?? 1`, nil, false)
+	expectRun(t, `out = undefined ?? undefined ?? 3`, nil, 3)
+	expectRun(t, `
+n := 0
+f := func() { n++ }
+a := 1 ?? f()
+out = n`, nil, 0)
+
+	expectError(t, `a := {}; a?.b = 1`, nil,
+		"optional chaining not allowed in assignment")
+	expectError(t, `a := [0]; a?[0], b := [1, 2]`, nil,
+		"optional chaining not allowed in assignment")
+	expectError(t, `a := {}; a?.b.c = 1`, nil,
+		"optional chaining not allowed in assignment")
+	expectError(t, `a := {b: 1}; a?.b()`, nil, "not callable")
 }
 
 func expectRun(
@@ -3954,6 +4949,10 @@ func objectZeroCopy(o tengo.Object) tengo.Object {
 		return &tengo.Int{}
 	case *tengo.Float:
 		return &tengo.Float{}
+	case *tengo.BigInt:
+		return &tengo.BigInt{Value: new(big.Int)}
+	case *tengo.Decimal:
+		return &tengo.Decimal{Value: new(big.Rat)}
 	case *tengo.Bool:
 		return &tengo.Bool{}
 	case *tengo.Char: